@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/crunchloop/opencligen/internal/gen"
+)
+
+// proxyProbeModule is the module whose versions are listed to check that
+// the module proxy is reachable: a dependency of every generated CLI
+const proxyProbeModule = "github.com/spf13/cobra"
+
+// proxyTimeout bounds the request to each module proxy
+const proxyTimeout = 10 * time.Second
+
+// goVersionPattern matches the major and minor version of a Go toolchain
+// version such as go1.22.5 or devel go1.24-abcdef
+var goVersionPattern = regexp.MustCompile(`go(\d+)\.(\d+)`)
+
+// Outcomes of a doctor check
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "FAIL"
+	checkSkip = "skip"
+)
+
+// checkResult is the outcome of a doctor check, with what to do about it
+// when it is not ok
+type checkResult struct {
+	status string
+	detail string
+	fix    string
+}
+
+// doctorFlags holds the doctor command's flags
+type doctorFlags struct {
+	outDir       string
+	templatesDir string
+	configPath   string
+}
+
+// newDoctorCmd creates the doctor command
+func newDoctorCmd() *cobra.Command {
+	f := &doctorFlags{}
+
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that the environment can generate and build CLIs",
+		Long: `Check the environment opencligen gen depends on, and print how to fix
+each problem found:
+
+- the Go toolchain is installed and recent enough for generated projects
+- the module proxy is reachable, for go mod tidy
+- the --templates overrides parse
+- the --out directory is writable
+
+The output directory and templates are read from .opencligen.yaml when not
+given. The command fails when a check fails.`,
+		Args: cobra.NoArgs,
+		// A failed check is not a usage error
+		SilenceUsage: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return applyProjectConfig(cmd, f.configPath)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(cmd, f)
+		},
+	}
+
+	doctorCmd.Flags().StringVar(&f.outDir, "out", "", "Output directory to check")
+	doctorCmd.Flags().StringVar(&f.templatesDir, "templates", "", "Directory with usage.tmpl and help.tmpl to check")
+	addProjectFlag(doctorCmd, &f.configPath)
+
+	return doctorCmd
+}
+
+func runDoctor(cmd *cobra.Command, f *doctorFlags) error {
+	ctx := cmd.Context()
+	checks := []struct {
+		name string
+		run  func() checkResult
+	}{
+		{"Go toolchain", func() checkResult { return checkGoToolchain(ctx) }},
+		{"Module proxy", func() checkResult { return checkModuleProxy(ctx) }},
+		{"Templates", func() checkResult { return checkTemplates(f.templatesDir) }},
+		{"Output directory", func() checkResult { return checkOutputDir(f.outDir) }},
+	}
+
+	w := cmd.OutOrStdout()
+	failed := 0
+	for _, check := range checks {
+		result := check.run()
+		fmt.Fprintf(w, "%-4s  %s: %s\n", result.status, check.name, result.detail)
+		if result.fix != "" {
+			fmt.Fprintf(w, "      fix: %s\n", result.fix)
+		}
+		if result.status == checkFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// goEnv returns the value of a go env variable, falling back to the
+// environment when the go command can't run
+func goEnv(ctx context.Context, name string) string {
+	out, err := exec.CommandContext(ctx, "go", "env", name).Output()
+	if err != nil {
+		return os.Getenv(name)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// checkGoToolchain checks that go runs and is at least gen.GoVersion
+func checkGoToolchain(ctx context.Context) checkResult {
+	install := "install Go " + gen.GoVersion + " or later from https://go.dev/dl/ and put it on PATH"
+	out, err := exec.CommandContext(ctx, "go", "env", "GOVERSION").Output()
+	if err != nil {
+		return checkResult{checkFail, fmt.Sprintf("go does not run: %v", err), install}
+	}
+	version := strings.TrimSpace(string(out))
+
+	major, minor, ok := parseGoVersion(version)
+	if !ok {
+		return checkResult{checkWarn, fmt.Sprintf("unknown version %q", version), ""}
+	}
+	wantMajor, wantMinor, _ := parseGoVersion("go" + gen.GoVersion)
+	if major < wantMajor || major == wantMajor && minor < wantMinor {
+		return checkResult{checkFail, fmt.Sprintf("%s is older than the Go %s generated projects require", version, gen.GoVersion), install}
+	}
+	return checkResult{checkOK, version, ""}
+}
+
+// parseGoVersion returns the major and minor version of a Go toolchain
+func parseGoVersion(version string) (major, minor int, ok bool) {
+	m := goVersionPattern.FindStringSubmatch(version)
+	if m == nil {
+		return 0, 0, false
+	}
+	major, _ = strconv.Atoi(m[1])
+	minor, _ = strconv.Atoi(m[2])
+	return major, minor, true
+}
+
+// checkModuleProxy checks that go mod tidy can download the dependencies of
+// generated projects: that a proxy of GOPROXY lists the versions of a
+// dependency, or that GOPROXY falls back to direct downloads
+func checkModuleProxy(ctx context.Context) checkResult {
+	goproxy := goEnv(ctx, "GOPROXY")
+	if goproxy == "" {
+		goproxy = "https://proxy.golang.org,direct"
+	}
+	fix := "check your network and HTTPS_PROXY, or set GOPROXY to a reachable proxy (go env -w GOPROXY=https://proxy.golang.org,direct)"
+
+	client := &http.Client{Timeout: proxyTimeout}
+	var problems []string
+	for _, proxy := range strings.FieldsFunc(goproxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		switch proxy {
+		case "off":
+			return checkResult{checkFail, "GOPROXY=off disables module downloads", "unset GOPROXY, or vendor the dependencies of generated projects"}
+		case "direct":
+			// Downloads from version control can't be checked cheaply
+			return checkResult{checkWarn, strings.Join(append(problems, "modules are downloaded directly from version control"), "; "), ""}
+		}
+
+		url := strings.TrimSuffix(proxy, "/") + "/" + proxyProbeModule + "/@v/list"
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", proxy, err))
+			continue
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", proxy, err))
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			problems = append(problems, fmt.Sprintf("%s: %s", proxy, resp.Status))
+			continue
+		}
+		return checkResult{checkOK, proxy + " is reachable", ""}
+	}
+	if len(problems) == 0 {
+		problems = append(problems, "GOPROXY lists no proxy")
+	}
+	return checkResult{checkFail, strings.Join(problems, "; "), fix}
+}
+
+// checkTemplates checks the --templates overrides
+func checkTemplates(dir string) checkResult {
+	if dir == "" {
+		return checkResult{checkSkip, "no --templates directory", ""}
+	}
+	if err := gen.CheckTemplates(dir); err != nil {
+		return checkResult{checkFail, err.Error(), "put valid cobra templates in usage.tmpl or help.tmpl, or drop --templates to use the default help"}
+	}
+	return checkResult{checkOK, dir + " is valid", ""}
+}
+
+// checkOutputDir checks that the --out directory can be written, or created
+// in its nearest existing parent
+func checkOutputDir(dir string) checkResult {
+	switch dir {
+	case "":
+		return checkResult{checkSkip, "no --out directory", ""}
+	case "-":
+		return checkResult{checkSkip, "--out - streams an archive to stdout", ""}
+	}
+	if err := gen.CheckOutDir(dir); err != nil {
+		if errors.Is(err, gen.ErrUnsafeOutDir) {
+			return checkResult{checkWarn, err.Error(), "generate into a subdirectory, or confirm with --yes"}
+		}
+		return checkResult{checkFail, err.Error(), ""}
+	}
+
+	existing := dir
+	for {
+		info, err := os.Stat(existing)
+		if err == nil {
+			if !info.IsDir() {
+				return checkResult{checkFail, existing + " is not a directory", "choose another --out"}
+			}
+			break
+		}
+		parent := filepath.Dir(existing)
+		if !errors.Is(err, os.ErrNotExist) || parent == existing {
+			return checkResult{checkFail, err.Error(), "choose another --out"}
+		}
+		existing = parent
+	}
+
+	probe, err := os.CreateTemp(existing, ".opencligen-doctor-*")
+	if err != nil {
+		return checkResult{checkFail, fmt.Sprintf("%s is not writable: %v", existing, err),
+			fmt.Sprintf("fix the permissions of %s, or choose another --out (or --out - for an archive)", existing)}
+	}
+	probe.Close()
+	_ = os.Remove(probe.Name())
+
+	if existing != dir {
+		return checkResult{checkOK, fmt.Sprintf("%s can be created in %s", dir, existing), ""}
+	}
+	return checkResult{checkOK, dir + " is writable", ""}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/crunchloop/opencligen/internal/plan"
+	"github.com/crunchloop/opencligen/internal/spec"
+)
+
+// initFlags holds the init command's flags
+type initFlags struct {
+	specPath string
+	outPath  string
+	overlay  bool
+	force    bool
+}
+
+// newInitCmd creates the init command
+func newInitCmd() *cobra.Command {
+	f := &initFlags{}
+
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Suggest x-cli annotations for a spec",
+		Long: `Read an OpenAPI spec and suggest x-cli annotations for its operations, as
+a starting point for customizing the generated CLI: a command name and group
+for each operation, short aliases such as ls and rm, and hidden: true for
+operations that look internal (admin, debug, internal or private paths, and
+health and metrics endpoints). Annotations already in the spec are kept.
+
+By default a copy of the spec with the suggestions added is written, as
+YAML; review and edit it, then generate from it. With --overlay, an OpenAPI
+Overlay document holding only the suggestions is written instead, for
+tools that apply overlays to a spec kept unchanged.
+
+  opencligen init --spec api.yaml --out api.cli.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInit(cmd, f)
+		},
+	}
+
+	initCmd.Flags().StringVar(&f.specPath, "spec", "", "Path to OpenAPI spec file (required)")
+	initCmd.Flags().StringVar(&f.outPath, "out", "", "File to write (default: stdout)")
+	initCmd.Flags().BoolVar(&f.overlay, "overlay", false, "Write an OpenAPI Overlay document instead of an annotated copy of the spec")
+	initCmd.Flags().BoolVar(&f.force, "force", false, "Overwrite an existing --out file")
+
+	_ = initCmd.MarkFlagRequired("spec")
+
+	return initCmd
+}
+
+func runInit(cmd *cobra.Command, f *initFlags) error {
+	if _, err := os.Stat(f.specPath); os.IsNotExist(err) {
+		return fmt.Errorf("spec file not found: %s", f.specPath)
+	}
+	if f.outPath != "" && !f.force {
+		if _, err := os.Stat(f.outPath); err == nil {
+			return fmt.Errorf("%s already exists (pass --force to overwrite it)", f.outPath)
+		}
+	}
+
+	logger, _, err := newLogger(cmd, slog.LevelInfo)
+	if err != nil {
+		return err
+	}
+	s, err := spec.Load(context.Background(), f.specPath)
+	if err != nil {
+		return fmt.Errorf("failed to load spec: %w", err)
+	}
+	logWarnings(logger, s.Warnings)
+	suggestions := plan.Suggest(s)
+
+	var out []byte
+	if f.overlay {
+		out, err = overlayDocument(s, suggestions)
+	} else {
+		out, err = annotateSpec(s.Source, suggestions)
+	}
+	if err != nil {
+		return err
+	}
+
+	if f.outPath == "" {
+		_, err = cmd.OutOrStdout().Write(out)
+	} else {
+		err = os.WriteFile(f.outPath, out, 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write annotations: %w", err)
+	}
+	logger.Info("Suggested x-cli annotations", "annotated", len(suggestions), "operations", len(s.Operations))
+	return nil
+}
+
+// overlay is an OpenAPI Overlay 1.0 document
+type overlay struct {
+	Overlay string          `yaml:"overlay"`
+	Info    overlayInfo     `yaml:"info"`
+	Actions []overlayAction `yaml:"actions"`
+}
+
+type overlayInfo struct {
+	Title   string `yaml:"title"`
+	Version string `yaml:"version"`
+}
+
+type overlayAction struct {
+	Target string                       `yaml:"target"`
+	Update map[string]spec.CliOverrides `yaml:"update"`
+}
+
+// overlayDocument returns an overlay that merges each suggestion into the
+// x-cli of its operation
+func overlayDocument(s *spec.Spec, suggestions []plan.Suggestion) ([]byte, error) {
+	doc := overlay{
+		Overlay: "1.0.0",
+		Info:    overlayInfo{Title: "x-cli annotations for " + s.Title, Version: s.Version},
+		Actions: []overlayAction{},
+	}
+	for _, suggestion := range suggestions {
+		doc.Actions = append(doc.Actions, overlayAction{
+			Target: fmt.Sprintf("$.paths['%s'].%s", suggestion.Path, strings.ToLower(suggestion.Method)),
+			Update: map[string]spec.CliOverrides{"x-cli": suggestion.Cli},
+		})
+	}
+	return encodeYAML(&doc)
+}
+
+// annotateSpec adds the suggestions to the x-cli of their operations in
+// source, keeping its order and comments. A JSON spec is returned as YAML.
+func annotateSpec(source []byte, suggestions []plan.Suggestion) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(source, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("the spec is empty")
+	}
+	if trimmed := bytes.TrimSpace(source); len(trimmed) > 0 && trimmed[0] == '{' {
+		blockStyle(&doc)
+	}
+
+	paths := mappingValue(doc.Content[0], "paths")
+	for _, suggestion := range suggestions {
+		op := mappingValue(mappingValue(paths, suggestion.Path), strings.ToLower(suggestion.Method))
+		if op == nil || op.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("cannot annotate %s %s: it is not defined inline in the spec (use --overlay)", suggestion.Method, suggestion.Path)
+		}
+		cli := mappingValue(op, "x-cli")
+		if cli == nil {
+			cli = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			op.Content = append(op.Content, scalarNode("x-cli"), cli)
+		}
+
+		var update yaml.Node
+		if err := update.Encode(suggestion.Cli); err != nil {
+			return nil, err
+		}
+		for i := 0; i+1 < len(update.Content); i += 2 {
+			if update.Content[i].Value == "aliases" {
+				update.Content[i+1].Style = yaml.FlowStyle
+			}
+		}
+		cli.Content = append(cli.Content, update.Content...)
+	}
+	return encodeYAML(&doc)
+}
+
+// mappingValue returns the value of key in mapping, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// blockStyle clears the flow style and quoting that nodes parsed from JSON
+// carry, so they are written as ordinary YAML
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func encodeYAML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// Log formats of --log-format
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// addLogFlags adds the flags that set what opencligen logs, and how, to
+// every command. With JSON logs, cobra's error message is replaced by an
+// error record, see execute.
+func addLogFlags(root *cobra.Command) {
+	root.PersistentFlags().BoolP("quiet", "q", false, "Only log warnings and errors")
+	root.PersistentFlags().BoolP("verbose", "v", false, "Also log debug details")
+	root.PersistentFlags().String("log-format", logFormatText, "Log format: "+logFormatText+" or "+logFormatJSON+" (one JSON object per line, for CI)")
+	root.MarkFlagsMutuallyExclusive("quiet", "verbose")
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if format, _ := cmd.Flags().GetString("log-format"); format == logFormatJSON {
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+		}
+		return nil
+	}
+}
+
+// execute runs the command tree of root, logging the error of a command
+// that silenced cobra's error message for JSON logs
+func execute(root *cobra.Command) error {
+	cmd, err := root.ExecuteC()
+	if err != nil && cmd.SilenceErrors {
+		if logger, _, lerr := newLogger(cmd, slog.LevelInfo); lerr == nil {
+			logger.Error(err.Error())
+		}
+	}
+	return err
+}
+
+// logSettings are the log flags of a command
+type logSettings struct {
+	level  slog.Level
+	format string
+}
+
+// commandLogSettings reads the log flags of cmd. Commands whose output is
+// on stdout log progress only with --verbose, so level is the default level.
+func commandLogSettings(cmd *cobra.Command, level slog.Level) (logSettings, error) {
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	format, _ := cmd.Flags().GetString("log-format")
+	switch {
+	case quiet:
+		level = slog.LevelWarn
+	case verbose:
+		level = slog.LevelDebug
+	}
+	if format != logFormatText && format != logFormatJSON {
+		return logSettings{}, fmt.Errorf("invalid --log-format %q: must be %s or %s", format, logFormatText, logFormatJSON)
+	}
+	return logSettings{level: level, format: format}, nil
+}
+
+// newLogger returns a logger writing to the command's stderr, which keeps
+// stdout for output such as plans and diffs
+func newLogger(cmd *cobra.Command, level slog.Level) (*slog.Logger, logSettings, error) {
+	settings, err := commandLogSettings(cmd, level)
+	if err != nil {
+		return nil, settings, err
+	}
+	w := cmd.ErrOrStderr()
+	if settings.format == logFormatJSON {
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: settings.level})), settings, nil
+	}
+	return slog.New(&textHandler{w: w, level: settings.level, mu: &sync.Mutex{}}), settings, nil
+}
+
+// toolOutput returns where the tools opencligen runs write their output:
+// through to stderr for text logs, or nil to capture it, so that quiet and
+// JSON logs stay clean and the output is only shown when a tool fails
+func (s logSettings) toolOutput(cmd *cobra.Command) io.Writer {
+	if s.format != logFormatText || s.level > slog.LevelInfo {
+		return nil
+	}
+	return cmd.ErrOrStderr()
+}
+
+// runTool runs c with its output going to out, or captured into the error
+// when out is nil
+func runTool(c *exec.Cmd, out io.Writer) error {
+	if out != nil {
+		c.Stdout = out
+		c.Stderr = out
+		return c.Run()
+	}
+	output, err := c.CombinedOutput()
+	if output = bytes.TrimSpace(output); err != nil && len(output) > 0 {
+		return fmt.Errorf("%w:\n%s", err, output)
+	}
+	return err
+}
+
+// textHandler writes log records as plain lines for people: the message
+// and its attributes as key=value, with warnings and errors marked
+type textHandler struct {
+	w      io.Writer
+	level  slog.Level
+	attrs  string // preformatted attributes of With
+	prefix string // group prefix of WithGroup
+	mu     *sync.Mutex
+}
+
+// Enabled reports whether level is logged
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+// Handle writes a record
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	switch {
+	case r.Level >= slog.LevelError:
+		b.WriteString("error: ")
+	case r.Level >= slog.LevelWarn:
+		b.WriteString("warning: ")
+	case r.Level < slog.LevelInfo:
+		b.WriteString("debug: ")
+	}
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, h.prefix, a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+// WithAttrs returns a handler that adds attrs to every record
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, a := range attrs {
+		writeAttr(&b, h.prefix, a)
+	}
+	h2 := *h
+	h2.attrs += b.String()
+	return &h2
+}
+
+// WithGroup returns a handler that qualifies the keys of later attributes
+// with name
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.prefix += name + "."
+	return &h2
+}
+
+// writeAttr writes " key=value", quoting values that need it
+func writeAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			writeAttr(b, prefix, ga)
+		}
+		return
+	}
+	value := a.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	fmt.Fprintf(b, " %s%s=%s", prefix, a.Key, value)
+}
@@ -1,17 +1,22 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/crunchloop/opencligen/internal/gen"
 	"github.com/crunchloop/opencligen/internal/plan"
-	"github.com/crunchloop/opencligen/internal/spec"
 )
 
 // Version information set by ldflags during build
@@ -20,16 +25,34 @@ var (
 	buildTime = "unknown"
 )
 
-var (
-	specPath   string
+// genFlags holds the gen command's flags. Generator settings are bound
+// directly to a gen.Options.
+type genFlags struct {
+	specPaths  []string
 	outDir     string
+	outArchive string
 	appName    string
 	moduleName string
 	doBuild    bool
 	dryRun     bool
-)
+	assumeYes  bool
+	showDiff   bool
+	depsFile   string
+	postGen    []string
+	specHeader []string
+	configPath string
+	filter     plan.Filter
+	options    gen.Options
+}
 
 func main() {
+	if err := execute(newRootCmd()); err != nil {
+		os.Exit(1)
+	}
+}
+
+// newRootCmd creates the opencligen command tree
+func newRootCmd() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:     "opencligen",
 		Short:   "Generate CLI tools from OpenAPI specifications",
@@ -42,6 +65,15 @@ with support for x-cli overrides for customizing names, flags, and configuration
 	// Set version template to include build time
 	rootCmd.SetVersionTemplate(fmt.Sprintf("opencligen version %s (built %s)\n", version, buildTime))
 
+	addLogFlags(rootCmd)
+	rootCmd.AddCommand(newGenCmd(), newPlanCmd(), newInitCmd(), newDoctorCmd())
+	return rootCmd
+}
+
+// newGenCmd creates the gen command
+func newGenCmd() *cobra.Command {
+	f := &genFlags{}
+
 	genCmd := &cobra.Command{
 		Use:   "gen",
 		Short: "Generate a CLI from an OpenAPI spec",
@@ -51,154 +83,330 @@ The generated CLI will have:
 - One command per endpoint
 - Commands grouped by tags
 - Support for x-cli overrides
-- JSON and SSE response handling`,
-		RunE: runGen,
+- JSON and SSE response handling
+
+Flags not given on the command line are read from .opencligen.yaml in the
+working directory, if present, so a project can check in its settings and
+regenerate with a plain "opencligen gen".`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return applyProjectConfig(cmd, f.configPath)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGen(cmd, f)
+		},
 	}
 
-	genCmd.Flags().StringVar(&specPath, "spec", "", "Path to OpenAPI spec file (required)")
-	genCmd.Flags().StringVar(&outDir, "out", "", "Output directory (required)")
-	genCmd.Flags().StringVar(&appName, "name", "", "Application name (required)")
-	genCmd.Flags().StringVar(&moduleName, "module", "", "Go module name (optional, defaults to app name)")
-	genCmd.Flags().BoolVar(&doBuild, "build", false, "Build the generated CLI after generation")
-	genCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print plan without generating files")
+	o := &f.options
+	genCmd.Flags().StringArrayVar(&f.specPaths, "spec", nil, "Path or http(s) URL of the OpenAPI spec (required; repeat as [name=]path to merge several services into one CLI)")
+	genCmd.Flags().StringVar(&f.outDir, "out", "", "Output directory (required unless --out-archive; - streams a .tar.gz archive to stdout)")
+	genCmd.Flags().StringVar(&f.outArchive, "out-archive", "", "Write the generated project to this .tar.gz archive instead of a directory")
+	genCmd.Flags().StringVar(&f.appName, "name", "", "Application name (required)")
+	genCmd.Flags().StringVar(&f.moduleName, "module", "", "Go module name (optional, defaults to app name)")
+	genCmd.Flags().BoolVar(&f.doBuild, "build", false, "Build the generated CLI after generation")
+	genCmd.Flags().BoolVar(&f.dryRun, "dry-run", false, "Print plan without generating files")
+	genCmd.Flags().BoolVar(&f.showDiff, "diff", false, "With --dry-run, print a unified diff against the existing output directory")
+	genCmd.Flags().BoolVar(&o.SharedRuntime, "shared-runtime", false, "Import "+gen.SharedRuntimeModule+" instead of copying the runtime into the project")
+	genCmd.Flags().StringVar(&o.RuntimeVersion, "runtime-version", "", "With --shared-runtime, the runtime module version to require (default: latest)")
+	genCmd.Flags().BoolVar(&o.NoGoMod, "no-gomod", false, "Don't generate go.mod, for output inside an existing module (requires --module)")
+	genCmd.Flags().StringVar(&f.depsFile, "deps-file", "", "With --no-gomod, also write the required dependencies to this file, one module@version per line")
+	genCmd.Flags().BoolVar(&o.WithRelease, "with-release", false, "Add a .goreleaser.yaml and a Dockerfile to the generated project")
+	genCmd.Flags().BoolVar(&o.WithMock, "with-mock", false, "Add a cmd/<name>-mock server that returns example responses for local testing")
+	genCmd.Flags().StringVar(&o.Emit, "emit", gen.EmitPerOperation, "Command file layout: "+gen.EmitPerOperation+" or "+gen.EmitPerGroup+" (one file per tag, for large APIs)")
+	genCmd.Flags().StringVar(&o.FileHeader, "file-header", "", "Comment placed at the top of every generated Go file (e.g. a license notice)")
+	genCmd.Flags().StringVar(&o.TemplatesDir, "templates", "", "Directory with usage.tmpl and help.tmpl cobra templates for the generated CLI")
+	genCmd.Flags().StringVar(&o.BuildTags, "build-tags", "", `Build constraint added to every generated Go file (e.g. "mytag" or "!windows")`)
+	genCmd.Flags().StringVar(&o.StreamBuildTags, "stream-build-tags", "", `Additional build constraint for event-stream command files (e.g. "!js")`)
+	genCmd.Flags().BoolVar(&o.Force, "force", false, "Overwrite generated files even if they were modified since the last generation")
+	genCmd.Flags().StringArrayVar(&f.postGen, "post-gen", nil, "Shell command to run in the output directory after generation (repeatable, e.g. \"gofumpt -w .\")")
+	genCmd.Flags().BoolVarP(&f.assumeYes, "yes", "y", false, "Skip confirmation when generating into a risky location")
+	genCmd.Flags().StringArrayVar(&f.specHeader, "spec-header", nil, `Header sent when fetching a spec URL, as "Name: value" (repeatable, e.g. "Authorization: Bearer $TOKEN")`)
+	addFilterFlags(genCmd, &f.filter)
+	addProjectFlag(genCmd, &f.configPath)
 
 	_ = genCmd.MarkFlagRequired("spec")
-	_ = genCmd.MarkFlagRequired("out")
+	genCmd.MarkFlagsOneRequired("out", "out-archive")
+	genCmd.MarkFlagsMutuallyExclusive("out", "out-archive")
 	_ = genCmd.MarkFlagRequired("name")
 
-	rootCmd.AddCommand(genCmd)
-
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
-	}
+	return genCmd
 }
 
-func runGen(cmd *cobra.Command, args []string) error {
+func runGen(cmd *cobra.Command, f *genFlags) error {
 	ctx := context.Background()
 
-	// Validate spec path
-	if _, err := os.Stat(specPath); os.IsNotExist(err) {
-		return fmt.Errorf("spec file not found: %s", specPath)
+	if f.outDir == "-" {
+		f.outDir, f.outArchive = "", "-"
+	}
+	if f.showDiff && !f.dryRun {
+		return fmt.Errorf("--diff requires --dry-run")
+	}
+	if f.outArchive != "" && (f.showDiff || f.doBuild || len(f.postGen) > 0) {
+		return fmt.Errorf("--diff, --build and --post-gen need an output directory, not an archive")
+	}
+	if f.options.RuntimeVersion != "" && !f.options.SharedRuntime {
+		return fmt.Errorf("--runtime-version requires --shared-runtime")
+	}
+	if f.options.NoGoMod && f.moduleName == "" {
+		return fmt.Errorf("--no-gomod requires --module, the import path of the output directory")
+	}
+	if f.depsFile != "" && !f.options.NoGoMod {
+		return fmt.Errorf("--deps-file requires --no-gomod")
+	}
+	if emit := f.options.Emit; emit != gen.EmitPerOperation && emit != gen.EmitPerGroup {
+		return fmt.Errorf("invalid --emit %q: must be %s or %s", emit, gen.EmitPerOperation, gen.EmitPerGroup)
+	}
+	if err := f.options.Validate(); err != nil {
+		return err
+	}
+	header, err := parseSpecHeaders(f.specHeader)
+	if err != nil {
+		return err
+	}
+	logger, logs, err := newLogger(cmd, slog.LevelInfo)
+	if err != nil {
+		return err
 	}
 
-	// Load and validate spec
-	fmt.Printf("Loading spec from %s...\n", specPath)
-	s, err := spec.Load(ctx, specPath)
+	// Load and validate the specs
+	s, err := loadSpecs(ctx, logger, f.specPaths, header)
 	if err != nil {
-		return fmt.Errorf("failed to load spec: %w", err)
+		return err
 	}
 
-	fmt.Printf("Loaded spec: %s v%s (%d operations)\n", s.Title, s.Version, len(s.Operations))
+	logger.Info("Loaded spec", "title", s.Title, "version", s.Version, "operations", len(s.Operations))
+	logger.Debug("Spec fingerprint", "sha256", s.SHA256)
+
+	if s, err = filterSpec(logger, s, f.filter); err != nil {
+		return err
+	}
 
 	// Set default module name
-	if moduleName == "" {
-		moduleName = appName
+	if f.moduleName == "" {
+		f.moduleName = f.appName
 	}
 
 	// Build plan
-	fmt.Println("Building command plan...")
-	p := plan.Build(s, appName, moduleName)
+	logger.Info("Building command plan")
+	p := plan.Build(s, f.appName, f.moduleName)
+	logWarnings(logger, p.Warnings)
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		commands := 0
+		for _, group := range p.Groups {
+			commands += len(group.Operations)
+		}
+		logger.Debug("Built command plan", "groups", len(p.Groups), "commands", commands)
+	}
 
-	if dryRun {
-		printPlan(p)
+	if f.dryRun {
+		if f.showDiff {
+			return printDiff(cmd.OutOrStdout(), p, f.outDir, f.options)
+		}
+		printPlan(cmd.OutOrStdout(), p)
 		return nil
 	}
 
+	if f.outArchive != "" {
+		return writeArchive(cmd, logger, f, p)
+	}
+
 	// Validate output directory
-	outDir, err = filepath.Abs(outDir)
+	outDir, err := filepath.Abs(f.outDir)
 	if err != nil {
 		return fmt.Errorf("invalid output directory: %w", err)
 	}
 
+	// Refuse obviously dangerous targets unless confirmed
+	if err := gen.CheckOutDir(outDir); err != nil {
+		if !errors.Is(err, gen.ErrUnsafeOutDir) {
+			return err
+		}
+		if !f.assumeYes && !confirm(cmd.InOrStdin(), cmd.ErrOrStderr(), fmt.Sprintf("%v. Generate anyway?", err)) {
+			return fmt.Errorf("%w (pass --yes to proceed)", err)
+		}
+	}
+
 	// Check if output directory is writable
 	if err := os.MkdirAll(outDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	// Generate
-	fmt.Printf("Generating CLI to %s...\n", outDir)
-	generator := gen.New(p, outDir)
+	logger.Info("Generating CLI", "dir", outDir)
+	generator := gen.New(p, outDir, gen.WithOptions(f.options))
 	if err := generator.Generate(); err != nil {
+		if errors.Is(err, gen.ErrModifiedFiles) {
+			return fmt.Errorf("%w (pass --force to overwrite them)", err)
+		}
 		return fmt.Errorf("generation failed: %w", err)
 	}
 
-	fmt.Println("Generation complete!")
+	logger.Info("Generation complete")
 
-	// Run go mod tidy
-	fmt.Println("Running go mod tidy...")
-	tidyCmd := exec.Command("go", "mod", "tidy")
-	tidyCmd.Dir = outDir
-	tidyCmd.Stdout = os.Stdout
-	tidyCmd.Stderr = os.Stderr
-	if err := tidyCmd.Run(); err != nil {
-		return fmt.Errorf("go mod tidy failed: %w", err)
+	toolOutput := logs.toolOutput(cmd)
+	if f.options.NoGoMod {
+		// The enclosing module owns go.mod; tell the user what it needs
+		if err := printRequirements(cmd.OutOrStdout(), logger, generator.Requirements(), f.depsFile); err != nil {
+			return err
+		}
+	} else {
+		// Run go mod tidy
+		logger.Info("Running go mod tidy")
+		tidyCmd := exec.Command("go", "mod", "tidy")
+		tidyCmd.Dir = outDir
+		if err := runTool(tidyCmd, toolOutput); err != nil {
+			return fmt.Errorf("go mod tidy failed (run opencligen doctor to check the environment): %w", err)
+		}
+	}
+
+	// Run post-generation hooks
+	if err := runPostGen(logger, toolOutput, outDir, f.postGen); err != nil {
+		return err
 	}
 
 	// Build if requested
-	if doBuild {
-		fmt.Println("Building CLI...")
-		binaryPath := filepath.Join(outDir, appName)
-		buildCmd := exec.Command("go", "build", "-o", binaryPath, fmt.Sprintf("./cmd/%s", appName))
+	if f.doBuild {
+		logger.Info("Building CLI")
+		binaryPath := filepath.Join(outDir, f.appName)
+		buildCmd := exec.Command("go", "build", "-o", binaryPath, fmt.Sprintf("./cmd/%s", f.appName))
 		buildCmd.Dir = outDir
-		buildCmd.Stdout = os.Stdout
-		buildCmd.Stderr = os.Stderr
-		if err := buildCmd.Run(); err != nil {
-			return fmt.Errorf("build failed: %w", err)
+		if err := runTool(buildCmd, toolOutput); err != nil {
+			return fmt.Errorf("build failed (run opencligen doctor to check the environment): %w", err)
 		}
-		fmt.Printf("Built binary: %s\n", binaryPath)
+		logger.Info("Built binary", "path", binaryPath)
 	}
 
-	fmt.Println("Done!")
+	logger.Info("Done")
 	return nil
 }
 
-func printPlan(p *plan.Plan) {
-	fmt.Printf("\n=== Command Plan for %s ===\n\n", p.AppName)
-	fmt.Printf("Module: %s\n\n", p.ModuleName)
-
-	for gi := range p.Groups {
-		group := &p.Groups[gi]
-		fmt.Printf("Group: %s\n", group.Name)
-		for oi := range group.Operations {
-			op := &group.Operations[oi]
-			cmdPath := ""
-			for i, part := range op.CommandPath {
-				if i > 0 {
-					cmdPath += " "
-				}
-				cmdPath += part
+// writeArchive writes the generated project to the --out-archive file, or
+// to stdout for "-". go mod tidy needs a directory, so the archive has no
+// go.sum.
+func writeArchive(cmd *cobra.Command, logger *slog.Logger, f *genFlags, p *plan.Plan) (err error) {
+	w, out := cmd.OutOrStdout(), cmd.OutOrStdout()
+	if f.outArchive == "-" {
+		// Stdout carries the archive
+		out = cmd.ErrOrStderr()
+		logger.Info("Generating CLI archive", "path", "stdout")
+	} else {
+		file, createErr := os.Create(f.outArchive)
+		if createErr != nil {
+			return fmt.Errorf("failed to create archive: %w", createErr)
+		}
+		defer func() {
+			if cerr := file.Close(); err == nil {
+				err = cerr
 			}
-
-			flags := ""
-			for fi := range op.Flags {
-				f := &op.Flags[fi]
-				if flags != "" {
-					flags += ", "
-				}
-				req := ""
-				if f.Required {
-					req = "*"
-				}
-				flags += fmt.Sprintf("--%s%s", f.FlagName, req)
+			// Don't leave a broken archive behind
+			if err != nil {
+				_ = os.Remove(f.outArchive)
 			}
+		}()
+		w = file
+		logger.Info("Generating CLI archive", "path", f.outArchive)
+	}
 
-			positionals := ""
-			for pi := range op.Positionals {
-				pos := &op.Positionals[pi]
-				positionals += fmt.Sprintf(" <%s>", pos.Name)
-			}
+	generator := gen.New(p, "", gen.WithOptions(f.options))
+	if err := generator.WriteArchive(w); err != nil {
+		return fmt.Errorf("generation failed: %w", err)
+	}
 
-			stream := ""
-			if op.IsEventStream {
-				stream = " [SSE]"
-			}
+	if f.options.NoGoMod {
+		if err := printRequirements(out, logger, generator.Requirements(), f.depsFile); err != nil {
+			return err
+		}
+	} else {
+		logger.Info("The archive has no go.sum: run go mod tidy after extracting it")
+	}
+	logger.Info("Done")
+	return nil
+}
 
-			fmt.Printf("  %s%s%s\n", cmdPath, positionals, stream)
-			if flags != "" {
-				fmt.Printf("    Flags: %s\n", flags)
-			}
-			fmt.Printf("    %s %s\n", op.Method, op.Path)
+// runPostGen runs each command through the shell in dir, stopping at the
+// first one that fails. The commands write their output to out, see runTool.
+func runPostGen(logger *slog.Logger, out io.Writer, dir string, commands []string) error {
+	for _, command := range commands {
+		logger.Info("Running post-gen command", "command", command)
+		hookCmd := shellCommand(command)
+		hookCmd.Dir = dir
+		if err := runTool(hookCmd, out); err != nil {
+			return fmt.Errorf("post-gen command %q failed: %w", command, err)
+		}
+	}
+	return nil
+}
+
+// shellCommand returns a command that runs command with the platform shell
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// printRequirements prints the dependencies the enclosing module must
+// require to w, and writes them to depsFile when set
+func printRequirements(w io.Writer, logger *slog.Logger, reqs []gen.Requirement, depsFile string) error {
+	fmt.Fprintln(w, "Skipped go.mod. Add these dependencies to your module:")
+	var lines strings.Builder
+	for _, r := range reqs {
+		fmt.Fprintf(w, "  go get %s\n", r)
+		lines.WriteString(r.String() + "\n")
+	}
+
+	if depsFile != "" {
+		if err := os.WriteFile(depsFile, []byte(lines.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write dependencies file: %w", err)
 		}
-		fmt.Println()
+		logger.Info("Wrote dependencies", "path", depsFile)
+	}
+	return nil
+}
+
+// printDiff renders the CLI in memory and prints a unified diff against the
+// current contents of dir to w
+func printDiff(w io.Writer, p *plan.Plan, dir string, opts gen.Options) error {
+	files, err := gen.New(p, dir, gen.WithOptions(opts)).Render()
+	if err != nil {
+		return fmt.Errorf("generation failed: %w", err)
+	}
+
+	diff, err := files.Diff(dir)
+	if err != nil {
+		return err
+	}
+
+	if diff == "" {
+		fmt.Fprintln(w, "No changes.")
+		return nil
+	}
+
+	fmt.Fprint(w, diff)
+	return nil
+}
+
+// confirm asks a yes/no question on out and reads the answer from in.
+// It returns false without prompting when in is not an interactive terminal.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	if f, ok := in.(*os.File); !ok || !isTerminal(f) {
+		return false
+	}
+
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// isTerminal reports whether f is attached to a character device
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
 	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
@@ -1,13 +1,25 @@
 package main
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/crunchloop/opencligen/internal/plan"
+	"github.com/crunchloop/opencligen/internal/spec"
 )
 
 // executeCommand runs the command with the given args and returns output
@@ -23,47 +35,7 @@ func executeCommand(root *cobra.Command, args ...string) (output string, err err
 
 // createTestCommand creates a fresh instance of the CLI for testing
 func createTestCommand() *cobra.Command {
-	var (
-		testSpecPath   string
-		testOutDir     string
-		testAppName    string
-		testModuleName string
-		testDryRun     bool
-	)
-
-	rootCmd := &cobra.Command{
-		Use:   "opencligen",
-		Short: "Generate CLI tools from OpenAPI specifications",
-	}
-
-	genCmd := &cobra.Command{
-		Use:   "gen",
-		Short: "Generate a CLI from an OpenAPI spec",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			// Reset global vars for testing
-			specPath = testSpecPath
-			outDir = testOutDir
-			appName = testAppName
-			moduleName = testModuleName
-			dryRun = testDryRun
-			doBuild = false
-
-			return runGen(cmd, args)
-		},
-	}
-
-	genCmd.Flags().StringVar(&testSpecPath, "spec", "", "Path to OpenAPI spec file (required)")
-	genCmd.Flags().StringVar(&testOutDir, "out", "", "Output directory (required)")
-	genCmd.Flags().StringVar(&testAppName, "name", "", "Application name (required)")
-	genCmd.Flags().StringVar(&testModuleName, "module", "", "Go module name (optional)")
-	genCmd.Flags().BoolVar(&testDryRun, "dry-run", false, "Print plan without generating files")
-
-	_ = genCmd.MarkFlagRequired("spec")
-	_ = genCmd.MarkFlagRequired("out")
-	_ = genCmd.MarkFlagRequired("name")
-
-	rootCmd.AddCommand(genCmd)
-	return rootCmd
+	return newRootCmd()
 }
 
 func TestGen_MissingRequiredFlags(t *testing.T) {
@@ -85,7 +57,7 @@ func TestGen_MissingRequiredFlags(t *testing.T) {
 		{
 			name:       "missing out flag",
 			args:       []string{"gen", "--spec", "test.json", "--name", "myapp"},
-			wantErrMsg: `required flag(s) "out" not set`,
+			wantErrMsg: "at least one of the flags in the group [out out-archive] is required",
 		},
 		{
 			name:       "missing name flag",
@@ -203,6 +175,97 @@ func TestGen_FullGeneration(t *testing.T) {
 	}
 }
 
+func TestGen_NoGoMod(t *testing.T) {
+	testSpecPath := filepath.Join("..", "..", "internal", "testdata", "dap.json")
+	tmpDir := t.TempDir()
+	depsPath := filepath.Join(t.TempDir(), "deps.txt")
+
+	_, err := executeCommand(createTestCommand(),
+		"gen",
+		"--spec", testSpecPath,
+		"--out", tmpDir,
+		"--name", "testcli",
+		"--module", "github.com/test/mono/tools/testcli",
+		"--no-gomod",
+		"--deps-file", depsPath,
+	)
+	if err != nil {
+		t.Fatalf("generation failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "go.mod")); !os.IsNotExist(err) {
+		t.Error("expected go.mod not to be generated")
+	}
+
+	deps, err := os.ReadFile(depsPath)
+	if err != nil {
+		t.Fatalf("failed to read dependencies file: %v", err)
+	}
+	if !strings.Contains(string(deps), "github.com/spf13/cobra@v1.10.2\n") {
+		t.Errorf("expected cobra in the dependencies file, got:\n%s", deps)
+	}
+}
+
+func TestGen_PostGen(t *testing.T) {
+	testSpecPath := filepath.Join("..", "..", "internal", "testdata", "dap.json")
+	tmpDir := t.TempDir()
+
+	_, err := executeCommand(createTestCommand(),
+		"gen",
+		"--spec", testSpecPath,
+		"--out", tmpDir,
+		"--name", "testcli",
+		"--module", "github.com/test/mono/tools/testcli",
+		"--no-gomod",
+		"--post-gen", "echo first > hooks.txt",
+		"--post-gen", "echo second >> hooks.txt",
+	)
+	if err != nil {
+		t.Fatalf("generation failed: %v", err)
+	}
+
+	// Hooks run in order, in the output directory
+	out, err := os.ReadFile(filepath.Join(tmpDir, "hooks.txt"))
+	if err != nil {
+		t.Fatalf("post-gen hooks did not run in the output directory: %v", err)
+	}
+	if got := strings.Fields(string(out)); strings.Join(got, " ") != "first second" {
+		t.Errorf("hooks.txt = %q, want first then second", out)
+	}
+}
+
+func TestGen_PostGenFailure(t *testing.T) {
+	testSpecPath := filepath.Join("..", "..", "internal", "testdata", "dap.json")
+
+	_, err := executeCommand(createTestCommand(),
+		"gen",
+		"--spec", testSpecPath,
+		"--out", t.TempDir(),
+		"--name", "testcli",
+		"--module", "github.com/test/mono/tools/testcli",
+		"--no-gomod",
+		"--post-gen", "exit 3",
+	)
+
+	if err == nil || !strings.Contains(err.Error(), `post-gen command "exit 3" failed`) {
+		t.Errorf("expected post-gen failure, got: %v", err)
+	}
+}
+
+func TestGen_NoGoModRequiresModule(t *testing.T) {
+	_, err := executeCommand(createTestCommand(),
+		"gen",
+		"--spec", "spec.json",
+		"--out", t.TempDir(),
+		"--name", "myapp",
+		"--no-gomod",
+	)
+
+	if err == nil || !strings.Contains(err.Error(), "--no-gomod requires --module") {
+		t.Errorf("expected '--no-gomod requires --module' error, got: %v", err)
+	}
+}
+
 func TestGen_InvalidSpec(t *testing.T) {
 	// Create a temp file with invalid JSON
 	tmpDir := t.TempDir()
@@ -264,3 +327,627 @@ func TestGen_DefaultModuleName(t *testing.T) {
 		t.Errorf("expected go.mod to contain 'module myapp', got: %s", string(content))
 	}
 }
+
+func TestGen_RefusesHomeDirectory(t *testing.T) {
+	testSpecPath := filepath.Join("..", "..", "internal", "testdata", "dap.json")
+
+	if _, err := os.Stat(testSpecPath); os.IsNotExist(err) {
+		t.Skipf("test spec file not found at %s", testSpecPath)
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cmd := createTestCommand()
+
+	_, err := executeCommand(cmd,
+		"gen",
+		"--spec", testSpecPath,
+		"--out", home,
+		"--name", "myapp",
+	)
+
+	if err == nil {
+		t.Fatal("expected error when generating into the home directory")
+	}
+
+	if !strings.Contains(err.Error(), "home directory") {
+		t.Errorf("expected 'home directory' error, got: %v", err)
+	}
+
+	entries, _ := os.ReadDir(home)
+	if len(entries) > 0 {
+		t.Error("expected no files to be written into the home directory")
+	}
+}
+
+func TestGen_DiffRequiresDryRun(t *testing.T) {
+	cmd := createTestCommand()
+
+	_, err := executeCommand(cmd,
+		"gen",
+		"--spec", "spec.json",
+		"--out", t.TempDir(),
+		"--name", "myapp",
+		"--diff",
+	)
+
+	if err == nil || !strings.Contains(err.Error(), "--diff requires --dry-run") {
+		t.Errorf("expected '--diff requires --dry-run' error, got: %v", err)
+	}
+}
+
+func TestGen_InvalidEmit(t *testing.T) {
+	cmd := createTestCommand()
+
+	_, err := executeCommand(cmd,
+		"gen",
+		"--spec", "spec.json",
+		"--out", t.TempDir(),
+		"--name", "myapp",
+		"--emit", "per-file",
+	)
+
+	if err == nil || !strings.Contains(err.Error(), "invalid --emit") {
+		t.Errorf("expected 'invalid --emit' error, got: %v", err)
+	}
+}
+
+func TestGen_DryRunDiff(t *testing.T) {
+	testSpecPath := filepath.Join("..", "..", "internal", "testdata", "dap.json")
+
+	if _, err := os.Stat(testSpecPath); os.IsNotExist(err) {
+		t.Skipf("test spec file not found at %s", testSpecPath)
+	}
+
+	tmpDir := t.TempDir()
+
+	_, err := executeCommand(createTestCommand(),
+		"gen",
+		"--spec", testSpecPath,
+		"--out", tmpDir,
+		"--name", "testcli",
+		"--dry-run",
+		"--diff",
+	)
+	if err != nil {
+		t.Fatalf("dry-run diff failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to read temp dir: %v", err)
+	}
+	if len(entries) > 0 {
+		t.Error("expected no files to be created during dry-run diff")
+	}
+}
+
+func TestPlan_Formats(t *testing.T) {
+	testSpecPath := filepath.Join("..", "..", "internal", "testdata", "openapi30.yaml")
+
+	output, err := executeCommand(createTestCommand(), "plan", "--spec", testSpecPath, "--name", "bookmarks")
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	var outline planOutline
+	if err := yaml.Unmarshal([]byte(output), &outline); err != nil {
+		t.Fatalf("expected YAML, got %v:\n%s", err, output)
+	}
+	if outline.App != "bookmarks" || len(outline.Groups) == 0 {
+		t.Fatalf("unexpected outline: %+v", outline)
+	}
+	var found bool
+	for _, group := range outline.Groups {
+		for _, c := range group.Commands {
+			if c.Command == "bookmarks get" {
+				found = c.Method == "GET" && c.Path == "/bookmarks/{bookmarkId}" && len(c.Args) == 1
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected the bookmarks get command in the outline:\n%s", output)
+	}
+	if strings.Contains(output, "openapi:") {
+		t.Error("expected the spec source to be left out")
+	}
+
+	output, err = executeCommand(createTestCommand(), "plan", "--spec", testSpecPath, "--name", "bookmarks", "--format", "json")
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	var fromJSON planOutline
+	if err := json.Unmarshal([]byte(output), &fromJSON); err != nil {
+		t.Fatalf("expected JSON, got %v:\n%s", err, output)
+	}
+	if len(fromJSON.Groups) != len(outline.Groups) {
+		t.Errorf("expected the same outline as JSON and YAML")
+	}
+
+	output, err = executeCommand(createTestCommand(), "plan", "--spec", testSpecPath, "--name", "bookmarks", "--format", "text")
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if !strings.Contains(output, "=== Command Plan for bookmarks ===") || !strings.Contains(output, "  bookmarks get <bookmarkId>\n") {
+		t.Errorf("expected the plan tree, got:\n%s", output)
+	}
+}
+
+func TestPlan_InvalidFlags(t *testing.T) {
+	testSpecPath := filepath.Join("..", "..", "internal", "testdata", "openapi30.yaml")
+	tests := []struct {
+		args       []string
+		wantErrMsg string
+	}{
+		{[]string{"plan", "--name", "bookmarks"}, `required flag(s) "spec" not set`},
+		{[]string{"plan", "--spec", testSpecPath}, `required flag(s) "name" not set`},
+		{[]string{"plan", "--spec", testSpecPath, "--name", "bookmarks", "--format", "xml"}, `invalid --format "xml"`},
+		{[]string{"plan", "--spec", "/nonexistent/spec.yaml", "--name", "bookmarks"}, "spec file not found"},
+	}
+	for _, tt := range tests {
+		_, err := executeCommand(createTestCommand(), tt.args...)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+			t.Errorf("%v: expected an error containing %q, got %v", tt.args, tt.wantErrMsg, err)
+		}
+	}
+}
+
+func TestInit_AnnotatedCopy(t *testing.T) {
+	testSpecPath := filepath.Join("..", "..", "internal", "testdata", "dap.json")
+	outPath := filepath.Join(t.TempDir(), "dap.cli.yaml")
+
+	output, err := executeCommand(createTestCommand(), "init", "--spec", testSpecPath, "--out", outPath)
+	if err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	if !strings.Contains(output, "Suggested x-cli annotations annotated=8 operations=8\n") {
+		t.Errorf("expected a summary, got:\n%s", output)
+	}
+
+	// The copy is a valid spec whose plan uses the suggestions
+	s, err := spec.Load(context.Background(), outPath)
+	if err != nil {
+		t.Fatalf("failed to load the annotated spec: %v", err)
+	}
+	p := plan.Build(s, "dap", "dap")
+	var found bool
+	for _, group := range p.Groups {
+		for _, op := range group.Operations {
+			if op.OperationID == "listTasks" {
+				found = strings.Join(op.CommandPath, " ") == "tasks list" && len(op.Aliases) == 1 && op.Aliases[0] == "ls"
+			}
+		}
+	}
+	if !found {
+		t.Error("expected listTasks to be tasks list with the ls alias")
+	}
+
+	// An existing file is only replaced with --force
+	if _, err := executeCommand(createTestCommand(), "init", "--spec", testSpecPath, "--out", outPath); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected the existing file to be refused, got %v", err)
+	}
+	if _, err := executeCommand(createTestCommand(), "init", "--spec", testSpecPath, "--out", outPath, "--force"); err != nil {
+		t.Errorf("expected --force to overwrite the file, got %v", err)
+	}
+}
+
+func TestInit_Overlay(t *testing.T) {
+	testSpecPath := filepath.Join("..", "..", "internal", "testdata", "openapi30.yaml")
+	outPath := filepath.Join(t.TempDir(), "overlay.yaml")
+
+	if _, err := executeCommand(createTestCommand(), "init", "--spec", testSpecPath, "--overlay", "--out", outPath); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc overlay
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("expected YAML, got %v:\n%s", err, data)
+	}
+	if doc.Overlay != "1.0.0" || len(doc.Actions) != 9 {
+		t.Fatalf("unexpected overlay:\n%s", data)
+	}
+	action := doc.Actions[5]
+	if action.Target != "$.paths['/bookmarks/{bookmarkId}/archive'].post" || action.Update["x-cli"].Name != "bookmarks archive" {
+		t.Errorf("unexpected action %+v", action)
+	}
+}
+
+func TestProjectConfig(t *testing.T) {
+	testSpecPath, err := filepath.Abs(filepath.Join("..", "..", "internal", "testdata", "openapi30.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "api.yaml"), mustReadFile(t, testSpecPath), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(dir, projectConfigFile)
+	config := "spec: api.yaml\nname: bookmarks\nmodule: github.com/acme/bookmarks\nemit: per-group\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The spec path is relative to the config file
+	output, err := executeCommand(createTestCommand(), "plan", "--config", configPath)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	var outline planOutline
+	if err := yaml.Unmarshal([]byte(output), &outline); err != nil {
+		t.Fatalf("expected YAML, got %v:\n%s", err, output)
+	}
+	if outline.App != "bookmarks" || outline.Module != "github.com/acme/bookmarks" {
+		t.Errorf("expected the app and module from the project config, got %s and %s", outline.App, outline.Module)
+	}
+
+	// Flags take precedence
+	output, err = executeCommand(createTestCommand(), "plan", "--config", configPath, "--name", "bm")
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if !strings.Contains(output, "app: bm\n") {
+		t.Errorf("expected --name to override the project config, got:\n%s", output)
+	}
+
+	// The config in the working directory is read by default
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	if _, err := executeCommand(createTestCommand(), "plan"); err != nil {
+		t.Errorf("expected plan to use %s, got %v", projectConfigFile, err)
+	}
+}
+
+func TestProjectConfig_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		config     string
+		wantErrMsg string
+	}{
+		{"spec: api.yaml\nnmae: bookmarks\n", "field nmae not found"},
+		{"spec: api.yaml\nname: bookmarks\nwith_mock: sometimes\n", "cannot unmarshal"},
+		{"spec: api.yaml\nname: bookmarks\nout: out\nemit: per-file\n", `invalid --emit "per-file"`},
+	}
+	for _, tt := range tests {
+		configPath := filepath.Join(dir, projectConfigFile)
+		if err := os.WriteFile(configPath, []byte(tt.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := executeCommand(createTestCommand(), "gen", "--config", configPath)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErrMsg) {
+			t.Errorf("%q: expected an error containing %q, got %v", tt.config, tt.wantErrMsg, err)
+		}
+	}
+
+	if _, err := executeCommand(createTestCommand(), "plan", "--config", filepath.Join(dir, "missing.yaml")); err == nil || !strings.Contains(err.Error(), "failed to read project config") {
+		t.Errorf("expected a missing --config file to be an error, got %v", err)
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestPlan_MergedSpecs(t *testing.T) {
+	testdata := filepath.Join("..", "..", "internal", "testdata")
+	output, err := executeCommand(createTestCommand(), "plan", "--name", "platform",
+		"--spec", filepath.Join(testdata, "openapi30.yaml"), "--spec", "tasks="+filepath.Join(testdata, "dap.json"))
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	var outline planOutline
+	if err := yaml.Unmarshal([]byte(output), &outline); err != nil {
+		t.Fatalf("expected YAML, got %v:\n%s", err, output)
+	}
+	services := map[string]bool{}
+	for _, group := range outline.Groups {
+		services[group.Service] = true
+		if !strings.HasPrefix(group.Commands[0].Command, group.Service+" "+group.Name+" ") {
+			t.Errorf("expected the commands of %s %s under the service, got %q", group.Service, group.Name, group.Commands[0].Command)
+		}
+	}
+	if len(services) != 2 || !services["openapi30"] || !services["tasks"] {
+		t.Errorf("expected the openapi30 and tasks services, got %v", services)
+	}
+}
+
+func TestServiceName(t *testing.T) {
+	tests := map[string]string{
+		"specs/billing.yaml":                           "billing",
+		"specs/User Accounts.json":                     "user-accounts",
+		"services/payments/api.yaml":                   "payments",
+		"openapi.yaml":                                 "openapi",
+		"2fa_v1.yaml":                                  "fa-v1",
+		"https://example.com/billing/openapi.json?v=2": "billing",
+	}
+	for path, want := range tests {
+		if got := serviceName(filepath.FromSlash(path)); got != want {
+			t.Errorf("serviceName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestProjectConfig_SpecList(t *testing.T) {
+	var c projectConfig
+	if err := yaml.Unmarshal([]byte("spec:\n  - billing=specs/billing.yaml\n  - /abs/users.yaml\n"), &c); err != nil {
+		t.Fatal(err)
+	}
+	settings := c.settings("repo")
+	want := []string{"billing=" + filepath.Join("repo", "specs", "billing.yaml"), "/abs/users.yaml"}
+	if len(settings) != 1 || settings[0].flag != "spec" || strings.Join(settings[0].values, ",") != strings.Join(want, ",") {
+		t.Errorf("expected the spec paths resolved against the config, got %+v", settings)
+	}
+}
+
+func TestPlan_Filters(t *testing.T) {
+	testSpecPath := filepath.Join("..", "..", "internal", "testdata", "openapi30.yaml")
+	output, err := executeCommand(createTestCommand(), "plan", "--name", "bm", "--spec", testSpecPath,
+		"--include-tag", "bookmarks,folders", "--include-operation", "getExport", "--exclude-path-prefix", "/bookmarks/{bookmarkId}")
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	var outline planOutline
+	if err := yaml.Unmarshal([]byte(output), &outline); err != nil {
+		t.Fatalf("expected YAML, got %v:\n%s", err, output)
+	}
+	var commands []string
+	for _, group := range outline.Groups {
+		for _, command := range group.Commands {
+			commands = append(commands, command.Command)
+		}
+	}
+	want := "bookmarks list, bookmarks create, export get, folders list"
+	if got := strings.Join(commands, ", "); got != want {
+		t.Errorf("commands = %s, want %s", got, want)
+	}
+
+	// An included tag that matches nothing is most likely a typo
+	_, err = executeCommand(createTestCommand(), "gen", "--name", "bm", "--spec", testSpecPath,
+		"--out", t.TempDir(), "--dry-run", "--include-tag", "bookmark")
+	if err == nil || !strings.Contains(err.Error(), `included tag "bookmark" matches no operations`) {
+		t.Errorf("expected an unmatched tag error, got %v", err)
+	}
+}
+
+func TestProjectConfig_Filters(t *testing.T) {
+	var c projectConfig
+	config := "include_tag: [bookmarks]\nexclude_path_prefix:\n  - /admin\n  - /internal\n"
+	if err := yaml.Unmarshal([]byte(config), &c); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, setting := range c.settings("repo") {
+		got = append(got, setting.flag+"="+strings.Join(setting.values, ","))
+	}
+	if want := "include-tag=bookmarks exclude-path-prefix=/admin,/internal"; strings.Join(got, " ") != want {
+		t.Errorf("settings = %s, want %s", strings.Join(got, " "), want)
+	}
+}
+
+func TestPlan_SpecURL(t *testing.T) {
+	data := mustReadFile(t, filepath.Join("..", "..", "internal", "testdata", "openapi30.yaml"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Team") != "cli" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	output, err := executeCommand(createTestCommand(), "plan", "--name", "bm", "--spec", server.URL+"/openapi.yaml",
+		"--spec-header", "Authorization: Bearer secret", "--spec-header", "X-Team:cli")
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if !strings.Contains(output, "title: Bookmarks API") {
+		t.Errorf("expected the plan of the fetched spec, got:\n%s", output)
+	}
+
+	_, err = executeCommand(createTestCommand(), "plan", "--name", "bm", "--spec", server.URL+"/openapi.yaml",
+		"--spec-header", "Bearer secret")
+	if err == nil || !strings.Contains(err.Error(), `invalid --spec-header "Bearer secret"`) {
+		t.Errorf("expected an invalid header error, got %v", err)
+	}
+}
+
+func TestGen_Logging(t *testing.T) {
+	specPath := filepath.Join(t.TempDir(), "api.yaml")
+	if err := os.WriteFile(specPath, []byte(`openapi: 3.0.3
+info:
+  title: Health API
+  version: 1.0.0
+servers:
+  - url: /v1
+paths:
+  /health:
+    get:
+      responses:
+        "200":
+          description: OK
+  /status:
+    get:
+      operationId: getStatus
+      responses:
+        "200":
+          description: OK
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) (stdout, stderr string, err error) {
+		var out, errOut bytes.Buffer
+		root := createTestCommand()
+		root.SetOut(&out)
+		root.SetErr(&errOut)
+		root.SetArgs(append([]string{"gen", "--spec", specPath, "--out", t.TempDir(), "--name", "health", "--dry-run"}, args...))
+		err = execute(root)
+		return out.String(), errOut.String(), err
+	}
+	warning := "warning: GET /health has neither an operationId nor an x-cli name to name its command after\n"
+
+	// Progress and warnings go to stderr, the plan to stdout
+	stdout, stderr, err := run()
+	if err != nil {
+		t.Fatalf("gen failed: %v", err)
+	}
+	for _, want := range []string{"Loading spec path=" + specPath + "\n", "Loaded spec title=\"Health API\" version=1.0.0 operations=2\n", warning,
+		"warning: server /v1 is relative and cannot be selected with --server\n"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("expected stderr to contain %q, got:\n%s", want, stderr)
+		}
+	}
+	if !strings.Contains(stdout, "=== Command Plan for health ===") || strings.Contains(stdout, "Loading spec") {
+		t.Errorf("expected only the plan on stdout, got:\n%s", stdout)
+	}
+
+	// --quiet leaves the warnings, -v adds debug details
+	if _, stderr, _ = run("--quiet"); strings.Contains(stderr, "Loading spec") || !strings.Contains(stderr, warning) {
+		t.Errorf("expected only warnings with --quiet, got:\n%s", stderr)
+	}
+	if _, stderr, _ = run("-v"); !strings.Contains(stderr, "debug: Built command plan groups=1 commands=2\n") {
+		t.Errorf("expected debug details with -v, got:\n%s", stderr)
+	}
+	if _, _, err = run("-q", "-v"); err == nil {
+		t.Error("expected --quiet and --verbose to be mutually exclusive")
+	}
+
+	// JSON logs are a record per line, also for the error
+	_, stderr, err = run("--log-format", "json", "--include-tag", "missing")
+	if err == nil {
+		t.Fatal("expected the unmatched tag to fail")
+	}
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	for _, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("expected a JSON record, got %q", line)
+		}
+	}
+	if last := lines[len(lines)-1]; !strings.Contains(last, `"level":"ERROR","msg":"included tag \"missing\" matches no operations"`) {
+		t.Errorf("expected the error as the last record, got %s", last)
+	}
+}
+
+// archiveNames returns the names of the entries of a .tar.gz archive
+func archiveNames(t *testing.T, archive []byte) []string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("expected a gzipped archive: %v", err)
+	}
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatalf("invalid archive: %v", err)
+		}
+		names = append(names, hdr.Name)
+	}
+}
+
+func TestGen_OutArchive(t *testing.T) {
+	testSpecPath, err := filepath.Abs(filepath.Join("..", "..", "internal", "testdata", "openapi30.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	// To a file, which takes precedence over out in the project config
+	configPath := filepath.Join(dir, projectConfigFile)
+	if err := os.WriteFile(configPath, []byte("out: ./cli\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archivePath := filepath.Join(dir, "cli.tar.gz")
+	if _, err := executeCommand(createTestCommand(), "gen", "--config", configPath, "--spec", testSpecPath,
+		"--name", "bm", "--out-archive", archivePath); err != nil {
+		t.Fatalf("gen failed: %v", err)
+	}
+	names := archiveNames(t, mustReadFile(t, archivePath))
+	if !slices.Contains(names, "bm/go.mod") || !slices.Contains(names, "bm/internal/commands/root.go") {
+		t.Errorf("expected the project below bm/, got %v", names)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cli")); !os.IsNotExist(err) {
+		t.Errorf("expected no output directory, got %v", err)
+	}
+
+	// To stdout, with the logs on stderr
+	var stdout, stderr bytes.Buffer
+	root := createTestCommand()
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetArgs([]string{"gen", "--spec", testSpecPath, "--name", "bm", "--out", "-"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("gen failed: %v", err)
+	}
+	if names := archiveNames(t, stdout.Bytes()); !slices.Contains(names, "bm/go.mod") {
+		t.Errorf("expected the archive on stdout, got %v", names)
+	}
+	if !strings.Contains(stderr.String(), "Generating CLI archive path=stdout") {
+		t.Errorf("expected the logs on stderr, got:\n%s", stderr.String())
+	}
+
+	_, err = executeCommand(createTestCommand(), "gen", "--spec", testSpecPath, "--name", "bm", "--out", "-", "--build")
+	if err == nil || !strings.Contains(err.Error(), "need an output directory") {
+		t.Errorf("expected --build to need a directory, got %v", err)
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/github.com/spf13/cobra/@v/list" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("v1.10.2\n"))
+	}))
+	defer proxy.Close()
+	t.Setenv("GOPROXY", proxy.URL)
+
+	templates := t.TempDir()
+	if err := os.WriteFile(filepath.Join(templates, "usage.tmpl"), []byte("{{.UseLine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "cli")
+
+	output, err := executeCommand(createTestCommand(), "doctor", "--out", out, "--templates", templates)
+	if err == nil || err.Error() != "1 of 4 checks failed" {
+		t.Errorf("expected the templates check to fail, got %v", err)
+	}
+	for _, want := range []string{
+		"ok    Go toolchain: go",
+		"ok    Module proxy: " + proxy.URL + " is reachable\n",
+		"FAIL  Templates: invalid usage template",
+		"      fix: put valid cobra templates in usage.tmpl or help.tmpl",
+		"ok    Output directory: " + out + " can be created in ",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected the output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	// Downloads are turned off
+	t.Setenv("GOPROXY", "off")
+	output, err = executeCommand(createTestCommand(), "doctor")
+	if err == nil || !strings.Contains(output, "FAIL  Module proxy: GOPROXY=off disables module downloads\n") {
+		t.Errorf("expected the proxy check to fail, got %v:\n%s", err, output)
+	}
+	if !strings.Contains(output, "skip  Templates: no --templates directory\n") {
+		t.Errorf("expected the templates check to be skipped, got:\n%s", output)
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/crunchloop/opencligen/internal/plan"
+)
+
+// Formats of the plan command
+const (
+	planFormatYAML = "yaml"
+	planFormatJSON = "json"
+	planFormatText = "text"
+)
+
+// planFlags holds the plan command's flags
+type planFlags struct {
+	specPaths  []string
+	appName    string
+	moduleName string
+	format     string
+	specHeader []string
+	configPath string
+	filter     plan.Filter
+}
+
+// newPlanCmd creates the plan command
+func newPlanCmd() *cobra.Command {
+	f := &planFlags{}
+
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Print the commands a spec generates, without generating them",
+		Long: `Load an OpenAPI spec, build the command plan and print it: every command
+with its HTTP operation, arguments and flags, grouped as in the generated CLI.
+
+The YAML and JSON output is stable, so it can be committed next to the spec
+and diffed in pull requests to review how a spec change affects the CLI:
+
+  opencligen plan --spec api.yaml --name myapp > cli-plan.yaml
+
+The spec, name and module are read from .opencligen.yaml when not given.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return applyProjectConfig(cmd, f.configPath)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPlan(cmd, f)
+		},
+	}
+
+	planCmd.Flags().StringArrayVar(&f.specPaths, "spec", nil, "Path or http(s) URL of the OpenAPI spec (required; repeat as [name=]path to merge several services)")
+	planCmd.Flags().StringVar(&f.appName, "name", "", "Application name (required)")
+	planCmd.Flags().StringVar(&f.moduleName, "module", "", "Go module name (optional, defaults to app name)")
+	planCmd.Flags().StringVar(&f.format, "format", planFormatYAML, "Output format: "+planFormatYAML+", "+planFormatJSON+" or "+planFormatText)
+	planCmd.Flags().StringArrayVar(&f.specHeader, "spec-header", nil, `Header sent when fetching a spec URL, as "Name: value" (repeatable, e.g. "Authorization: Bearer $TOKEN")`)
+	addFilterFlags(planCmd, &f.filter)
+	addProjectFlag(planCmd, &f.configPath)
+
+	_ = planCmd.MarkFlagRequired("spec")
+	_ = planCmd.MarkFlagRequired("name")
+
+	return planCmd
+}
+
+func runPlan(cmd *cobra.Command, f *planFlags) error {
+	switch f.format {
+	case planFormatYAML, planFormatJSON, planFormatText:
+	default:
+		return fmt.Errorf("invalid --format %q: must be %s, %s or %s", f.format, planFormatYAML, planFormatJSON, planFormatText)
+	}
+	header, err := parseSpecHeaders(f.specHeader)
+	if err != nil {
+		return err
+	}
+	// The plan is the output; progress is only logged with --verbose
+	logger, _, err := newLogger(cmd, slog.LevelWarn)
+	if err != nil {
+		return err
+	}
+	s, err := loadSpecs(context.Background(), logger, f.specPaths, header)
+	if err != nil {
+		return err
+	}
+	if s, err = filterSpec(logger, s, f.filter); err != nil {
+		return err
+	}
+	if f.moduleName == "" {
+		f.moduleName = f.appName
+	}
+	p := plan.Build(s, f.appName, f.moduleName)
+	logWarnings(logger, p.Warnings)
+
+	out := cmd.OutOrStdout()
+	switch f.format {
+	case planFormatJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(outlinePlan(p))
+	case planFormatText:
+		printPlan(out, p)
+		return nil
+	}
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(outlinePlan(p)); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// planOutline is the reviewable part of a plan: the command surface of the
+// generated CLI, without the embedded spec or example responses
+type planOutline struct {
+	App     string          `json:"app" yaml:"app"`
+	Module  string          `json:"module" yaml:"module"`
+	Spec    specOutline     `json:"spec" yaml:"spec"`
+	Auth    []string        `json:"auth,omitempty" yaml:"auth,omitempty"`
+	Servers []serverOutline `json:"servers,omitempty" yaml:"servers,omitempty"`
+	Groups  []groupOutline  `json:"groups" yaml:"groups"`
+}
+
+type specOutline struct {
+	Title   string `json:"title" yaml:"title"`
+	Version string `json:"version" yaml:"version"`
+}
+
+type serverOutline struct {
+	Name string `json:"name" yaml:"name"`
+	URL  string `json:"url" yaml:"url"`
+}
+
+type groupOutline struct {
+	Service  string           `json:"service,omitempty" yaml:"service,omitempty"`
+	Name     string           `json:"name" yaml:"name"`
+	Section  string           `json:"section,omitempty" yaml:"section,omitempty"`
+	Commands []commandOutline `json:"commands" yaml:"commands"`
+}
+
+type commandOutline struct {
+	Command     string        `json:"command" yaml:"command"`
+	Method      string        `json:"method" yaml:"method"`
+	Path        string        `json:"path" yaml:"path"`
+	OperationID string        `json:"operation_id,omitempty" yaml:"operation_id,omitempty"`
+	Summary     string        `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Aliases     []string      `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Hidden      bool          `json:"hidden,omitempty" yaml:"hidden,omitempty"`
+	Args        []string      `json:"args,omitempty" yaml:"args,omitempty"`
+	Flags       []flagOutline `json:"flags,omitempty" yaml:"flags,omitempty"`
+	Body        bool          `json:"body,omitempty" yaml:"body,omitempty"`
+	Paginated   bool          `json:"paginated,omitempty" yaml:"paginated,omitempty"`
+	Stream      bool          `json:"stream,omitempty" yaml:"stream,omitempty"`
+	Accept      []string      `json:"accept,omitempty" yaml:"accept,omitempty"`
+}
+
+type flagOutline struct {
+	Name      string        `json:"name" yaml:"name"`
+	Shorthand string        `json:"shorthand,omitempty" yaml:"shorthand,omitempty"`
+	Type      string        `json:"type" yaml:"type"`
+	In        string        `json:"in,omitempty" yaml:"in,omitempty"`
+	Required  bool          `json:"required,omitempty" yaml:"required,omitempty"`
+	Default   interface{}   `json:"default,omitempty" yaml:"default,omitempty"`
+	Enum      []interface{} `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Env       string        `json:"env,omitempty" yaml:"env,omitempty"`
+	Config    string        `json:"config,omitempty" yaml:"config,omitempty"`
+}
+
+// outlinePlan returns the outline of p
+func outlinePlan(p *plan.Plan) planOutline {
+	o := planOutline{
+		App:    p.AppName,
+		Module: p.ModuleName,
+		Spec:   specOutline{Title: p.Spec.Title, Version: p.Spec.Version},
+		Groups: []groupOutline{},
+	}
+
+	if p.Auth.Bearer {
+		o.Auth = append(o.Auth, "bearer")
+	}
+	if p.Auth.ClientCredentials != nil {
+		o.Auth = append(o.Auth, "oauth2-client-credentials")
+	}
+	if p.Auth.DeviceCode != nil {
+		o.Auth = append(o.Auth, "oauth2-device-code")
+	}
+	if p.Auth.Signing != nil {
+		o.Auth = append(o.Auth, "signing-"+p.Auth.Signing.Type)
+	}
+	for _, s := range p.Servers {
+		o.Servers = append(o.Servers, serverOutline{Name: s.Name, URL: s.URL})
+	}
+
+	for gi := range p.Groups {
+		group := &p.Groups[gi]
+		g := groupOutline{Service: group.Service, Name: group.Name, Section: group.Section, Commands: []commandOutline{}}
+		for oi := range group.Operations {
+			op := &group.Operations[oi]
+			c := commandOutline{
+				Command:     strings.Join(op.CommandPath, " "),
+				Method:      op.Method,
+				Path:        op.Path,
+				OperationID: op.OperationID,
+				Summary:     op.Summary,
+				Aliases:     op.Aliases,
+				Hidden:      op.Hidden,
+				Body:        op.HasJSONBody,
+				Paginated:   op.Pagination != nil,
+				Stream:      op.IsEventStream,
+				Accept:      op.Accept,
+			}
+			for _, pos := range op.Positionals {
+				c.Args = append(c.Args, pos.Name)
+			}
+			for fi := range op.Flags {
+				flag := &op.Flags[fi]
+				c.Flags = append(c.Flags, flagOutline{
+					Name:      flag.FlagName,
+					Shorthand: flag.Shorthand,
+					Type:      flag.Type,
+					In:        flag.In,
+					Required:  flag.Required,
+					Default:   flag.Default,
+					Enum:      flag.Enum,
+					Env:       flag.EnvVar,
+					Config:    flag.ConfigKey,
+				})
+			}
+			g.Commands = append(g.Commands, c)
+		}
+		o.Groups = append(o.Groups, g)
+	}
+	return o
+}
+
+// printPlan writes the plan as a human-readable tree
+func printPlan(w io.Writer, p *plan.Plan) {
+	fmt.Fprintf(w, "\n=== Command Plan for %s ===\n\n", p.AppName)
+	fmt.Fprintf(w, "Module: %s\n\n", p.ModuleName)
+
+	for gi := range p.Groups {
+		group := &p.Groups[gi]
+		if group.Service != "" {
+			fmt.Fprintf(w, "Group: %s %s\n", group.Service, group.Name)
+		} else {
+			fmt.Fprintf(w, "Group: %s\n", group.Name)
+		}
+		for oi := range group.Operations {
+			op := &group.Operations[oi]
+			cmdPath := ""
+			for i, part := range op.CommandPath {
+				if i > 0 {
+					cmdPath += " "
+				}
+				cmdPath += part
+			}
+
+			flags := ""
+			for fi := range op.Flags {
+				f := &op.Flags[fi]
+				if flags != "" {
+					flags += ", "
+				}
+				req := ""
+				if f.Required {
+					req = "*"
+				}
+				flags += fmt.Sprintf("--%s%s", f.FlagName, req)
+			}
+
+			positionals := ""
+			for pi := range op.Positionals {
+				pos := &op.Positionals[pi]
+				positionals += fmt.Sprintf(" <%s>", pos.Name)
+			}
+
+			stream := ""
+			if op.IsEventStream {
+				stream = " [SSE]"
+			}
+
+			fmt.Fprintf(w, "  %s%s%s\n", cmdPath, positionals, stream)
+			if flags != "" {
+				fmt.Fprintf(w, "    Flags: %s\n", flags)
+			}
+			fmt.Fprintf(w, "    %s %s\n", op.Method, op.Path)
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/crunchloop/opencligen/internal/spec"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// projectConfigFile is the project config read from the working directory
+const projectConfigFile = ".opencligen.yaml"
+
+// projectConfig is a project config file: the settings of opencligen for a
+// repository, so that running it without flags is reproducible and the
+// settings are reviewed with the code. Each setting is the default of the
+// flag of the same name, with dashes for underscores. Relative paths are
+// relative to the file.
+type projectConfig struct {
+	Spec            specList `yaml:"spec"`
+	Out             string   `yaml:"out"`
+	OutArchive      string   `yaml:"out_archive"`
+	Name            string   `yaml:"name"`
+	Module          string   `yaml:"module"`
+	Build           *bool    `yaml:"build"`
+	SharedRuntime   *bool    `yaml:"shared_runtime"`
+	RuntimeVersion  string   `yaml:"runtime_version"`
+	NoGoMod         *bool    `yaml:"no_gomod"`
+	DepsFile        string   `yaml:"deps_file"`
+	WithRelease     *bool    `yaml:"with_release"`
+	WithMock        *bool    `yaml:"with_mock"`
+	Emit            string   `yaml:"emit"`
+	FileHeader      string   `yaml:"file_header"`
+	Templates       string   `yaml:"templates"`
+	BuildTags       string   `yaml:"build_tags"`
+	StreamBuildTags string   `yaml:"stream_build_tags"`
+	PostGen         []string `yaml:"post_gen"`
+
+	IncludeTag        []string `yaml:"include_tag"`
+	ExcludeTag        []string `yaml:"exclude_tag"`
+	IncludeOperation  []string `yaml:"include_operation"`
+	ExcludePathPrefix []string `yaml:"exclude_path_prefix"`
+}
+
+// specList is the spec setting: a path, or a list of paths (as name=path
+// or path) to merge several services
+type specList []string
+
+// UnmarshalYAML accepts a single path as well as a list
+func (l *specList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = specList{node.Value}
+		return nil
+	}
+	var paths []string
+	if err := node.Decode(&paths); err != nil {
+		return err
+	}
+	*l = paths
+	return nil
+}
+
+// replacedBy maps settings to the flag that replaces them when given on the
+// command line, so they don't conflict
+var replacedBy = map[string]string{
+	"out":         "out-archive",
+	"out-archive": "out",
+}
+
+// projectSetting is a setting of a project config and the flag it sets
+type projectSetting struct {
+	flag   string
+	values []string
+}
+
+// settings returns the settings of c that are set, with paths resolved
+// against dir
+func (c *projectConfig) settings(dir string) []projectSetting {
+	var settings []projectSetting
+	str := func(flag, value string) {
+		if value != "" {
+			settings = append(settings, projectSetting{flag, []string{value}})
+		}
+	}
+	path := func(flag, value string) {
+		if value != "" && !filepath.IsAbs(value) {
+			value = filepath.Join(dir, value)
+		}
+		str(flag, value)
+	}
+	boolean := func(flag string, value *bool) {
+		if value != nil {
+			str(flag, strconv.FormatBool(*value))
+		}
+	}
+
+	var specs []string
+	for _, arg := range c.Spec {
+		// Resolve the path of name=path; URLs are kept as is
+		name, specPath, ok := strings.Cut(arg, "=")
+		if !ok || !serviceNamePattern.MatchString(name) {
+			name, specPath = "", arg
+		}
+		if !filepath.IsAbs(specPath) && !spec.IsURL(specPath) {
+			specPath = filepath.Join(dir, specPath)
+		}
+		if name != "" {
+			specPath = name + "=" + specPath
+		}
+		specs = append(specs, specPath)
+	}
+	if len(specs) > 0 {
+		settings = append(settings, projectSetting{"spec", specs})
+	}
+	path("out", c.Out)
+	path("out-archive", c.OutArchive)
+	str("name", c.Name)
+	str("module", c.Module)
+	boolean("build", c.Build)
+	boolean("shared-runtime", c.SharedRuntime)
+	str("runtime-version", c.RuntimeVersion)
+	boolean("no-gomod", c.NoGoMod)
+	path("deps-file", c.DepsFile)
+	boolean("with-release", c.WithRelease)
+	boolean("with-mock", c.WithMock)
+	str("emit", c.Emit)
+	str("file-header", c.FileHeader)
+	path("templates", c.Templates)
+	str("build-tags", c.BuildTags)
+	str("stream-build-tags", c.StreamBuildTags)
+	list := func(flag string, values []string) {
+		if len(values) > 0 {
+			settings = append(settings, projectSetting{flag, values})
+		}
+	}
+	list("post-gen", c.PostGen)
+	list("include-tag", c.IncludeTag)
+	list("exclude-tag", c.ExcludeTag)
+	list("include-operation", c.IncludeOperation)
+	list("exclude-path-prefix", c.ExcludePathPrefix)
+	return settings
+}
+
+// addProjectFlag adds the --config flag of a command that reads the project
+// config
+func addProjectFlag(cmd *cobra.Command, path *string) {
+	cmd.Flags().StringVar(path, "config", "", "Project config file (default: "+projectConfigFile+" in the working directory, if present)")
+}
+
+// applyProjectConfig sets the flags of cmd that were not given on the
+// command line from the project config at path, or from the one in the
+// working directory when path is empty
+func applyProjectConfig(cmd *cobra.Command, path string) error {
+	explicit := path != ""
+	if !explicit {
+		path = projectConfigFile
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read project config: %w", err)
+	}
+
+	var c projectConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid project config %s: %w", path, err)
+	}
+
+	for _, setting := range c.settings(filepath.Dir(path)) {
+		flag := cmd.Flags().Lookup(setting.flag)
+		if flag == nil || flag.Changed || cmd.Flags().Changed(replacedBy[setting.flag]) {
+			continue
+		}
+		for _, value := range setting.values {
+			if err := cmd.Flags().Set(setting.flag, value); err != nil {
+				return fmt.Errorf("invalid %s in %s: %w", strings.ReplaceAll(setting.flag, "-", "_"), path, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/crunchloop/opencligen/internal/plan"
+	"github.com/crunchloop/opencligen/internal/spec"
+	"github.com/spf13/cobra"
+)
+
+// serviceNamePattern matches the service names of --spec name=path
+var serviceNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// genericSpecNames are spec file names that say nothing about the service,
+// which is then named after the directory
+var genericSpecNames = map[string]bool{"openapi": true, "swagger": true, "api": true, "spec": true}
+
+// loadSpecs loads the spec of each --spec, a file or a URL fetched with the
+// given header. A single spec loads as is; several are merged into one spec
+// with a service per spec, named as given with name=path or else after the
+// file. Progress and the warnings of the specs go to logger.
+func loadSpecs(ctx context.Context, logger *slog.Logger, args []string, header http.Header) (*spec.Spec, error) {
+	type specArg struct{ name, path string }
+	var parsed []specArg
+	named := false
+	for _, arg := range args {
+		a := specArg{path: arg}
+		if name, path, ok := strings.Cut(arg, "="); ok && serviceNamePattern.MatchString(name) {
+			a = specArg{name: name, path: path}
+			named = true
+		}
+		if _, err := os.Stat(a.path); os.IsNotExist(err) && !spec.IsURL(a.path) {
+			return nil, fmt.Errorf("spec file not found: %s", a.path)
+		}
+		parsed = append(parsed, a)
+	}
+
+	if len(parsed) == 1 && !named {
+		logger.Info("Loading spec", "path", parsed[0].path)
+		s, err := loadSpec(ctx, parsed[0].path, header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load spec: %w", err)
+		}
+		logWarnings(logger, s.Warnings)
+		return s, nil
+	}
+
+	// Services without a name given are named after their file; names that
+	// are taken get a number, in order
+	taken := make(map[string]bool)
+	for _, a := range parsed {
+		taken[a.name] = true
+	}
+	var specs []spec.NamedSpec
+	for _, a := range parsed {
+		name := a.name
+		if name == "" {
+			base := serviceName(a.path)
+			name = base
+			for n := 2; taken[name]; n++ {
+				name = base + "-" + strconv.Itoa(n)
+			}
+			taken[name] = true
+		}
+
+		logger.Info("Loading spec", "service", name, "path", a.path)
+		s, err := loadSpec(ctx, a.path, header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load spec of %s: %w", name, err)
+		}
+		specs = append(specs, spec.NamedSpec{Name: name, Spec: s})
+	}
+
+	s, err := spec.Merge(specs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge specs: %w", err)
+	}
+	logWarnings(logger, s.Warnings)
+	return s, nil
+}
+
+// loadSpec loads a spec from a file, or from a URL with header
+func loadSpec(ctx context.Context, location string, header http.Header) (*spec.Spec, error) {
+	if spec.IsURL(location) {
+		return spec.LoadURL(ctx, location, header)
+	}
+	return spec.Load(ctx, location)
+}
+
+// parseSpecHeaders parses the "Name: value" headers of --spec-header
+func parseSpecHeaders(values []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, value := range values {
+		name, v, ok := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid --spec-header %q: must be \"Name: value\"", value)
+		}
+		header.Add(name, strings.TrimSpace(v))
+	}
+	return header, nil
+}
+
+// addFilterFlags adds the flags that select the operations of the specs to
+// generate commands for
+func addFilterFlags(cmd *cobra.Command, filter *plan.Filter) {
+	cmd.Flags().StringSliceVar(&filter.IncludeTags, "include-tag", nil, "Only generate commands for operations with this tag (repeatable)")
+	cmd.Flags().StringSliceVar(&filter.ExcludeTags, "exclude-tag", nil, "Don't generate commands for operations with this tag (repeatable)")
+	cmd.Flags().StringSliceVar(&filter.IncludeOperations, "include-operation", nil, "Only generate commands for the operation with this operationId, in addition to --include-tag (repeatable)")
+	cmd.Flags().StringSliceVar(&filter.ExcludePathPrefixes, "exclude-path-prefix", nil, "Don't generate commands for operations under this path, e.g. /admin (repeatable)")
+}
+
+// filterSpec applies the filter flags to s, logging the operations left
+func filterSpec(logger *slog.Logger, s *spec.Spec, filter plan.Filter) (*spec.Spec, error) {
+	if filter.IsZero() {
+		return s, nil
+	}
+	filtered, err := filter.Apply(s)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Filtered operations", "kept", len(filtered.Operations), "operations", len(s.Operations))
+	return filtered, nil
+}
+
+// logWarnings logs the warnings of a spec or plan
+func logWarnings(logger *slog.Logger, warnings []string) {
+	for _, warning := range warnings {
+		logger.Warn(warning)
+	}
+}
+
+// serviceName derives a service name from the path or URL of its spec
+func serviceName(path string) string {
+	if u, err := url.Parse(path); err == nil && spec.IsURL(path) {
+		path = filepath.FromSlash(u.Path)
+	}
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if genericSpecNames[strings.ToLower(base)] {
+		if dir := filepath.Base(filepath.Dir(path)); dir != "." && dir != string(filepath.Separator) {
+			base = dir
+		}
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(base) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9' && b.Len() > 0:
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+	if name := strings.TrimSuffix(b.String(), "-"); name != "" {
+		return name
+	}
+	return "service"
+}
@@ -0,0 +1,85 @@
+package gen
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"time"
+)
+
+// archiveTime is the modification time of every archive entry, so that
+// the same files always make the same archive
+var archiveTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// WriteArchive renders all files for the CLI and writes them to w as a
+// gzipped tarball, below a directory named after the app, without touching
+// the output directory. The archive includes the manifest, so the extracted
+// directory regenerates like one written by Generate.
+func (g *Generator) WriteArchive(w io.Writer) error {
+	files, err := g.Render()
+	if err != nil {
+		return err
+	}
+	manifest, err := newManifest(files).encode()
+	if err != nil {
+		return err
+	}
+
+	archived := maps.Clone(files)
+	archived[ManifestFile] = manifest
+	return archived.WriteArchive(w, g.AppName)
+}
+
+// WriteArchive writes every file in the set to w as a gzipped tarball,
+// below dir. Entries are sorted and carry no timestamps or owners, so the
+// same set always makes the same archive.
+func (fs FileSet) WriteArchive(w io.Writer, dir string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	written := make(map[string]bool)
+	var writeDir func(name string) error
+	writeDir = func(name string) error {
+		if name == "." || written[name] {
+			return nil
+		}
+		// Parents first, for extractors that don't create them
+		if err := writeDir(path.Dir(name)); err != nil {
+			return err
+		}
+		written[name] = true
+		return tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeDir,
+			Name:     name + "/",
+			Mode:     0755,
+			ModTime:  archiveTime,
+		})
+	}
+
+	for _, p := range fs.Paths() {
+		name := path.Join(dir, p)
+		if err := writeDir(path.Dir(name)); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", path.Dir(p), err)
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(fs[p])),
+			ModTime:  archiveTime,
+		}); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", p, err)
+		}
+		if _, err := tw.Write(fs[p]); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", p, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
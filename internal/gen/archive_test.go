@@ -0,0 +1,69 @@
+package gen
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func TestWriteArchive(t *testing.T) {
+	g := New(loadDapPlan(t), "")
+	var archive bytes.Buffer
+	if err := g.WriteArchive(&archive); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	files, err := g.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("expected a gzipped archive: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	dirs := map[string]bool{}
+	archived := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid archive: %v", err)
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			dirs[hdr.Name] = true
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archived[hdr.Name] = content
+	}
+
+	// Every file is below the app directory
+	for _, p := range files.Paths() {
+		if !bytes.Equal(archived[g.AppName+"/"+p], files[p]) {
+			t.Errorf("expected %s in the archive", p)
+		}
+	}
+	if len(archived) != len(files)+1 || archived[g.AppName+"/"+ManifestFile] == nil {
+		t.Errorf("expected the files and the manifest, got %d entries for %d files", len(archived), len(files))
+	}
+	if !dirs[g.AppName+"/"] || !dirs[g.AppName+"/internal/"] || !dirs[g.AppName+"/internal/commands/"] {
+		t.Errorf("expected the parent directories, got %v", dirs)
+	}
+
+	// The same plan always makes the same archive
+	var again bytes.Buffer
+	if err := New(loadDapPlan(t), "").WriteArchive(&again); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(archive.Bytes(), again.Bytes()) {
+		t.Error("expected a reproducible archive")
+	}
+}
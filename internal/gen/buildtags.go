@@ -0,0 +1,51 @@
+package gen
+
+import (
+	"fmt"
+	"go/build/constraint"
+	"strings"
+
+	"github.com/crunchloop/opencligen/internal/plan"
+)
+
+// buildConstraint combines build constraint expressions (e.g. "!js" or
+// "linux || darwin") into a single //go:build line. Empty expressions are
+// skipped; it returns "" when nothing remains.
+func buildConstraint(exprs ...string) (string, error) {
+	var combined constraint.Expr
+	for _, e := range exprs {
+		if strings.TrimSpace(e) == "" {
+			continue
+		}
+
+		x, err := constraint.Parse("//go:build " + e)
+		if err != nil {
+			return "", fmt.Errorf("invalid build constraint %q: %w", e, err)
+		}
+
+		if combined == nil {
+			combined = x
+		} else {
+			combined = &constraint.AndExpr{X: combined, Y: x}
+		}
+	}
+
+	if combined == nil {
+		return "", nil
+	}
+	return "//go:build " + combined.String(), nil
+}
+
+// streamConstraint returns StreamBuildTags for files whose commands all
+// consume event streams, and "" for anything else
+func (g *Generator) streamConstraint(ops ...plan.OpPlan) string {
+	if len(ops) == 0 {
+		return ""
+	}
+	for i := range ops {
+		if !ops[i].IsEventStream {
+			return ""
+		}
+	}
+	return g.StreamBuildTags
+}
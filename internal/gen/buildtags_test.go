@@ -0,0 +1,65 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildConstraint(t *testing.T) {
+	tests := []struct {
+		exprs []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"", "  "}, ""},
+		{[]string{"mytag"}, "//go:build mytag"},
+		{[]string{"mytag", "!js"}, "//go:build mytag && !js"},
+		{[]string{"linux || darwin", "!js"}, "//go:build (linux || darwin) && !js"},
+	}
+
+	for _, tt := range tests {
+		got, err := buildConstraint(tt.exprs...)
+		if err != nil {
+			t.Errorf("buildConstraint(%q) failed: %v", tt.exprs, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("buildConstraint(%q) = %q, want %q", tt.exprs, got, tt.want)
+		}
+	}
+
+	if _, err := buildConstraint("linux ||"); err == nil {
+		t.Error("expected an error for an invalid expression")
+	}
+}
+
+func TestRender_BuildTags(t *testing.T) {
+	p := loadDapPlan(t)
+
+	g := New(p, t.TempDir())
+	g.BuildTags = "mytag"
+	g.StreamBuildTags = "!js"
+	files, err := g.Render()
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	for _, name := range files.Paths() {
+		if !strings.HasSuffix(name, ".go") {
+			continue
+		}
+
+		want := "//go:build mytag\n"
+		if name == "internal/commands/stream.go" || name == "internal/commands/stream_subscribe.go" {
+			want = "//go:build mytag && !js\n"
+		}
+		if !strings.HasPrefix(string(files[name]), want) {
+			t.Errorf("expected %s to start with %q, got:\n%.80s", name, want, files[name])
+		}
+	}
+
+	g.StreamBuildTags = "linux ||"
+	if _, err := g.Render(); err == nil {
+		t.Error("expected an invalid build constraint to fail rendering")
+	}
+}
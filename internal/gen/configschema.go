@@ -0,0 +1,233 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/crunchloop/opencligen/internal/plan"
+)
+
+// jsonSchema is the subset of JSON Schema used to describe the generated
+// CLI's config file
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type"`
+	Format               string                 `json:"format,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+}
+
+// configSchema builds the JSON Schema for the generated CLI's config file.
+// Keys declared with x-cli "config" on parameters are looked up in the
+// headers map, so they are described there.
+func (g *Generator) configSchema() *jsonSchema {
+	envPrefix := strings.ToUpper(g.AppName)
+
+	headers := &jsonSchema{
+		Type:                 "object",
+		Description:          `Headers sent with every request (overridden by --header). Values may be templates, e.g. Bearer {{ env "TOKEN" }}`,
+		AdditionalProperties: &jsonSchema{Type: "string"},
+	}
+
+	// Several operations may share a config key; describe each key once
+	usedBy := map[string][]string{}
+	for _, group := range g.Plan.Groups {
+		for _, op := range group.Operations {
+			for _, p := range op.Flags {
+				if p.ConfigKey == "" {
+					continue
+				}
+				usedBy[p.ConfigKey] = append(usedBy[p.ConfigKey], fmt.Sprintf("%s --%s", strings.Join(op.CommandPath, " "), p.FlagName))
+			}
+		}
+	}
+	if len(usedBy) > 0 {
+		headers.Properties = make(map[string]*jsonSchema, len(usedBy))
+		for key, uses := range usedBy {
+			sort.Strings(uses)
+			headers.Properties[key] = &jsonSchema{
+				Type:        "string",
+				Description: "Default for " + strings.Join(uses, ", "),
+			}
+		}
+	}
+
+	schema := &jsonSchema{
+		Schema:      "https://json-schema.org/draft/2020-12/schema",
+		Title:       g.AppName + " configuration",
+		Description: fmt.Sprintf("Config file for the %[1]s CLI, read from ~/.config/%[1]s/config.yaml", g.AppName),
+		Type:        "object",
+		Properties: map[string]*jsonSchema{
+			"base_url": {
+				Type:        "string",
+				Format:      "uri",
+				Description: fmt.Sprintf("Base URL for the API (overridden by --base-url or %s_BASE_URL)", envPrefix),
+			},
+			"headers": headers,
+			"audit_log": {
+				Type:        "string",
+				Description: fmt.Sprintf("Append a hash-chained audit record of every request to this file (overridden by --audit-log or %s_AUDIT_LOG)", envPrefix),
+			},
+			"log_file": {
+				Type:        "string",
+				Description: fmt.Sprintf("Append a JSON line per request and response to this file, with credentials masked (overridden by --log-file or %s_LOG_FILE)", envPrefix),
+			},
+			"read_only": {
+				Type:        "boolean",
+				Description: fmt.Sprintf("Block every request except GET and HEAD (overridden by --read-only or %s_READ_ONLY)", envPrefix),
+			},
+			"cache": {
+				Type:        "boolean",
+				Description: "Cache GET responses and revalidate them with the server (overridden by --cache)",
+			},
+			"cookie_jar": {
+				Type:        "boolean",
+				Description: "Store cookies the API sets and send them with later requests (overridden by --cookie-jar)",
+			},
+			"send_request_id": {
+				Type:        "boolean",
+				Description: "Send a generated X-Request-Id header with the requests of each command (overridden by --request-id)",
+			},
+			"user_agent": {
+				Type:        "string",
+				Description: fmt.Sprintf("User-Agent sent with every request, in place of %s/<version> (opencligen)", g.AppName),
+			},
+			"proxy": {
+				Type:        "string",
+				Description: "HTTP or SOCKS5 proxy for every request, in place of HTTP_PROXY, HTTPS_PROXY and ALL_PROXY (overridden by --proxy)",
+			},
+			"ca_cert": {
+				Type:        "string",
+				Description: "PEM file of CA certificates trusted in addition to the system's (overridden by --cacert)",
+			},
+			"client_cert": {
+				Type:        "string",
+				Description: "PEM file of a client certificate for mutual TLS, optionally with its key (overridden by --cert)",
+			},
+			"client_key": {
+				Type:        "string",
+				Description: "PEM file of the client certificate's private key (overridden by --key)",
+			},
+			"insecure_skip_verify": {
+				Type:        "boolean",
+				Description: "Accept any server certificate, for testing only (overridden by --insecure-skip-verify)",
+			},
+			"exit_codes": {
+				Type:                 "object",
+				Description:          "Exit codes by response status (404), status class (4xx), or network for requests that got no response; the defaults are 2 for 4xx, 3 for 5xx and 4 for network",
+				AdditionalProperties: &jsonSchema{Type: "integer"},
+			},
+			"auth_command": {
+				Type:        "array",
+				Items:       &jsonSchema{Type: "string"},
+				Description: "Command whose output is sent as the bearer token when no token is set, e.g. [\"vault\", \"read\", \"-field=token\", \"secret/api\"]",
+			},
+			"otel_endpoint": {
+				Type:        "string",
+				Format:      "uri",
+				Description: "OTLP/HTTP collector to export an OpenTelemetry trace of each command to (overridden by OTEL_EXPORTER_OTLP_ENDPOINT)",
+			},
+			"otel_headers": {
+				Type:                 "object",
+				Description:          "Headers sent with trace exports, e.g. collector credentials (overridden by OTEL_EXPORTER_OTLP_HEADERS)",
+				AdditionalProperties: &jsonSchema{Type: "string"},
+			},
+			"credential_store": {
+				Type:        "string",
+				Enum:        []string{"file", "credentials_file", "keychain"},
+				Description: fmt.Sprintf("Where secrets such as tokens are kept: this file, credentials.yaml next to it, or the OS keychain (overridden by %s_CREDENTIAL_STORE)", envPrefix),
+			},
+		},
+		AdditionalProperties: false,
+	}
+
+	if len(g.Plan.Servers) > 0 {
+		names := make([]string, len(g.Plan.Servers))
+		for i, s := range g.Plan.Servers {
+			names[i] = s.Name
+		}
+		schema.Properties["server"] = &jsonSchema{
+			Type:        "string",
+			Enum:        names,
+			Description: "Server of the API to use when base_url is not set (overridden by --server)",
+		}
+	}
+
+	if g.Plan.Auth.Bearer {
+		schema.Properties["token"] = &jsonSchema{
+			Type:        "string",
+			Description: fmt.Sprintf("Bearer token sent in the Authorization header (overridden by --token or %s_TOKEN)", envPrefix),
+		}
+	}
+
+	if signing := g.Plan.Auth.Signing; signing != nil && signing.Type == plan.SigningHMACSHA256 {
+		schema.Properties["signing_key_id"] = &jsonSchema{
+			Type:        "string",
+			Description: fmt.Sprintf("Key ID sent with HMAC-signed requests (overridden by %s_SIGNING_KEY_ID)", envPrefix),
+		}
+		schema.Properties["signing_secret"] = &jsonSchema{
+			Type:        "string",
+			Description: fmt.Sprintf("Secret that HMAC-signs every request (overridden by %s_SIGNING_SECRET)", envPrefix),
+		}
+	}
+
+	if g.Plan.Auth.DeviceCode != nil {
+		schema.Properties["client_id"] = &jsonSchema{
+			Type:        "string",
+			Description: fmt.Sprintf("OAuth2 client ID used by auth login (overridden by --client-id or %s_CLIENT_ID)", envPrefix),
+		}
+	}
+
+	if g.Plan.Auth.ClientCredentials != nil {
+		schema.Properties["client_id"] = &jsonSchema{
+			Type:        "string",
+			Description: fmt.Sprintf("OAuth2 client ID exchanged for access tokens (overridden by --client-id or %s_CLIENT_ID)", envPrefix),
+		}
+		schema.Properties["client_secret"] = &jsonSchema{
+			Type:        "string",
+			Description: fmt.Sprintf("OAuth2 client secret (overridden by --client-secret or %s_CLIENT_SECRET)", envPrefix),
+		}
+	}
+
+	addProfiles(schema, envPrefix)
+	return schema
+}
+
+// profileKeys are the settings a profile may set
+var profileKeys = []string{"base_url", "server", "token", "client_id", "client_secret", "signing_key_id", "signing_secret", "headers", "auth_command"}
+
+// addProfiles describes the profiles setting, whose entries take the
+// profileKeys of schema
+func addProfiles(schema *jsonSchema, envPrefix string) {
+	profile := &jsonSchema{
+		Type:                 "object",
+		Properties:           map[string]*jsonSchema{},
+		AdditionalProperties: false,
+	}
+	for _, key := range profileKeys {
+		if prop, ok := schema.Properties[key]; ok {
+			profile.Properties[key] = prop
+		}
+	}
+	schema.Properties["profiles"] = &jsonSchema{
+		Type:                 "object",
+		Description:          fmt.Sprintf("Named sets of settings, e.g. one per environment, selected with --profile or %s_PROFILE; headers are merged, other settings replace the top-level ones", envPrefix),
+		AdditionalProperties: profile,
+	}
+}
+
+func (g *Generator) generateConfigSchema() error {
+	content, err := json.MarshalIndent(g.configSchema(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	g.addFile("config.schema.json", append(content, '\n'))
+	return nil
+}
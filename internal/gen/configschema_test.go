@@ -0,0 +1,142 @@
+package gen
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/crunchloop/opencligen/internal/plan"
+)
+
+func TestRender_ConfigSchema(t *testing.T) {
+	files, err := New(loadDapPlan(t), t.TempDir()).Render()
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(files["config.schema.json"], &schema); err != nil {
+		t.Fatalf("config.schema.json is not valid JSON: %v", err)
+	}
+
+	props, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		t.Fatal("expected schema to have properties")
+	}
+	for _, key := range []string{"base_url", "headers", "audit_log", "log_file", "read_only", "cache", "cookie_jar", "send_request_id", "user_agent", "proxy", "ca_cert", "client_cert", "client_key", "insecure_skip_verify", "exit_codes", "auth_command", "otel_endpoint", "otel_headers", "credential_store", "profiles"} {
+		if _, ok := props[key]; !ok {
+			t.Errorf("expected schema to describe %s", key)
+		}
+	}
+	if items, _ := props["auth_command"].(map[string]interface{})["items"].(map[string]interface{}); items["type"] != "string" {
+		t.Errorf("expected auth_command to be a list of strings, got %v", props["auth_command"])
+	}
+	profile, _ := props["profiles"].(map[string]interface{})["additionalProperties"].(map[string]interface{})
+	profileProps, _ := profile["properties"].(map[string]interface{})
+	if _, ok := profileProps["base_url"]; !ok {
+		t.Errorf("expected profiles to take base_url, got %v", props["profiles"])
+	}
+	if _, ok := profileProps["proxy"]; ok {
+		t.Error("expected profiles not to take proxy")
+	}
+	if schema["additionalProperties"] != false {
+		t.Error("expected unknown keys to be rejected")
+	}
+}
+
+func TestConfigSchema_XCliConfigKeys(t *testing.T) {
+	p := &plan.Plan{
+		AppName:    "acme",
+		ModuleName: "github.com/example/acme",
+		Groups: []plan.GroupPlan{{
+			Name: "orders",
+			Operations: []plan.OpPlan{
+				{
+					CommandPath: []string{"orders", "list"},
+					Flags:       []plan.ParamPlan{{Name: "org", FlagName: "org", ConfigKey: "org_id"}},
+				},
+				{
+					CommandPath: []string{"orders", "create"},
+					Flags:       []plan.ParamPlan{{Name: "org", FlagName: "org", ConfigKey: "org_id"}},
+				},
+			},
+		}},
+	}
+
+	schema := New(p, t.TempDir()).configSchema()
+
+	key, ok := schema.Properties["headers"].Properties["org_id"]
+	if !ok {
+		t.Fatal("expected x-cli config key under headers")
+	}
+	if key.Type != "string" {
+		t.Errorf("expected string type, got %s", key.Type)
+	}
+	if !strings.Contains(key.Description, "orders create --org") || !strings.Contains(key.Description, "orders list --org") {
+		t.Errorf("expected description to name both flags, got %q", key.Description)
+	}
+}
+
+func TestConfigSchema_BearerToken(t *testing.T) {
+	p := &plan.Plan{AppName: "acme", ModuleName: "github.com/example/acme"}
+	if _, ok := New(p, t.TempDir()).configSchema().Properties["token"]; ok {
+		t.Error("expected no token setting without bearer auth")
+	}
+
+	p.Auth.Bearer = true
+	token, ok := New(p, t.TempDir()).configSchema().Properties["token"]
+	if !ok || !strings.Contains(token.Description, "ACME_TOKEN") {
+		t.Errorf("expected a token setting naming ACME_TOKEN, got %+v", token)
+	}
+}
+
+func TestConfigSchema_ClientCredentials(t *testing.T) {
+	p := &plan.Plan{AppName: "acme", ModuleName: "github.com/example/acme"}
+	if _, ok := New(p, t.TempDir()).configSchema().Properties["client_id"]; ok {
+		t.Error("expected no client_id setting without client credentials")
+	}
+
+	p.Auth.ClientCredentials = &plan.OAuth2Flow{TokenURL: "https://auth.example.com/token"}
+	props := New(p, t.TempDir()).configSchema().Properties
+	for _, key := range []string{"client_id", "client_secret"} {
+		if prop, ok := props[key]; !ok || !strings.Contains(prop.Description, "ACME_"+strings.ToUpper(key)) {
+			t.Errorf("expected a %s setting naming its env var, got %+v", key, prop)
+		}
+	}
+
+	// The device code flow only takes a client ID
+	p.Auth = plan.AuthPlan{DeviceCode: &plan.OAuth2Flow{TokenURL: "https://auth.example.com/token"}}
+	props = New(p, t.TempDir()).configSchema().Properties
+	if _, ok := props["client_secret"]; ok || props["client_id"] == nil {
+		t.Errorf("expected only a client_id setting for the device code flow, got %v", props)
+	}
+}
+
+func TestConfigSchema_Servers(t *testing.T) {
+	p := &plan.Plan{AppName: "acme", ModuleName: "github.com/example/acme"}
+	if _, ok := New(p, t.TempDir()).configSchema().Properties["server"]; ok {
+		t.Error("expected no server setting without servers")
+	}
+
+	p.Servers = []plan.Server{{Name: "production", URL: "https://api.example.com"}, {Name: "sandbox", URL: "https://sandbox.example.com"}}
+	server, ok := New(p, t.TempDir()).configSchema().Properties["server"]
+	if !ok || strings.Join(server.Enum, ",") != "production,sandbox" {
+		t.Errorf("expected a server setting taking the server names, got %+v", server)
+	}
+}
+
+func TestConfigSchema_Signing(t *testing.T) {
+	p := &plan.Plan{AppName: "acme", ModuleName: "github.com/example/acme"}
+	p.Auth.Signing = &plan.Signing{Type: plan.SigningAWSSigV4, Service: "execute-api"}
+	if _, ok := New(p, t.TempDir()).configSchema().Properties["signing_secret"]; ok {
+		t.Error("expected no signing_secret setting for SigV4, which reads the AWS environment")
+	}
+
+	p.Auth.Signing = &plan.Signing{Type: plan.SigningHMACSHA256}
+	props := New(p, t.TempDir()).configSchema().Properties
+	for _, key := range []string{"signing_key_id", "signing_secret"} {
+		if prop, ok := props[key]; !ok || !strings.Contains(prop.Description, "ACME_"+strings.ToUpper(key)) {
+			t.Errorf("expected a %s setting naming its env var, got %+v", key, prop)
+		}
+	}
+}
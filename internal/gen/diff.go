@@ -0,0 +1,174 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// Diff returns a unified diff between the files currently in dir and the
+// files in the set. Files that do not exist yet are diffed against
+// /dev/null; files in dir that are not part of the set are ignored.
+func (fs FileSet) Diff(dir string) (string, error) {
+	var out strings.Builder
+
+	for _, p := range fs.Paths() {
+		existing, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(p)))
+		oldName := "a/" + p
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			existing = nil
+			oldName = "/dev/null"
+		case err != nil:
+			return "", fmt.Errorf("failed to read %s: %w", p, err)
+		}
+
+		if bytes.Equal(existing, fs[p]) {
+			continue
+		}
+
+		out.WriteString(unifiedDiff(oldName, "b/"+p, splitLines(existing), splitLines(fs[p])))
+	}
+
+	return out.String(), nil
+}
+
+// splitLines splits content into lines, dropping the trailing newline
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+// diffOp is a single line-level edit: ' ' (keep), '-' (delete) or '+' (insert)
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines computes a minimal line edit script using a longest common
+// subsequence table. Generated files are small enough for the quadratic cost.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+
+	return ops
+}
+
+// unifiedDiff formats the differences between a and b as a unified diff
+func unifiedDiff(oldName, newName string, a, b []string) string {
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	for start := 0; start < len(ops); {
+		// Find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend the hunk until there are more than 2*diffContext unchanged
+		// lines between changes
+		last := first
+		for k := first; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				last = k
+			} else if k-last > 2*diffContext {
+				break
+			}
+		}
+
+		hunkStart := max(first-diffContext, start)
+		hunkEnd := min(last+diffContext+1, len(ops))
+
+		// Line numbers at the start of the hunk
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:hunkStart] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+
+		oldCount, newCount := 0, 0
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		for _, op := range ops[hunkStart:hunkEnd] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			out.WriteByte('\n')
+		}
+
+		start = hunkEnd
+	}
+
+	return out.String()
+}
+
+// hunkRange formats a hunk range in unified diff notation
+func hunkRange(line, count int) string {
+	if count == 0 {
+		// An empty range refers to the line before the hunk
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
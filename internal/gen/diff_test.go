@@ -0,0 +1,108 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff_ModifiedLine(t *testing.T) {
+	a := []string{"one", "two", "three", "four", "five", "six", "seven", "eight", "nine"}
+	b := []string{"one", "two", "three", "four", "FIVE", "six", "seven", "eight", "nine"}
+
+	got := unifiedDiff("a/f.go", "b/f.go", a, b)
+	want := `--- a/f.go
++++ b/f.go
+@@ -2,7 +2,7 @@
+ two
+ three
+ four
+-five
++FIVE
+ six
+ seven
+ eight
+`
+	if got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiff_NewFile(t *testing.T) {
+	got := unifiedDiff("/dev/null", "b/f.go", nil, []string{"package main", ""})
+	want := `--- /dev/null
++++ b/f.go
+@@ -0,0 +1,2 @@
++package main
++
+`
+	if got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiff_SeparateHunks(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20; i++ {
+		line := string(rune('a' + i))
+		a = append(a, line)
+		b = append(b, line)
+	}
+	b[1] = "X"
+	b[18] = "Y"
+
+	got := unifiedDiff("a/f", "b/f", a, b)
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Errorf("expected 2 hunks, got %d:\n%s", n, got)
+	}
+}
+
+func TestFileSet_Diff(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "same.txt"), []byte("same\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "changed.txt"), []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := FileSet{
+		"same.txt":    []byte("same\n"),
+		"changed.txt": []byte("new\n"),
+		"sub/new.txt": []byte("added\n"),
+	}
+
+	diff, err := files.Diff(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Contains(diff, "same.txt") {
+		t.Error("expected unchanged file to be omitted from diff")
+	}
+	if !strings.Contains(diff, "-old\n+new\n") {
+		t.Errorf("expected changed file in diff, got:\n%s", diff)
+	}
+	if !strings.Contains(diff, "--- /dev/null\n+++ b/sub/new.txt") {
+		t.Errorf("expected new file diffed against /dev/null, got:\n%s", diff)
+	}
+}
+
+func TestRender_DoesNotWrite(t *testing.T) {
+	outDir := t.TempDir()
+
+	files, err := New(loadDapPlan(t), outDir).Render()
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	if _, ok := files["internal/commands/root.go"]; !ok {
+		t.Error("expected root.go to be rendered")
+	}
+
+	entries, _ := os.ReadDir(outDir)
+	if len(entries) > 0 {
+		t.Error("expected Render to leave the output directory untouched")
+	}
+}
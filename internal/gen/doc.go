@@ -20,4 +20,8 @@
 //	if err := generator.Generate(); err != nil {
 //	    log.Fatal(err)
 //	}
+//
+// Generate renders every file into an in-memory FileSet before writing it.
+// Render can be used directly to inspect the output, or to diff it against
+// an existing directory with FileSet.Diff, without touching the disk.
 package gen
@@ -1,12 +1,30 @@
 package gen
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	goruntime "runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/crunchloop/opencligen/internal/plan"
 	"github.com/crunchloop/opencligen/internal/spec"
@@ -77,6 +95,23 @@ func TestE2E_GeneratedCLI_Help(t *testing.T) {
 		}
 	})
 
+	// Test version output includes the API revision
+	t.Run("version", func(t *testing.T) {
+		output, err := exec.Command(binaryPath, "--version").CombinedOutput()
+		if err != nil {
+			t.Fatalf("version command failed: %v", err)
+		}
+
+		versionText := string(output)
+
+		if !strings.Contains(versionText, "dap version dev") {
+			t.Errorf("expected CLI version in output, got: %s", versionText)
+		}
+		if !strings.Contains(versionText, "API: DAP API 1.0.0 (sha256:"+p.Spec.SHA256+")") {
+			t.Errorf("expected spec title, version and hash in output, got: %s", versionText)
+		}
+	})
+
 	// Test tasks group help
 	t.Run("tasks group help", func(t *testing.T) {
 		output, err := exec.Command(binaryPath, "tasks", "--help").CombinedOutput()
@@ -213,6 +248,21 @@ func TestE2E_GeneratedCLI_RequiresBaseURL(t *testing.T) {
 	if !strings.Contains(string(output), "base URL is required") {
 		t.Errorf("expected error about missing base URL, got: %s", output)
 	}
+	// config init must work without a base URL
+	configHome := t.TempDir()
+	cmd = exec.Command(binaryPath, "config", "init")
+	cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+configHome)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("config init failed: %v\n%s", err, output)
+	}
+
+	info, err := os.Stat(filepath.Join(configHome, "dap", "config.yaml"))
+	if err != nil {
+		t.Fatalf("expected config file to be written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected config file permissions 0600, got %o", info.Mode().Perm())
+	}
 }
 
 func TestE2E_AnnotatedCLI(t *testing.T) {
@@ -300,3 +350,2375 @@ func TestE2E_AnnotatedCLI(t *testing.T) {
 		}
 	})
 }
+
+// buildTestCLI generates and builds a CLI from the given spec and returns
+// the path to the binary
+func buildTestCLI(t *testing.T, specPath, appName string) string {
+	t.Helper()
+	return buildTestCLIWith(t, specPath, appName, nil)
+}
+
+// buildTestCLIWith is like buildTestCLI but lets configure adjust the
+// generator before generation
+func buildTestCLIWith(t testing.TB, specPath, appName string, configure func(*Generator)) string {
+	t.Helper()
+
+	s, err := spec.Load(context.Background(), specPath)
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	return buildPlanCLI(t, plan.Build(s, appName, "github.com/example/"+appName), configure)
+}
+
+// buildPlanCLI generates and builds a CLI from a plan and returns the path to
+// the binary
+func buildPlanCLI(t testing.TB, p *plan.Plan, configure func(*Generator)) string {
+	t.Helper()
+
+	appName := p.AppName
+	outDir := t.TempDir()
+
+	g := New(p, outDir)
+	if configure != nil {
+		configure(g)
+	}
+	if err := g.Generate(); err != nil {
+		t.Fatalf("generation failed: %v", err)
+	}
+
+	tidyCmd := exec.Command("go", "mod", "tidy")
+	tidyCmd.Dir = outDir
+	if output, err := tidyCmd.CombinedOutput(); err != nil {
+		t.Fatalf("go mod tidy failed: %v\n%s", err, output)
+	}
+
+	binaryPath := filepath.Join(outDir, appName)
+	buildCmd := exec.Command("go", "build", "-o", binaryPath, "./cmd/"+appName)
+	buildCmd.Dir = outDir
+	if output, err := buildCmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, output)
+	}
+
+	return binaryPath
+}
+
+func TestE2E_PaginationEnvelope(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": [{"id": "b1"}, {"id": "b2"}], "next_cursor": "abc"}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+
+	cmd := exec.Command(binaryPath, "bookmarks", "list", "--base-url", server.URL, "--output", "jsonl")
+	var stdout, stderr strings.Builder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("list command failed: %v\n%s", err, stderr.String())
+	}
+
+	if stdout.String() != "{\"id\":\"b1\"}\n{\"id\":\"b2\"}\n" {
+		t.Errorf("expected unwrapped items, got %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "next cursor: abc") {
+		t.Errorf("expected next cursor trailer on stderr, got %q", stderr.String())
+	}
+}
+
+func TestE2E_PaginationAll(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			_, _ = w.Write([]byte(`{"data": [{"id": "b1"}, {"id": "b2"}], "next_cursor": "c2"}`))
+		case "c2":
+			_, _ = w.Write([]byte(`{"data": [{"id": "b3"}], "next_cursor": ""}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(args ...string) (string, string) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"bookmarks", "list", "--base-url", server.URL, "--all"}, args...)...)
+		var stdout, stderr strings.Builder
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("list --all failed: %v\n%s", err, stderr.String())
+		}
+		return stdout.String(), stderr.String()
+	}
+
+	stdout, _ := run("--output", "jsonl")
+	if stdout != "{\"id\":\"b1\"}\n{\"id\":\"b2\"}\n{\"id\":\"b3\"}\n" {
+		t.Errorf("expected the items of both pages, got %q", stdout)
+	}
+
+	stdout, _ = run("--output", "table", "--columns", "id,missing")
+	if stdout != "ID  MISSING\nb1  \nb2  \nb3  \n" {
+		t.Errorf("expected a table of both pages, got %q", stdout)
+	}
+
+	stdout, _ = run("--output", "jsonl", "--query", "[1:].id")
+	if stdout != "\"b2\"\n\"b3\"\n" {
+		t.Errorf("expected the query to apply to the items of both pages, got %q", stdout)
+	}
+
+	stdout, _ = run("--output", `go-template={{range .}}{{.id}} {{end}}`)
+	if stdout != "b1 b2 b3 " {
+		t.Errorf("expected the template to render the items of both pages, got %q", stdout)
+	}
+
+	stdout, stderr := run("--max-items", "2")
+	var items []map[string]string
+	if err := json.Unmarshal([]byte(stdout), &items); err != nil || len(items) != 2 {
+		t.Errorf("expected a JSON array of 2 items, got %v: %s", err, stdout)
+	}
+	if !strings.Contains(stderr, "stopped after 2 items") {
+		t.Errorf("expected a notice about --max-items, got %q", stderr)
+	}
+
+	// Operations that are not paginated have no --all
+	output, err := exec.Command(binaryPath, "bookmarks", "get", "b1", "--all").CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "unknown flag: --all") {
+		t.Errorf("expected --all to be rejected, got %v:\n%s", err, output)
+	}
+}
+
+func TestE2E_APIPassthrough(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var gotMethod, gotURI, gotAuth, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotMethod, gotURI, gotAuth, gotBody = r.Method, r.RequestURI, r.Header.Get("Authorization"), string(body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/dap.json", "dap")
+
+	output, err := exec.Command(binaryPath, "api", "post", "/v2/undocumented?x=1",
+		"--base-url", server.URL,
+		"--header", "Authorization: Bearer t0ken",
+		"--data", `{"a": 1}`,
+	).CombinedOutput()
+	if err != nil {
+		t.Fatalf("api command failed: %v\n%s", err, output)
+	}
+
+	if gotMethod != "POST" || gotURI != "/v2/undocumented?x=1" {
+		t.Errorf("unexpected request %s %s", gotMethod, gotURI)
+	}
+	if gotAuth != "Bearer t0ken" {
+		t.Errorf("expected global headers to be applied, got %q", gotAuth)
+	}
+	if gotBody != `{"a": 1}` {
+		t.Errorf("unexpected body %q", gotBody)
+	}
+	if !strings.Contains(string(output), `"ok": true`) {
+		t.Errorf("expected response to be printed, got %s", output)
+	}
+}
+
+func TestE2E_AuditLog(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/dap.json", "dap")
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+
+	for _, id := range []string{"1", "2"} {
+		cmd := exec.Command(binaryPath, "tasks", "get", id, "--base-url", server.URL)
+		cmd.Env = append(os.Environ(), "DAP_AUDIT_LOG="+auditPath)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("tasks get failed: %v\n%s", err, output)
+		}
+	}
+
+	output, err := exec.Command(binaryPath, "audit", "verify", auditPath).CombinedOutput()
+	if err != nil {
+		t.Fatalf("audit verify failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "2 entries verified") {
+		t.Errorf("expected 2 verified entries, got %s", output)
+	}
+
+	content, _ := os.ReadFile(auditPath)
+	if !strings.Contains(string(content), `"command":"dap tasks get"`) {
+		t.Errorf("expected command path in audit log, got %s", content)
+	}
+}
+
+func TestE2E_Find(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var gotURI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURI = r.RequestURI
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/dap.json", "dap")
+
+	output, err := exec.Command(binaryPath, "find", "tasks", "get").CombinedOutput()
+	if err != nil {
+		t.Fatalf("find failed: %v\n%s", err, output)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if !strings.HasPrefix(lines[0], "dap tasks get") {
+		t.Errorf("expected 'dap tasks get' to be the best match, got:\n%s", output)
+	}
+	if !strings.Contains(lines[0], "GET") {
+		t.Errorf("expected the HTTP method to be listed, got %q", lines[0])
+	}
+
+	output, err = exec.Command(binaryPath, "find", "--exec", "tasks", "get", "--", "42", "--base-url", server.URL).CombinedOutput()
+	if err != nil {
+		t.Fatalf("find --exec failed: %v\n%s", err, output)
+	}
+	if !strings.HasSuffix(gotURI, "/42") {
+		t.Errorf("expected the best match to run with passthrough args, got %q", gotURI)
+	}
+}
+
+func TestE2E_EmitPerGroup(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var gotURI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURI = r.RequestURI
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLIWith(t, "../testdata/dap.json", "dap", func(g *Generator) {
+		g.Emit = EmitPerGroup
+	})
+
+	output, err := exec.Command(binaryPath, "tasks", "--help").CombinedOutput()
+	if err != nil {
+		t.Fatalf("tasks --help failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "get") {
+		t.Errorf("expected tasks help to list get, got:\n%s", output)
+	}
+
+	output, err = exec.Command(binaryPath, "tasks", "get", "42", "--base-url", server.URL).CombinedOutput()
+	if err != nil {
+		t.Fatalf("tasks get failed: %v\n%s", err, output)
+	}
+	if !strings.HasSuffix(gotURI, "/42") {
+		t.Errorf("expected request for task 42, got %q", gotURI)
+	}
+}
+
+func TestE2E_CustomHelpTemplates(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	dir := t.TempDir()
+	usage := "Acme Corp CLI\n\nUsage:\n  {{.UseLine}}\n{{if .HasAvailableLocalFlags}}\nFlags:\n{{flagUsagesWithEnv .LocalFlags | trimTrailingWhitespaces}}\n{{end}}"
+	if err := os.WriteFile(filepath.Join(dir, "usage.tmpl"), []byte(usage), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	binaryPath := buildTestCLIWith(t, "../testdata/annotated.json", "annotated", func(g *Generator) {
+		g.TemplatesDir = dir
+	})
+
+	output, err := exec.Command(binaryPath, "tasks", "activities", "--help").CombinedOutput()
+	if err != nil {
+		t.Fatalf("help failed: %v\n%s", err, output)
+	}
+	helpText := string(output)
+
+	if !strings.Contains(helpText, "Acme Corp CLI") {
+		t.Errorf("expected branded usage, got:\n%s", helpText)
+	}
+	if !strings.Contains(helpText, "[$ORG_ID]") {
+		t.Errorf("expected the org flag to show its environment variable, got:\n%s", helpText)
+	}
+	if strings.Contains(helpText, "Global Flags:") {
+		t.Errorf("expected the custom template to replace cobra's, got:\n%s", helpText)
+	}
+}
+
+func TestE2E_StreamBuildTags(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	// Built without the tag, the streaming commands are left out
+	binaryPath := buildTestCLIWith(t, "../testdata/dap.json", "dap", func(g *Generator) {
+		g.StreamBuildTags = "streaming"
+	})
+
+	output, err := exec.Command(binaryPath, "--help").CombinedOutput()
+	if err != nil {
+		t.Fatalf("help failed: %v\n%s", err, output)
+	}
+	if strings.Contains(string(output), "stream") {
+		t.Errorf("expected the stream group to be excluded, got:\n%s", output)
+	}
+	if !strings.Contains(string(output), "tasks") {
+		t.Errorf("expected the other groups to remain, got:\n%s", output)
+	}
+}
+
+func TestE2E_NoGoMod(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	s, err := spec.Load(context.Background(), "../testdata/dap.json")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	// Generate into a subdirectory of an existing module
+	repoDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoDir, "go.mod"), []byte("module example.com/mono\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := plan.Build(s, "dap", "example.com/mono/tools/dap")
+	g := New(p, filepath.Join(repoDir, "tools", "dap"))
+	g.NoGoMod = true
+	if err := g.Generate(); err != nil {
+		t.Fatalf("generation failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(repoDir, "tools", "dap", "go.mod")); !os.IsNotExist(err) {
+		t.Fatal("expected no go.mod in the output directory")
+	}
+
+	args := []string{"get"}
+	for _, r := range g.Requirements() {
+		args = append(args, r.String())
+	}
+	getCmd := exec.Command("go", args...)
+	getCmd.Dir = repoDir
+	if output, err := getCmd.CombinedOutput(); err != nil {
+		t.Fatalf("go get failed: %v\n%s", err, output)
+	}
+
+	buildCmd := exec.Command("go", "build", "-o", filepath.Join(repoDir, "dap"), "./tools/dap/cmd/dap")
+	buildCmd.Dir = repoDir
+	if output, err := buildCmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, output)
+	}
+}
+
+func TestE2E_MockServer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	binaryPath := buildTestCLIWith(t, "../testdata/openapi30.yaml", "bookmarks", func(g *Generator) {
+		g.WithMock = true
+	})
+
+	outDir := filepath.Dir(binaryPath)
+	mockPath := filepath.Join(outDir, "bookmarks-mock")
+	buildCmd := exec.Command("go", "build", "-o", mockPath, "./cmd/bookmarks-mock")
+	buildCmd.Dir = outDir
+	if output, err := buildCmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, output)
+	}
+
+	// Reserve a free port for the mock
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	mock := exec.Command(mockPath, "-addr", addr)
+	if err := mock.Start(); err != nil {
+		t.Fatalf("failed to start mock: %v", err)
+	}
+	defer func() { _ = mock.Process.Kill(); _ = mock.Wait() }()
+
+	// Wait for the mock to accept connections
+	for i := 0; ; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if i == 50 {
+			t.Fatalf("mock server did not start: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	output, err := exec.Command(binaryPath, "bookmarks", "get", "any-id", "--base-url", "http://"+addr).CombinedOutput()
+	if err != nil {
+		t.Fatalf("bookmarks get failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "bm_123") {
+		t.Errorf("expected the example response, got:\n%s", output)
+	}
+
+	output, err = exec.Command(binaryPath, "bookmarks", "get", "--base-url", "http://"+addr+"/missing", "any-id").CombinedOutput()
+	if err == nil {
+		t.Errorf("expected an unknown path to fail, got:\n%s", output)
+	}
+}
+
+func TestE2E_BearerToken(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var auth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "bm_1"}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	configHome := t.TempDir()
+	run := func(env []string, args ...string) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"bookmarks", "get", "bm_1", "--base-url", server.URL}, args...)...)
+		cmd.Env = append(os.Environ(), append(env, "XDG_CONFIG_HOME="+configHome)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("bookmarks get failed: %v\n%s", err, output)
+		}
+	}
+
+	run(nil, "--token", "from-flag")
+	run([]string{"BOOKMARKS_TOKEN=from-env"})
+	run([]string{"BOOKMARKS_TOKEN=from-env"}, "--token", "from-flag")
+	run([]string{"BOOKMARKS_TOKEN=from-env"}, "--header", "Authorization: Basic abc")
+	run(nil)
+
+	// A credential helper supplies the token when none is set
+	configDir := filepath.Join(configHome, "bookmarks")
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	config := `auth_command: ["sh", "-c", "echo from-helper"]`
+	if err := os.WriteFile(filepath.Join(configDir, "config.yaml"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	run(nil)
+	run([]string{"BOOKMARKS_TOKEN=from-env"})
+
+	want := []string{"Bearer from-flag", "Bearer from-env", "Bearer from-flag", "Basic abc", "", "Bearer from-helper", "Bearer from-env"}
+	if strings.Join(auth, "|") != strings.Join(want, "|") {
+		t.Errorf("Authorization headers = %q, want %q", auth, want)
+	}
+
+	// The token is listed in help but its value never is
+	cmd := exec.Command(binaryPath, "--help")
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("help failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "Bearer token for the API (JWT)") || strings.Contains(string(output), "secret-value") {
+		t.Errorf("unexpected help output:\n%s", output)
+	}
+
+	// --verbose logs the request with the token masked
+	cmd = exec.Command(binaryPath, "bookmarks", "get", "bm_1", "--base-url", server.URL, "--verbose")
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("bookmarks get --verbose failed: %v\n%s", err, stderr.String())
+	}
+	log := stderr.String()
+	if !strings.Contains(log, "> GET "+server.URL+"/bookmarks/bm_1") || !strings.Contains(log, "> Authorization: Bearer ***") ||
+		!strings.Contains(log, "< HTTP/1.1 200 OK") || strings.Contains(log, "secret-value") {
+		t.Errorf("unexpected verbose log:\n%s", log)
+	}
+
+	// --timing prints the phases of the request
+	cmd = exec.Command(binaryPath, "bookmarks", "get", "bm_1", "--base-url", server.URL, "--timing")
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+	stderr.Reset()
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("bookmarks get --timing failed: %v\n%s", err, stderr.String())
+	}
+	if log := stderr.String(); !strings.HasPrefix(log, "* timing GET "+server.URL+"/bookmarks/bm_1: connect ") || !strings.Contains(log, ", ttfb ") {
+		t.Errorf("unexpected timing output:\n%s", log)
+	}
+}
+
+func TestE2E_DryRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"bookmarks", "create", "--base-url", server.URL, "--dry-run"}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	output, err := run("--idempotency-key", "key-1", "--data", `{"url": "https://example.com"}`)
+	if err != nil {
+		t.Fatalf("bookmarks create --dry-run failed: %v\n%s", err, output)
+	}
+	want := "POST " + server.URL + "/bookmarks\n" +
+		"Authorization: Bearer ***\n" +
+		"Content-Type: application/json\n" +
+		"User-Agent: bookmarks/dev (opencligen)\n" +
+		"X-Idempotency-Key: key-1\n" +
+		"\n" +
+		`{"url": "https://example.com"}` + "\n"
+	if output != want {
+		t.Errorf("dry run output = %q, want %q", output, want)
+	}
+
+	// Extra headers are added, and malformed ones rejected
+	output, err = run("-H", "X-Trace-Id: t1", "--idempotency-key", "key-1", "--data", `{"url": "https://example.com"}`)
+	if err != nil || !strings.Contains(output, "X-Trace-Id: t1\n") {
+		t.Errorf("expected the extra header in the request, got %v:\n%s", err, output)
+	}
+	output, err = run("--query-param", "beta=1", "--idempotency-key", "key-1", "--data", `{"url": "https://example.com"}`)
+	if err != nil || !strings.Contains(output, "/bookmarks?beta=1\n") {
+		t.Errorf("expected the extra query parameter in the request, got %v:\n%s", err, output)
+	}
+	if output, err := run("-H", "X-Trace-Id", "--idempotency-key", "key-1"); err == nil || !strings.Contains(output, `invalid header "X-Trace-Id"`) {
+		t.Errorf("expected the malformed header to be rejected, got %v:\n%s", err, output)
+	}
+
+	// Field arguments build the body in place of --data
+	output, err = run("--idempotency-key", "key-1", "url=https://example.com", "starred:=true", "tags[]=go")
+	if err != nil || !strings.HasSuffix(output, "\n"+`{"starred":true,"tags":["go"],"url":"https://example.com"}`+"\n") {
+		t.Errorf("expected a body built from the fields, got %v:\n%s", err, output)
+	}
+	if output, err := run("--idempotency-key", "key-1", "--data", `{}`, "url=https://example.com"); err == nil || !strings.Contains(output, "cannot be combined") {
+		t.Errorf("expected fields and --data to be rejected together, got %v:\n%s", err, output)
+	}
+	body := filepath.Join(t.TempDir(), "bookmark.json")
+	if err := os.WriteFile(body, []byte(`{"url": "https://example.com", "tags": ["go"]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	output, err = run("--idempotency-key", "key-1", "--data", "@"+body, "--set", "tags.-=cli", "--set", "meta.rank:=2")
+	if err != nil || !strings.HasSuffix(output, "\n"+`{"meta":{"rank":2},"tags":["go","cli"],"url":"https://example.com"}`+"\n") {
+		t.Errorf("expected --set to change the loaded body, got %v:\n%s", err, output)
+	}
+	if output, err := run("--idempotency-key", "key-1", "starred:=yes"); err == nil || !strings.Contains(output, `invalid JSON in field "starred:=yes"`) {
+		t.Errorf("expected invalid JSON in a field to be rejected, got %v:\n%s", err, output)
+	}
+
+	// The request is still validated
+	if output, err := run("--data", `{"url": "https://example.com"}`); err == nil {
+		t.Errorf("expected the missing required header to fail, got:\n%s", output)
+	}
+	output, err = run("--idempotency-key", "key-1", "--data", `{"title": 3}`)
+	if err == nil || !strings.Contains(output, "missing required property 'url'\n  title: expected string, got integer\n") {
+		t.Errorf("expected the body to be checked against its schema, got %v:\n%s", err, output)
+	}
+	if output, err := run("--idempotency-key", "key-1", "--data", `{"title": 3}`, "--no-validate"); err != nil || !strings.HasSuffix(output, "\n"+`{"title": 3}`+"\n") {
+		t.Errorf("expected --no-validate to send the body as it is, got %v:\n%s", err, output)
+	}
+	if calls != 0 {
+		t.Errorf("expected no request to reach the server, got %d", calls)
+	}
+}
+
+func TestE2E_RawAndQuiet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	body := "{\"id\":\"b1\",  \"url\":\"https://example.com\"}"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"bookmarks", "get", "b1", "--base-url", server.URL}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	if output, err := run("--raw"); err != nil || output != body {
+		t.Errorf("expected the body as received, got %v: %q", err, output)
+	}
+	if output, err := run("-q"); err != nil || output != "b1\n" {
+		t.Errorf("expected only the ID, got %v: %q", err, output)
+	}
+	if output, err := run("--raw", "--quiet"); err == nil || !strings.Contains(output, "[raw quiet]") {
+		t.Errorf("expected --raw and --quiet to be rejected together, got %v:\n%s", err, output)
+	}
+
+	path := filepath.Join(t.TempDir(), "b1.json")
+	if output, err := run("--output-file", path); err != nil || !strings.Contains(output, "Saved 41 B to "+path) {
+		t.Errorf("expected the body to be saved, got %v: %q", err, output)
+	}
+	if data, _ := os.ReadFile(path); string(data) != body {
+		t.Errorf("expected the file to hold the body as received, got %q", data)
+	}
+}
+
+func TestE2E_Compression(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var gotAccept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAccept = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.Contains(gotAccept, "gzip") {
+			_, _ = w.Write([]byte(`{"id": "b1"}`))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write([]byte(`{"id": "b1"}`))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"bookmarks", "get", "b1", "--base-url", server.URL, "-q"}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	if output, err := run(); err != nil || output != "b1\n" || gotAccept != "gzip, deflate" {
+		t.Errorf("expected a compressed response to be requested and decoded, got %q with %v:\n%s", gotAccept, err, output)
+	}
+	if output, err := run("--compressed=false"); err != nil || output != "b1\n" || gotAccept != "identity" {
+		t.Errorf("expected an uncompressed response to be requested, got %q with %v:\n%s", gotAccept, err, output)
+	}
+}
+
+func TestE2E_Redirects(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/bookmarks/b1" {
+			http.Redirect(w, r, "/bookmarks/b2", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "b2"}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(args ...string) (string, string, error) {
+		t.Helper()
+		requests = 0
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(binaryPath, append([]string{"bookmarks", "get", "b1", "--base-url", server.URL, "-q"}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		return stdout.String(), stderr.String(), err
+	}
+
+	if stdout, stderr, err := run(); err != nil || stdout != "b2\n" || requests != 2 {
+		t.Errorf("expected the redirect to be followed, got %v:\n%s%s", err, stdout, stderr)
+	}
+	if _, stderr, err := run("--max-redirects", "0"); err == nil || !strings.Contains(stderr, "stopped after 0 redirects") || requests != 1 {
+		t.Errorf("expected --max-redirects 0 to fail on the redirect, got %v:\n%s", err, stderr)
+	}
+	stdout, stderr, err := run("--no-follow")
+	if err != nil || stdout != server.URL+"/bookmarks/b2\n" || stderr != "Redirect not followed: 302 Found\n" || requests != 1 {
+		t.Errorf("expected --no-follow to print the redirect, got %v:\n%s%s", err, stdout, stderr)
+	}
+}
+
+func TestE2E_IfMatchAuto(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var ifMatch string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v3"`)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"id": "b1"}`))
+			return
+		}
+		ifMatch = r.Header.Get("If-Match")
+		if ifMatch != `"v3"` {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "b1"}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(ifMatch string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, "bookmarks", "update", "b1", "url=https://example.com", "--if-match", ifMatch, "--base-url", server.URL, "-q")
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	if output, err := run("auto"); err != nil || output != "b1\n" || ifMatch != `"v3"` {
+		t.Errorf("expected the current ETag to be sent, got %q with %v:\n%s", ifMatch, err, output)
+	}
+	if output, err := run(`"v2"`); err == nil || !strings.Contains(output, "412") {
+		t.Errorf("expected a stale ETag to fail, got %v:\n%s", err, output)
+	}
+}
+
+func TestE2E_Resolve(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var gotHost string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+		_, _ = w.Write([]byte(`{"id": "b1"}`))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	cmd := exec.Command(binaryPath, "bookmarks", "get", "b1", "-q",
+		"--base-url", "http://canary.example.invalid:"+port, "--resolve", "canary.example.invalid:"+port+":127.0.0.1")
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+	if output, err := cmd.CombinedOutput(); err != nil || string(output) != "b1\n" {
+		t.Fatalf("bookmarks get failed: %v\n%s", err, output)
+	}
+	if gotHost != "canary.example.invalid:"+port {
+		t.Errorf("expected the request to name the overridden host, got %q", gotHost)
+	}
+}
+
+func TestE2E_Proxy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var gotURL string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "b1"}`))
+	}))
+	defer proxy.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	cmd := exec.Command(binaryPath, "bookmarks", "get", "b1", "--base-url", "http://api.example.invalid", "--proxy", proxy.URL, "-q")
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("bookmarks get --proxy failed: %v\n%s", err, output)
+	}
+	if string(output) != "b1\n" || gotURL != "http://api.example.invalid/bookmarks/b1" {
+		t.Errorf("expected the request to go through the proxy, got %q for %q", output, gotURL)
+	}
+
+	// ALL_PROXY applies when the scheme has no proxy of its own
+	gotURL = ""
+	cmd = exec.Command(binaryPath, "bookmarks", "get", "b1", "--base-url", "http://api.example.invalid", "-q")
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir(),
+		"HTTP_PROXY=", "http_proxy=", "NO_PROXY=", "no_proxy=", "all_proxy=", "ALL_PROXY="+proxy.URL)
+	output, err = cmd.CombinedOutput()
+	if err != nil || gotURL != "http://api.example.invalid/bookmarks/b1" {
+		t.Errorf("expected the request to go through ALL_PROXY, got %v for %q:\n%s", err, gotURL, output)
+	}
+}
+
+func TestE2E_TLS(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "b1"}`))
+	}))
+	defer server.Close()
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"bookmarks", "get", "b1", "--base-url", server.URL, "-q"}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	if output, err := run(); err == nil || !strings.Contains(output, "certificate") {
+		t.Errorf("expected the untrusted certificate to be rejected, got %v:\n%s", err, output)
+	}
+	if output, err := run("--cacert", caPath); err != nil || output != "b1\n" {
+		t.Errorf("expected the CA bundle to be trusted, got %v:\n%s", err, output)
+	}
+	if output, err := run("--insecure-skip-verify"); err != nil || !strings.Contains(output, "Warning: TLS certificate verification is disabled") {
+		t.Errorf("expected verification to be skipped with a warning, got %v:\n%s", err, output)
+	}
+}
+
+func TestE2E_ResponseCache(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"id": "b1"}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	cacheHome := t.TempDir()
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, args...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir(), "XDG_CACHE_HOME="+cacheHome)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	for i := 0; i < 2; i++ {
+		if output, err := run("bookmarks", "get", "b1", "--base-url", server.URL, "--cache", "-q"); err != nil || output != "b1\n" {
+			t.Fatalf("bookmarks get --cache failed: %v\n%s", err, output)
+		}
+	}
+	if notModified != 1 {
+		t.Errorf("expected the second request to be answered from the cache, got %d 304s", notModified)
+	}
+
+	if output, err := run("cache", "clear"); err != nil || !strings.Contains(output, "Cleared "+filepath.Join(cacheHome, "bookmarks", "responses")) {
+		t.Errorf("cache clear failed: %v\n%s", err, output)
+	}
+	if _, err := os.Stat(filepath.Join(cacheHome, "bookmarks", "responses")); !os.IsNotExist(err) {
+		t.Errorf("expected the cache to be removed, got %v", err)
+	}
+}
+
+func TestE2E_ExitCodes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	exitCode := func(configHome string, baseURL string) int {
+		t.Helper()
+		cmd := exec.Command(binaryPath, "bookmarks", "get", "b1", "--base-url", baseURL)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+configHome)
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("expected the command to fail, got %v", err)
+		}
+		return exitErr.ExitCode()
+	}
+
+	if code := exitCode(t.TempDir(), server.URL); code != 2 {
+		t.Errorf("expected exit code 2 for a 404, got %d", code)
+	}
+	if code := exitCode(t.TempDir(), "http://127.0.0.1:1"); code != 4 {
+		t.Errorf("expected exit code 4 for a refused connection, got %d", code)
+	}
+
+	configHome := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configHome, "bookmarks"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configHome, "bookmarks", "config.yaml"), []byte("exit_codes:\n  404: 10\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if code := exitCode(configHome, server.URL); code != 10 {
+		t.Errorf("expected the configured exit code 10 for a 404, got %d", code)
+	}
+
+	// The error quotes the request ID for support
+	cmd := exec.Command(binaryPath, "bookmarks", "get", "b1", "--base-url", server.URL, "--request-id", "support-1234")
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+	output, _ := cmd.CombinedOutput()
+	if !strings.Contains(string(output), "request failed with status 404 (request ID support-1234)") {
+		t.Errorf("expected the request ID in the error, got:\n%s", output)
+	}
+}
+
+func TestE2E_JSONErrors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error": "not found"}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(args ...string) (string, string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"--base-url", server.URL}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+		var stdout, stderr strings.Builder
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		return stdout.String(), stderr.String(), err
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"bookmarks", "get", "b1", "--output", "json"}, `{"error":{"status":404,"message":"request failed with status 404","body":{"error":"not found"}}}`},
+		{[]string{"bookmarks", "get", "--output", "jsonl"}, `{"error":{"message":"missing required argument: bookmarkId"}}`},
+	}
+	for _, tt := range tests {
+		stdout, stderr, err := run(tt.args...)
+		if err == nil || stdout != "" || stderr != tt.want+"\n" {
+			t.Errorf("%v: expected a JSON error on stderr, got %v\nstdout: %s\nstderr: %s", tt.args, err, stdout, stderr)
+		}
+	}
+
+	// Without an explicit JSON format, errors stay as text
+	_, stderr, _ := run("bookmarks", "get", "b1")
+	if !strings.HasPrefix(stderr, "Error: HTTP 404") {
+		t.Errorf("expected a text error by default, got:\n%s", stderr)
+	}
+}
+
+func TestE2E_CookieJar(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var gotCookie string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCookie = r.Header.Get("Cookie")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/"})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "b1"}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	configHome := t.TempDir()
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, args...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+configHome)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	for i, want := range []string{"", "session=s1"} {
+		if output, err := run("bookmarks", "get", "b1", "--base-url", server.URL, "--cookie-jar"); err != nil {
+			t.Fatalf("bookmarks get --cookie-jar failed: %v\n%s", err, output)
+		}
+		if gotCookie != want {
+			t.Errorf("run %d: expected cookie %q, got %q", i+1, want, gotCookie)
+		}
+	}
+
+	if output, err := run("cookies", "clear"); err != nil || !strings.Contains(output, "cookies.json") {
+		t.Fatalf("cookies clear failed: %v\n%s", err, output)
+	}
+	if output, err := run("bookmarks", "get", "b1", "--base-url", server.URL, "--cookie-jar"); err != nil || gotCookie != "" {
+		t.Errorf("expected no cookie after cookies clear, got %q, %v\n%s", gotCookie, err, output)
+	}
+}
+
+func TestE2E_Profiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var gotAuth, gotEnv string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotEnv = r.Header.Get("Authorization"), r.Header.Get("X-Env")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "b1"}`))
+	}))
+	defer server.Close()
+
+	configHome := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configHome, "bookmarks"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	config := "base_url: http://127.0.0.1:1\n" +
+		"profiles:\n" +
+		"  staging:\n" +
+		"    base_url: " + server.URL + "\n" +
+		"    token: staging-token\n" +
+		"    headers:\n" +
+		"      X-Env: staging\n"
+	if err := os.WriteFile(filepath.Join(configHome, "bookmarks", "config.yaml"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(env []string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"bookmarks", "get", "b1"}, args...)...)
+		cmd.Env = append(os.Environ(), append(env, "XDG_CONFIG_HOME="+configHome)...)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	if output, err := run(nil, "--profile", "staging"); err != nil {
+		t.Fatalf("bookmarks get --profile staging failed: %v\n%s", err, output)
+	}
+	if gotAuth != "Bearer staging-token" || gotEnv != "staging" {
+		t.Errorf("expected the staging token and headers, got %q and %q", gotAuth, gotEnv)
+	}
+
+	gotAuth = ""
+	if output, err := run([]string{"BOOKMARKS_PROFILE=staging"}); err != nil || gotAuth != "Bearer staging-token" {
+		t.Errorf("expected BOOKMARKS_PROFILE to select the profile, got %q, %v\n%s", gotAuth, err, output)
+	}
+
+	if output, err := run(nil, "--profile", "prod"); err == nil || !strings.Contains(output, `unknown profile "prod" (must be one of: staging)`) {
+		t.Errorf("expected an unknown profile error, got %v\n%s", err, output)
+	}
+}
+
+func TestE2E_Batch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var mu sync.Mutex
+	created := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "POST":
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			created[r.Header.Get("X-Idempotency-Key")] = string(body)
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		case r.URL.Path == "/bookmarks/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": "not found"}`))
+		default:
+			_, _ = w.Write([]byte(`{"id": "` + path.Base(r.URL.Path) + `"}`))
+		}
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"bookmarks", "--base-url", server.URL, "--batch"}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		return string(output), err
+	}
+
+	output, err := run(`{"args": ["b1"]}`+"\n"+`{"args": ["missing"]}`+"\n"+`{"args": ["b2"], "nope": 1}`+"\n"+`{"args": ["b3"]}`, "get", "--concurrency", "2")
+	if err == nil {
+		t.Error("expected the failed lines to fail the command")
+	}
+	want := `{"line":1,"status":200,"response":{"id":"b1"}}` + "\n" +
+		`{"line":2,"status":404,"response":{"error":"not found"},"error":"request failed with status 404"}` + "\n" +
+		`{"line":3,"error":"unknown flag --nope"}` + "\n" +
+		`{"line":4,"status":200,"response":{"id":"b3"}}` + "\n"
+	if output != want {
+		t.Errorf("unexpected batch output:\n%s\nwant:\n%s", output, want)
+	}
+
+	// Flags on the command line apply to every line; a line's own win
+	output, err = run(`{"data": {"url": "https://a.example.com"}}`+"\n"+`{"idempotency-key": "k2", "data": {"url": "https://b.example.com"}}`, "create", "--idempotency-key", "k1")
+	if err != nil {
+		t.Fatalf("bookmarks create --batch failed: %v\n%s", err, output)
+	}
+	if created["k1"] != `{"url": "https://a.example.com"}` || created["k2"] != `{"url": "https://b.example.com"}` {
+		t.Errorf("expected a bookmark per line, got %v", created)
+	}
+	if !strings.Contains(output, `{"line":2,"status":201,"response":{"url":"https://b.example.com"}}`) {
+		t.Errorf("expected a result per line, got:\n%s", output)
+	}
+}
+
+func TestE2E_DateFlags(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"events", "subscribe", "--base-url", "https://api.example.com", "--dry-run"}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	output, err := run("--since", "2024-01-01T08:00:00+02:00")
+	if err != nil || !strings.Contains(output, "/events?since=2024-01-01T06%3A00%3A00Z\n") {
+		t.Errorf("expected the time in UTC RFC 3339, got %v\n%s", err, output)
+	}
+	since := time.Now().UTC().Add(-24 * time.Hour).Format("2006-01-02T15")
+	if output, err := run("--since", "now-24h"); err != nil || !strings.Contains(output, "/events?since="+url.QueryEscape(since)) {
+		t.Errorf("expected a relative time to be resolved, got %v\n%s", err, output)
+	}
+	if output, err := run("--since", "last week"); err == nil || !strings.Contains(output, `invalid value for --since: invalid date-time "last week"`) {
+		t.Errorf("expected an invalid time to be rejected, got %v\n%s", err, output)
+	}
+}
+
+func TestE2E_ParamChecks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"--base-url", "https://api.example.com", "--dry-run"}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	if output, err := run("bookmarks", "list", "--per-page", "50"); err != nil {
+		t.Errorf("expected a value in range to pass, got %v\n%s", err, output)
+	}
+	if output, err := run("bookmarks", "list", "--per-page", "500"); err == nil || !strings.Contains(output, "invalid value for --per-page: 500 is above the maximum of 100") {
+		t.Errorf("expected the maximum to be enforced, got %v\n%s", err, output)
+	}
+	if output, err := run("export", "get", "--format", "xml"); err == nil || !strings.Contains(output, `invalid value for --format: "xml" is not one of: json, html, csv`) {
+		t.Errorf("expected the enum to be enforced, got %v\n%s", err, output)
+	}
+
+	// Lines of --batch input are checked as the command line is
+	cmd := exec.Command(binaryPath, "--base-url", "https://api.example.com", "--dry-run", "bookmarks", "list", "--batch")
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+	cmd.Stdin = strings.NewReader(`{"per-page": 500}` + "\n")
+	output, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), `"error":"invalid value for --per-page: 500 is above the maximum of 100"`) {
+		t.Errorf("expected the batch line to be checked, got %v\n%s", err, output)
+	}
+}
+
+func TestE2E_EnumCompletion(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	// Completion works before a base URL is configured
+	cmd := exec.Command(binaryPath, "__complete", "export", "get", "--format", "")
+	cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+t.TempDir())
+	output, err := cmd.CombinedOutput()
+	if err != nil || !strings.HasPrefix(string(output), "json\nhtml\ncsv\n:4\n") {
+		t.Errorf("expected the enum values without file completion, got %v\n%s", err, output)
+	}
+}
+
+func TestE2E_Accept(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.Header.Get("Accept"))
+		_, _ = w.Write([]byte("id,title\nb1,Go\n"))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	cmd := exec.Command(binaryPath, "export", "get", "--format", "csv", "--accept", "text/csv", "--base-url", server.URL)
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+	if output, err := cmd.CombinedOutput(); err != nil || string(output) != "id,title\nb1,Go\n" {
+		t.Errorf("expected the CSV as received, got %v\n%s", err, output)
+	}
+
+	cmd = exec.Command(binaryPath, "__complete", "export", "get", "--accept", "")
+	cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+t.TempDir())
+	if output, err := cmd.CombinedOutput(); err != nil || !strings.HasPrefix(string(output), "application/json\ntext/csv\ntext/html\n:4\n") {
+		t.Errorf("expected the declared content types, got %v\n%s", err, output)
+	}
+
+	// Operations with a single content type have no --accept
+	cmd = exec.Command(binaryPath, "bookmarks", "get", "b1", "--accept", "text/csv", "--base-url", server.URL)
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+	if output, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(output), "unknown flag: --accept") {
+		t.Errorf("expected --accept to be rejected, got %v\n%s", err, output)
+	}
+}
+
+func TestE2E_APICompletion(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var auth string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items": [{"id": "t1"}, {"id": "t2"}]}`))
+	}))
+	defer api.Close()
+
+	specPath := filepath.Join(t.TempDir(), "spec.yaml")
+	specContent := `openapi: 3.0.3
+info:
+  title: Acme
+  version: "1.0"
+paths:
+  /things:
+    get:
+      operationId: listThings
+      tags: [things]
+      x-cli:
+        envelope:
+          items: items
+      responses:
+        "200":
+          description: OK
+  /things/{thingId}:
+    get:
+      operationId: getThing
+      tags: [things]
+      parameters:
+        - name: thingId
+          in: path
+          required: true
+          schema:
+            type: string
+          x-cli:
+            complete:
+              operationId: listThings
+      responses:
+        "200":
+          description: OK
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+security:
+  - bearer: []
+`
+	if err := os.WriteFile(specPath, []byte(specContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	binaryPath := buildTestCLI(t, specPath, "acme")
+	cmd := exec.Command(binaryPath, "__complete", "things", "get", "--base-url", api.URL, "")
+	cmd.Env = append(os.Environ(), "ACME_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+	output, err := cmd.CombinedOutput()
+	if err != nil || !strings.HasPrefix(string(output), "t1\nt2\n:4\n") {
+		t.Errorf("expected the IDs from the list endpoint, got %v\n%s", err, output)
+	}
+	if auth != "Bearer secret-value" {
+		t.Errorf("expected the completion request to be authenticated, got %q", auth)
+	}
+
+	// Without a base URL nothing is completed, rather than files
+	cmd = exec.Command(binaryPath, "__complete", "things", "get", "")
+	cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+t.TempDir())
+	output, err = cmd.CombinedOutput()
+	if err != nil || !strings.HasPrefix(string(output), ":4\n") {
+		t.Errorf("expected no completions, got %v\n%s", err, output)
+	}
+}
+
+func TestE2E_UserAgent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(configHome string, args ...string) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"bookmarks", "get", "b1", "--base-url", server.URL}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+configHome)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("bookmarks get failed: %v\n%s", err, output)
+		}
+	}
+
+	run(t.TempDir())
+	if userAgent != "bookmarks/dev (opencligen)" {
+		t.Errorf("expected the CLI's User-Agent, got %q", userAgent)
+	}
+
+	configHome := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configHome, "bookmarks"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configHome, "bookmarks", "config.yaml"), []byte("user_agent: nightly-sync/2.0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	run(configHome)
+	if userAgent != "nightly-sync/2.0" {
+		t.Errorf("expected the configured User-Agent, got %q", userAgent)
+	}
+	run(configHome, "-H", "User-Agent: probe")
+	if userAgent != "probe" {
+		t.Errorf("expected -H to replace the User-Agent, got %q", userAgent)
+	}
+}
+
+func TestE2E_Tracing(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var traceParent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get("traceparent")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	var exported string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		exported = r.URL.Path + " " + string(body)
+	}))
+	defer collector.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(env ...string) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, "bookmarks", "get", "b1", "--base-url", server.URL)
+		cmd.Env = append(os.Environ(), append([]string{"BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME=" + t.TempDir()}, env...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("bookmarks get failed: %v\n%s", err, output)
+		}
+	}
+
+	// Off unless a collector is configured
+	run("OTEL_EXPORTER_OTLP_ENDPOINT=")
+	if traceParent != "" || exported != "" {
+		t.Errorf("expected no tracing by default, got %q and %q", traceParent, exported)
+	}
+
+	run("OTEL_EXPORTER_OTLP_ENDPOINT="+collector.URL, "TRACEPARENT=00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	if !strings.HasPrefix(traceParent, "00-0af7651916cd43dd8448eb211c80319c-") {
+		t.Errorf("expected the request to continue the trace, got %q", traceParent)
+	}
+	for _, want := range []string{"/v1/traces ", `"name":"bookmarks bookmarks get"`, `"parentSpanId":"b7ad6b7169203331"`, `"stringValue":"bookmarks"`} {
+		if !strings.Contains(exported, want) {
+			t.Errorf("expected the export to contain %s, got %s", want, exported)
+		}
+	}
+}
+
+func TestE2E_LogFile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id": "b1"}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	logPath := filepath.Join(t.TempDir(), "requests.log")
+	for _, args := range [][]string{{"--log-file", logPath}, nil} {
+		cmd := exec.Command(binaryPath, append([]string{"bookmarks", "get", "b1", "--base-url", server.URL, "-q"}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir(), "BOOKMARKS_LOG_FILE="+logPath)
+		if output, err := cmd.CombinedOutput(); err != nil || string(output) != "b1\n" {
+			t.Fatalf("bookmarks get failed: %v\n%s", err, output)
+		}
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a line per run, from the flag and the env var, got:\n%s", data)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("invalid log line: %v", err)
+	}
+	if entry["command"] != "bookmarks bookmarks get" || entry["status"] != 200.0 || entry["url"] != server.URL+"/bookmarks/b1" {
+		t.Errorf("unexpected log line: %s", lines[0])
+	}
+	if strings.Contains(string(data), "secret-value") {
+		t.Errorf("expected the token to be masked, got:\n%s", data)
+	}
+}
+
+func TestE2E_StdinValues(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(stdin string, args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"--base-url", "https://api.example.com", "--dry-run"}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	if output, err := run(" b1\n", "bookmarks", "get", "-"); err != nil || !strings.HasPrefix(output, "GET https://api.example.com/bookmarks/b1\n") {
+		t.Errorf("expected the positional argument from stdin, got %v\n%s", err, output)
+	}
+	if output, err := run("now-1h\n", "events", "subscribe", "--since", "-"); err != nil || !strings.Contains(output, "/events?since=") {
+		t.Errorf("expected the flag from stdin, as a date, got %v\n%s", err, output)
+	}
+	if output, err := run("b1\nb2\n", "bookmarks", "get", "-"); err == nil || !strings.Contains(output, "more than one line") {
+		t.Errorf("expected several lines to be rejected, got %v\n%s", err, output)
+	}
+	if output, err := run("{}", "bookmarks", "update", "-", "--data", "@-"); err == nil || !strings.Contains(output, "cannot both read from stdin") {
+		t.Errorf("expected \"-\" and --data @- to be rejected together, got %v\n%s", err, output)
+	}
+}
+
+func TestE2E_NoInput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(env string, args ...string) (string, error) {
+		t.Helper()
+		// /dev/null is a character device, so it stands in for a terminal
+		tty, err := os.Open(os.DevNull)
+		if err != nil {
+			t.Skipf("no %s: %v", os.DevNull, err)
+		}
+		defer tty.Close()
+		cmd := exec.Command(binaryPath, append([]string{"--base-url", "https://api.example.com", "--dry-run"}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir(), env)
+		cmd.Stdin = tty
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	for _, tt := range []struct {
+		env  string
+		args []string
+	}{
+		{"", []string{"bookmarks", "get", "-", "--no-input"}},
+		{"BOOKMARKS_NO_INPUT=1", []string{"bookmarks", "get", "-"}},
+		{"", []string{"bookmarks", "update", "b1", "--data", "@-", "--no-input"}},
+		{"", []string{"bookmarks", "get", "--batch", "--no-input"}},
+	} {
+		output, err := run(tt.env, tt.args...)
+		if err == nil || !strings.Contains(output, "input required but --no-input is set") {
+			t.Errorf("%v: expected stdin not to be read, got %v\n%s", tt.args, err, output)
+		}
+	}
+
+	// Missing values fail with just the error, without the usage text
+	output, err := run("", "bookmarks", "get", "--no-input")
+	if err == nil || output != "Error: missing required argument: bookmarkId\n" {
+		t.Errorf("expected only the error, got %v\n%s", err, output)
+	}
+}
+
+func TestE2E_ConfigGetSet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	configHome := t.TempDir()
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, args...)
+		cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+configHome)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	for _, args := range [][]string{
+		{"config", "init"},
+		{"config", "set", "base_url", "https://api.example.com"},
+		{"config", "set", "profiles.staging.base_url", "https://staging.example.com"},
+		{"config", "set", "profiles.staging.token", "staging-token"},
+		{"config", "set", "read_only", "true"},
+		{"config", "unset", "read_only"},
+	} {
+		if output, err := run(args...); err != nil {
+			t.Fatalf("%v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	path := filepath.Join(configHome, "bookmarks", "config.yaml")
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the config file with 0600 permissions, got %v", err)
+	}
+
+	output, err := run("config", "get", "profiles.staging.token")
+	if err != nil || output != "staging-token\n" {
+		t.Errorf("expected the stored token, got %v\n%s", err, output)
+	}
+	output, err = run("config", "list")
+	want := "base_url=https://api.example.com\nprofiles.staging.base_url=https://staging.example.com\nprofiles.staging.token=***\n"
+	if err != nil || output != want {
+		t.Errorf("expected\n%s\ngot %v\n%s", want, err, output)
+	}
+
+	// The settings are used by API commands
+	output, err = run("bookmarks", "get", "b1", "--dry-run", "--profile", "staging")
+	if err != nil || !strings.Contains(output, "GET https://staging.example.com/bookmarks/b1") || !strings.Contains(output, "Bearer ***") {
+		t.Errorf("expected the staging profile to be used, got %v\n%s", err, output)
+	}
+
+	// Keys must be settings of the config file
+	output, err = run("config", "set", "base_ulr", "https://api.example.com")
+	if err == nil || !strings.Contains(output, `unknown config key "base_ulr"`) {
+		t.Errorf("expected an unknown key to be rejected, got %v\n%s", err, output)
+	}
+	output, err = run("config", "get", "read_only")
+	if err == nil || !strings.Contains(output, "read_only is not set") {
+		t.Errorf("expected a removed key to be missing, got %v\n%s", err, output)
+	}
+}
+
+func TestE2E_CredentialsFile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	configHome := t.TempDir()
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, args...)
+		cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+configHome, "XDG_CACHE_HOME="+t.TempDir())
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	for _, args := range [][]string{
+		{"config", "set", "base_url", "https://api.example.com"},
+		{"config", "set", "token", "file-token"},
+		{"config", "set", "credential_store", "credentials_file"},
+		{"config", "set", "token", "secret-value"},
+	} {
+		if output, err := run(args...); err != nil {
+			t.Fatalf("%v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	// The token moved from the config file to the credentials file
+	config, err := os.ReadFile(filepath.Join(configHome, "bookmarks", "config.yaml"))
+	if err != nil || strings.Contains(string(config), "token") {
+		t.Errorf("expected no token in the config file, got %v\n%s", err, config)
+	}
+	credentials := filepath.Join(configHome, "bookmarks", "credentials.yaml")
+	data, err := os.ReadFile(credentials)
+	if err != nil || string(data) != "token: secret-value\n" {
+		t.Errorf("expected the token in the credentials file, got %v\n%s", err, data)
+	}
+	output, err := run("bookmarks", "get", "b1", "--dry-run", "--verbose")
+	if err != nil || !strings.Contains(output, "Bearer ***") {
+		t.Errorf("expected the stored token to be sent, got %v\n%s", err, output)
+	}
+
+	// A credentials file others can read is refused until fixed
+	if err := os.Chmod(credentials, 0644); err != nil {
+		t.Fatal(err)
+	}
+	output, err = run("bookmarks", "get", "b1", "--dry-run")
+	if err == nil || !strings.Contains(output, "accessible by other users") {
+		t.Errorf("expected insecure permissions to be refused, got %v\n%s", err, output)
+	}
+	output, err = run("config", "fix-permissions")
+	if err != nil || !strings.Contains(output, "Fixed "+credentials+" (644 -> 600)") {
+		t.Errorf("expected the credentials file to be fixed, got %v\n%s", err, output)
+	}
+	if output, err := run("bookmarks", "get", "b1", "--dry-run"); err != nil {
+		t.Errorf("expected the fixed file to be used, got %v\n%s", err, output)
+	}
+}
+
+func TestE2E_Servers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	configHome := t.TempDir()
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, append([]string{"bookmarks", "get", "b1", "--dry-run"}, args...)...)
+		cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+configHome)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	const production = "GET https://api.bookmarks.example.com/v2/bookmarks/b1"
+	if output, err := run("--server", "production"); err != nil || !strings.Contains(output, production) {
+		t.Errorf("expected --server to select the production URL, got %v\n%s", err, output)
+	}
+	if output, err := run("--server", "staging"); err == nil || !strings.Contains(output, `unknown server "staging" (must be one of: production)`) {
+		t.Errorf("expected an unknown server error, got %v\n%s", err, output)
+	}
+	if output, err := run("--server", "production", "--base-url", "http://127.0.0.1:1"); err == nil || !strings.Contains(output, "none of the others can be") {
+		t.Errorf("expected --server and --base-url to be mutually exclusive, got %v\n%s", err, output)
+	}
+
+	if err := os.MkdirAll(filepath.Join(configHome, "bookmarks"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(configHome, "bookmarks", "config.yaml"), []byte("server: production\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := run(); err != nil || !strings.Contains(output, production) {
+		t.Errorf("expected the server setting to select the production URL, got %v\n%s", err, output)
+	}
+}
+
+func TestE2E_EventStream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("id: 1\nevent: created\ndata: {\"id\": \"b1\"}\n\n" +
+			"id: 2\nevent: deleted\ndata: {\"id\": \"b0\"}\n\n"))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	cmd := exec.Command(binaryPath, "events", "subscribe", "--base-url", server.URL, "--event", "created", "--event-metadata")
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("events subscribe failed: %v\n%s", err, output)
+	}
+	if string(output) != `{"event":"created","id":"1","data":{"id":"b1"}}`+"\n" {
+		t.Errorf("expected only the created event with its metadata, got %q", output)
+	}
+	if goruntime.GOOS == "windows" {
+		return
+	}
+	cmd = exec.Command(binaryPath, "events", "subscribe", "--base-url", server.URL, "--exec", `echo "$EVENT_ID:$EVENT_NAME:$(cat)"`)
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+	if output, err = cmd.CombinedOutput(); err != nil {
+		t.Fatalf("events subscribe --exec failed: %v\n%s", err, output)
+	}
+	if string(output) != "1:created:{\"id\": \"b1\"}\n2:deleted:{\"id\": \"b0\"}\n" {
+		t.Errorf("expected the command to run for each event, got %q", output)
+	}
+}
+
+func TestE2E_Interrupt(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+	if goruntime.GOOS == "windows" {
+		t.Skip("os.Interrupt cannot be sent on windows")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"id\": \"b1\"}\n\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	cmd := exec.Command(binaryPath, "events", "subscribe", "--base-url", server.URL)
+	cmd.Env = append(os.Environ(), "BOOKMARKS_TOKEN=secret-value", "XDG_CONFIG_HOME="+t.TempDir())
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Interrupt once the first event is printed
+	first := make([]byte, 1)
+	if _, err := stdout.Read(first); err != nil {
+		t.Fatalf("expected an event before the interrupt: %v", err)
+	}
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, stdout)
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 130 {
+		t.Errorf("expected exit code 130, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "interrupted; the output is incomplete") {
+		t.Errorf("expected a partial output notice, got:\n%s", stderr.String())
+	}
+}
+
+func TestE2E_KeychainToken(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+	if goruntime.GOOS != "linux" {
+		t.Skip("the fake keychain is a secret-tool script")
+	}
+
+	// A secret-tool that keeps secrets as files
+	binDir, secrets := t.TempDir(), t.TempDir()
+	script := `#!/bin/sh
+cmd=$1; shift
+while [ $# -gt 0 ]; do
+	case $1 in
+	service) svc=$2; shift 2 ;;
+	account) acct=$2; shift 2 ;;
+	*) shift ;;
+	esac
+done
+f="` + secrets + `/$svc.$acct"
+case $cmd in
+store) cat > "$f" ;;
+lookup) [ -f "$f" ] || exit 1; cat "$f" ;;
+clear) [ -f "$f" ] || exit 1; rm "$f" ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(binDir, "secret-tool"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var auth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": "bm_1"}`))
+	}))
+	defer server.Close()
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+	env := append(os.Environ(), "PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"), "XDG_CONFIG_HOME="+t.TempDir())
+
+	cmd := exec.Command(binaryPath, "config", "set-secret", "token")
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader("kc-token\n")
+	if output, err := cmd.CombinedOutput(); err != nil || !strings.Contains(string(output), "Stored token in the keychain") {
+		t.Fatalf("config set-secret failed: %v\n%s", err, output)
+	}
+
+	for _, store := range []string{"keychain", "file"} {
+		cmd := exec.Command(binaryPath, "bookmarks", "get", "bm_1", "--base-url", server.URL)
+		cmd.Env = append(env, "BOOKMARKS_CREDENTIAL_STORE="+store)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("bookmarks get failed: %v\n%s", err, output)
+		}
+	}
+	if strings.Join(auth, "|") != "Bearer kc-token|" {
+		t.Errorf("expected the keychain token only with credential_store keychain, got %q", auth)
+	}
+
+	cmd = exec.Command(binaryPath, "config", "set-secret", "password")
+	cmd.Env = env
+	if output, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(output), "invalid secret") {
+		t.Errorf("expected an invalid secret error, got %v:\n%s", err, output)
+	}
+}
+
+func TestE2E_ClientCredentials(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var tokenRequests int
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		if id, secret, _ := r.BasicAuth(); id != "my-client" || secret != "s3cret" || r.FormValue("scope") != "read" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "access-1", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer tokenServer.Close()
+
+	var auth []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok": true}`))
+	}))
+	defer api.Close()
+
+	specPath := filepath.Join(t.TempDir(), "spec.yaml")
+	specContent := `openapi: 3.0.3
+info:
+  title: Acme
+  version: "1.0"
+paths:
+  /things:
+    get:
+      operationId: listThings
+      tags: [things]
+      responses:
+        "200":
+          description: OK
+components:
+  securitySchemes:
+    oauth:
+      type: oauth2
+      flows:
+        clientCredentials:
+          tokenUrl: ` + tokenServer.URL + `
+          scopes:
+            read: Read access
+security:
+  - oauth: [read]
+`
+	if err := os.WriteFile(specPath, []byte(specContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	binaryPath := buildTestCLI(t, specPath, "acme")
+	cacheHome := t.TempDir()
+	for i := 0; i < 2; i++ {
+		cmd := exec.Command(binaryPath, "things", "list", "--base-url", api.URL, "--client-id", "my-client")
+		cmd.Env = append(os.Environ(), "ACME_CLIENT_SECRET=s3cret", "XDG_CACHE_HOME="+cacheHome, "XDG_CONFIG_HOME="+t.TempDir())
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("things list failed: %v\n%s", err, output)
+		}
+	}
+
+	// The second run reuses the cached token
+	if tokenRequests != 1 {
+		t.Errorf("expected 1 token request, got %d", tokenRequests)
+	}
+	if strings.Join(auth, ",") != "Bearer access-1,Bearer access-1" {
+		t.Errorf("unexpected Authorization headers: %q", auth)
+	}
+
+	// Bad credentials fail with the token endpoint's error
+	cmd := exec.Command(binaryPath, "things", "list", "--base-url", api.URL, "--client-id", "other", "--client-secret", "x")
+	cmd.Env = append(os.Environ(), "XDG_CACHE_HOME="+cacheHome, "XDG_CONFIG_HOME="+t.TempDir())
+	output, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "invalid_client") {
+		t.Errorf("expected an invalid_client error, got %v:\n%s", err, output)
+	}
+}
+
+func TestE2E_DeviceLogin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "acme-cli" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid_client"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"device_code": "dev-1", "user_code": "WDJB-MJHT", "verification_uri": "https://example.com/device", "expires_in": 600, "interval": 1}`))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.FormValue("grant_type") != "refresh_token":
+			_, _ = w.Write([]byte(`{"access_token": "device-token", "token_type": "Bearer", "refresh_token": "refresh-1", "expires_in": 3600}`))
+		case r.FormValue("refresh_token") == "refresh-1" && r.FormValue("client_id") == "acme-cli":
+			_, _ = w.Write([]byte(`{"access_token": "refreshed-token", "token_type": "Bearer", "expires_in": 3600}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid_grant"}`))
+		}
+	})
+	var auth string
+	mux.HandleFunc("/things", func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"ok": true}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	specPath := filepath.Join(t.TempDir(), "spec.yaml")
+	specContent := `openapi: 3.0.3
+info:
+  title: Acme
+  version: "1.0"
+paths:
+  /things:
+    get:
+      operationId: listThings
+      tags: [things]
+      responses:
+        "200":
+          description: OK
+components:
+  securitySchemes:
+    oauth:
+      type: oauth2
+      flows:
+        x-deviceAuthorization:
+          deviceAuthorizationUrl: ` + server.URL + `/device
+          tokenUrl: ` + server.URL + `/token
+          x-client-id: acme-cli
+          scopes: {}
+`
+	if err := os.WriteFile(specPath, []byte(specContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	binaryPath := buildTestCLI(t, specPath, "acme")
+	configHome := t.TempDir()
+	run := func(args ...string) (string, string, error) {
+		t.Helper()
+		cmd := exec.Command(binaryPath, args...)
+		cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+configHome)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		return stdout.String(), stderr.String(), err
+	}
+
+	if _, stderr, err := run("auth", "status"); err == nil || !strings.Contains(stderr, "not logged in") {
+		t.Errorf("expected status to report not logged in, got %v:\n%s", err, stderr)
+	}
+
+	stdout, stderr, err := run("auth", "login")
+	if err != nil {
+		t.Fatalf("auth login failed: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stderr, "WDJB-MJHT") || !strings.Contains(stderr, "https://example.com/device") {
+		t.Errorf("expected the user code and verification URI, got:\n%s", stderr)
+	}
+	if !strings.Contains(stdout, "Logged in to acme") {
+		t.Errorf("expected a login confirmation, got:\n%s", stdout)
+	}
+
+	info, err := os.Stat(filepath.Join(configHome, "acme", "token.json"))
+	if err != nil {
+		t.Fatalf("expected the token to be stored: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected token file permissions 0600, got %o", info.Mode().Perm())
+	}
+
+	if stdout, stderr, err := run("auth", "status"); err != nil || !strings.Contains(stdout, "Logged in to acme until") {
+		t.Errorf("expected status to report the login, got %v:\n%s%s", err, stdout, stderr)
+	}
+
+	if _, stderr, err := run("things", "list", "--base-url", server.URL); err != nil {
+		t.Fatalf("things list failed: %v\n%s", err, stderr)
+	}
+	if auth != "Bearer device-token" {
+		t.Errorf("expected the stored token to be sent, got %q", auth)
+	}
+
+	// An expired token is refreshed with its refresh token and stored again
+	tokenPath := filepath.Join(configHome, "acme", "token.json")
+	expired := func(refreshToken string) {
+		t.Helper()
+		token := `{"access_token": "device-token", "refresh_token": "` + refreshToken + `", "expiry": "2000-01-01T00:00:00Z"}`
+		if err := os.WriteFile(tokenPath, []byte(token), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expired("refresh-1")
+	if stdout, stderr, err := run("auth", "status"); err != nil || !strings.Contains(stdout, "is refreshed on the next request") {
+		t.Errorf("expected status to report the token will be refreshed, got %v:\n%s%s", err, stdout, stderr)
+	}
+	if _, stderr, err := run("things", "list", "--base-url", server.URL); err != nil {
+		t.Fatalf("things list failed: %v\n%s", err, stderr)
+	}
+	if auth != "Bearer refreshed-token" {
+		t.Errorf("expected the refreshed token to be sent, got %q", auth)
+	}
+	if data, _ := os.ReadFile(tokenPath); !strings.Contains(string(data), `"refreshed-token"`) || !strings.Contains(string(data), `"refresh-1"`) {
+		t.Errorf("expected the refreshed token to be stored, got %s", data)
+	}
+
+	// Only when the refresh fails is the user told to log in again
+	expired("revoked")
+	if _, stderr, err := run("things", "list", "--base-url", server.URL); err == nil || !strings.Contains(stderr, "run 'acme auth login' to log in again") {
+		t.Errorf("expected a prompt to log in again, got %v:\n%s", err, stderr)
+	}
+
+	if _, stderr, err := run("auth", "logout"); err != nil {
+		t.Fatalf("auth logout failed: %v\n%s", err, stderr)
+	}
+	if _, stderr, err := run("things", "list", "--base-url", server.URL); err != nil {
+		t.Fatalf("things list failed: %v\n%s", err, stderr)
+	}
+	if auth != "" {
+		t.Errorf("expected no token after logout, got %q", auth)
+	}
+
+	// A client ID given on the command line replaces the spec's
+	if _, stderr, err := run("auth", "login", "--client-id", "other"); err == nil || !strings.Contains(stderr, "invalid_client") {
+		t.Errorf("expected the device endpoint to reject the client, got %v:\n%s", err, stderr)
+	}
+}
+
+func TestE2E_HMACSigning(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var signature, timestamp, keyID, body string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get("X-Acme-Signature")
+		timestamp = r.Header.Get("X-Timestamp")
+		keyID = r.Header.Get("X-Key-Id")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok": true}`))
+	}))
+	defer api.Close()
+
+	specPath := filepath.Join(t.TempDir(), "spec.yaml")
+	specContent := `openapi: 3.0.3
+info:
+  title: Acme
+  version: "1.0"
+x-cli:
+  signing:
+    type: hmac-sha256
+    signatureHeader: X-Acme-Signature
+paths:
+  /things:
+    post:
+      operationId: createThing
+      tags: [things]
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                name:
+                  type: string
+      responses:
+        "201":
+          description: Created
+`
+	if err := os.WriteFile(specPath, []byte(specContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	binaryPath := buildTestCLI(t, specPath, "acme")
+	cmd := exec.Command(binaryPath, "things", "create", "--base-url", api.URL, "--data", `{"name": "widget"}`)
+	cmd.Env = append(os.Environ(), "ACME_SIGNING_SECRET=s3cret", "ACME_SIGNING_KEY_ID=key-1", "XDG_CONFIG_HOME="+t.TempDir())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("things create failed: %v\n%s", err, output)
+	}
+
+	// The signature covers the final body
+	bodyHash := sha256.Sum256([]byte(body))
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	fmt.Fprintf(mac, "POST\n/things\n%s\n%s", timestamp, hex.EncodeToString(bodyHash[:]))
+	if want := hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("signature = %q, want %q (body %q)", signature, want, body)
+	}
+	if keyID != "key-1" {
+		t.Errorf("expected the key ID header, got %q", keyID)
+	}
+
+	// Without a secret the request is not sent
+	signature = ""
+	cmd = exec.Command(binaryPath, "things", "create", "--base-url", api.URL, "--data", `{"name": "widget"}`)
+	cmd.Env = append(os.Environ(), "XDG_CONFIG_HOME="+t.TempDir())
+	output, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "requests must be signed") {
+		t.Errorf("expected a missing secret error, got %v:\n%s", err, output)
+	}
+	if signature != "" {
+		t.Error("expected no request without a signing secret")
+	}
+}
+
+func TestE2E_Describe(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	binaryPath := buildTestCLI(t, "../testdata/dap.json", "dap")
+
+	// Works without a base URL
+	output, err := exec.Command(binaryPath, "describe", "tasks", "create").CombinedOutput()
+	if err != nil {
+		t.Fatalf("describe failed: %v\n%s", err, output)
+	}
+	for _, want := range []string{
+		"POST /v1/tasks\nCreate a new task\n",
+		"X-User-Id  header  string  yes       User ID for the request",
+		"Request body (application/json, required):\n  type: object\n",
+		"201  Task created  application/json",
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("expected describe output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	output, err = exec.Command(binaryPath, "describe", "tasks", "create", "--json").Output()
+	if err != nil {
+		t.Fatalf("describe --json failed: %v", err)
+	}
+	var op struct {
+		Method      string
+		Path        string
+		RequestBody struct{ Required bool }
+	}
+	if err := json.Unmarshal(output, &op); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if op.Method != "POST" || op.Path != "/v1/tasks" || !op.RequestBody.Required {
+		t.Errorf("unexpected description: %+v", op)
+	}
+
+	output, err = exec.Command(binaryPath, "describe", "tasks").CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "is not an API command") {
+		t.Errorf("expected describing a group to fail, got %v:\n%s", err, output)
+	}
+}
+
+func TestE2E_SmokeTest(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+
+	testCmd := exec.Command("go", "test", "-v", "./cmd/bookmarks")
+	testCmd.Dir = filepath.Dir(binaryPath)
+	output, err := testCmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("generated smoke test failed: %v\n%s", err, output)
+	}
+
+	// Every group and operation gets a subtest
+	for _, name := range []string{"bookmarks_bookmarks", "bookmarks_bookmarks_archive-bookmark", "bookmarks_events_subscribe"} {
+		if !strings.Contains(string(output), "--- PASS: TestSmoke_Help/"+name+" ") {
+			t.Errorf("expected a passing subtest for %s, got:\n%s", name, output)
+		}
+	}
+}
+
+func TestE2E_SharedRuntime(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	p := loadDapPlan(t)
+	outDir := t.TempDir()
+
+	g := New(p, outDir)
+	g.SharedRuntime = true
+	if err := g.Generate(); err != nil {
+		t.Fatalf("generation failed: %v", err)
+	}
+
+	// Point the shared runtime at this checkout instead of a published release
+	runtimeDir, err := filepath.Abs("../../runtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	editCmd := exec.Command("go", "mod", "edit", "-replace", SharedRuntimeModule+"="+runtimeDir)
+	editCmd.Dir = outDir
+	if output, err := editCmd.CombinedOutput(); err != nil {
+		t.Fatalf("go mod edit failed: %v\n%s", err, output)
+	}
+
+	tidyCmd := exec.Command("go", "mod", "tidy")
+	tidyCmd.Dir = outDir
+	if output, err := tidyCmd.CombinedOutput(); err != nil {
+		t.Fatalf("go mod tidy failed: %v\n%s", err, output)
+	}
+
+	binaryPath := filepath.Join(outDir, "dap")
+	buildCmd := exec.Command("go", "build", "-o", binaryPath, "./cmd/dap")
+	buildCmd.Dir = outDir
+	if output, err := buildCmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, output)
+	}
+
+	output, err := exec.Command(binaryPath, "tasks", "--help").CombinedOutput()
+	if err != nil {
+		t.Fatalf("help command failed: %v\n%s", err, output)
+	}
+	if !strings.Contains(string(output), "list") {
+		t.Errorf("expected tasks help to list subcommands, got %s", output)
+	}
+}
+
+func TestE2E_SpecCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	binaryPath := buildTestCLI(t, "../testdata/openapi30.yaml", "bookmarks")
+
+	source, err := os.ReadFile("../testdata/openapi30.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := exec.Command(binaryPath, "spec").Output()
+	if err != nil {
+		t.Fatalf("spec command failed: %v", err)
+	}
+	if string(output) != string(source) {
+		t.Error("expected spec to print the embedded document verbatim")
+	}
+
+	output, err = exec.Command(binaryPath, "spec", "--json").Output()
+	if err != nil {
+		t.Fatalf("spec --json failed: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(output, &doc); err != nil {
+		t.Fatalf("expected spec --json to print JSON: %v", err)
+	}
+	if doc["openapi"] != "3.0.3" {
+		t.Errorf("expected openapi version 3.0.3, got %v", doc["openapi"])
+	}
+}
+
+func TestE2E_MergedSpecs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test in short mode")
+	}
+
+	var specs []spec.NamedSpec
+	for _, service := range []struct{ name, path string }{{"bookmarks", "../testdata/openapi30.yaml"}, {"dap", "../testdata/dap.json"}} {
+		s, err := spec.Load(context.Background(), service.path)
+		if err != nil {
+			t.Fatalf("failed to load spec: %v", err)
+		}
+		specs = append(specs, spec.NamedSpec{Name: service.name, Spec: s})
+	}
+	merged, err := spec.Merge(specs)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	binaryPath := buildPlanCLI(t, plan.Build(merged, "platform", "github.com/example/platform"), nil)
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	// Each service's commands, with the server path of its spec
+	for _, args := range [][]string{{"dap", "workspaces", "get", "w1"}, {"bookmarks", "bookmarks", "get", "b1"}} {
+		output, err := exec.Command(binaryPath, append(args, "--base-url", server.URL, "--token", "x")...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%v failed: %v\n%s", args, err, output)
+		}
+	}
+	if want := "/v1/workspaces/w1 /v2/bookmarks/b1"; strings.Join(paths, " ") != want {
+		t.Errorf("expected requests to %v, got %v", want, paths)
+	}
+
+	output, err := exec.Command(binaryPath, "--help").CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "Services:\n  bookmarks   Bookmarks API\n  dap         DAP API\n") {
+		t.Errorf("expected the services in the root help, got %v:\n%s", err, output)
+	}
+
+	// The embedded document describes the merged operations
+	output, err = exec.Command(binaryPath, "describe", "dap", "tasks", "create").CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "POST /v1/tasks\n") {
+		t.Errorf("expected describe to find the merged operation, got %v:\n%s", err, output)
+	}
+
+	testCmd := exec.Command("go", "test", "./cmd/platform")
+	testCmd.Dir = filepath.Dir(binaryPath)
+	if output, err := testCmd.CombinedOutput(); err != nil {
+		t.Errorf("generated smoke test failed: %v\n%s", err, output)
+	}
+}
@@ -0,0 +1,90 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// windowsReserved are device names that cannot be used as file names on
+// Windows, with or without an extension
+var windowsReserved = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true,
+	"com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true,
+	"lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// buildSuffixes are file name suffixes the go command treats as build
+// constraints (_test, _GOOS, _GOARCH)
+var buildSuffixes = map[string]bool{
+	"test": true,
+
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+	"hurd": true, "illumos": true, "ios": true, "js": true, "linux": true, "nacl": true,
+	"netbsd": true, "openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+	"windows": true, "zos": true,
+
+	"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+	"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true,
+	"mips64le": true, "mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+	"ppc64le": true, "riscv": true, "riscv64": true, "s390": true, "s390x": true,
+	"sparc": true, "sparc64": true, "wasm": true,
+}
+
+// sanitizeFileName turns name into a lowercase Go source file base name that
+// is valid on every platform and is not mistaken for a test or
+// platform-specific file by the go command
+func sanitizeFileName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	base := b.String()
+
+	// The go command ignores files starting with _ or .
+	if base == "" || base[0] == '_' {
+		base = "cmd" + base
+	}
+
+	if windowsReserved[base] {
+		base += "_cmd"
+	}
+
+	if i := strings.LastIndexByte(base, '_'); i >= 0 && buildSuffixes[base[i+1:]] {
+		base += "_cmd"
+	}
+
+	return base
+}
+
+// fileNamer hands out unique file names, comparing case-insensitively so
+// the generated project can be checked out on macOS and Windows
+type fileNamer struct {
+	used map[string]bool
+}
+
+func newFileNamer() *fileNamer {
+	return &fileNamer{used: make(map[string]bool)}
+}
+
+// reserve marks a file name as taken
+func (n *fileNamer) reserve(fileName string) {
+	n.used[strings.ToLower(fileName)] = true
+}
+
+// name returns a unique, sanitized .go file name for name, appending _2,
+// _3, ... when a previous name already claimed it
+func (n *fileNamer) name(name string) string {
+	base := sanitizeFileName(name)
+	fileName := base + ".go"
+	for i := 2; n.used[fileName]; i++ {
+		fileName = fmt.Sprintf("%s_%d.go", base, i)
+	}
+	n.reserve(fileName)
+	return fileName
+}
@@ -0,0 +1,51 @@
+package gen
+
+import "testing"
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"tasks_get", "tasks_get"},
+		{"Tasks_Get", "tasks_get"},
+		{"users-list", "users-list"},
+		{"v1.items/get", "v1_items_get"},
+		{"con", "con_cmd"},
+		{"AUX", "aux_cmd"},
+		{"console", "console"},
+		{"users_test", "users_test_cmd"},
+		{"builds_windows", "builds_windows_cmd"},
+		{"nodes_arm64", "nodes_arm64_cmd"},
+		{"_internal", "cmd_internal"},
+		{".hidden", "cmd_hidden"},
+		{"", "cmd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := sanitizeFileName(tt.input); got != tt.expected {
+				t.Errorf("sanitizeFileName(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFileNamer_DeduplicatesCaseInsensitively(t *testing.T) {
+	namer := newFileNamer()
+	namer.reserve("config.go")
+
+	got := []string{
+		namer.name("tasks_get"),
+		namer.name("Tasks_Get"),
+		namer.name("TASKS_GET"),
+		namer.name("config"),
+	}
+	want := []string{"tasks_get.go", "tasks_get_2.go", "tasks_get_3.go", "config_2.go"}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("name %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package gen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileSet is an in-memory set of generated files, keyed by slash-separated
+// path relative to the output directory.
+type FileSet map[string][]byte
+
+// Paths returns the file paths in sorted order
+func (fs FileSet) Paths() []string {
+	paths := make([]string, 0, len(fs))
+	for p := range fs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// WriteDir writes every file in the set below dir, creating parent
+// directories as needed. Paths that would resolve outside dir (e.g. through
+// a symlink) are rejected with ErrPathEscapesOutDir.
+func (fs FileSet) WriteDir(dir string) error {
+	for _, p := range fs.Paths() {
+		outPath, err := safeJoin(dir, filepath.FromSlash(p))
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(outPath), err)
+		}
+
+		if err := os.WriteFile(outPath, fs[p], 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -3,11 +3,16 @@ package gen
 import (
 	"bytes"
 	"embed"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"go/format"
-	"os"
-	"path/filepath"
+	"path"
+	"regexp"
+	goruntime "runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"unicode"
 
@@ -20,47 +25,107 @@ var templateFS embed.FS
 //go:embed runtime/*.go
 var runtimeFS embed.FS
 
+// SharedRuntimeModule is the import path of the public runtime module used
+// when Generator.SharedRuntime is set
+const SharedRuntimeModule = "github.com/crunchloop/opencligen/runtime"
+
+// GoVersion is the Go version generated projects require, in their go.mod
+const GoVersion = "1.22"
+
 // Generator generates a CLI from a plan
 type Generator struct {
 	Plan       *plan.Plan
 	OutDir     string
 	AppName    string
 	ModuleName string
+
+	Options
+
+	files   FileSet
+	filesMu sync.Mutex
 }
 
-// New creates a new Generator
-func New(p *plan.Plan, outDir string) *Generator {
-	return &Generator{
+// New creates a new Generator configured by opts
+func New(p *plan.Plan, outDir string, opts ...Option) *Generator {
+	g := &Generator{
 		Plan:       p,
 		OutDir:     outDir,
 		AppName:    p.AppName,
 		ModuleName: p.ModuleName,
 	}
+	for _, opt := range opts {
+		opt(&g.Options)
+	}
+	return g
 }
 
-// Generate generates all files for the CLI
+// Generate renders all files for the CLI and writes them to the output
+// directory. If rendering fails part way, the files rendered so far are
+// still written to aid debugging.
+//
+// A manifest of the generated files is kept in the output directory. Unless
+// Force is set, Generate refuses to overwrite or delete generated files that
+// were edited since the last generation, returning an error that wraps
+// ErrModifiedFiles. Previously generated files that are no longer produced
+// are removed.
 func (g *Generator) Generate() error {
-	// Create output directories
-	dirs := []string{
-		filepath.Join(g.OutDir, "cmd", g.AppName),
-		filepath.Join(g.OutDir, "internal", "runtime"),
-		filepath.Join(g.OutDir, "internal", "commands"),
+	files, renderErr := g.Render()
+
+	prev, err := readManifest(g.OutDir)
+	if err != nil {
+		return err
 	}
 
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	if !g.Force {
+		modified, err := prev.modified(g.OutDir, files)
+		if err != nil {
+			return err
 		}
+		if len(modified) > 0 {
+			return fmt.Errorf("%w: %s", ErrModifiedFiles, strings.Join(modified, ", "))
+		}
+	}
+
+	if err := files.WriteDir(g.OutDir); err != nil {
+		return err
+	}
+	if renderErr != nil {
+		return renderErr
+	}
+
+	if err := prev.removeStale(g.OutDir, files); err != nil {
+		return err
+	}
+	return newManifest(files).write(g.OutDir)
+}
+
+// Render renders all files for the CLI into memory without touching the
+// output directory
+func (g *Generator) Render() (FileSet, error) {
+	g.files = make(FileSet)
+	if err := g.render(); err != nil {
+		return g.files, err
+	}
+	return g.files, nil
+}
+
+func (g *Generator) render() error {
+	if err := g.Options.Validate(); err != nil {
+		return err
 	}
 
 	// Generate go.mod
-	if err := g.generateGoMod(); err != nil {
-		return fmt.Errorf("failed to generate go.mod: %w", err)
+	if !g.NoGoMod {
+		if err := g.generateGoMod(); err != nil {
+			return fmt.Errorf("failed to generate go.mod: %w", err)
+		}
 	}
 
 	// Copy runtime files
-	if err := g.copyRuntimeFiles(); err != nil {
-		return fmt.Errorf("failed to copy runtime files: %w", err)
+	if !g.SharedRuntime {
+		if err := g.copyRuntimeFiles(); err != nil {
+			return fmt.Errorf("failed to copy runtime files: %w", err)
+		}
 	}
 
 	// Generate main.go
@@ -68,11 +133,95 @@ func (g *Generator) Generate() error {
 		return fmt.Errorf("failed to generate main.go: %w", err)
 	}
 
+	// Generate the smoke test
+	if err := g.generateSmokeTest(); err != nil {
+		return fmt.Errorf("failed to generate smoke_test.go: %w", err)
+	}
+
 	// Generate root.go
 	if err := g.generateRoot(); err != nil {
 		return fmt.Errorf("failed to generate root.go: %w", err)
 	}
 
+	// Generate version.go
+	if err := g.generateVersion(); err != nil {
+		return fmt.Errorf("failed to generate version.go: %w", err)
+	}
+
+	// Generate config.go
+	if err := g.generateConfig(); err != nil {
+		return fmt.Errorf("failed to generate config.go: %w", err)
+	}
+
+	// Generate api.go
+	if err := g.generateAPI(); err != nil {
+		return fmt.Errorf("failed to generate api.go: %w", err)
+	}
+
+	// Generate audit.go
+	if err := g.generateAudit(); err != nil {
+		return fmt.Errorf("failed to generate audit.go: %w", err)
+	}
+
+	// Generate cache.go
+	if err := g.generateCache(); err != nil {
+		return fmt.Errorf("failed to generate cache.go: %w", err)
+	}
+
+	// Generate cookies.go
+	if err := g.generateCookies(); err != nil {
+		return fmt.Errorf("failed to generate cookies.go: %w", err)
+	}
+
+	// Generate batch.go, which runs operation commands for --batch
+	if err := g.generateBatch(); err != nil {
+		return fmt.Errorf("failed to generate batch.go: %w", err)
+	}
+
+	// Generate find.go
+	if err := g.generateFind(); err != nil {
+		return fmt.Errorf("failed to generate find.go: %w", err)
+	}
+
+	// Generate auth.go
+	if err := g.generateAuth(); err != nil {
+		return fmt.Errorf("failed to generate auth.go: %w", err)
+	}
+
+	// Generate spec.go and embed the source document
+	if err := g.generateSpec(); err != nil {
+		return fmt.Errorf("failed to generate spec.go: %w", err)
+	}
+
+	// Generate describe.go, which reads the embedded spec
+	if err := g.generateDescribe(); err != nil {
+		return fmt.Errorf("failed to generate describe.go: %w", err)
+	}
+
+	// Generate config.schema.json
+	if err := g.generateConfigSchema(); err != nil {
+		return fmt.Errorf("failed to generate config.schema.json: %w", err)
+	}
+
+	// Generate README.md
+	if err := g.generateReadme(); err != nil {
+		return fmt.Errorf("failed to generate README.md: %w", err)
+	}
+
+	// Generate release tooling
+	if g.WithRelease {
+		if err := g.generateRelease(); err != nil {
+			return fmt.Errorf("failed to generate release files: %w", err)
+		}
+	}
+
+	// Generate the mock server
+	if g.WithMock {
+		if err := g.generateMock(); err != nil {
+			return fmt.Errorf("failed to generate mock server: %w", err)
+		}
+	}
+
 	// Generate group and operation files
 	if err := g.generateCommands(); err != nil {
 		return fmt.Errorf("failed to generate commands: %w", err)
@@ -81,23 +230,68 @@ func (g *Generator) Generate() error {
 	return nil
 }
 
+// Requirement is a module the generated code imports
+type Requirement struct {
+	Path    string
+	Version string // empty to let go resolve the latest version
+}
+
+// String formats the requirement as an argument to go get
+func (r Requirement) String() string {
+	if r.Version == "" {
+		return r.Path + "@latest"
+	}
+	return r.Path + "@" + r.Version
+}
+
+// Requirements returns the modules the generated code imports directly.
+// With NoGoMod, these must be added to the enclosing module. The versions
+// are those this module builds and tests with, see go.mod.
+func (g *Generator) Requirements() []Requirement {
+	reqs := []Requirement{
+		{Path: "github.com/spf13/cobra", Version: "v1.10.2"},
+		{Path: "github.com/spf13/pflag", Version: "v1.0.9"},
+		{Path: "gopkg.in/yaml.v3", Version: "v3.0.1"},
+	}
+	if g.SharedRuntime {
+		reqs = append([]Requirement{{Path: SharedRuntimeModule, Version: g.RuntimeVersion}}, reqs...)
+	} else {
+		// The vendored runtime evaluates --query with go-jmespath
+		reqs = append(reqs, Requirement{Path: "github.com/jmespath/go-jmespath", Version: "v0.4.0"})
+	}
+	return reqs
+}
+
 func (g *Generator) generateGoMod() error {
+	var requires strings.Builder
+	for _, r := range g.Requirements() {
+		// go mod tidy adds requirements without a pinned version
+		if r.Version != "" {
+			fmt.Fprintf(&requires, "\t%s %s\n", r.Path, r.Version)
+		}
+	}
+
 	content := fmt.Sprintf(`module %s
 
-go 1.22
+go %s
 
 require (
-	github.com/spf13/cobra v1.8.1
-	gopkg.in/yaml.v3 v3.0.1
-)
+%s)
 
-require (
-	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
-)
-`, g.ModuleName)
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
+`, g.ModuleName, GoVersion, requires.String())
 
-	return os.WriteFile(filepath.Join(g.OutDir, "go.mod"), []byte(content), 0644)
+	g.addFile("go.mod", []byte(content))
+	return nil
+}
+
+// runtimeImport returns the import path of the runtime package used by the
+// generated commands
+func (g *Generator) runtimeImport() string {
+	if g.SharedRuntime {
+		return SharedRuntimeModule
+	}
+	return g.ModuleName + "/internal/runtime"
 }
 
 func (g *Generator) copyRuntimeFiles() error {
@@ -116,8 +310,8 @@ func (g *Generator) copyRuntimeFiles() error {
 			return err
 		}
 
-		outPath := filepath.Join(g.OutDir, "internal", "runtime", entry.Name())
-		if err := os.WriteFile(outPath, content, 0644); err != nil {
+		outPath := path.Join("internal", "runtime", entry.Name())
+		if err := g.addSource(outPath, content); err != nil {
 			return err
 		}
 	}
@@ -136,7 +330,39 @@ func (g *Generator) generateMain() error {
 		"AppName":    g.AppName,
 	}
 
-	return g.executeTemplate(tmpl, data, filepath.Join(g.OutDir, "cmd", g.AppName, "main.go"))
+	return g.executeTemplate(tmpl, data, path.Join("cmd", g.AppName, "main.go"))
+}
+
+// generateSmokeTest writes a test next to main.go that runs --help for the
+// root and every group and operation in the plan
+func (g *Generator) generateSmokeTest() error {
+	tmpl, err := template.ParseFS(templateFS, "templates/smoke_test.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	commands := [][]string{{}}
+	for _, service := range g.Plan.Services {
+		commands = append(commands, []string{service.Name})
+	}
+	for gi := range g.Plan.Groups {
+		group := &g.Plan.Groups[gi]
+		if group.Service != "" {
+			commands = append(commands, []string{group.Service, group.Name})
+			continue
+		}
+		commands = append(commands, []string{group.Name})
+		for oi := range group.Operations {
+			commands = append(commands, group.Operations[oi].CommandPath)
+		}
+	}
+
+	data := map[string]interface{}{
+		"AppName":  g.AppName,
+		"Commands": commands,
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("cmd", g.AppName, "smoke_test.go"))
 }
 
 func (g *Generator) generateRoot() error {
@@ -145,12 +371,431 @@ func (g *Generator) generateRoot() error {
 		return err
 	}
 
+	templates, err := g.helpTemplates()
+	if err != nil {
+		return err
+	}
+
+	tokenUsage := "Bearer token for the API"
+	if format := g.Plan.Auth.BearerFormat; format != "" {
+		tokenUsage += " (" + format + ")"
+	}
+
+	data := map[string]interface{}{
+		"ModuleName":    g.ModuleName,
+		"AppName":       g.AppName,
+		"RuntimeImport": g.runtimeImport(),
+		"Sections":      g.helpSections(),
+		"Auth":          g.Plan.Auth,
+		"Servers":       g.Plan.Servers,
+		"TokenUsage":    tokenUsage,
+		"UsageTemplate": quoteLong(templates.Usage),
+		"HelpTemplate":  quoteLong(templates.Help),
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("internal", "commands", "root.go"))
+}
+
+// mockAddr is the default listen address of the generated mock server
+const mockAddr = "127.0.0.1:8080"
+
+// mockAddrIf returns mockAddr when the mock server is generated, and ""
+// otherwise
+func mockAddrIf(withMock bool) string {
+	if withMock {
+		return mockAddr
+	}
+	return ""
+}
+
+func (g *Generator) generateMock() error {
+	tmpl, err := template.ParseFS(templateFS, "templates/mock_main.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	var routes []map[string]interface{}
+	for gi := range g.Plan.Groups {
+		for oi := range g.Plan.Groups[gi].Operations {
+			op := &g.Plan.Groups[gi].Operations[oi]
+			route := map[string]interface{}{
+				"Method":      op.Method,
+				"Path":        op.Path,
+				"Status":      200,
+				"ContentType": "",
+				"Body":        "",
+			}
+			if ex := op.Example; ex != nil {
+				route["Status"] = ex.Status
+				route["ContentType"] = ex.ContentType
+				route["Body"] = string(ex.Body)
+			}
+			routes = append(routes, route)
+		}
+	}
+
+	data := map[string]interface{}{
+		"AppName":     g.AppName,
+		"DefaultAddr": mockAddr,
+		"BaseURLEnv":  strings.ToUpper(g.AppName) + "_BASE_URL",
+		"Routes":      routes,
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("cmd", g.AppName+"-mock", "main.go"))
+}
+
+// helpSection is a section of the generated root help listing command groups
+type helpSection struct {
+	ID    string
+	Title string
+}
+
+// helpSections returns the help sections used by the plan's groups:
+// resources first, then streaming, then custom x-cli sections in order of
+// first use
+func (g *Generator) helpSections() []helpSection {
+	if len(g.Plan.Services) > 0 {
+		// Only the services are on the root
+		return []helpSection{{ID: sectionID(plan.SectionServices), Title: plan.SectionServices}}
+	}
+
+	seen := map[string]bool{}
+	var custom []string
+	for _, group := range g.Plan.Groups {
+		title := groupSection(group)
+		if !seen[title] && title != plan.SectionResources && title != plan.SectionStreaming {
+			custom = append(custom, title)
+		}
+		seen[title] = true
+	}
+
+	var sections []helpSection
+	for _, title := range append([]string{plan.SectionResources, plan.SectionStreaming}, custom...) {
+		if seen[title] {
+			sections = append(sections, helpSection{ID: sectionID(title), Title: title})
+		}
+	}
+	return sections
+}
+
+// groupSection returns the help section of a group, defaulting to resources
+func groupSection(group plan.GroupPlan) string {
+	if group.Section == "" {
+		return plan.SectionResources
+	}
+	return group.Section
+}
+
+// sectionID derives a cobra group ID from a help section title
+func sectionID(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "-") {
+			b.WriteByte('-')
+		}
+	}
+	return "section-" + strings.TrimSuffix(b.String(), "-")
+}
+
+func (g *Generator) generateVersion() error {
+	tmpl, err := template.ParseFS(templateFS, "templates/version.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{
+		"ModuleName":  g.ModuleName,
+		"AppName":     g.AppName,
+		"SpecTitle":   escapeDescription(g.Plan.Spec.Title),
+		"SpecVersion": escapeDescription(g.Plan.Spec.Version),
+		"SpecSHA256":  g.Plan.Spec.SHA256,
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("internal", "commands", "version.go"))
+}
+
+func (g *Generator) generateConfig() error {
+	tmpl, err := template.ParseFS(templateFS, "templates/config.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{
+		"ModuleName":    g.ModuleName,
+		"AppName":       g.AppName,
+		"RuntimeImport": g.runtimeImport(),
+		"EnvPrefix":     strings.ToUpper(g.AppName),
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("internal", "commands", "config.go"))
+}
+
+func (g *Generator) generateAPI() error {
+	tmpl, err := template.ParseFS(templateFS, "templates/api.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{
+		"ModuleName":    g.ModuleName,
+		"AppName":       g.AppName,
+		"RuntimeImport": g.runtimeImport(),
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("internal", "commands", "api.go"))
+}
+
+func (g *Generator) generateAudit() error {
+	tmpl, err := template.ParseFS(templateFS, "templates/audit.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{
+		"ModuleName":    g.ModuleName,
+		"AppName":       g.AppName,
+		"RuntimeImport": g.runtimeImport(),
+		"EnvPrefix":     strings.ToUpper(g.AppName),
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("internal", "commands", "audit.go"))
+}
+
+func (g *Generator) generateCache() error {
+	tmpl, err := template.ParseFS(templateFS, "templates/cache.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{
+		"ModuleName":    g.ModuleName,
+		"AppName":       g.AppName,
+		"RuntimeImport": g.runtimeImport(),
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("internal", "commands", "cache.go"))
+}
+
+func (g *Generator) generateCookies() error {
+	tmpl, err := template.ParseFS(templateFS, "templates/cookies.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{
+		"ModuleName":    g.ModuleName,
+		"AppName":       g.AppName,
+		"RuntimeImport": g.runtimeImport(),
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("internal", "commands", "cookies.go"))
+}
+
+func (g *Generator) generateBatch() error {
+	tmpl, err := template.ParseFS(templateFS, "templates/batch.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{
+		"RuntimeImport": g.runtimeImport(),
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("internal", "commands", "batch.go"))
+}
+
+func (g *Generator) generateFind() error {
+	tmpl, err := template.ParseFS(templateFS, "templates/find.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	data := map[string]string{
+		"ModuleName":    g.ModuleName,
+		"AppName":       g.AppName,
+		"RuntimeImport": g.runtimeImport(),
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("internal", "commands", "find.go"))
+}
+
+func (g *Generator) generateAuth() error {
+	flow := g.Plan.Auth.DeviceCode
+	if flow == nil {
+		return nil
+	}
+
+	tmpl, err := template.ParseFS(templateFS, "templates/auth.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{
+		"ModuleName":    g.ModuleName,
+		"AppName":       g.AppName,
+		"RuntimeImport": g.runtimeImport(),
+		"EnvPrefix":     strings.ToUpper(g.AppName),
+		"Flow":          flow,
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("internal", "commands", "auth.go"))
+}
+
+func (g *Generator) generateSpec() error {
+	source := g.Plan.Spec.Source
+	if len(source) == 0 {
+		return nil
+	}
+
+	tmpl, err := template.ParseFS(templateFS, "templates/spec.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	format := "yaml"
+	if json.Valid(source) {
+		format = "json"
+	}
+	fileName := "openapi." + format
+
+	data := map[string]string{
+		"ModuleName":    g.ModuleName,
+		"AppName":       g.AppName,
+		"RuntimeImport": g.runtimeImport(),
+		"FileName":      fileName,
+		"Format":        format,
+	}
+
+	g.addFile(path.Join("internal", "commands", fileName), source)
+	return g.executeTemplate(tmpl, data, path.Join("internal", "commands", "spec.go"))
+}
+
+func (g *Generator) generateDescribe() error {
+	if len(g.Plan.Spec.Source) == 0 {
+		return nil
+	}
+
+	tmpl, err := template.ParseFS(templateFS, "templates/describe.go.tmpl")
+	if err != nil {
+		return err
+	}
+
+	example := "<group> <command>"
+	for _, group := range g.Plan.Groups {
+		if len(group.Operations) > 0 {
+			example = strings.Join(group.Operations[0].CommandPath, " ")
+			break
+		}
+	}
+
+	data := map[string]string{
+		"ModuleName":    g.ModuleName,
+		"AppName":       g.AppName,
+		"RuntimeImport": g.runtimeImport(),
+		"Example":       example,
+	}
+
+	return g.executeTemplate(tmpl, data, path.Join("internal", "commands", "describe.go"))
+}
+
+func (g *Generator) generateReadme() error {
+	tmpl, err := template.New("readme.md.tmpl").Funcs(template.FuncMap{
+		"md": escapeMarkdown,
+	}).ParseFS(templateFS, "templates/readme.md.tmpl")
+	if err != nil {
+		return err
+	}
+
+	var groups, envFlags []map[string]interface{}
+	for _, group := range g.Plan.Groups {
+		var commands []map[string]interface{}
+		for _, op := range group.Operations {
+			if op.Hidden {
+				continue
+			}
+
+			use := strings.Join(op.CommandPath, " ")
+			for _, p := range op.Positionals {
+				use += fmt.Sprintf(" <%s>", p.Name)
+			}
+
+			for _, f := range op.Flags {
+				if f.EnvVar != "" {
+					envFlags = append(envFlags, map[string]interface{}{
+						"EnvVar":   f.EnvVar,
+						"Command":  g.AppName + " " + strings.Join(op.CommandPath, " "),
+						"FlagName": f.FlagName,
+					})
+				}
+			}
+
+			commands = append(commands, map[string]interface{}{
+				"Use":         use,
+				"Method":      op.Method,
+				"Path":        op.Path,
+				"Summary":     op.Summary,
+				"Flags":       op.Flags,
+				"HasJSONBody": op.HasJSONBody,
+				"SetFlag":     op.SetFlag,
+			})
+		}
+		if len(commands) == 0 {
+			continue
+		}
+
+		name := group.Name
+		if group.Service != "" {
+			name = group.Service + " " + group.Name
+		}
+		groups = append(groups, map[string]interface{}{
+			"Name":        name,
+			"Description": group.Description,
+			"Commands":    commands,
+		})
+	}
+
+	data := map[string]interface{}{
+		"ModuleName":  g.ModuleName,
+		"AppName":     g.AppName,
+		"EnvPrefix":   strings.ToUpper(g.AppName),
+		"SpecTitle":   g.Plan.Spec.Title,
+		"SpecVersion": g.Plan.Spec.Version,
+		"MockAddr":    mockAddrIf(g.WithMock),
+		"Auth":        g.Plan.Auth,
+		"Servers":     g.Plan.Servers,
+		"Groups":      groups,
+		"EnvFlags":    envFlags,
+	}
+
+	return g.executeTemplate(tmpl, data, "README.md")
+}
+
+func (g *Generator) generateRelease() error {
 	data := map[string]string{
 		"ModuleName": g.ModuleName,
 		"AppName":    g.AppName,
 	}
 
-	return g.executeTemplate(tmpl, data, filepath.Join(g.OutDir, "internal", "commands", "root.go"))
+	files := []struct {
+		tmpl    string
+		outPath string
+	}{
+		{"templates/goreleaser.yaml.tmpl", ".goreleaser.yaml"},
+		{"templates/Dockerfile.tmpl", "Dockerfile"},
+	}
+	for _, f := range files {
+		tmpl, err := template.ParseFS(templateFS, f.tmpl)
+		if err != nil {
+			return err
+		}
+		if err := g.executeTemplate(tmpl, data, f.outPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (g *Generator) generateCommands() error {
@@ -164,42 +809,211 @@ func (g *Generator) generateCommands() error {
 		return err
 	}
 
-	for _, group := range g.Plan.Groups {
-		// Generate group file
-		groupData := map[string]string{
-			"VarName":     toVarName(group.Name),
-			"Name":        group.Name,
-			"Description": fmt.Sprintf("%s commands", capitalize(group.Name)),
+	// File names are allocated up front, in plan order, so that collisions
+	// resolve the same way regardless of rendering order. Utility command
+	// files rendered earlier keep their names.
+	namer := newFileNamer()
+	for p := range g.files {
+		if dir, file := path.Split(p); dir == "internal/commands/" {
+			namer.reserve(file)
 		}
+	}
+
+	var tasks []func() error
+	for _, service := range g.Plan.Services {
+		serviceFile := path.Join("internal", "commands", namer.name(service.Name))
+		tasks = append(tasks, func() error {
+			src, err := renderTemplate(groupTmpl, serviceData(service))
+			if err == nil {
+				err = g.addSource(serviceFile, src)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to generate service %s: %w", service.Name, err)
+			}
+			return nil
+		})
+	}
+	for gi := range g.Plan.Groups {
+		group := g.Plan.Groups[gi]
+		groupFile := path.Join("internal", "commands", namer.name(groupKey(group)))
 
-		groupFile := filepath.Join(g.OutDir, "internal", "commands", fmt.Sprintf("%s.go", group.Name))
-		if err := g.executeTemplate(groupTmpl, groupData, groupFile); err != nil {
-			return fmt.Errorf("failed to generate group %s: %w", group.Name, err)
+		if g.Emit == EmitPerGroup {
+			tasks = append(tasks, func() error {
+				if err := g.generateGroupFile(groupTmpl, opTmpl, group, groupFile); err != nil {
+					return fmt.Errorf("failed to generate group %s: %w", group.Name, err)
+				}
+				return nil
+			})
+			continue
 		}
 
+		// Generate group file
+		tasks = append(tasks, func() error {
+			src, err := renderTemplate(groupTmpl, groupData(group))
+			if err == nil {
+				err = g.addSource(groupFile, src, g.streamConstraint(group.Operations...))
+			}
+			if err != nil {
+				return fmt.Errorf("failed to generate group %s: %w", group.Name, err)
+			}
+			return nil
+		})
+
 		// Generate operation files
 		for oi := range group.Operations {
-			op := &group.Operations[oi]
-			if err := g.generateOperation(opTmpl, group, *op); err != nil {
-				return fmt.Errorf("failed to generate operation %s: %w", op.OperationID, err)
+			op := group.Operations[oi]
+			opFile := path.Join("internal", "commands", namer.name(groupKey(group)+"_"+op.CommandPath[len(op.CommandPath)-1]))
+			tasks = append(tasks, func() error {
+				src, err := renderTemplate(opTmpl, g.operationData(group, op))
+				if err == nil {
+					err = g.addSource(opFile, src, g.streamConstraint(op))
+				}
+				if err != nil {
+					return fmt.Errorf("failed to generate operation %s: %w", op.OperationID, err)
+				}
+				return nil
+			})
+		}
+	}
+
+	return runParallel(tasks, g.concurrency())
+}
+
+// allHidden reports whether every operation is hidden, in which case the
+// group command is hidden too
+func allHidden(ops []plan.OpPlan) bool {
+	for i := range ops {
+		if !ops[i].Hidden {
+			return false
+		}
+	}
+	return len(ops) > 0
+}
+
+// concurrency returns the effective number of rendering workers
+func (g *Generator) concurrency() int {
+	if g.Concurrency > 0 {
+		return g.Concurrency
+	}
+	return goruntime.GOMAXPROCS(0)
+}
+
+// runParallel runs tasks on a bounded pool of workers and returns all of
+// their errors joined together, in task order
+func runParallel(tasks []func() error, workers int) error {
+	errs := make([]error, len(tasks))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(tasks)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = tasks[i]()
 			}
+		}()
+	}
+
+	for i := range tasks {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// generateGroupFile renders a group command and all of its operations into a
+// single file
+func (g *Generator) generateGroupFile(groupTmpl, opTmpl *template.Template, group plan.GroupPlan, outPath string) error {
+	srcs := make([][]byte, 0, len(group.Operations)+1)
+
+	src, err := renderTemplate(groupTmpl, groupData(group))
+	if err != nil {
+		return err
+	}
+	srcs = append(srcs, src)
+
+	for oi := range group.Operations {
+		op := group.Operations[oi]
+		src, err := renderTemplate(opTmpl, g.operationData(group, op))
+		if err != nil {
+			return fmt.Errorf("failed to render operation %s: %w", op.OperationID, err)
 		}
+		srcs = append(srcs, src)
 	}
 
-	return nil
+	merged, err := mergeGoFiles(srcs...)
+	if err != nil {
+		return err
+	}
+	return g.addSource(outPath, merged, g.streamConstraint(group.Operations...))
 }
 
-func (g *Generator) generateOperation(tmpl *template.Template, group plan.GroupPlan, op plan.OpPlan) error {
+// groupKey names a group uniquely among the groups of all services, for
+// file and variable names
+func groupKey(group plan.GroupPlan) string {
+	if group.Service == "" {
+		return group.Name
+	}
+	return group.Service + "_" + group.Name
+}
+
+// groupData builds the template data for a group command. The groups of a
+// service are listed in its help without sections.
+func groupData(group plan.GroupPlan) map[string]interface{} {
+	data := map[string]interface{}{
+		"ParentVarName": "root",
+		"GroupID":       sectionID(groupSection(group)),
+		"VarName":       toVarName(groupKey(group)),
+		"Name":          group.Name,
+		"Description":   fmt.Sprintf("%s commands", capitalize(group.Name)),
+		"Hidden":        allHidden(group.Operations),
+	}
+	if group.Service != "" {
+		data["ParentVarName"] = toVarName(group.Service)
+		data["GroupID"] = ""
+	}
+	return data
+}
+
+// serviceData builds the template data for the command of a service
+func serviceData(service plan.ServicePlan) map[string]interface{} {
+	description := service.Description
+	if description == "" {
+		description = fmt.Sprintf("%s commands", capitalize(service.Name))
+	}
+	return map[string]interface{}{
+		"ParentVarName": "root",
+		"GroupID":       sectionID(plan.SectionServices),
+		"VarName":       toVarName(service.Name),
+		"Name":          service.Name,
+		"Description":   escapeDescription(description),
+		"Hidden":        false,
+	}
+}
+
+// operationData builds the template data for an operation command
+func (g *Generator) operationData(group plan.GroupPlan, op plan.OpPlan) map[string]interface{} {
 	// Determine command name (last element of command path)
 	cmdName := op.CommandPath[len(op.CommandPath)-1]
 
 	// Build positionals data
+	argCompletion := false
 	positionals := make([]map[string]interface{}, len(op.Positionals))
 	for i := range op.Positionals {
 		p := &op.Positionals[i]
 		positionals[i] = map[string]interface{}{
-			"Name":    p.Name,
-			"VarName": toVarName(p.FlagName),
+			"Name":     p.Name,
+			"VarName":  toVarName(p.FlagName),
+			"Format":   dateFormat(p.Format),
+			"Checks":   paramChecks(*p),
+			"Enum":     enumValues(*p),
+			"Complete": p.Complete,
+		}
+		if len(p.Enum) > 0 || p.Complete != nil {
+			argCompletion = true
 		}
 	}
 
@@ -219,10 +1033,15 @@ func (g *Generator) generateOperation(tmpl *template.Template, group plan.GroupP
 			"Type":        p.Type,
 			"Required":    p.Required,
 			"DefaultStr":  defaultStr,
-			"Description": escapeDescription(p.Description),
+			"Description": escapeDescription(p.Description + dateHint(p.Format) + etagHint(*p)),
 			"Shorthand":   p.Shorthand,
 			"EnvVar":      p.EnvVar,
 			"In":          p.In,
+			"Format":      dateFormat(p.Format),
+			"Checks":      paramChecks(*p),
+			"Enum":        enumValues(*p),
+			"Complete":    p.Complete,
+			"ETagAuto":    p.ETagAuto,
 		}
 	}
 
@@ -231,60 +1050,211 @@ func (g *Generator) generateOperation(tmpl *template.Template, group plan.GroupP
 	for i := range op.Positionals {
 		use += fmt.Sprintf(" <%s>", op.Positionals[i].Name)
 	}
-
-	// Check if any flags are required
-	hasRequiredFlags := false
-	for i := range op.Flags {
-		if op.Flags[i].Required {
-			hasRequiredFlags = true
-			break
-		}
+	if op.HasJSONBody {
+		use += " [field=value | field:=json ...]"
 	}
 
-	opVarName := toVarName(group.Name + "_" + cmdName)
+	opVarName := toVarName(groupKey(group) + "_" + cmdName)
 
 	data := map[string]interface{}{
-		"ModuleName":       g.ModuleName,
-		"AppName":          g.AppName,
-		"OpVarName":        opVarName,
-		"ParentVarName":    toVarName(group.Name),
-		"Use":              use,
-		"Summary":          escapeDescription(op.Summary),
-		"Description":      escapeDescription(op.Description),
-		"Method":           op.Method,
-		"Path":             op.Path,
-		"Positionals":      positionals,
-		"Flags":            flags,
-		"HasJSONBody":      op.HasJSONBody,
-		"IsEventStream":    op.IsEventStream,
-		"Hidden":           op.Hidden,
-		"Aliases":          op.Aliases,
-		"HasRequiredFlags": hasRequiredFlags,
-	}
-
-	fileName := fmt.Sprintf("%s_%s.go", group.Name, cmdName)
-	filePath := filepath.Join(g.OutDir, "internal", "commands", fileName)
-
-	return g.executeTemplate(tmpl, data, filePath)
+		"ModuleName":    g.ModuleName,
+		"AppName":       g.AppName,
+		"RuntimeImport": g.runtimeImport(),
+		"OpVarName":     opVarName,
+		"Constructor":   "new" + capitalize(opVarName) + "Cmd",
+		"ParentVarName": toVarName(groupKey(group)),
+		"Use":           use,
+		"Summary":       escapeDescription(op.Summary),
+		"Description":   escapeDescription(op.Description),
+		"Method":        op.Method,
+		"Path":          op.Path,
+		"Positionals":   positionals,
+		"ArgCompletion": argCompletion,
+		"Flags":         flags,
+		"HasJSONBody":   op.HasJSONBody,
+		"SetFlag":       op.SetFlag,
+		"IsEventStream": op.IsEventStream,
+		"EventFlags":    op.EventFlags,
+		"Hidden":        op.Hidden,
+		"Aliases":       op.Aliases,
+		"SuggestFor":    op.SuggestFor,
+		"Envelope":      op.Envelope,
+		"Pagination":    op.Pagination,
+		"Columns":       op.Columns,
+		"Accept":        op.Accept,
+		"Long":          quoteLong(longHelp(op)),
+		"HasHints":      op.Hints.RateCost != 0 || op.Hints.ExpectedLatency != 0,
+		"RateCost":      op.Hints.RateCost,
+		"LatencyNanos":  int64(op.Hints.ExpectedLatency),
+	}
+
+	return data
 }
 
 func (g *Generator) executeTemplate(tmpl *template.Template, data interface{}, outPath string) error {
+	src, err := renderTemplate(tmpl, data)
+	if err != nil {
+		return err
+	}
+	return g.addSource(outPath, src)
+}
+
+// renderTemplate executes tmpl with data and returns the raw output
+func renderTemplate(tmpl *template.Template, data interface{}) ([]byte, error) {
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// addSource adds src to the file set. Go code is formatted and gets the
+// FileHeader and a //go:build line combining BuildTags with any extra
+// constraints.
+func (g *Generator) addSource(outPath string, src []byte, constraints ...string) error {
+	if path.Ext(outPath) != ".go" {
+		g.addFile(outPath, src)
+		return nil
+	}
+
+	line, err := buildConstraint(append([]string{g.BuildTags}, constraints...)...)
+	if err != nil {
 		return err
 	}
+	if line != "" {
+		src = append([]byte(line+"\n\n"), src...)
+	}
+	if header := g.fileHeader(); header != "" {
+		src = append([]byte(header+"\n"), src...)
+	}
 
-	// Format the Go code
-	formatted, err := format.Source(buf.Bytes())
+	// Drop imports left unused by conditional template branches, then
+	// format the Go code
+	formatted, err := removeUnusedImports(src)
+	if err == nil {
+		formatted, err = format.Source(formatted)
+	}
 	if err != nil {
-		// If formatting fails, write unformatted for debugging
-		if writeErr := os.WriteFile(outPath, buf.Bytes(), 0644); writeErr != nil {
-			return writeErr
-		}
+		// If formatting fails, keep unformatted output for debugging
+		g.addFile(outPath, src)
 		return fmt.Errorf("failed to format %s: %w", outPath, err)
 	}
 
-	return os.WriteFile(outPath, formatted, 0644)
+	g.addFile(outPath, formatted)
+	return nil
+}
+
+// addFile records content for a slash-separated path relative to the output
+// directory
+func (g *Generator) addFile(relPath string, content []byte) {
+	g.filesMu.Lock()
+	defer g.filesMu.Unlock()
+	g.files[relPath] = content
+}
+
+// longHelp builds the long help text for an operation from its summary,
+// description and any operational hints. It returns "" when there is nothing
+// beyond the summary, so cobra falls back to the short help.
+func longHelp(op plan.OpPlan) string {
+	var sections []string
+	if desc := strings.TrimSpace(op.Description); desc != "" {
+		sections = append(sections, desc)
+	}
+
+	var hints []string
+	if op.Hints.RateCost != 0 {
+		hints = append(hints, fmt.Sprintf("  Rate limit cost:  %v", op.Hints.RateCost))
+	}
+	if op.Hints.ExpectedLatency != 0 {
+		hints = append(hints, fmt.Sprintf("  Expected latency: %v", op.Hints.ExpectedLatency))
+	}
+	if len(hints) > 0 {
+		sections = append(sections, "Hints:\n"+strings.Join(hints, "\n"))
+	}
+
+	if len(sections) == 0 {
+		return ""
+	}
+
+	// Cobra shows Long instead of Short, so keep the summary first
+	if summary := strings.TrimSpace(op.Summary); summary != "" {
+		sections = append([]string{summary}, sections...)
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// quoteLong returns s as a Go string literal, or "" when s is empty
+func quoteLong(s string) string {
+	if s == "" {
+		return ""
+	}
+	return strconv.Quote(s)
+}
+
+// dateFormat returns format when it is one the runtime parses friendly
+// values for (date-time or date), and "" otherwise
+func dateFormat(format string) string {
+	if format == "date-time" || format == "date" {
+		return format
+	}
+	return ""
+}
+
+// dateHint is appended to the help of a date flag, naming the values it
+// accepts besides the wire format
+func dateHint(format string) string {
+	switch format {
+	case "date-time":
+		return " (RFC 3339, a date, or relative like now-24h)"
+	case "date":
+		return " (YYYY-MM-DD, or relative like now-7d)"
+	}
+	return ""
+}
+
+// etagHint describes the "auto" value of an If-Match header flag
+func etagHint(p plan.ParamPlan) string {
+	if !p.ETagAuto {
+		return ""
+	}
+	return ` ("auto" for the resource's current ETag)`
+}
+
+// paramChecks returns the runtime checks of the spec's constraints on a
+// parameter, as arguments to runtime.CheckValue, or "" when it has none.
+// Patterns Go cannot compile are left to the server.
+func paramChecks(p plan.ParamPlan) string {
+	var checks []string
+	if p.Min != nil {
+		checks = append(checks, fmt.Sprintf("runtime.Minimum(%s)", strconv.FormatFloat(*p.Min, 'g', -1, 64)))
+	}
+	if p.Max != nil {
+		checks = append(checks, fmt.Sprintf("runtime.Maximum(%s)", strconv.FormatFloat(*p.Max, 'g', -1, 64)))
+	}
+	if values := enumValues(p); len(values) > 0 {
+		for i, v := range values {
+			values[i] = strconv.Quote(v)
+		}
+		checks = append(checks, fmt.Sprintf("runtime.OneOf(%s)", strings.Join(values, ", ")))
+	}
+	if _, err := regexp.Compile(p.Pattern); p.Pattern != "" && err == nil {
+		checks = append(checks, fmt.Sprintf("runtime.Matches(%s)", strconv.Quote(p.Pattern)))
+	}
+	return strings.Join(checks, ", ")
+}
+
+// enumValues returns the enum of a parameter as they are written on the
+// command line
+func enumValues(p plan.ParamPlan) []string {
+	values := make([]string, len(p.Enum))
+	for i, v := range p.Enum {
+		if f, ok := v.(float64); ok {
+			// 1000000, not 1e+06
+			v = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		values[i] = fmt.Sprint(v)
+	}
+	return values
 }
 
 // toVarName converts a kebab-case string to a valid Go variable name
@@ -326,6 +1296,12 @@ func capitalize(s string) string {
 	return string(runes)
 }
 
+// escapeMarkdown makes text safe to use inside a Markdown table cell
+func escapeMarkdown(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.Join(strings.Fields(s), " ")
+}
+
 // escapeDescription escapes a string for use in Go code
 func escapeDescription(s string) string {
 	s = strings.ReplaceAll(s, `\`, `\\`)
@@ -1,12 +1,18 @@
 package gen
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 
+	"gopkg.in/yaml.v3"
+
 	"github.com/crunchloop/opencligen/internal/plan"
 	"github.com/crunchloop/opencligen/internal/spec"
 )
@@ -34,7 +40,10 @@ func TestGenerate(t *testing.T) {
 	// Verify files exist
 	expectedFiles := []string{
 		"go.mod",
+		"README.md",
+		"config.schema.json",
 		"cmd/dap/main.go",
+		"cmd/dap/smoke_test.go",
 		"internal/runtime/runtime.go",
 		"internal/runtime/request.go",
 		"internal/runtime/body.go",
@@ -42,6 +51,17 @@ func TestGenerate(t *testing.T) {
 		"internal/runtime/sse.go",
 		"internal/runtime/config.go",
 		"internal/commands/root.go",
+		"internal/commands/version.go",
+		"internal/commands/config.go",
+		"internal/commands/api.go",
+		"internal/commands/audit.go",
+		"internal/commands/cache.go",
+		"internal/commands/cookies.go",
+		"internal/commands/batch.go",
+		"internal/commands/find.go",
+		"internal/commands/describe.go",
+		"internal/commands/spec.go",
+		"internal/commands/openapi.json",
 		"internal/commands/tasks.go",
 		"internal/commands/workspaces.go",
 		"internal/commands/stream.go",
@@ -100,3 +120,325 @@ func TestGenerate_BuildsSuccessfully(t *testing.T) {
 		t.Error("expected binary to be created")
 	}
 }
+
+// loadDapPlan loads the dap test spec and builds its plan
+func loadDapPlan(t *testing.T) *plan.Plan {
+	t.Helper()
+	s, err := spec.Load(context.Background(), "../testdata/dap.json")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+	return plan.Build(s, "dap", "github.com/example/dap")
+}
+
+func TestRender_ParallelMatchesSequential(t *testing.T) {
+	p := loadDapPlan(t)
+
+	sequential := New(p, t.TempDir())
+	sequential.Concurrency = 1
+	want, err := sequential.Render()
+	if err != nil {
+		t.Fatalf("sequential render failed: %v", err)
+	}
+
+	parallel := New(p, t.TempDir())
+	parallel.Concurrency = 8
+	got, err := parallel.Render()
+	if err != nil {
+		t.Fatalf("parallel render failed: %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d files, got %d", len(want), len(got))
+	}
+	for name, content := range want {
+		if !bytes.Equal(got[name], content) {
+			t.Errorf("file %s differs between sequential and parallel rendering", name)
+		}
+	}
+}
+
+func TestRunParallel_AggregatesErrors(t *testing.T) {
+	var ran atomic.Int32
+	tasks := []func() error{
+		func() error { ran.Add(1); return nil },
+		func() error { ran.Add(1); return errors.New("first failure") },
+		func() error { ran.Add(1); return nil },
+		func() error { ran.Add(1); return errors.New("second failure") },
+	}
+
+	err := runParallel(tasks, 2)
+	if err == nil {
+		t.Fatal("expected aggregated error")
+	}
+	if !strings.Contains(err.Error(), "first failure") || !strings.Contains(err.Error(), "second failure") {
+		t.Errorf("expected both errors to be reported, got %v", err)
+	}
+	if ran.Load() != 4 {
+		t.Errorf("expected all tasks to run, ran %d", ran.Load())
+	}
+}
+
+func TestRender_SharedRuntime(t *testing.T) {
+	p := loadDapPlan(t)
+
+	g := New(p, t.TempDir())
+	g.SharedRuntime = true
+	g.RuntimeVersion = "v0.1.0"
+	files, err := g.Render()
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	for _, path := range files.Paths() {
+		if strings.HasPrefix(path, "internal/runtime/") {
+			t.Errorf("expected no vendored runtime files, got %s", path)
+		}
+		if strings.Contains(string(files[path]), "github.com/example/dap/internal/runtime") {
+			t.Errorf("expected %s not to import the vendored runtime", path)
+		}
+	}
+
+	if !strings.Contains(string(files["go.mod"]), SharedRuntimeModule+" v0.1.0") {
+		t.Errorf("expected go.mod to require the shared runtime, got:\n%s", files["go.mod"])
+	}
+	if !strings.Contains(string(files["internal/commands/root.go"]), `"`+SharedRuntimeModule+`"`) {
+		t.Error("expected root.go to import the shared runtime")
+	}
+}
+
+// TestRuntimeCopiesInSync guards against editing one copy of the runtime and
+// forgetting "make sync-runtime"
+func TestRuntimeCopiesInSync(t *testing.T) {
+	skel, err := filepath.Glob("../runtime_skel/*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]byte{}
+	for _, f := range skel {
+		if strings.HasSuffix(f, "_test.go") {
+			continue
+		}
+		content, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[filepath.Base(f)] = content
+	}
+
+	for _, dir := range []string{"runtime", "../../runtime"} {
+		copies, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(copies) != len(want) {
+			t.Errorf("%s has %d files, runtime_skel has %d; run make sync-runtime", dir, len(copies), len(want))
+		}
+		for _, f := range copies {
+			content, err := os.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(content, want[filepath.Base(f)]) {
+				t.Errorf("%s differs from runtime_skel; run make sync-runtime", f)
+			}
+		}
+	}
+}
+
+// TestRequirementsMatchGoMod guards against generated projects requiring
+// other versions of the dependencies than this module is tested with
+func TestRequirementsMatchGoMod(t *testing.T) {
+	goMod, err := os.ReadFile("../../go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := map[string]string{}
+	for _, line := range strings.Split(string(goMod), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 {
+			versions[fields[0]] = fields[1]
+		}
+	}
+
+	for _, r := range New(&plan.Plan{}, "").Requirements() {
+		if got := versions[r.Path]; got != r.Version {
+			t.Errorf("generated projects require %s, go.mod has %s %s", r, r.Path, got)
+		}
+	}
+}
+
+func TestRender_Readme(t *testing.T) {
+	files, err := New(loadDapPlan(t), t.TempDir()).Render()
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	readme := string(files["README.md"])
+	expected := []string{
+		"go install github.com/example/dap/cmd/dap@latest",
+		"| API base URL (required) | `--base-url` | `DAP_BASE_URL` | `base_url` |",
+		"| `dap tasks get <id>` | `GET /v1/tasks/{id}` | Get a task by ID |",
+		"| `--user-id` | yes | User ID for the request |",
+		"| `--data` | no |",
+	}
+	for _, want := range expected {
+		if !strings.Contains(readme, want) {
+			t.Errorf("expected README to contain %q", want)
+		}
+	}
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	got := escapeMarkdown("Returns a | b\nacross lines")
+	if got != "Returns a \\| b across lines" {
+		t.Errorf("unexpected escape result %q", got)
+	}
+}
+
+func TestParamChecks(t *testing.T) {
+	min, max := 1.0, 1e6
+	got := paramChecks(plan.ParamPlan{
+		Min:     &min,
+		Max:     &max,
+		Enum:    []interface{}{"a", 2.0},
+		Pattern: `^[a-z]+$`,
+	})
+	want := `runtime.Minimum(1), runtime.Maximum(1e+06), runtime.OneOf("a", "2"), runtime.Matches("^[a-z]+$")`
+	if got != want {
+		t.Errorf("paramChecks() = %s, want %s", got, want)
+	}
+
+	// Patterns Go cannot compile are not checked
+	if got := paramChecks(plan.ParamPlan{Pattern: `^(?!x)`}); got != "" {
+		t.Errorf("expected no checks, got %s", got)
+	}
+}
+
+func TestRender_WithRelease(t *testing.T) {
+	p := loadDapPlan(t)
+
+	files, err := New(p, t.TempDir()).Render()
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if _, ok := files[".goreleaser.yaml"]; ok {
+		t.Error("expected no release files without WithRelease")
+	}
+
+	g := New(p, t.TempDir())
+	g.WithRelease = true
+	files, err = g.Render()
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	var goreleaser map[string]interface{}
+	if err := yaml.Unmarshal(files[".goreleaser.yaml"], &goreleaser); err != nil {
+		t.Fatalf(".goreleaser.yaml is not valid YAML: %v", err)
+	}
+	ldflags := "-X github.com/example/dap/internal/commands.version={{ .Version }}"
+	if !strings.Contains(string(files[".goreleaser.yaml"]), ldflags) {
+		t.Errorf("expected goreleaser to stamp the version, got:\n%s", files[".goreleaser.yaml"])
+	}
+
+	dockerfile := string(files["Dockerfile"])
+	if !strings.Contains(dockerfile, "./cmd/dap") || !strings.Contains(dockerfile, `ENTRYPOINT ["/usr/local/bin/dap"]`) {
+		t.Errorf("unexpected Dockerfile:\n%s", dockerfile)
+	}
+}
+
+func TestRender_EmitPerGroup(t *testing.T) {
+	p := loadDapPlan(t)
+
+	g := New(p, t.TempDir())
+	g.Emit = EmitPerGroup
+	files, err := g.Render()
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	var commandFiles []string
+	for _, name := range files.Paths() {
+		if strings.HasPrefix(name, "internal/commands/") && strings.HasSuffix(name, ".go") {
+			commandFiles = append(commandFiles, name)
+		}
+	}
+	// root, version, config, api, audit, cache, cookies, batch, find, spec
+	// and describe plus one file per group
+	if want := 11 + len(p.Groups); len(commandFiles) != want {
+		t.Errorf("expected %d command files, got %d: %v", want, len(commandFiles), commandFiles)
+	}
+
+	for gi := range p.Groups {
+		group := &p.Groups[gi]
+		src := string(files["internal/commands/"+group.Name+".go"])
+		for oi := range group.Operations {
+			op := &group.Operations[oi]
+			constructor := "new" + capitalize(toVarName(group.Name+"_"+op.CommandPath[len(op.CommandPath)-1])) + "Cmd"
+			if !strings.Contains(src, "func "+constructor+"()") {
+				t.Errorf("expected %s.go to define %s", group.Name, constructor)
+			}
+		}
+	}
+}
+
+func TestRender_RejectsUnknownEmitMode(t *testing.T) {
+	g := New(loadDapPlan(t), t.TempDir())
+	g.Emit = "per-file"
+	if _, err := g.Render(); err == nil {
+		t.Fatal("expected an error for an unknown emit mode")
+	}
+}
+
+func TestRender_HelpTemplates(t *testing.T) {
+	p := loadDapPlan(t)
+	p.Templates.Usage = "x-cli usage {{.UseLine}}\n"
+	p.Templates.Help = "x-cli help\n"
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "usage.tmpl"), []byte("Acme usage {{.UseLine}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := New(p, t.TempDir())
+	g.TemplatesDir = dir
+	files, err := g.Render()
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	root := string(files["internal/commands/root.go"])
+	if !strings.Contains(root, `rootCmd.SetUsageTemplate("Acme usage {{.UseLine}}\n")`) {
+		t.Errorf("expected usage.tmpl to override the x-cli usage template, got:\n%s", root)
+	}
+	if !strings.Contains(root, `rootCmd.SetHelpTemplate("x-cli help\n")`) {
+		t.Errorf("expected the x-cli help template to be kept, got:\n%s", root)
+	}
+
+	p.Templates.Usage = "{{.UseLine"
+	if _, err := New(p, t.TempDir()).Render(); err == nil || !strings.Contains(err.Error(), "invalid usage template") {
+		t.Errorf("expected an invalid usage template error, got %v", err)
+	}
+}
+
+func TestCheckTemplates(t *testing.T) {
+	dir := t.TempDir()
+	if err := CheckTemplates(dir); err == nil || !strings.Contains(err.Error(), "has neither usage.tmpl nor help.tmpl") {
+		t.Errorf("expected an empty directory error, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "help.tmpl"), []byte("{{.Long}}\n{{.UsageString}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckTemplates(dir); err != nil {
+		t.Errorf("expected help.tmpl to be valid, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "usage.tmpl"), []byte("{{rpad .Name"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckTemplates(dir); err == nil || !strings.Contains(err.Error(), "invalid usage template") {
+		t.Errorf("expected an invalid usage template error, got %v", err)
+	}
+}
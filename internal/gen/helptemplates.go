@@ -0,0 +1,111 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/crunchloop/opencligen/internal/plan"
+)
+
+// Files read from Generator.TemplatesDir
+const (
+	usageTemplateFile = "usage.tmpl"
+	helpTemplateFile  = "help.tmpl"
+)
+
+// helpTemplateFuncs stubs the functions available to cobra templates in a
+// generated CLI: cobra's own plus those registered by the generated root.
+// It is only used to check templates at generation time.
+var helpTemplateFuncs = template.FuncMap{
+	"trim":                    stubFunc,
+	"trimRightSpace":          stubFunc,
+	"trimTrailingWhitespaces": stubFunc,
+	"appendIfNotPresent":      stubFunc,
+	"rpad":                    stubFunc,
+	"gt":                      stubFunc,
+	"eq":                      stubFunc,
+	"flagUsagesWithEnv":       stubFunc,
+}
+
+func stubFunc(...interface{}) string { return "" }
+
+// helpTemplates returns the cobra templates to install on the generated
+// root: the plan's x-cli templates, overridden by any usage.tmpl and
+// help.tmpl in TemplatesDir. Templates are parsed to catch syntax errors
+// before they reach the generated CLI.
+func (g *Generator) helpTemplates() (plan.HelpTemplates, error) {
+	templates := g.Plan.Templates
+
+	files := []struct {
+		name string
+		file string
+		text *string
+	}{
+		{"usage", usageTemplateFile, &templates.Usage},
+		{"help", helpTemplateFile, &templates.Help},
+	}
+
+	if g.TemplatesDir != "" {
+		for _, f := range files {
+			data, err := os.ReadFile(filepath.Join(g.TemplatesDir, f.file))
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return templates, err
+			}
+			*f.text = string(data)
+		}
+	}
+
+	for _, f := range files {
+		if err := parseHelpTemplate(f.name, *f.text); err != nil {
+			return templates, err
+		}
+	}
+
+	return templates, nil
+}
+
+// CheckTemplates checks the usage.tmpl and help.tmpl in dir, as read with
+// Options.TemplatesDir: dir must be a directory holding at least one of
+// them, and they must parse.
+func CheckTemplates(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	found := false
+	for _, f := range []struct{ name, file string }{{"usage", usageTemplateFile}, {"help", helpTemplateFile}} {
+		data, err := os.ReadFile(filepath.Join(dir, f.file))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		if err := parseHelpTemplate(f.name, string(data)); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s has neither %s nor %s", dir, usageTemplateFile, helpTemplateFile)
+	}
+	return nil
+}
+
+// parseHelpTemplate checks that a cobra template parses
+func parseHelpTemplate(name, text string) error {
+	if _, err := template.New(name).Funcs(helpTemplateFuncs).Parse(text); err != nil {
+		return fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return nil
+}
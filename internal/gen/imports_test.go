@@ -0,0 +1,62 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRemoveUnusedImports(t *testing.T) {
+	src := []byte(`package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/example/app/internal/runtime"
+	"gopkg.in/yaml.v3"
+	_ "embed"
+)
+
+func run() error {
+	ctx := context.Background()
+	_ = yaml.Marshal
+	return rt.Do(ctx, runtime.NewRequest("GET", "/"))
+}
+`)
+
+	out, err := removeUnusedImports(src)
+	if err != nil {
+		t.Fatalf("removeUnusedImports failed: %v", err)
+	}
+	got := string(out)
+
+	for _, removed := range []string{`"fmt"`, `"os"`} {
+		if strings.Contains(got, removed) {
+			t.Errorf("expected %s to be removed, got:\n%s", removed, got)
+		}
+	}
+	for _, kept := range []string{`"context"`, `"github.com/example/app/internal/runtime"`, `"gopkg.in/yaml.v3"`, `_ "embed"`} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("expected %s to be kept, got:\n%s", kept, got)
+		}
+	}
+}
+
+func TestAssumedPackageName(t *testing.T) {
+	tests := map[string]string{
+		"fmt":                          "fmt",
+		"net/http":                     "http",
+		"gopkg.in/yaml.v3":             "yaml",
+		"github.com/go-chi/chi/v5":     "chi",
+		"github.com/mattn/go-isatty":   "isatty",
+		"github.com/example/app/v2":    "app",
+		"github.com/spf13/cobra":       "cobra",
+		"example.com/internal/runtime": "runtime",
+	}
+	for importPath, want := range tests {
+		if got := assumedPackageName(importPath); got != want {
+			t.Errorf("assumedPackageName(%q) = %q, want %q", importPath, got, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ManifestFile is the name of the manifest written to the output directory
+const ManifestFile = ".opencligen-manifest.json"
+
+// ErrModifiedFiles is returned by Generate when regeneration would overwrite
+// or delete generated files that were edited since the last generation.
+var ErrModifiedFiles = errors.New("generated files were modified")
+
+// userEditable lists generated files that are expected to change after
+// generation: go mod tidy rewrites go.mod every time.
+var userEditable = map[string]bool{
+	"go.mod": true,
+}
+
+// Manifest records the files written by the last generation together with
+// their SHA-256 hashes, so regeneration can detect user edits and remove
+// files that are no longer generated.
+type Manifest struct {
+	Files map[string]string `json:"files"`
+}
+
+// newManifest builds the manifest for a file set
+func newManifest(files FileSet) *Manifest {
+	m := &Manifest{Files: make(map[string]string, len(files))}
+	for p, content := range files {
+		m.Files[p] = hashContent(content)
+	}
+	return m
+}
+
+// readManifest reads the manifest from dir. A missing manifest yields an
+// empty one, as for a first generation.
+func readManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &Manifest{Files: map[string]string{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", ManifestFile, err)
+	}
+	if m.Files == nil {
+		m.Files = map[string]string{}
+	}
+	return &m, nil
+}
+
+// encode returns the contents of the manifest file
+func (m *Manifest) encode() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// write stores the manifest in dir
+func (m *Manifest) write(dir string) error {
+	data, err := m.encode()
+	if err != nil {
+		return err
+	}
+
+	outPath, err := safeJoin(dir, ManifestFile)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, data, 0644)
+}
+
+// modified returns the previously generated files in dir that were edited
+// since the last generation and would be overwritten or deleted by writing
+// next, in sorted order
+func (m *Manifest) modified(dir string, next FileSet) ([]string, error) {
+	var paths []string
+	for p, hash := range m.Files {
+		if userEditable[p] {
+			continue
+		}
+
+		current, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(p)))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+
+		if hashContent(current) == hash {
+			continue
+		}
+		if content, ok := next[p]; ok && hashContent(content) == hashContent(current) {
+			continue
+		}
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// removeStale deletes previously generated files that are not part of next,
+// along with any directories left empty
+func (m *Manifest) removeStale(dir string, next FileSet) error {
+	for p := range m.Files {
+		if _, ok := next[p]; ok {
+			continue
+		}
+
+		stalePath, err := safeJoin(dir, filepath.FromSlash(p))
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(stalePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove stale file %s: %w", p, err)
+		}
+
+		// Remove parent directories that are now empty; os.Remove refuses
+		// to delete non-empty ones
+		for parent := filepath.Dir(stalePath); parent != filepath.Clean(dir); parent = filepath.Dir(parent) {
+			if os.Remove(parent) != nil {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// hashContent returns the hex-encoded SHA-256 of content
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
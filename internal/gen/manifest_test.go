@@ -0,0 +1,111 @@
+package gen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate_WritesManifest(t *testing.T) {
+	outDir := t.TempDir()
+	if err := New(loadDapPlan(t), outDir).Generate(); err != nil {
+		t.Fatalf("generation failed: %v", err)
+	}
+
+	m, err := readManifest(outDir)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(outDir, "internal", "commands", "root.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Files["internal/commands/root.go"] != hashContent(content) {
+		t.Error("expected manifest to record the hash of root.go")
+	}
+
+	// Regenerating over untouched output succeeds
+	if err := New(loadDapPlan(t), outDir).Generate(); err != nil {
+		t.Fatalf("regeneration failed: %v", err)
+	}
+}
+
+func TestGenerate_RefusesToOverwriteModifiedFiles(t *testing.T) {
+	outDir := t.TempDir()
+	if err := New(loadDapPlan(t), outDir).Generate(); err != nil {
+		t.Fatalf("generation failed: %v", err)
+	}
+
+	edited := filepath.Join(outDir, "internal", "commands", "tasks_get.go")
+	if err := os.WriteFile(edited, []byte("package commands // edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// go mod tidy rewrites go.mod, which must not count as an edit
+	if err := os.WriteFile(filepath.Join(outDir, "go.mod"), []byte("module tidied\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := New(loadDapPlan(t), outDir).Generate()
+	if !errors.Is(err, ErrModifiedFiles) {
+		t.Fatalf("expected ErrModifiedFiles, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "internal/commands/tasks_get.go") || strings.Contains(err.Error(), "go.mod") {
+		t.Errorf("expected error to name only the edited file, got %v", err)
+	}
+	if content, _ := os.ReadFile(edited); !strings.Contains(string(content), "edited") {
+		t.Error("expected the edited file to be left alone")
+	}
+
+	g := New(loadDapPlan(t), outDir)
+	g.Force = true
+	if err := g.Generate(); err != nil {
+		t.Fatalf("forced regeneration failed: %v", err)
+	}
+	if content, _ := os.ReadFile(edited); strings.Contains(string(content), "edited") {
+		t.Error("expected --force to overwrite the edited file")
+	}
+}
+
+func TestGenerate_RemovesStaleFiles(t *testing.T) {
+	outDir := t.TempDir()
+	if err := New(loadDapPlan(t), outDir).Generate(); err != nil {
+		t.Fatalf("generation failed: %v", err)
+	}
+
+	// Drop the health group, as if its operations were removed from the spec
+	p := loadDapPlan(t)
+	for i, group := range p.Groups {
+		if group.Name == "health" {
+			p.Groups = append(p.Groups[:i], p.Groups[i+1:]...)
+			break
+		}
+	}
+
+	userFile := filepath.Join(outDir, "internal", "commands", "custom.go")
+	if err := os.WriteFile(userFile, []byte("package commands\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := New(p, outDir).Generate(); err != nil {
+		t.Fatalf("regeneration failed: %v", err)
+	}
+
+	for _, f := range []string{"health.go", "health_ping.go"} {
+		if _, err := os.Stat(filepath.Join(outDir, "internal", "commands", f)); !os.IsNotExist(err) {
+			t.Errorf("expected stale file %s to be removed", f)
+		}
+	}
+	if _, err := os.Stat(userFile); err != nil {
+		t.Error("expected files not created by the generator to be kept")
+	}
+
+	m, err := readManifest(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Files["internal/commands/health.go"]; ok {
+		t.Error("expected stale file to be dropped from the manifest")
+	}
+}
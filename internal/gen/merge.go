@@ -0,0 +1,104 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mergeGoFiles combines Go source files of the same package into one file.
+// Imports are deduplicated and regrouped into standard library and
+// third-party blocks; the declarations of each file follow in order. Text
+// before the first file's package clause (e.g. build constraints) is kept.
+func mergeGoFiles(srcs ...[]byte) ([]byte, error) {
+	if len(srcs) == 0 {
+		return nil, fmt.Errorf("no files to merge")
+	}
+
+	var (
+		header  []byte
+		pkgName string
+		std     = make(map[string]bool)
+		other   = make(map[string]bool)
+		decls   bytes.Buffer
+	)
+
+	for i, src := range srcs {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "", src, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse file %d: %w", i, err)
+		}
+
+		if i == 0 {
+			pkgName = f.Name.Name
+			header = src[:fset.Position(f.Package).Offset]
+		} else if f.Name.Name != pkgName {
+			return nil, fmt.Errorf("cannot merge package %s into package %s", f.Name.Name, pkgName)
+		}
+
+		for _, spec := range f.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return nil, err
+			}
+			line := spec.Path.Value
+			if spec.Name != nil {
+				line = spec.Name.Name + " " + line
+			}
+			if isStdlibImport(importPath) {
+				std[line] = true
+			} else {
+				other[line] = true
+			}
+		}
+
+		// With ImportsOnly, Decls holds just the import declarations, so
+		// everything after the last one is the body of the file
+		bodyStart := fset.Position(f.Name.End()).Offset
+		if n := len(f.Decls); n > 0 {
+			bodyStart = fset.Position(f.Decls[n-1].End()).Offset
+		}
+		decls.WriteString("\n")
+		decls.Write(bytes.TrimSpace(src[bodyStart:]))
+		decls.WriteString("\n")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	fmt.Fprintf(&buf, "package %s\n", pkgName)
+	if len(std)+len(other) > 0 {
+		buf.WriteString("\nimport (\n")
+		writeImportGroup(&buf, std)
+		if len(std) > 0 && len(other) > 0 {
+			buf.WriteString("\n")
+		}
+		writeImportGroup(&buf, other)
+		buf.WriteString(")\n")
+	}
+	buf.Write(decls.Bytes())
+	return buf.Bytes(), nil
+}
+
+// writeImportGroup writes the import lines in sorted order
+func writeImportGroup(buf *bytes.Buffer, lines map[string]bool) {
+	sorted := make([]string, 0, len(lines))
+	for line := range lines {
+		sorted = append(sorted, line)
+	}
+	sort.Strings(sorted)
+	for _, line := range sorted {
+		fmt.Fprintf(buf, "\t%s\n", line)
+	}
+}
+
+// isStdlibImport reports whether importPath belongs to the standard library,
+// whose first path element never contains a dot
+func isStdlibImport(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
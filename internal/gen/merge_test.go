@@ -0,0 +1,66 @@
+package gen
+
+import (
+	"go/format"
+	"strings"
+	"testing"
+)
+
+func TestMergeGoFiles(t *testing.T) {
+	a := []byte(`package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var aCmd = &cobra.Command{Use: "a"}
+
+func init() { fmt.Println("a") }
+`)
+	b := []byte(`package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// bCmd is documented
+var bCmd = &cobra.Command{Use: "b"}
+
+func init() { fmt.Println("b", os.Args) }
+`)
+
+	merged, err := mergeGoFiles(a, b)
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	formatted, err := format.Source(merged)
+	if err != nil {
+		t.Fatalf("merged source does not parse: %v\n%s", err, merged)
+	}
+	got := string(formatted)
+
+	wantImports := "import (\n\t\"fmt\"\n\t\"os\"\n\n\t\"github.com/spf13/cobra\"\n)\n"
+	if !strings.Contains(got, wantImports) {
+		t.Errorf("expected deduplicated, grouped imports, got:\n%s", got)
+	}
+	for _, want := range []string{"var aCmd", "// bCmd is documented\nvar bCmd", `fmt.Println("b", os.Args)`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected merged file to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "aCmd") > strings.Index(got, "bCmd") {
+		t.Error("expected declarations to keep file order")
+	}
+}
+
+func TestMergeGoFiles_RejectsMixedPackages(t *testing.T) {
+	_, err := mergeGoFiles([]byte("package a\n"), []byte("package b\n"))
+	if err == nil {
+		t.Fatal("expected an error when merging different packages")
+	}
+}
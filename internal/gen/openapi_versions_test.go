@@ -142,6 +142,13 @@ func TestE2E_OpenAPI30_Bookmarks(t *testing.T) {
 		if !strings.Contains(helpText, "--format") {
 			t.Error("expected export get help to contain --format flag")
 		}
+
+		if !strings.Contains(helpText, "Rate limit cost:  10") {
+			t.Error("expected export get help to show the rate limit cost hint")
+		}
+		if !strings.Contains(helpText, "Expected latency: 30s") {
+			t.Error("expected export get help to show the expected latency hint")
+		}
 	})
 
 	// Test events subscribe exists (SSE)
@@ -238,6 +245,66 @@ func TestE2E_OpenAPI31_Notes(t *testing.T) {
 		}
 	})
 
+	// Test root help separates commands into sections
+	t.Run("root help groups commands into sections", func(t *testing.T) {
+		output, err := exec.Command(binaryPath, "--help").CombinedOutput()
+		if err != nil {
+			t.Fatalf("help command failed: %v", err)
+		}
+
+		helpText := string(output)
+
+		sections := []string{"Resource Commands:", "Streaming:", "Organization:", "Utility Commands:"}
+		last := -1
+		for _, section := range sections {
+			idx := strings.Index(helpText, section)
+			if idx < 0 {
+				t.Errorf("expected help to contain section %q", section)
+				continue
+			}
+			if idx < last {
+				t.Errorf("expected section %q to come after the previous one", section)
+			}
+			last = idx
+		}
+
+		// sync is streaming-only and should be listed under Streaming
+		streaming := helpText[strings.Index(helpText, "Streaming:"):]
+		if !strings.Contains(streaming[:strings.Index(streaming, "Organization:")], "sync") {
+			t.Error("expected sync group in the Streaming section")
+		}
+	})
+
+	// Test mistyped commands get suggestions
+	t.Run("typos suggest commands", func(t *testing.T) {
+		output, err := exec.Command(binaryPath, "note", "lst").CombinedOutput()
+		if err == nil {
+			t.Fatal("expected unknown command to fail")
+		}
+		if !strings.Contains(string(output), "Did you mean this?") || !strings.Contains(string(output), "notes list") {
+			t.Errorf("expected suggestion for 'notes list', got:\n%s", output)
+		}
+
+		output, err = exec.Command(binaryPath, "notes", "publish").CombinedOutput()
+		if err == nil {
+			t.Fatal("expected unknown command to fail")
+		}
+		if !strings.Contains(string(output), "notes share-note") {
+			t.Errorf("expected x-cli suggestFor to suggest 'notes share-note', got:\n%s", output)
+		}
+	})
+
+	// Test group commands print help without a base URL
+	t.Run("group without subcommand prints help", func(t *testing.T) {
+		output, err := exec.Command(binaryPath, "notes").CombinedOutput()
+		if err != nil {
+			t.Fatalf("expected group help to succeed, got %v\n%s", err, output)
+		}
+		if !strings.Contains(string(output), "Available Commands") {
+			t.Errorf("expected group help, got:\n%s", output)
+		}
+	})
+
 	// Test notes list has expected flags
 	t.Run("notes list has filter flags", func(t *testing.T) {
 		output, err := exec.Command(binaryPath, "notes", "list", "--help").CombinedOutput()
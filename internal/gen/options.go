@@ -0,0 +1,176 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Command file layouts for Options.Emit
+const (
+	EmitPerOperation = "per-operation"
+	EmitPerGroup     = "per-group"
+)
+
+// Options configures a Generator. The zero value generates a standalone
+// cobra CLI with one file per operation. Options can be set directly on a
+// Generator or passed to New as functional options.
+type Options struct {
+	// SharedRuntime makes the generated code import SharedRuntimeModule
+	// instead of vendoring a copy of the runtime into internal/runtime, so
+	// runtime fixes reach every CLI with a dependency bump. RuntimeVersion
+	// pins the required version; when empty, go mod tidy resolves the latest.
+	SharedRuntime  bool
+	RuntimeVersion string
+
+	// WithMock adds a cmd/<app>-mock server that returns example responses
+	// for every operation
+	WithMock bool
+
+	// NoGoMod skips go.mod so the CLI can be generated into a directory of
+	// an existing module. ModuleName must then be the import path of the
+	// output directory, and Requirements lists the dependencies the
+	// enclosing module needs.
+	NoGoMod bool
+
+	// WithRelease adds a .goreleaser.yaml and a Dockerfile to the project
+	WithRelease bool
+
+	// Emit selects how command files are laid out: EmitPerOperation (the
+	// default when empty) writes one file per operation, EmitPerGroup writes
+	// each group and all of its operations into a single file
+	Emit string
+
+	// FileHeader is a comment placed at the top of every generated Go file,
+	// such as a license notice. Lines are prefixed with "// " unless they
+	// already are comments.
+	FileHeader string
+
+	// BuildTags is a build constraint expression (e.g. "mytag" or
+	// "!windows") added as a //go:build line to every generated Go file
+	BuildTags string
+
+	// StreamBuildTags is an additional build constraint for files that only
+	// hold event-stream commands, so streaming support can be left out of
+	// some builds (e.g. "!js")
+	StreamBuildTags string
+
+	// TemplatesDir holds usage.tmpl and help.tmpl files that replace the
+	// generated root's cobra usage and help templates, taking precedence
+	// over the spec's x-cli usageTemplate and helpTemplate
+	TemplatesDir string
+
+	// Force lets Generate overwrite and delete generated files that were
+	// edited since the last generation
+	Force bool
+
+	// Concurrency bounds the number of files rendered in parallel. Zero
+	// means runtime.GOMAXPROCS(0).
+	Concurrency int
+}
+
+// Validate reports options that cannot be generated
+func (o *Options) Validate() error {
+	if o.Emit != "" && o.Emit != EmitPerOperation && o.Emit != EmitPerGroup {
+		return fmt.Errorf("unknown emit mode %q (want %s or %s)", o.Emit, EmitPerOperation, EmitPerGroup)
+	}
+	if o.RuntimeVersion != "" && !o.SharedRuntime {
+		return errors.New("a runtime version requires the shared runtime")
+	}
+	if o.Concurrency < 0 {
+		return fmt.Errorf("invalid concurrency %d", o.Concurrency)
+	}
+	if _, err := buildConstraint(o.BuildTags, o.StreamBuildTags); err != nil {
+		return err
+	}
+	return nil
+}
+
+// fileHeader formats FileHeader as a Go comment block, or "" when unset
+func (o *Options) fileHeader() string {
+	text := strings.TrimRight(o.FileHeader, "\n")
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.HasPrefix(line, "//"):
+			b.WriteString(line)
+		case line == "":
+			b.WriteString("//")
+		default:
+			b.WriteString("// " + line)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Option configures a Generator created with New
+type Option func(*Options)
+
+// WithOptions replaces all options with o
+func WithOptions(o Options) Option {
+	return func(dst *Options) { *dst = o }
+}
+
+// WithSharedRuntime imports SharedRuntimeModule at version instead of
+// vendoring the runtime. An empty version resolves the latest.
+func WithSharedRuntime(version string) Option {
+	return func(o *Options) {
+		o.SharedRuntime = true
+		o.RuntimeVersion = version
+	}
+}
+
+// WithMockServer adds a cmd/<app>-mock server
+func WithMockServer() Option {
+	return func(o *Options) { o.WithMock = true }
+}
+
+// WithReleaseFiles adds a .goreleaser.yaml and a Dockerfile
+func WithReleaseFiles() Option {
+	return func(o *Options) { o.WithRelease = true }
+}
+
+// WithoutGoMod skips go.mod for output inside an existing module
+func WithoutGoMod() Option {
+	return func(o *Options) { o.NoGoMod = true }
+}
+
+// WithEmitMode sets the command file layout
+func WithEmitMode(mode string) Option {
+	return func(o *Options) { o.Emit = mode }
+}
+
+// WithFileHeader places header at the top of every generated Go file
+func WithFileHeader(header string) Option {
+	return func(o *Options) { o.FileHeader = header }
+}
+
+// WithBuildTags adds a build constraint to every generated Go file
+func WithBuildTags(expr string) Option {
+	return func(o *Options) { o.BuildTags = expr }
+}
+
+// WithStreamBuildTags adds a build constraint to event-stream command files
+func WithStreamBuildTags(expr string) Option {
+	return func(o *Options) { o.StreamBuildTags = expr }
+}
+
+// WithTemplates reads usage.tmpl and help.tmpl from dir
+func WithTemplates(dir string) Option {
+	return func(o *Options) { o.TemplatesDir = dir }
+}
+
+// WithForce overwrites generated files even if they were edited
+func WithForce() Option {
+	return func(o *Options) { o.Force = true }
+}
+
+// WithConcurrency bounds the number of files rendered in parallel
+func WithConcurrency(n int) Option {
+	return func(o *Options) { o.Concurrency = n }
+}
@@ -0,0 +1,122 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew_AppliesOptions(t *testing.T) {
+	g := New(loadDapPlan(t), t.TempDir(),
+		WithEmitMode(EmitPerGroup),
+		WithSharedRuntime("v0.3.0"),
+		WithFileHeader("Copyright Acme"),
+		WithConcurrency(2),
+	)
+
+	want := Options{
+		SharedRuntime:  true,
+		RuntimeVersion: "v0.3.0",
+		Emit:           EmitPerGroup,
+		FileHeader:     "Copyright Acme",
+		Concurrency:    2,
+	}
+	if g.Options != want {
+		t.Errorf("options = %+v, want %+v", g.Options, want)
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{"zero value", Options{}, ""},
+		{"unknown emit mode", Options{Emit: "per-file"}, "unknown emit mode"},
+		{"runtime version without shared runtime", Options{RuntimeVersion: "v1.0.0"}, "shared runtime"},
+		{"negative concurrency", Options{Concurrency: -1}, "invalid concurrency"},
+		{"invalid build tags", Options{BuildTags: "linux ||"}, "build"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOptions_FileHeader(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"\n", ""},
+		{"Copyright Acme", "// Copyright Acme\n"},
+		{"Copyright Acme\n\nSPDX-License-Identifier: MIT\n", "// Copyright Acme\n//\n// SPDX-License-Identifier: MIT\n"},
+		{"// Already a comment", "// Already a comment\n"},
+	}
+
+	for _, tt := range tests {
+		o := Options{FileHeader: tt.header}
+		if got := o.fileHeader(); got != tt.want {
+			t.Errorf("fileHeader(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestRender_FileHeader(t *testing.T) {
+	g := New(loadDapPlan(t), t.TempDir(), WithFileHeader("Copyright Acme"), WithBuildTags("mytag"))
+	files, err := g.Render()
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	for _, path := range files.Paths() {
+		src := string(files[path])
+		if !strings.HasSuffix(path, ".go") {
+			if strings.Contains(src, "Copyright Acme") {
+				t.Errorf("%s: header added to a non-Go file", path)
+			}
+			continue
+		}
+		if !strings.HasPrefix(src, "// Copyright Acme\n\n//go:build mytag\n") {
+			t.Errorf("%s: missing file header before the build constraint:\n%s", path, src[:min(len(src), 200)])
+		}
+	}
+}
+
+func TestRender_IndependentGenerators(t *testing.T) {
+	p := loadDapPlan(t)
+
+	perOp := New(p, t.TempDir())
+	perGroup := New(p, t.TempDir(), WithEmitMode(EmitPerGroup), WithFileHeader("Acme"))
+
+	opFiles, err := perOp.Render()
+	if err != nil {
+		t.Fatalf("per-operation render failed: %v", err)
+	}
+	groupFiles, err := perGroup.Render()
+	if err != nil {
+		t.Fatalf("per-group render failed: %v", err)
+	}
+
+	if len(groupFiles) >= len(opFiles) {
+		t.Errorf("per-group output has %d files, want fewer than per-operation's %d", len(groupFiles), len(opFiles))
+	}
+	if strings.HasPrefix(string(opFiles["internal/commands/root.go"]), "// Acme") {
+		t.Error("per-operation output picked up the other generator's file header")
+	}
+	if !strings.HasPrefix(string(groupFiles["internal/commands/root.go"]), "// Acme") {
+		t.Error("per-group output is missing its file header")
+	}
+}
@@ -0,0 +1,309 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxBatchErrorBody is the largest error response body a BatchResult keeps
+const maxBatchErrorBody = 1 << 20
+
+// BatchLine is one line of batch input: a JSON object of flag values by
+// flag name, with the positional arguments under "args"
+type BatchLine struct {
+	Number int // 1-based, counting blank lines
+	Args   []string
+	Flags  map[string]json.RawMessage
+}
+
+// BatchResult is written for each line of batch input. Response is the
+// response body, as JSON when it is JSON and as a string otherwise; for a
+// failed request it is the error response body.
+type BatchResult struct {
+	Line     int             `json:"line"`
+	Status   int             `json:"status,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// BatchFunc runs the command for one line of batch input, sending its
+// requests with rt
+type BatchFunc func(ctx context.Context, rt *Runtime, line BatchLine) error
+
+// parseBatchLine parses the JSON object of a line of batch input
+func parseBatchLine(number int, data []byte) (BatchLine, error) {
+	line := BatchLine{Number: number}
+	if err := json.Unmarshal(data, &line.Flags); err != nil || line.Flags == nil {
+		return line, fmt.Errorf("line %d is not a JSON object", number)
+	}
+	if args, ok := line.Flags["args"]; ok {
+		if err := json.Unmarshal(args, &line.Args); err != nil {
+			return line, fmt.Errorf("line %d: args must be a list of strings", number)
+		}
+		delete(line.Flags, "args")
+	}
+	return line, nil
+}
+
+// Batch calls run for each line of in, with at most concurrency lines in
+// flight, and writes a BatchResult for each to Output as a JSON line, in
+// the order of the input. Each line gets its own runtime, so its response
+// is captured in its result rather than printed. With BatchRate, the
+// requests of all lines are spaced to spend at most that rate-limit cost
+// per second. An error is returned when any line failed.
+func (r *Runtime) Batch(ctx context.Context, in io.Reader, concurrency int, run BatchFunc) error {
+	if concurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d (must be at least 1)", concurrency)
+	}
+	if r.BatchRate < 0 {
+		return fmt.Errorf("invalid rate %v (must not be negative)", r.BatchRate)
+	}
+	var pacer *batchPacer
+	if r.BatchRate > 0 {
+		pacer = &batchPacer{rate: r.BatchRate}
+	}
+
+	// Lines are started in order and their results are written in the same
+	// order, so a slow line holds back the results after it but not the
+	// requests
+	pending := make(chan chan BatchResult, concurrency)
+	slots := make(chan struct{}, concurrency)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(pending)
+		reader := bufio.NewReader(in)
+		for number := 1; ; number++ {
+			data, err := reader.ReadBytes('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				readErr <- fmt.Errorf("failed to read batch input: %w", err)
+				return
+			}
+			if data = bytes.TrimSpace(data); len(data) > 0 {
+				select {
+				case slots <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				done := make(chan BatchResult, 1)
+				pending <- done
+				go func(number int, data []byte) {
+					defer func() { <-slots }()
+					done <- r.runBatchLine(ctx, number, data, run, pacer)
+				}(number, data)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// On Ctrl-C, stop without waiting for the input to end
+	total, failed := 0, 0
+	for {
+		var done chan BatchResult
+		select {
+		case done = <-pending:
+		case <-ctx.Done():
+		}
+		if done == nil {
+			break
+		}
+		result := <-done
+		total++
+		if result.Error != "" {
+			failed++
+		}
+		line, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+		if _, err := fmt.Fprintln(r.Output, string(line)); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case err := <-readErr:
+		return err
+	default:
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return fmt.Errorf("%w; the remaining lines were not run", ErrInterrupted)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d lines failed", failed, total)
+	}
+	return nil
+}
+
+// runBatchLine runs one line of batch input and returns its result. Its
+// requests wait for pacer, when there is one.
+func (r *Runtime) runBatchLine(ctx context.Context, number int, data []byte, run BatchFunc, pacer *batchPacer) BatchResult {
+	result := BatchResult{Line: number}
+	line, err := parseBatchLine(number, data)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var out bytes.Buffer
+	var last batchResponse
+	lineRT := r.fork(&out, io.Discard)
+	lineRT.middleware = append([]Middleware{last.record}, lineRT.middleware...)
+	if pacer != nil {
+		lineRT.middleware = append(lineRT.middleware, pacer.transport)
+	}
+
+	err = run(ctx, lineRT, line)
+	result.Status = last.status
+	body := out.Bytes()
+	if err != nil {
+		result.Error = err.Error()
+		body = last.errorBody
+	}
+	result.Response = batchResponseBody(body)
+	return result
+}
+
+// batchResponseBody returns body as JSON, compacted, or as a JSON string
+// when it is not JSON
+func batchResponseBody(body []byte) json.RawMessage {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err == nil {
+		return compact.Bytes()
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+// batchResponse records the status of the last response to a line's
+// requests, and its body when it is an error
+type batchResponse struct {
+	status    int
+	errorBody []byte
+}
+
+func (b *batchResponse) record(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		b.status, b.errorBody = resp.StatusCode, nil
+		if resp.StatusCode >= 300 {
+			body, err := io.ReadAll(io.LimitReader(resp.Body, maxBatchErrorBody))
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			b.errorBody = body
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		return resp, nil
+	})
+}
+
+// batchPacer spaces requests so that their rate-limit cost stays within a
+// budget per second: after a request costing c, the next one waits c/rate
+// seconds
+type batchPacer struct {
+	rate float64
+	mu   sync.Mutex
+	next time.Time // when the next request may be sent
+}
+
+// pacerNow and pacerWait are the clock of batchPacer and how it waits for
+// d or until ctx is done; tests replace them to pace without delay
+var (
+	pacerNow  = time.Now
+	pacerWait = func(ctx context.Context, d time.Duration) error {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	}
+)
+
+// wait waits for the turn of a request costing cost
+func (p *batchPacer) wait(ctx context.Context, cost float64) error {
+	if cost <= 0 {
+		cost = 1
+	}
+	p.mu.Lock()
+	now := pacerNow()
+	start := p.next
+	if start.Before(now) {
+		start = now
+	}
+	p.next = start.Add(time.Duration(cost / p.rate * float64(time.Second)))
+	p.mu.Unlock()
+
+	if delay := start.Sub(now); delay > 0 {
+		return pacerWait(ctx, delay)
+	}
+	return nil
+}
+
+// transport is middleware that waits for the turn of each request, costing
+// the rate cost of its hints
+func (p *batchPacer) transport(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		hints, _ := hintsOf(req)
+		if err := p.wait(req.Context(), hints.rateCost); err != nil {
+			return nil, err
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+// fork returns a copy of r for one line of batch input, printing JSON to
+// out and messages to errOut. Settings that choose another output, like a
+// table or a file, do not apply to a line.
+func (r *Runtime) fork(out, errOut io.Writer) *Runtime {
+	r.headersMu.RLock()
+	headers := maps.Clone(r.Headers)
+	r.headersMu.RUnlock()
+
+	return &Runtime{
+		BaseURL:            r.BaseURL,
+		HTTPClient:         r.HTTPClient,
+		Headers:            headers,
+		ExtraQuery:         r.ExtraQuery,
+		Timeout:            r.Timeout,
+		Output:             out,
+		ErrOutput:          errOut,
+		Format:             FormatJSON,
+		Query:              r.Query,
+		Audit:              r.Audit,
+		ReadOnly:           r.ReadOnly,
+		Signer:             r.Signer,
+		Debug:              r.Debug,
+		Log:                r.Log,
+		Timing:             r.Timing,
+		Tracer:             r.Tracer,
+		DryRun:             r.DryRun,
+		middleware:         append([]Middleware(nil), r.middleware...),
+		StreamTimeout:      r.StreamTimeout,
+		IdleTimeout:        r.IdleTimeout,
+		DisableCompression: r.DisableCompression,
+		MaxRedirects:       r.MaxRedirects,
+		NoFollow:           r.NoFollow,
+	}
+}
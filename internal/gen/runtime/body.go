@@ -23,6 +23,9 @@ func LoadBody(data string) ([]byte, error) {
 
 		if path == "-" {
 			// Read from stdin
+			if err := CheckInput(os.Stdin, "the body for --data @-"); err != nil {
+				return nil, err
+			}
 			return io.ReadAll(os.Stdin)
 		}
 
@@ -37,3 +40,22 @@ func LoadBody(data string) ([]byte, error) {
 	// Treat as raw JSON
 	return []byte(data), nil
 }
+
+// ReadValue reads a single value from r for a "-" argument, so an ID printed
+// by a previous command can be piped in. Surrounding whitespace is trimmed,
+// and empty or multi-line input is rejected, since each value needs its own
+// request.
+func ReadValue(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read value from stdin: %w", err)
+	}
+	value := strings.TrimSpace(string(data))
+	switch {
+	case value == "":
+		return "", fmt.Errorf("no value on stdin for \"-\"")
+	case strings.ContainsAny(value, "\r\n"):
+		return "", fmt.Errorf("stdin has more than one line for \"-\", which takes a single value")
+	}
+	return value, nil
+}
@@ -0,0 +1,195 @@
+package runtime
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxCachedBody is the largest response body ResponseCache stores
+const maxCachedBody = 10 << 20
+
+// ResponseCache stores GET responses that carry an ETag or Last-Modified
+// validator in files under Dir, and revalidates them with conditional
+// requests: when the server answers 304 Not Modified, the stored response is
+// used. Responses are keyed by URL and the credentials they were fetched
+// with, so users sharing a cache never see each other's responses.
+type ResponseCache struct {
+	Dir string
+}
+
+// NewResponseCache returns the response cache of appName under the user
+// cache dir ($XDG_CACHE_HOME on Linux)
+func NewResponseCache(appName string) (*ResponseCache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine cache directory: %w", err)
+	}
+	return &ResponseCache{Dir: filepath.Join(dir, appName, "responses")}, nil
+}
+
+// cachedResponse is a stored response
+type cachedResponse struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// Middleware returns middleware that serves GET requests from the cache
+// when the server confirms the stored response is still current
+func (c *ResponseCache) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+				return next.RoundTrip(req)
+			}
+
+			key := c.key(req)
+			cached := c.load(key)
+			if cached != nil {
+				req = req.Clone(req.Context())
+				if etag := cached.Header.Get("ETag"); etag != "" && req.Header.Get("If-None-Match") == "" {
+					req.Header.Set("If-None-Match", etag)
+				}
+				if modified := cached.Header.Get("Last-Modified"); modified != "" && req.Header.Get("If-Modified-Since") == "" {
+					req.Header.Set("If-Modified-Since", modified)
+				}
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+
+			if resp.StatusCode == http.StatusNotModified && cached != nil {
+				resp.Body.Close()
+				return cached.response(req), nil
+			}
+			if !cacheable(resp) {
+				return resp, nil
+			}
+			return c.store(key, req, resp)
+		})
+	}
+}
+
+// Clear removes every stored response
+func (c *ResponseCache) Clear() error {
+	return os.RemoveAll(c.Dir)
+}
+
+// key identifies the response to req by its URL and credentials
+func (c *ResponseCache) key(req *http.Request) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s", req.URL, req.Header.Get("Authorization"), req.Header.Get("Cookie"), req.Header.Get("Accept"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *ResponseCache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+// load returns the stored response for key, or nil when there is none
+func (c *ResponseCache) load(key string) *cachedResponse {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		// A corrupt entry is as good as none
+		return nil
+	}
+	return &cached
+}
+
+// cacheable reports whether resp may be stored: a complete 200 response with
+// a validator, that is not an event stream and allows storing
+func cacheable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	if resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		return false
+	}
+	if strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store") {
+		return false
+	}
+	contentType := resp.Header.Get("Content-Type")
+	return !isEventStream(contentType) && !isNDJSON(contentType) && resp.ContentLength <= maxCachedBody
+}
+
+// store saves resp under key and returns it with its body still readable.
+// Bodies larger than maxCachedBody are passed on without being stored.
+func (c *ResponseCache) store(key string, req *http.Request, resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBody+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxCachedBody {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Failing to cache must not fail the request
+	cached := &cachedResponse{URL: req.URL.String(), StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
+	_ = c.save(key, cached)
+	return resp, nil
+}
+
+// save writes cached under key, through a temporary file so concurrent
+// readers never see a partial entry
+func (c *ResponseCache) save(key string, cached *cachedResponse) error {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Dir, ".response-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+// response rebuilds the stored response as the answer to req
+func (cached *cachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cached.StatusCode, http.StatusText(cached.StatusCode)),
+		StatusCode:    cached.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cached.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       req,
+	}
+}
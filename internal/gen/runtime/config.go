@@ -1,11 +1,16 @@
 package runtime
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	goruntime "runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"text/template"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,12 +20,88 @@ var DefaultWarningWriter io.Writer = os.Stderr
 
 // Config holds the CLI configuration
 type Config struct {
-	BaseURL string            `yaml:"base_url"`
-	Headers map[string]string `yaml:"headers"`
+	BaseURL       string            `yaml:"base_url"`
+	Server        string            `yaml:"server"` // a server named in the spec, used without base_url
+	Token         string            `yaml:"token"`
+	ClientID      string            `yaml:"client_id"`
+	ClientSecret  string            `yaml:"client_secret"`
+	SigningKeyID  string            `yaml:"signing_key_id"` // for HMAC request signing
+	SigningSecret string            `yaml:"signing_secret"`
+	Headers       map[string]string `yaml:"headers"`
+	AuditLog      string            `yaml:"audit_log"`
+	LogFile       string            `yaml:"log_file"` // JSON lines of requests, see RequestLog
+	ReadOnly      bool              `yaml:"read_only"`
+	Cache         bool              `yaml:"cache"`      // cache GET responses, see ResponseCache
+	CookieJar     bool              `yaml:"cookie_jar"` // keep cookies between runs, see CookieJar
+	Proxy         string            `yaml:"proxy"`      // replaces HTTP_PROXY, HTTPS_PROXY and ALL_PROXY
+	SendRequestID bool              `yaml:"send_request_id"`
+	UserAgent     string            `yaml:"user_agent"` // replaces <app>/<version> (opencligen)
+
+	// OpenTelemetry collector to export a trace of each command to, see
+	// Tracer, and headers sent with the export
+	OtelEndpoint string            `yaml:"otel_endpoint"`
+	OtelHeaders  map[string]string `yaml:"otel_headers"`
+
+	// TLS settings for APIs behind a private PKI, see TLSOptions
+	CACert             string `yaml:"ca_cert"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	ClientCert         string `yaml:"client_cert"`
+	ClientKey          string `yaml:"client_key"`
+
+	// AuthCommand is a credential helper whose output is sent as the bearer
+	// token when no token is set, e.g. ["vault", "read", "-field=token",
+	// "secret/api"]
+	AuthCommand []string `yaml:"auth_command"`
+
+	// ExitCodes replaces DefaultExitCodes for the statuses and classes it
+	// names, e.g. {"404": 10, "5xx": 3}
+	ExitCodes ExitCodes `yaml:"exit_codes"`
+
+	// CredentialStore selects where secret settings are kept: "file" (this
+	// config), "credentials_file" (a file of their own, see CredentialsFile)
+	// or "keychain" (the OS credential store, see Keychain)
+	CredentialStore string `yaml:"credential_store"`
+
+	// Profiles are named sets of settings, e.g. one per environment, that
+	// replace the settings above when selected with --profile or
+	// <APP>_PROFILE. Profile is the selected one.
+	Profiles map[string]Profile `yaml:"profiles"`
+	Profile  string             `yaml:"-"`
+}
+
+// Profile holds the settings that differ between environments. Headers are
+// merged into the top-level headers; other settings replace the top-level
+// ones when set.
+type Profile struct {
+	BaseURL       string            `yaml:"base_url"`
+	Server        string            `yaml:"server"`
+	Token         string            `yaml:"token"`
+	ClientID      string            `yaml:"client_id"`
+	ClientSecret  string            `yaml:"client_secret"`
+	SigningKeyID  string            `yaml:"signing_key_id"`
+	SigningSecret string            `yaml:"signing_secret"`
+	Headers       map[string]string `yaml:"headers"`
+	AuthCommand   []string          `yaml:"auth_command"`
 }
 
-// LoadConfig loads configuration from file and environment
+// LoadConfig loads configuration from file and environment, with the
+// profile named by <APP>_PROFILE if set
 func LoadConfig(appName string) (*Config, error) {
+	return LoadProfile(appName, "")
+}
+
+// ProfileName returns the profile to use: the given one (e.g. from
+// --profile), or else the one named by <APP>_PROFILE
+func ProfileName(appName, profile string) string {
+	if profile != "" {
+		return profile
+	}
+	return os.Getenv(strings.ToUpper(appName) + "_PROFILE")
+}
+
+// LoadProfile loads configuration from file and environment, with the
+// settings of the profile selected by ProfileName applied over the file's
+func LoadProfile(appName, profile string) (*Config, error) {
 	config := &Config{
 		Headers: make(map[string]string),
 	}
@@ -28,40 +109,236 @@ func LoadConfig(appName string) (*Config, error) {
 	// Try to load from config file
 	configPath := getConfigPath(appName)
 	if configPath != "" {
-		if err := loadConfigFile(configPath, config); err != nil {
+		if err := loadConfigFile(appName, configPath, config); err != nil {
 			// Config file is optional, ignore errors
 			_ = err
 		}
 	}
 
+	// The profile overrides the rest of the config file
+	if name := ProfileName(appName, profile); name != "" {
+		if err := config.useProfile(name); err != nil {
+			return nil, err
+		}
+	}
+
+	// Header values may be templates, e.g. reading a token from the environment
+	if err := config.expandHeaders(); err != nil {
+		return nil, err
+	}
+
 	// Environment variables override config file
 	envPrefix := strings.ToUpper(appName) + "_"
 	if baseURL := os.Getenv(envPrefix + "BASE_URL"); baseURL != "" {
 		config.BaseURL = baseURL
 	}
+	if token := os.Getenv(envPrefix + "TOKEN"); token != "" {
+		config.Token = token
+	}
+	if clientID := os.Getenv(envPrefix + "CLIENT_ID"); clientID != "" {
+		config.ClientID = clientID
+	}
+	if clientSecret := os.Getenv(envPrefix + "CLIENT_SECRET"); clientSecret != "" {
+		config.ClientSecret = clientSecret
+	}
+	if keyID := os.Getenv(envPrefix + "SIGNING_KEY_ID"); keyID != "" {
+		config.SigningKeyID = keyID
+	}
+	if secret := os.Getenv(envPrefix + "SIGNING_SECRET"); secret != "" {
+		config.SigningSecret = secret
+	}
+	if auditLog := os.Getenv(envPrefix + "AUDIT_LOG"); auditLog != "" {
+		config.AuditLog = auditLog
+	}
+	if logFile := os.Getenv(envPrefix + "LOG_FILE"); logFile != "" {
+		config.LogFile = logFile
+	}
+	if readOnly, err := strconv.ParseBool(os.Getenv(envPrefix + "READ_ONLY")); err == nil {
+		config.ReadOnly = readOnly
+	}
+	if store := os.Getenv(envPrefix + "CREDENTIAL_STORE"); store != "" {
+		config.CredentialStore = store
+	}
+
+	// The standard OpenTelemetry variables override the collector settings
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		config.OtelEndpoint = endpoint
+	}
+	if headers := os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"); headers != "" {
+		config.OtelHeaders = OTLPHeaders(headers)
+	}
+
+	// Stored secrets fill in what the file and environment leave unset
+	if err := config.loadSecrets(appName); err != nil {
+		return nil, err
+	}
 
 	return config, nil
 }
 
+// useProfile applies the settings of the named profile
+func (c *Config) useProfile(name string) error {
+	p, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return fmt.Errorf("unknown profile %q (no profiles are configured)", name)
+		}
+		names := make([]string, 0, len(c.Profiles))
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown profile %q (must be one of: %s)", name, strings.Join(names, ", "))
+	}
+
+	c.Profile = name
+	// base_url and server both choose the API, so either replaces both
+	if p.BaseURL != "" || p.Server != "" {
+		c.BaseURL, c.Server = p.BaseURL, p.Server
+	}
+	for _, s := range []struct{ value, field *string }{
+		{&p.Token, &c.Token},
+		{&p.ClientID, &c.ClientID},
+		{&p.ClientSecret, &c.ClientSecret},
+		{&p.SigningKeyID, &c.SigningKeyID},
+		{&p.SigningSecret, &c.SigningSecret},
+	} {
+		if *s.value != "" {
+			*s.field = *s.value
+		}
+	}
+	if c.Headers == nil {
+		c.Headers = make(map[string]string)
+	}
+	for k, v := range p.Headers {
+		// Replace the top-level header whatever the case of its name
+		for existing := range c.Headers {
+			if strings.EqualFold(existing, k) {
+				delete(c.Headers, existing)
+			}
+		}
+		c.Headers[k] = v
+	}
+	if len(p.AuthCommand) > 0 {
+		c.AuthCommand = p.AuthCommand
+	}
+	return nil
+}
+
+// headerFuncs are available to header templates in the config file in
+// addition to the text/template builtins
+var headerFuncs = template.FuncMap{
+	// env returns an environment variable, failing when it is not set so a
+	// missing credential is not sent as an empty one
+	"env": func(name string) (string, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	},
+	// envOr returns an environment variable, or fallback when it is not set
+	"envOr": func(name, fallback string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		return fallback
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// expandHeaders expands the templates in header values from the config
+// file, e.g. "Bearer {{ env \"PROD_TOKEN\" }}" or "{{ .Profile }}-team", so
+// one config can serve several users and environments. Values without
+// "{{" are used as they are.
+func (c *Config) expandHeaders() error {
+	data := struct{ Profile string }{c.Profile}
+	for _, headers := range []map[string]string{c.Headers, c.OtelHeaders} {
+		for name, value := range headers {
+			if !strings.Contains(value, "{{") {
+				continue
+			}
+			tmpl, err := template.New(name).Funcs(headerFuncs).Option("missingkey=error").Parse(value)
+			if err != nil {
+				return fmt.Errorf("invalid template in header %s: %w", name, err)
+			}
+			var expanded strings.Builder
+			if err := tmpl.Execute(&expanded, data); err != nil {
+				return fmt.Errorf("failed to expand header %s: %w", name, err)
+			}
+			headers[name] = expanded.String()
+		}
+	}
+	return nil
+}
+
+// configDir returns the directory holding the config file for appName
+func configDir(appName string) (string, error) {
+	return configDirFor(goruntime.GOOS, appName)
+}
+
+// configDirFor returns the config directory of appName on goos:
+// $XDG_CONFIG_HOME/<app> when set, and otherwise %AppData%\<app> on
+// Windows, ~/Library/Application Support/<app> on macOS and
+// ~/.config/<app> elsewhere. On Windows and macOS, an existing
+// ~/.config/<app> is kept, as it was the only location before.
+func configDirFor(goos, appName string) (string, error) {
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return filepath.Join(configHome, appName), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	legacy := filepath.Join(home, ".config", appName)
+
+	var configHome string
+	switch goos {
+	case "windows":
+		configHome = os.Getenv("AppData")
+		if configHome == "" {
+			return "", errors.New("%AppData% is not set")
+		}
+	case "darwin", "ios":
+		configHome = filepath.Join(home, "Library", "Application Support")
+	default:
+		return legacy, nil
+	}
+
+	dir := filepath.Join(configHome, appName)
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		if info, err := os.Stat(legacy); err == nil && info.IsDir() {
+			return legacy, nil
+		}
+	}
+	return dir, nil
+}
+
+// DefaultConfigPath returns the path a new config file should be written to
+func DefaultConfigPath(appName string) (string, error) {
+	dir, err := configDir(appName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.yaml"), nil
+}
+
 // getConfigPath returns the path to the config file
 func getConfigPath(appName string) string {
-	// Try XDG_CONFIG_HOME first
-	configHome := os.Getenv("XDG_CONFIG_HOME")
-	if configHome == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return ""
-		}
-		configHome = filepath.Join(home, ".config")
+	dir, err := configDir(appName)
+	if err != nil {
+		return ""
 	}
 
-	configPath := filepath.Join(configHome, appName, "config.yaml")
+	configPath := filepath.Join(dir, "config.yaml")
 	if _, err := os.Stat(configPath); err == nil {
 		return configPath
 	}
 
 	// Try .yml extension
-	configPath = filepath.Join(configHome, appName, "config.yml")
+	configPath = filepath.Join(dir, "config.yml")
 	if _, err := os.Stat(configPath); err == nil {
 		return configPath
 	}
@@ -69,8 +346,159 @@ func getConfigPath(appName string) string {
 	return ""
 }
 
+// starterConfig is the commented-out template written by InitConfig
+const starterConfig = `# Configuration for %[1]s
+#
+# Values here are used when the corresponding flag or environment variable is
+# not set. This file may contain credentials; keep it readable only by you.
+
+# Base URL of the API (overridden by --base-url or %[2]s_BASE_URL)
+# base_url: https://api.example.com
+
+# Server of the API by the name --server takes, for APIs that list several
+# servers (used when base_url is not set; overridden by --server)
+# server: sandbox
+
+# Bearer token sent as "Authorization: Bearer <token>", for APIs that use
+# bearer authentication (overridden by --token or %[2]s_TOKEN)
+# token: <token>
+
+# OAuth2 client credentials exchanged for access tokens, for APIs that use the
+# client credentials flow (overridden by --client-id and --client-secret, or
+# %[2]s_CLIENT_ID and %[2]s_CLIENT_SECRET)
+# client_id: my-client
+# client_secret: <secret>
+
+# Key ID and secret for APIs that require HMAC-signed requests (overridden by
+# %[2]s_SIGNING_KEY_ID and %[2]s_SIGNING_SECRET)
+# signing_key_id: my-key
+# signing_secret: <secret>
+
+# Keep token, client_secret and signing_secret out of this file: in
+# credentials.yaml next to it (credentials_file), or in the OS keychain
+# (keychain) (overridden by %[2]s_CREDENTIAL_STORE). Store them with
+# "%[1]s config set-secret <key>" or "%[1]s config set <key> <value>".
+# credential_store: keychain
+
+# Command printing a token to send as "Authorization: Bearer <token>" when
+# no token is set, for fetching credentials from a secret manager
+# auth_command: ["vault", "read", "-field=token", "secret/api"]
+
+# Headers sent with every request. Values may read environment variables
+# with {{ env "NAME" }} (an error when unset) or {{ envOr "NAME" "default" }},
+# and the selected profile with {{ .Profile }}.
+# headers:
+#   Authorization: Bearer <token>
+#   X-Org-Id: my-org
+#   X-Api-Key: '{{ env "PROD_API_KEY" }}'
+
+# Named sets of settings, e.g. one per environment, selected with --profile
+# or %[2]s_PROFILE. A profile's headers are added to those above, and its
+# other settings replace the ones above.
+# profiles:
+#   staging:
+#     base_url: https://staging.api.example.com
+#     token: <token>
+#   prod:
+#     base_url: https://api.example.com
+#     headers:
+#       X-Org-Id: my-org
+
+# Block every request except GET and HEAD (overridden by --read-only or
+# %[2]s_READ_ONLY)
+# read_only: true
+
+# Cache GET responses that have an ETag or Last-Modified header, and revalidate
+# them with the server (overridden by --cache). Clear with "%[1]s cache clear".
+# cache: true
+
+# Send a generated X-Request-Id header, the same for every request of a
+# command, so failures can be traced in the server logs (overridden by
+# --request-id)
+# send_request_id: true
+
+# User-Agent sent with every request, in place of "%[1]s/<version>
+# (opencligen)"
+# user_agent: my-script/1.0
+
+# Store cookies the API sets, such as a session cookie set by a login
+# request, and send them with later requests (overridden by --cookie-jar).
+# Clear with "%[1]s cookies clear".
+# cookie_jar: true
+
+# HTTP or SOCKS5 proxy for every request, e.g. socks5://bastion:1080
+# (overridden by --proxy). Without it, HTTP_PROXY, HTTPS_PROXY, ALL_PROXY and
+# NO_PROXY are used.
+# proxy: http://proxy.example.com:8080
+
+# CA certificates (PEM) to trust in addition to the system's, for APIs with a
+# private PKI (overridden by --cacert)
+# ca_cert: ~/.config/%[1]s/ca.pem
+
+# Client certificate and key (PEM) for mutual TLS (overridden by --cert and
+# --key). The key may be in the certificate file.
+# client_cert: ~/.config/%[1]s/client.pem
+# client_key: ~/.config/%[1]s/client.key
+
+# Accept any server certificate. Only for testing: this exposes requests,
+# including credentials, to anyone on the network path.
+# insecure_skip_verify: true
+
+# Exit codes by response status, status class, or "network" for requests that
+# got no response. The defaults are 2 for 4xx, 3 for 5xx and 4 for network.
+# exit_codes:
+#   404: 10
+#   5xx: 3
+
+# Append a hash-chained audit record of every request to this file
+# (overridden by --audit-log or %[2]s_AUDIT_LOG)
+# audit_log: ~/.local/state/%[1]s/audit.log
+
+# Append a JSON line per request, with its response status and headers, to
+# this file, with credentials masked (overridden by --log-file or
+# %[2]s_LOG_FILE)
+# log_file: ~/.local/state/%[1]s/requests.log
+
+# Export an OpenTelemetry trace of each command, with a span per request, to
+# this OTLP/HTTP collector, and send a traceparent header with every request
+# (overridden by OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_HEADERS)
+# otel_endpoint: http://localhost:4318
+# otel_headers:
+#   x-api-key: collector-key
+`
+
+// InitConfig writes a starter config file for appName and returns its path.
+// The file is created with 0600 permissions. An existing file is only
+// replaced when force is true.
+func InitConfig(appName string, force bool) (string, error) {
+	path, err := DefaultConfigPath(appName)
+	if err != nil {
+		return "", fmt.Errorf("failed to determine config path: %w", err)
+	}
+
+	if _, err := os.Stat(path); err == nil && !force {
+		return "", fmt.Errorf("config file %s already exists (use --force to overwrite)", path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	content := fmt.Sprintf(starterConfig, appName, strings.ToUpper(appName))
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return "", fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	// WriteFile keeps the mode of an existing file, so enforce it explicitly
+	if err := os.Chmod(path, 0600); err != nil {
+		return "", fmt.Errorf("failed to set config file permissions: %w", err)
+	}
+
+	return path, nil
+}
+
 // loadConfigFile loads configuration from a YAML file
-func loadConfigFile(path string, config *Config) error {
+func loadConfigFile(appName, path string, config *Config) error {
 	// Check file permissions for security
 	info, err := os.Stat(path)
 	if err != nil {
@@ -81,7 +509,7 @@ func loadConfigFile(path string, config *Config) error {
 	mode := info.Mode().Perm()
 	if mode&0044 != 0 { // Check if group or others have read permission
 		fmt.Fprintf(DefaultWarningWriter, "Warning: config file %s has insecure permissions %o. "+
-			"Consider running: chmod 600 %s\n", path, mode, path)
+			"Consider running: %s config fix-permissions\n", path, mode, appName)
 	}
 
 	data, err := os.ReadFile(path)
@@ -92,6 +520,18 @@ func loadConfigFile(path string, config *Config) error {
 	return yaml.Unmarshal(data, config)
 }
 
+// ExpandHome replaces a leading "~/" in path with the user's home directory
+func ExpandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
 // GetEnvOrConfig returns a value from environment, config, or default
 func GetEnvOrConfig(envVar, configKey, defaultValue string, config *Config) string {
 	// Environment takes precedence
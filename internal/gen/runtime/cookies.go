@@ -0,0 +1,250 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CookieJar keeps the cookies set by the API in a file readable only by the
+// user, and sends them with later requests, so session-based APIs work
+// across invocations. Cookies are matched to requests as a browser would.
+type CookieJar struct {
+	Path string
+
+	mu      sync.Mutex
+	loaded  bool
+	jar     *cookiejar.Jar
+	cookies []storedCookie
+}
+
+// NewCookieJar returns the cookie jar of appName, next to its config file.
+// Each profile of config has its own jar.
+func NewCookieJar(appName string, config *Config) (*CookieJar, error) {
+	dir, err := configDir(appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine config directory: %w", err)
+	}
+	name := "cookies.json"
+	if config != nil && config.Profile != "" {
+		name = "cookies-" + config.Profile + ".json"
+	}
+	return &CookieJar{Path: filepath.Join(dir, name)}, nil
+}
+
+// storedCookie is a cookie with the URL of the response that set it, so it
+// can be set again the same way
+type storedCookie struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Expires  time.Time `json:"expires"` // zero for session cookies
+	Secure   bool      `json:"secure,omitempty"`
+	HTTPOnly bool      `json:"http_only,omitempty"`
+}
+
+func (c storedCookie) cookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Expires:  c.Expires,
+		Secure:   c.Secure,
+		HttpOnly: c.HTTPOnly,
+	}
+}
+
+// expired reports whether c has expired at now
+func (c storedCookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+// sameCookie reports whether a and b are the same cookie, which the later
+// one replaces
+func sameCookie(a, b storedCookie) bool {
+	return a.Name == b.Name && a.Domain == b.Domain && a.scope() == b.scope()
+}
+
+// scope returns the host (for host-only cookies) and path c applies to
+func (c storedCookie) scope() string {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return ""
+	}
+	host := ""
+	if c.Domain == "" {
+		host = u.Hostname()
+	}
+	p := c.Path
+	if p == "" || p[0] != '/' {
+		// The default path is the directory of the request path (RFC 6265)
+		p = "/"
+		if i := strings.LastIndex(u.Path, "/"); i > 0 {
+			p = u.Path[:i]
+		}
+	}
+	return host + p
+}
+
+// Middleware returns middleware that adds the stored cookies to each request
+// and stores the cookies each response sets. Failing to store cookies does
+// not fail the request.
+func (j *CookieJar) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			j.mu.Lock()
+			err := j.load()
+			var cookies []*http.Cookie
+			if err == nil {
+				cookies = j.jar.Cookies(req.URL)
+			}
+			j.mu.Unlock()
+			if err != nil {
+				return nil, err
+			}
+
+			if len(cookies) > 0 {
+				req = req.Clone(req.Context())
+				for _, c := range cookies {
+					req.AddCookie(c)
+				}
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			if set := resp.Cookies(); len(set) > 0 {
+				_ = j.store(req.URL, set)
+			}
+			return resp, nil
+		})
+	}
+}
+
+// load reads the stored cookies into the jar, once
+func (j *CookieJar) load() error {
+	if j.loaded {
+		return nil
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(j.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read cookies: %w", err)
+	}
+	var cookies []storedCookie
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &cookies); err != nil {
+			// A corrupt jar is as good as an empty one
+			cookies = nil
+		}
+	}
+
+	now := time.Now()
+	for _, c := range cookies {
+		u, err := url.Parse(c.URL)
+		if err != nil || c.expired(now) {
+			continue
+		}
+		jar.SetCookies(u, []*http.Cookie{c.cookie()})
+		j.cookies = append(j.cookies, c)
+	}
+	j.jar, j.loaded = jar, true
+	return nil
+}
+
+// store adds the cookies a response to u set, and saves the jar
+func (j *CookieJar) store(u *url.URL, set []*http.Cookie) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar.SetCookies(u, set)
+
+	now := time.Now()
+	for _, c := range set {
+		stored := storedCookie{
+			URL:      u.String(),
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+		}
+		// Max-Age takes precedence over Expires, and is kept as an expiry
+		// time since it counts from now
+		switch {
+		case c.MaxAge > 0:
+			stored.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case c.MaxAge < 0:
+			stored.Expires = now
+		}
+
+		kept := j.cookies[:0]
+		for _, existing := range j.cookies {
+			if !sameCookie(existing, stored) {
+				kept = append(kept, existing)
+			}
+		}
+		j.cookies = kept
+		if !stored.expired(now) {
+			j.cookies = append(j.cookies, stored)
+		}
+	}
+	return j.save()
+}
+
+// save writes the stored cookies, through a temporary file so concurrent
+// invocations never read a partial jar
+func (j *CookieJar) save() error {
+	data, err := json.MarshalIndent(j.cookies, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(j.Path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".cookies-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), j.Path)
+}
+
+// Clear removes every stored cookie
+func (j *CookieJar) Clear() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.loaded, j.jar, j.cookies = false, nil, nil
+	if err := os.Remove(j.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// RunAuthCommand runs a credential helper (the auth_command setting) and
+// returns its output with surrounding whitespace trimmed. The helper shares
+// the terminal's stdin and stderr so it can prompt for a password or MFA
+// code, except with NoInput, where its stdin is empty.
+func RunAuthCommand(ctx context.Context, argv []string) (string, error) {
+	if len(argv) == 0 {
+		return "", errors.New("auth_command is empty")
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	if !NoInput {
+		cmd.Stdin = os.Stdin
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("auth_command %s failed: %w", argv[0], err)
+	}
+
+	credential := strings.TrimSpace(string(out))
+	if credential == "" {
+		return "", fmt.Errorf("auth_command %s printed no credential", argv[0])
+	}
+	return credential, nil
+}
@@ -0,0 +1,196 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CredentialsFile is a SecretStore that keeps secrets in a file of their own
+// next to the config file, so the config can be shared or kept in version
+// control without them. It is written with 0600 permissions, and refused
+// while other users can read it.
+type CredentialsFile struct {
+	Path string
+}
+
+// NewCredentialsFile returns the credentials file of appName
+func NewCredentialsFile(appName string) (*CredentialsFile, error) {
+	dir, err := configDir(appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine config directory: %w", err)
+	}
+	return &CredentialsFile{Path: filepath.Join(dir, "credentials.yaml")}, nil
+}
+
+// String returns the path of the file, for messages
+func (f *CredentialsFile) String() string {
+	return f.Path
+}
+
+// Get returns the secret stored for key
+func (f *CredentialsFile) Get(key string) (string, error) {
+	secrets, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// Set stores value for key, replacing any existing value
+func (f *CredentialsFile) Set(key, value string) error {
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+	secrets[key] = value
+	return f.save(secrets)
+}
+
+// Delete removes the secret stored for key
+func (f *CredentialsFile) Delete(key string) error {
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[key]; !ok {
+		return nil
+	}
+	delete(secrets, key)
+	return f.save(secrets)
+}
+
+func (f *CredentialsFile) load() (map[string]string, error) {
+	secrets := make(map[string]string)
+	info, err := os.Stat(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mode := info.Mode().Perm(); mode&0077 != 0 {
+		return nil, fmt.Errorf("credentials file %s is accessible by other users (permissions %o); run: chmod 600 %s", f.Path, mode, f.Path)
+	}
+
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", f.Path, err)
+	}
+	return secrets, nil
+}
+
+func (f *CredentialsFile) save(secrets map[string]string) error {
+	data, err := yaml.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so an interrupted write cannot lose
+	// the other secrets
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), ".credentials-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+// SecretSetting reports whether the dotted config key names a secret
+// setting, e.g. token or profiles.staging.token, and returns its key in a
+// SecretStore
+func SecretSetting(key string) (string, bool) {
+	parts := strings.Split(key, ".")
+	var profile string
+	switch {
+	case len(parts) == 1:
+	case len(parts) == 3 && parts[0] == "profiles" && parts[1] != "":
+		profile = parts[1]
+	default:
+		return "", false
+	}
+	if ValidateSecretKey(parts[len(parts)-1]) != nil {
+		return "", false
+	}
+	return SecretKey(profile, parts[len(parts)-1]), true
+}
+
+// FixPermissions makes the files of appName readable only by the user: its
+// config and credentials files, stored tokens and cookies, and cached
+// responses. Directories get 0700 and files lose their group and other
+// permissions. It returns each path it changed, with the old and new modes.
+func FixPermissions(appName string) ([]string, error) {
+	var roots []string
+	if dir, err := configDir(appName); err == nil {
+		roots = append(roots, dir)
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		roots = append(roots, filepath.Join(dir, appName))
+	}
+
+	var fixed []string
+	for _, root := range roots {
+		// Follow a symlinked directory, e.g. one managed with dotfiles
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if errors.Is(err, os.ErrNotExist) && path == root {
+				return filepath.SkipDir
+			}
+			if err != nil {
+				return err
+			}
+			// Symlinks are left alone: their target may be anywhere
+			if d.Type()&fs.ModeSymlink != 0 {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			mode := info.Mode().Perm()
+			want := mode &^ 0077
+			if d.IsDir() {
+				want = 0700
+			}
+			if mode == want {
+				return nil
+			}
+			if err := os.Chmod(path, want); err != nil {
+				return err
+			}
+			fixed = append(fixed, fmt.Sprintf("%s (%o -> %o)", path, mode, want))
+			return nil
+		})
+		if err != nil {
+			return fixed, fmt.Errorf("failed to fix permissions: %w", err)
+		}
+	}
+	return fixed, nil
+}
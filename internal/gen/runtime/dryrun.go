@@ -0,0 +1,19 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// writeDryRun prints the request that would be sent: the method and URL,
+// the headers and the body, with credentials masked. Headers added later by
+// middleware or a signer, such as OAuth2 access tokens, are not shown.
+func writeDryRun(w io.Writer, req *http.Request, body []byte) {
+	fmt.Fprintf(w, "%s %s\n", req.Method, redactQuery(req.URL))
+	writeHeaders(w, "", req.Header)
+	if len(body) > 0 {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimRight(string(body), "\n"))
+	}
+}
@@ -0,0 +1,51 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// dryRunETag stands in for the ETag of a resource in a dry run, where it is
+// not fetched
+const dryRunETag = "<etag>"
+
+// CurrentETag returns the ETag the resource req changes has now, from a GET
+// of req's path with its path parameters, to send as If-Match so the change
+// fails when someone else changed the resource in between. In a dry run,
+// the GET is printed instead and a placeholder is returned.
+func (r *Runtime) CurrentETag(ctx context.Context, req *Request) (string, error) {
+	get := NewRequest(http.MethodGet, req.Path)
+	for name, value := range req.PathParams {
+		get.SetPathParam(name, value)
+	}
+	httpReq, err := r.build(ctx, get)
+	if err != nil {
+		return "", err
+	}
+	if r.DryRun {
+		writeDryRun(r.Output, httpReq, nil)
+		fmt.Fprintln(r.Output)
+		return dryRunETag, nil
+	}
+
+	resp, err := r.send(httpReq, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	if err := checkStatus(resp, body, outputOptions{ErrOut: r.ErrOutput}.errOut()); err != nil {
+		return "", err
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("GET %s returned no ETag to send as If-Match", redactQuery(httpReq.URL))
+	}
+	return etag, nil
+}
@@ -0,0 +1,39 @@
+package runtime
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// RequestIDHeader is the header a request ID is sent in
+const RequestIDHeader = "X-Request-Id"
+
+// requestIDHeaders are the response headers APIs commonly return a request
+// ID in, most common first
+var requestIDHeaders = []string{RequestIDHeader, "Request-Id", "X-Correlation-Id"}
+
+// NewRequestID returns a random version 4 UUID to send as a request ID
+func NewRequestID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate a request ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// requestID returns the request ID the server returned with resp, or else
+// the one sent with the request, so errors can quote it to support
+func requestID(resp *http.Response) string {
+	for _, name := range requestIDHeaders {
+		if id := resp.Header.Get(name); id != "" {
+			return id
+		}
+	}
+	if resp.Request != nil {
+		return resp.Request.Header.Get(RequestIDHeader)
+	}
+	return ""
+}
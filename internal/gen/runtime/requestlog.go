@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestLogEntry is a single line of the request log. Credentials in
+// headers and the URL are masked, and bodies are never recorded.
+type RequestLogEntry struct {
+	Time            time.Time         `json:"time"`
+	Command         string            `json:"command"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	Status          int               `json:"status,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	DurationMS      int64             `json:"duration_ms"`
+	Error           string            `json:"error,omitempty"`
+}
+
+// RequestLog writes a JSON line per request to Out, whatever --verbose is
+// set to: the request as it is sent and the response status and headers,
+// or the error, with credentials masked as by DebugLog
+type RequestLog struct {
+	Out     io.Writer
+	Command string
+
+	// mu keeps the lines of concurrent requests, e.g. of --batch, whole
+	mu sync.Mutex
+}
+
+// OpenLogFile opens path for appending log lines, creating it and its
+// directory readable only by the user
+func OpenLogFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create log file directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return f, nil
+}
+
+// transport logs requests and responses around next
+func (l *RequestLog) transport(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := req.Header.Clone()
+		if header.Get("Host") == "" && req.Host != "" {
+			header.Set("Host", req.Host)
+		}
+		entry := RequestLogEntry{
+			Time:           time.Now().UTC(),
+			Command:        l.Command,
+			Method:         req.Method,
+			URL:            redactQuery(req.URL),
+			RequestHeaders: redactHeaders(header),
+		}
+
+		resp, err := next.RoundTrip(req)
+		entry.DurationMS = time.Since(entry.Time).Milliseconds()
+		if err != nil {
+			entry.Error = err.Error()
+		} else {
+			entry.Status = resp.StatusCode
+			entry.ResponseHeaders = redactHeaders(resp.Header)
+		}
+		l.write(entry)
+		return resp, err
+	})
+}
+
+// write appends entry as one line, in a single write so lines from several
+// processes appending to the same file do not interleave
+func (l *RequestLog) write(entry RequestLogEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.Out.Write(append(line, '\n'))
+}
+
+// redactHeaders flattens header into one value per name, masking
+// credentials
+func redactHeaders(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for name, values := range header {
+		redacted := make([]string, len(values))
+		for i, value := range values {
+			redacted[i] = redactHeader(name, value)
+		}
+		out[name] = strings.Join(redacted, ", ")
+	}
+	return out
+}
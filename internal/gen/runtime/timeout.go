@@ -0,0 +1,83 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultIdleTimeout is how long an event stream may go without data before
+// it is ended
+const DefaultIdleTimeout = 5 * time.Minute
+
+// idleTimeout cancels a request when no data arrives for a while: first
+// while waiting for the response, then between reads of its body. A nil
+// *idleTimeout does nothing.
+type idleTimeout struct {
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	expired atomic.Bool
+}
+
+// withIdleTimeout returns httpReq with a context that is canceled once no
+// data has arrived for timeout
+func withIdleTimeout(httpReq *http.Request, timeout time.Duration) (*http.Request, *idleTimeout) {
+	ctx, cancel := context.WithCancel(httpReq.Context())
+	idle := &idleTimeout{timeout: timeout, cancel: cancel}
+	idle.timer = time.AfterFunc(timeout, func() {
+		idle.expired.Store(true)
+		cancel()
+	})
+	return httpReq.WithContext(ctx), idle
+}
+
+// explain replaces err with an idle timeout error when the timeout caused it
+func (t *idleTimeout) explain(err error) error {
+	if t == nil || err == nil || !t.expired.Load() {
+		return err
+	}
+	return fmt.Errorf("no data received for %s", t.timeout)
+}
+
+// stop releases the timer and the request context
+func (t *idleTimeout) stop() {
+	if t == nil {
+		return
+	}
+	t.timer.Stop()
+	t.cancel()
+}
+
+// wrap returns body with the timer restarted on each read that returns data
+func (t *idleTimeout) wrap(body io.ReadCloser) io.ReadCloser {
+	if t == nil {
+		return body
+	}
+	return &idleBody{body: body, idle: t}
+}
+
+type idleBody struct {
+	body io.ReadCloser
+	idle *idleTimeout
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.idle.timer.Reset(b.idle.timeout)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = b.idle.explain(err)
+	}
+	return n, err
+}
+
+func (b *idleBody) Close() error {
+	b.idle.stop()
+	return b.body.Close()
+}
@@ -0,0 +1,339 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxValidationProblems bounds the problems a ValidationError lists
+const maxValidationProblems = 20
+
+// ValidationError lists what is wrong with a request body, one problem per
+// field, e.g. "owner: missing required property 'name'"
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid request body:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// ValidateBody checks a JSON request body against the JSON Schema of the
+// operation's request body, as returned by DescribeOperation. It covers the
+// keywords OpenAPI documents use to describe bodies: types (with nullable),
+// required and unknown properties, enums, lengths, ranges, patterns and
+// allOf, anyOf and oneOf. Read-only properties are not required, and
+// unresolved $refs (recursive schemas) accept anything.
+func ValidateBody(schema json.RawMessage, body []byte) error {
+	var s map[string]interface{}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return fmt.Errorf("invalid request body schema: %w", err)
+	}
+	value, err := decodeJSON(body)
+	if err != nil {
+		return &ValidationError{Problems: []string{"not valid JSON: " + err.Error()}}
+	}
+
+	v := &validator{}
+	v.validate(s, value, "")
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// validator collects the problems found while walking a body
+type validator struct {
+	problems []string
+}
+
+func (v *validator) report(path, format string, args ...interface{}) {
+	if len(v.problems) == maxValidationProblems {
+		v.problems = append(v.problems, "...")
+	}
+	if len(v.problems) > maxValidationProblems {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if path != "" {
+		msg = path + ": " + msg
+	}
+	v.problems = append(v.problems, msg)
+}
+
+// valid reports whether value matches schema, without reporting problems
+func valid(schema map[string]interface{}, value interface{}) bool {
+	v := &validator{}
+	v.validate(schema, value, "")
+	return len(v.problems) == 0
+}
+
+func (v *validator) validate(schema map[string]interface{}, value interface{}, path string) {
+	if schema == nil {
+		return
+	}
+	if _, ok := schema["$ref"]; ok {
+		return
+	}
+
+	for _, sub := range schemaList(schema["allOf"]) {
+		v.validate(sub, value, path)
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		alternatives := schemaList(schema[key])
+		if len(alternatives) == 0 {
+			continue
+		}
+		matched := false
+		for _, sub := range alternatives {
+			if valid(sub, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.report(path, "does not match any of the allowed schemas")
+			return
+		}
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 && !matchesType(types, value) {
+		v.report(path, "expected %s, got %s", strings.Join(types, " or "), jsonType(value))
+		return
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, value) {
+		allowed := make([]string, len(enum))
+		for i, e := range enum {
+			allowed[i] = compactValue(e)
+		}
+		v.report(path, "must be one of %s (got %s)", strings.Join(allowed, ", "), compactValue(value))
+	}
+	if c, ok := schema["const"]; ok && !equalValues(c, value) {
+		v.report(path, "must be %s (got %s)", compactValue(c), compactValue(value))
+	}
+
+	switch val := value.(type) {
+	case string:
+		v.validateString(schema, val, path)
+	case json.Number:
+		v.validateNumber(schema, val, path)
+	case []interface{}:
+		v.validateArray(schema, val, path)
+	case map[string]interface{}:
+		v.validateObject(schema, val, path)
+	}
+}
+
+func (v *validator) validateString(schema map[string]interface{}, s, path string) {
+	length := utf8.RuneCountInString(s)
+	if min, ok := schemaNumber(schema["minLength"]); ok && float64(length) < min {
+		v.report(path, "must be at least %v characters", min)
+	}
+	if max, ok := schemaNumber(schema["maxLength"]); ok && float64(length) > max {
+		v.report(path, "must be at most %v characters", max)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		// Patterns Go cannot compile, e.g. with lookaheads, are skipped
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(s) {
+			v.report(path, "must match the pattern %s", pattern)
+		}
+	}
+}
+
+func (v *validator) validateNumber(schema map[string]interface{}, n json.Number, path string) {
+	f, err := n.Float64()
+	if err != nil {
+		return
+	}
+	// exclusiveMinimum and exclusiveMaximum are booleans qualifying minimum
+	// and maximum in OpenAPI 3.0, and numbers of their own in 3.1
+	if min, ok := schemaNumber(schema["minimum"]); ok {
+		if schema["exclusiveMinimum"] == true && f <= min {
+			v.report(path, "must be greater than %v", min)
+		} else if f < min {
+			v.report(path, "must be at least %v", min)
+		}
+	}
+	if min, ok := schemaNumber(schema["exclusiveMinimum"]); ok && f <= min {
+		v.report(path, "must be greater than %v", min)
+	}
+	if max, ok := schemaNumber(schema["maximum"]); ok {
+		if schema["exclusiveMaximum"] == true && f >= max {
+			v.report(path, "must be less than %v", max)
+		} else if f > max {
+			v.report(path, "must be at most %v", max)
+		}
+	}
+	if max, ok := schemaNumber(schema["exclusiveMaximum"]); ok && f >= max {
+		v.report(path, "must be less than %v", max)
+	}
+}
+
+func (v *validator) validateArray(schema map[string]interface{}, items []interface{}, path string) {
+	if min, ok := schemaNumber(schema["minItems"]); ok && float64(len(items)) < min {
+		v.report(path, "must have at least %v items", min)
+	}
+	if max, ok := schemaNumber(schema["maxItems"]); ok && float64(len(items)) > max {
+		v.report(path, "must have at most %v items", max)
+	}
+	if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range items {
+			v.validate(itemSchema, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func (v *validator) validateObject(schema map[string]interface{}, obj map[string]interface{}, path string) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, name := range stringList(schema["required"]) {
+		if _, ok := obj[name]; ok {
+			continue
+		}
+		// Read-only properties are only sent by the server
+		if prop, _ := properties[name].(map[string]interface{}); prop["readOnly"] == true {
+			continue
+		}
+		v.report(path, "missing required property '%s'", name)
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		childPath := name
+		if path != "" {
+			childPath = path + "." + name
+		}
+		if prop, ok := properties[name].(map[string]interface{}); ok {
+			v.validate(prop, obj[name], childPath)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.report(path, "unknown property '%s'", name)
+			}
+		case map[string]interface{}:
+			v.validate(additional, obj[name], childPath)
+		}
+	}
+}
+
+// schemaTypes returns the types a schema allows, with null when it is
+// nullable, or nil when it allows any type
+func schemaTypes(schema map[string]interface{}) []string {
+	var types []string
+	switch t := schema["type"].(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		types = stringList(t)
+	}
+	if len(types) > 0 && schema["nullable"] == true {
+		types = append(types, "null")
+	}
+	return types
+}
+
+// matchesType reports whether value is of one of types
+func matchesType(types []string, value interface{}) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type of a decoded value; numbers without
+// a fractional part are integers
+func jsonType(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := val.Float64(); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// containsValue reports whether list holds a value equal to value
+func containsValue(list []interface{}, value interface{}) bool {
+	for _, item := range list {
+		if equalValues(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValues compares two JSON values, numbers by their value
+func equalValues(a, b interface{}) bool {
+	if na, ok := a.(json.Number); ok {
+		nb, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fa, errA := na.Float64()
+		fb, errB := nb.Float64()
+		return errA == nil && errB == nil && fa == fb
+	}
+	if _, ok := b.(json.Number); ok {
+		return false
+	}
+	return compactValue(a) == compactValue(b)
+}
+
+// compactValue returns value as compact JSON, for messages and comparisons
+func compactValue(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// schemaList returns the schemas of an allOf, anyOf or oneOf
+func schemaList(value interface{}) []map[string]interface{} {
+	list, _ := value.([]interface{})
+	var schemas []map[string]interface{}
+	for _, item := range list {
+		if s, ok := item.(map[string]interface{}); ok {
+			schemas = append(schemas, s)
+		}
+	}
+	return schemas
+}
+
+// stringList returns the strings of a JSON list
+func stringList(value interface{}) []string {
+	list, _ := value.([]interface{})
+	var strs []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// schemaNumber returns a numeric schema keyword
+func schemaNumber(value interface{}) (float64, bool) {
+	f, ok := value.(float64)
+	return f, ok
+}
@@ -0,0 +1,99 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsafeOutDir is returned by CheckOutDir when the output directory is a
+// location generation should not write into without explicit confirmation.
+var ErrUnsafeOutDir = errors.New("unsafe output directory")
+
+// ErrPathEscapesOutDir is returned when a generated file would be written
+// outside the output directory, typically through a symlink.
+var ErrPathEscapesOutDir = errors.New("path escapes output directory")
+
+// CheckOutDir verifies that dir is a reasonable generation target. It rejects
+// the filesystem root and the user's home directory (after resolving
+// symlinks), since a mistyped --out pointing there could clobber unrelated
+// files. The returned error wraps ErrUnsafeOutDir.
+func CheckOutDir(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid output directory: %w", err)
+	}
+
+	resolved := resolveExisting(abs)
+
+	if isFilesystemRoot(resolved) {
+		return fmt.Errorf("%w: %s is the filesystem root", ErrUnsafeOutDir, abs)
+	}
+
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		if resolved == resolveExisting(filepath.Clean(home)) {
+			return fmt.Errorf("%w: %s is the home directory", ErrUnsafeOutDir, abs)
+		}
+	}
+
+	return nil
+}
+
+// safeJoin joins rel onto root and verifies that the result, after resolving
+// any symlinks in the portion of the path that already exists, is still
+// located inside root.
+func safeJoin(root, rel string) (string, error) {
+	if filepath.IsAbs(rel) {
+		return "", fmt.Errorf("%w: %s is absolute", ErrPathEscapesOutDir, rel)
+	}
+
+	joined := filepath.Join(root, rel)
+	resolvedRoot := resolveExisting(root)
+	resolved := resolveExisting(joined)
+
+	if !isWithin(resolvedRoot, resolved) {
+		return "", fmt.Errorf("%w: %s resolves to %s", ErrPathEscapesOutDir, rel, resolved)
+	}
+
+	return joined, nil
+}
+
+// resolveExisting resolves symlinks for the longest existing prefix of path
+// and re-appends the remaining, not yet created, components.
+func resolveExisting(path string) string {
+	path = filepath.Clean(path)
+
+	var rest []string
+	current := path
+	for {
+		if resolved, err := filepath.EvalSymlinks(current); err == nil {
+			for i := len(rest) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, rest[i])
+			}
+			return resolved
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return path
+		}
+		rest = append(rest, filepath.Base(current))
+		current = parent
+	}
+}
+
+// isWithin reports whether path is root or a descendant of root
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// isFilesystemRoot reports whether path is "/" or a bare volume root like C:\
+func isFilesystemRoot(path string) bool {
+	return filepath.Dir(path) == path
+}
@@ -0,0 +1,92 @@
+package gen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckOutDir_RejectsRoot(t *testing.T) {
+	err := CheckOutDir("/")
+	if !errors.Is(err, ErrUnsafeOutDir) {
+		t.Errorf("expected ErrUnsafeOutDir for /, got %v", err)
+	}
+}
+
+func TestCheckOutDir_RejectsHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	err := CheckOutDir(home)
+	if !errors.Is(err, ErrUnsafeOutDir) {
+		t.Errorf("expected ErrUnsafeOutDir for home directory, got %v", err)
+	}
+
+	// A symlink pointing at home is just as dangerous
+	link := filepath.Join(t.TempDir(), "home-link")
+	if err := os.Symlink(home, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := CheckOutDir(link); !errors.Is(err, ErrUnsafeOutDir) {
+		t.Errorf("expected ErrUnsafeOutDir for symlink to home, got %v", err)
+	}
+}
+
+func TestCheckOutDir_AllowsSubdirectory(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if err := CheckOutDir(filepath.Join(home, "projects", "mycli")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSafeJoin_RejectsSymlinkEscape(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.Symlink(outside, filepath.Join(root, "internal")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	_, err := safeJoin(root, filepath.Join("internal", "runtime", "runtime.go"))
+	if !errors.Is(err, ErrPathEscapesOutDir) {
+		t.Errorf("expected ErrPathEscapesOutDir, got %v", err)
+	}
+}
+
+func TestSafeJoin_AllowsNestedPaths(t *testing.T) {
+	root := t.TempDir()
+
+	path, err := safeJoin(root, filepath.Join("internal", "commands", "root.go"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != filepath.Join(root, "internal", "commands", "root.go") {
+		t.Errorf("unexpected path %q", path)
+	}
+
+	if _, err := safeJoin(root, filepath.Join("..", "escape.go")); !errors.Is(err, ErrPathEscapesOutDir) {
+		t.Errorf("expected ErrPathEscapesOutDir for parent traversal, got %v", err)
+	}
+}
+
+func TestGenerate_RefusesSymlinkedSubdirectory(t *testing.T) {
+	outDir := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.Symlink(outside, filepath.Join(outDir, "internal")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	gen := New(loadDapPlan(t), outDir)
+	if err := gen.Generate(); !errors.Is(err, ErrPathEscapesOutDir) {
+		t.Fatalf("expected ErrPathEscapesOutDir, got %v", err)
+	}
+
+	entries, _ := os.ReadDir(outside)
+	if len(entries) > 0 {
+		t.Error("expected nothing to be written through the symlink")
+	}
+}
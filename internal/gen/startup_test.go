@@ -0,0 +1,79 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// writeLargeSpec writes an OpenAPI document with groups*opsPerGroup
+// operations and returns its path
+func writeLargeSpec(tb testing.TB, groups, opsPerGroup int) string {
+	tb.Helper()
+
+	paths := map[string]interface{}{}
+	for g := 0; g < groups; g++ {
+		tag := fmt.Sprintf("resource%d", g)
+		for o := 0; o < opsPerGroup; o++ {
+			paths[fmt.Sprintf("/v1/%s/action%d/{id}", tag, o)] = map[string]interface{}{
+				"get": map[string]interface{}{
+					"operationId": fmt.Sprintf("%sAction%d", tag, o),
+					"tags":        []string{tag},
+					"summary":     fmt.Sprintf("Run action %d on a %s", o, tag),
+					"parameters": []interface{}{
+						map[string]interface{}{"name": "id", "in": "path", "required": true, "schema": map[string]string{"type": "string"}},
+						map[string]interface{}{"name": "filter", "in": "query", "schema": map[string]string{"type": "string"}},
+						map[string]interface{}{"name": "limit", "in": "query", "schema": map[string]string{"type": "integer"}},
+					},
+					"responses": map[string]interface{}{"200": map[string]string{"description": "OK"}},
+				},
+			}
+		}
+	}
+
+	doc, err := json.Marshal(map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]string{"title": "Large API", "version": "1.0.0"},
+		"paths":   paths,
+	})
+	if err != nil {
+		tb.Fatalf("failed to marshal spec: %v", err)
+	}
+
+	specPath := filepath.Join(tb.TempDir(), "large.json")
+	if err := os.WriteFile(specPath, doc, 0644); err != nil {
+		tb.Fatalf("failed to write spec: %v", err)
+	}
+	return specPath
+}
+
+// BenchmarkStartup measures how long a CLI with 2,000 commands takes to run.
+// Operation commands are built lazily, so invoking a single command only
+// constructs its own group while root help still builds the full tree.
+func BenchmarkStartup(b *testing.B) {
+	binaryPath := buildTestCLIWith(b, writeLargeSpec(b, 40, 50), "large", func(g *Generator) {
+		g.Emit = EmitPerGroup
+	})
+
+	cases := []struct {
+		name string
+		args []string
+	}{
+		{"CommandHelp", []string{"resource7", "action3", "--help"}},
+		{"GroupHelp", []string{"resource7", "--help"}},
+		{"RootHelp", []string{"--help"}},
+	}
+
+	for _, bc := range cases {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if output, err := exec.Command(binaryPath, bc.args...).CombinedOutput(); err != nil {
+					b.Fatalf("%v failed: %v\n%s", bc.args, err, output)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,11 @@
 package plan
 
 import (
+	"fmt"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/crunchloop/opencligen/internal/spec"
 )
@@ -11,36 +15,229 @@ func Build(s *spec.Spec, appName, moduleName string) *Plan {
 	plan := &Plan{
 		AppName:    appName,
 		ModuleName: moduleName,
+		Spec: SpecInfo{
+			Title:   s.Title,
+			Version: s.Version,
+			SHA256:  s.SHA256,
+			Source:  s.Source,
+		},
 	}
 
-	// Group operations by tag
-	groups := make(map[string][]spec.Operation)
+	if s.GlobalCli != nil {
+		plan.Templates = HelpTemplates{
+			Usage: s.GlobalCli.UsageTemplate,
+			Help:  s.GlobalCli.HelpTemplate,
+		}
+	}
+
+	plan.Servers, plan.Warnings = buildServers(s.Servers)
+	plan.Auth = buildAuth(s.Security)
+	if s.GlobalCli != nil {
+		plan.Auth.Signing = buildSigning(s.GlobalCli.Signing)
+	}
+
+	for _, service := range s.Services {
+		plan.Services = append(plan.Services, ServicePlan{Name: service.Name, Description: service.Title})
+	}
+
+	// Group operations by service and tag
+	type groupKey struct{ service, tag string }
+	groups := make(map[groupKey][]spec.Operation)
 	for i := range s.Operations {
 		op := &s.Operations[i]
 		tag := op.Tag
 		if tag == "" {
 			tag = "default"
 		}
-		groups[tag] = append(groups[tag], *op)
+		key := groupKey{op.Service, tag}
+		groups[key] = append(groups[key], *op)
 	}
 
 	// Sort group names for deterministic output
-	groupNames := make([]string, 0, len(groups))
-	for name := range groups {
-		groupNames = append(groupNames, name)
+	keys := make([]groupKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
 	}
-	sort.Strings(groupNames)
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].service != keys[j].service {
+			return keys[i].service < keys[j].service
+		}
+		return keys[i].tag < keys[j].tag
+	})
 
 	// Build group plans
-	for _, groupName := range groupNames {
-		ops := groups[groupName]
-		groupPlan := buildGroupPlan(groupName, ops)
+	for _, key := range keys {
+		groupPlan := buildGroupPlan(key.tag, groups[key])
+		groupPlan.Service = key.service
 		plan.Groups = append(plan.Groups, groupPlan)
 	}
+	resolveCompletions(plan)
+	resolveETags(plan)
+	checkCommandPaths(plan)
 
 	return plan
 }
 
+// resolveCompletions fills in the path and envelope of the list operation
+// each x-cli complete names, within the service of the parameter. Loaded
+// specs are validated, so the list operation is only missing when filtered
+// out, which leaves the parameter without completion.
+func resolveCompletions(plan *Plan) {
+	type opKey struct{ service, operationID string }
+	ops := make(map[opKey]*OpPlan)
+	for i := range plan.Groups {
+		for j := range plan.Groups[i].Operations {
+			op := &plan.Groups[i].Operations[j]
+			ops[opKey{plan.Groups[i].Service, op.OperationID}] = op
+		}
+	}
+
+	resolve := func(service string, op *OpPlan, params []ParamPlan) {
+		for i := range params {
+			c := params[i].Complete
+			if c == nil {
+				continue
+			}
+			if list, ok := ops[opKey{service, c.OperationID}]; ok {
+				c.Path = list.Path
+				c.Envelope = list.Envelope
+			} else {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s: %s completes from %s, which is not in the CLI",
+					strings.Join(op.CommandPath, " "), params[i].Name, c.OperationID))
+				params[i].Complete = nil
+			}
+		}
+	}
+	for i := range plan.Groups {
+		for j := range plan.Groups[i].Operations {
+			op := &plan.Groups[i].Operations[j]
+			resolve(plan.Groups[i].Service, op, op.Positionals)
+			resolve(plan.Groups[i].Service, op, op.Flags)
+		}
+	}
+}
+
+// checkCommandPaths warns about operations whose command path is taken by
+// an earlier one, which makes them unreachable
+func checkCommandPaths(plan *Plan) {
+	taken := make(map[string]string)
+	for _, group := range plan.Groups {
+		for _, op := range group.Operations {
+			path := strings.Join(op.CommandPath, " ")
+			if other, ok := taken[path]; ok {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s %s and %s are both the command %q; name one with x-cli name",
+					op.Method, op.Path, other, path))
+				continue
+			}
+			taken[path] = op.Method + " " + op.Path
+		}
+	}
+}
+
+// resolveETags marks the If-Match headers that can be filled in with the
+// ETag of the resource, from the GET operation of the same path
+func resolveETags(plan *Plan) {
+	readable := map[string]bool{}
+	for _, group := range plan.Groups {
+		for _, op := range group.Operations {
+			if op.Method == "GET" {
+				readable[op.Path] = true
+			}
+		}
+	}
+
+	for i := range plan.Groups {
+		for j := range plan.Groups[i].Operations {
+			op := &plan.Groups[i].Operations[j]
+			if op.Method == "GET" || !readable[op.Path] {
+				continue
+			}
+			for k := range op.Flags {
+				if op.Flags[k].In == "header" && strings.EqualFold(op.Flags[k].Name, "If-Match") {
+					op.Flags[k].ETagAuto = true
+				}
+			}
+		}
+	}
+}
+
+// buildServers names the spec's servers for --server. Servers without an
+// x-cli name are named after the first word of their description, or
+// numbered when that is empty or taken. Relative server URLs cannot be used
+// as a base URL and are left out, as are servers whose name is taken, with
+// a warning.
+func buildServers(servers []spec.Server) ([]Server, []string) {
+	taken := map[string]bool{}
+	for _, s := range servers {
+		if s.Name != "" {
+			taken[s.Name] = true
+		}
+	}
+
+	var result []Server
+	var warnings []string
+	for i, s := range servers {
+		if !strings.Contains(s.URL, "://") {
+			warnings = append(warnings, fmt.Sprintf("server %s is relative and cannot be selected with --server", s.URL))
+			continue
+		}
+		name := s.Name
+		if name == "" {
+			name = DeriveServerName(s.Description)
+			for n := i + 1; name == "" || taken[name]; n++ {
+				name = "server" + strconv.Itoa(n)
+			}
+			taken[name] = true
+		} else if slices.ContainsFunc(result, func(r Server) bool { return r.Name == name }) {
+			warnings = append(warnings, fmt.Sprintf("server %s is left out: its x-cli name %q is taken", s.URL, name))
+			continue
+		}
+		result = append(result, Server{Name: name, URL: s.URL, Description: s.Description})
+	}
+	return result, warnings
+}
+
+// buildAuth picks the authentication settings for the declared security
+// schemes
+func buildAuth(schemes []spec.SecurityScheme) AuthPlan {
+	var auth AuthPlan
+	for _, scheme := range schemes {
+		if scheme.Type == spec.SecurityTypeHTTP && scheme.Scheme == spec.HTTPSchemeBearer && !auth.Bearer {
+			auth.Bearer = true
+			auth.BearerFormat = scheme.BearerFormat
+		}
+		if flow := scheme.ClientCredentials; flow != nil && auth.ClientCredentials == nil {
+			auth.ClientCredentials = &OAuth2Flow{TokenURL: flow.TokenURL, Scopes: scheme.Scopes}
+		}
+		if flow := scheme.DeviceAuthorization; flow != nil && auth.DeviceCode == nil {
+			auth.DeviceCode = &OAuth2Flow{
+				TokenURL:               flow.TokenURL,
+				Scopes:                 scheme.Scopes,
+				DeviceAuthorizationURL: flow.DeviceAuthorizationURL,
+				ClientID:               flow.ClientID,
+			}
+		}
+	}
+	return auth
+}
+
+// buildSigning converts the x-cli signing settings, or returns nil when
+// requests are not signed
+func buildSigning(signing *spec.Signing) *Signing {
+	if signing == nil {
+		return nil
+	}
+	return &Signing{
+		Type:            signing.Type,
+		Region:          signing.Region,
+		Service:         signing.Service,
+		SignatureHeader: signing.SignatureHeader,
+		TimestampHeader: signing.TimestampHeader,
+		KeyIDHeader:     signing.KeyIDHeader,
+		Base64:          signing.Encoding == "base64",
+	}
+}
+
 func buildGroupPlan(name string, ops []spec.Operation) GroupPlan {
 	group := GroupPlan{
 		Name: DeriveGroupName(name),
@@ -51,9 +248,72 @@ func buildGroupPlan(name string, ops []spec.Operation) GroupPlan {
 		group.Operations = append(group.Operations, opPlan)
 	}
 
+	group.Section = groupSection(ops, group.Operations)
+
 	return group
 }
 
+// exampleResponse picks the first successful response as the one a mock
+// server returns. Ranges such as "2XX" are served as their base status.
+func exampleResponse(responses []spec.Response) *ExampleResponse {
+	for i := range responses {
+		code := responses[i].StatusCode
+		if len(code) != 3 || code[0] != '2' {
+			continue
+		}
+
+		status, err := strconv.Atoi(strings.ReplaceAll(strings.ToUpper(code), "X", "0"))
+		if err != nil {
+			continue
+		}
+		return &ExampleResponse{
+			Status:      status,
+			ContentType: responses[i].ExampleType,
+			Body:        responses[i].Example,
+		}
+	}
+	return nil
+}
+
+// acceptTypes returns the content types of the successful responses, which
+// --accept chooses between, or nil when there are fewer than two
+func acceptTypes(responses []spec.Response) []string {
+	var types []string
+	for i := range responses {
+		if !strings.HasPrefix(responses[i].StatusCode, "2") {
+			continue
+		}
+		for _, ct := range responses[i].ContentTypes {
+			if !slices.Contains(types, ct) {
+				types = append(types, ct)
+			}
+		}
+	}
+	if len(types) < 2 {
+		return nil
+	}
+	sort.Strings(types)
+	return types
+}
+
+// groupSection picks the help section for a group: the first x-cli section
+// among its operations, otherwise Streaming when every operation is an event
+// stream and Resource Commands when not
+func groupSection(ops []spec.Operation, opPlans []OpPlan) string {
+	for i := range ops {
+		if ops[i].Cli != nil && ops[i].Cli.Section != "" {
+			return ops[i].Cli.Section
+		}
+	}
+
+	for i := range opPlans {
+		if !opPlans[i].IsEventStream {
+			return SectionResources
+		}
+	}
+	return SectionStreaming
+}
+
 func buildOpPlan(groupName string, op spec.Operation) OpPlan {
 	opPlan := OpPlan{
 		Method:        op.Method,
@@ -63,6 +323,29 @@ func buildOpPlan(groupName string, op spec.Operation) OpPlan {
 		Description:   op.Description,
 		HasJSONBody:   op.HasJSONBody(),
 		IsEventStream: op.HasEventStream(),
+		Columns:       op.Columns,
+		Hints: Hints{
+			RateCost:        op.Hints.RateCost,
+			ExpectedLatency: op.Hints.ExpectedLatency,
+		},
+	}
+
+	opPlan.Example = exampleResponse(op.Responses)
+	opPlan.Accept = acceptTypes(op.Responses)
+
+	if op.Envelope != nil {
+		opPlan.Envelope = &Envelope{
+			Items: op.Envelope.Items,
+			Total: op.Envelope.Total,
+			Next:  op.Envelope.Next,
+		}
+	}
+	if op.Pagination != nil {
+		opPlan.Pagination = &Pagination{
+			CursorParam: op.Pagination.CursorParam,
+			PageParam:   op.Pagination.PageParam,
+			LinkHeader:  op.Pagination.LinkHeader,
+		}
 	}
 
 	// Determine command path
@@ -78,11 +361,17 @@ func buildOpPlan(groupName string, op spec.Operation) OpPlan {
 	if op.Cli != nil {
 		opPlan.Hidden = op.Cli.Hidden
 		opPlan.Aliases = op.Cli.Aliases
+		// cobra resolves aliases exactly; listing them in SuggestFor also
+		// catches differently cased spellings
+		opPlan.SuggestFor = append(append([]string(nil), op.Cli.Aliases...), op.Cli.SuggestFor...)
 		if op.Cli.Group != "" {
 			// Override the group in the command path
 			opPlan.CommandPath[0] = DeriveGroupName(op.Cli.Group)
 		}
 	}
+	if op.Service != "" {
+		opPlan.CommandPath = append([]string{op.Service}, opPlan.CommandPath...)
+	}
 
 	// Process parameters
 	// First, collect path params to determine positional order
@@ -122,6 +411,23 @@ func buildOpPlan(groupName string, op spec.Operation) OpPlan {
 		opPlan.Flags = append(opPlan.Flags, paramPlan)
 	}
 
+	// --all, --max-items, --set and the event stream flags would clash with
+	// parameters of the same name
+	opPlan.EventFlags = opPlan.IsEventStream
+	opPlan.SetFlag = opPlan.HasJSONBody
+	for _, f := range opPlan.Flags {
+		switch f.FlagName {
+		case "all", "max-items":
+			opPlan.Pagination = nil
+		case "event", "event-metadata", "exec":
+			opPlan.EventFlags = false
+		case "set":
+			opPlan.SetFlag = false
+		case "accept":
+			opPlan.Accept = nil
+		}
+	}
+
 	return opPlan
 }
 
@@ -134,6 +440,8 @@ func buildParamPlan(p spec.Param) ParamPlan {
 		Default:     p.Default,
 		Min:         p.Min,
 		Max:         p.Max,
+		Enum:        p.Enum,
+		Pattern:     p.Pattern,
 		Description: p.Description,
 		In:          p.In,
 	}
@@ -150,6 +458,17 @@ func buildParamPlan(p spec.Param) ParamPlan {
 		plan.Shorthand = p.Cli.Shorthand
 		plan.EnvVar = p.Cli.Env
 		plan.ConfigKey = p.Cli.ConfigKey
+		if c := p.Cli.Complete; c != nil {
+			plan.Complete = &Completion{OperationID: c.OperationID, Field: c.Field}
+			if plan.Complete.Field == "" {
+				plan.Complete.Field = "id"
+			}
+		}
+	}
+
+	// -q and -H are taken by the global --quiet and --header flags
+	if plan.Shorthand == "q" || plan.Shorthand == "H" {
+		plan.Shorthand = ""
 	}
 
 	return plan
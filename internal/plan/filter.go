@@ -0,0 +1,97 @@
+package plan
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/crunchloop/opencligen/internal/spec"
+)
+
+// Filter selects the operations of a spec to generate commands for, to carve
+// a smaller CLI out of a large spec. The zero Filter selects everything.
+type Filter struct {
+	// IncludeTags and IncludeOperations, when either is set, select only the
+	// operations with one of the tags or operationIds. Tags match by name or
+	// by their group name, so "User Accounts" and "user-accounts" are alike.
+	IncludeTags       []string
+	IncludeOperations []string
+
+	// ExcludeTags and ExcludePathPrefixes drop operations, even included ones
+	ExcludeTags         []string
+	ExcludePathPrefixes []string
+}
+
+// IsZero reports whether f selects every operation
+func (f Filter) IsZero() bool {
+	return len(f.IncludeTags) == 0 && len(f.IncludeOperations) == 0 &&
+		len(f.ExcludeTags) == 0 && len(f.ExcludePathPrefixes) == 0
+}
+
+// Match reports whether f selects op
+func (f Filter) Match(op spec.Operation) bool {
+	if slices.ContainsFunc(f.ExcludeTags, func(tag string) bool { return sameTag(tag, op.Tag) }) {
+		return false
+	}
+	for _, prefix := range f.ExcludePathPrefixes {
+		if hasPathPrefix(op.Path, prefix) {
+			return false
+		}
+	}
+	if len(f.IncludeTags) == 0 && len(f.IncludeOperations) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(f.IncludeTags, func(tag string) bool { return sameTag(tag, op.Tag) }) ||
+		slices.Contains(f.IncludeOperations, op.OperationID)
+}
+
+// Apply returns a copy of s with only the operations f selects, and only
+// the services that still have operations. It fails when an included tag or
+// operation matches nothing, which is most likely a typo, or when no
+// operation is left.
+func (f Filter) Apply(s *spec.Spec) (*spec.Spec, error) {
+	for _, tag := range f.IncludeTags {
+		if !slices.ContainsFunc(s.Operations, func(op spec.Operation) bool { return sameTag(tag, op.Tag) }) {
+			return nil, fmt.Errorf("included tag %q matches no operations", tag)
+		}
+	}
+	for _, id := range f.IncludeOperations {
+		if !slices.ContainsFunc(s.Operations, func(op spec.Operation) bool { return op.OperationID == id }) {
+			return nil, fmt.Errorf("included operation %q matches no operations", id)
+		}
+	}
+
+	filtered := *s
+	filtered.Operations = nil
+	services := make(map[string]bool)
+	for _, op := range s.Operations {
+		if f.Match(op) {
+			filtered.Operations = append(filtered.Operations, op)
+			services[op.Service] = true
+		}
+	}
+	if len(filtered.Operations) == 0 {
+		return nil, fmt.Errorf("the filters exclude every operation")
+	}
+
+	filtered.Services = nil
+	for _, service := range s.Services {
+		if services[service.Name] {
+			filtered.Services = append(filtered.Services, service)
+		}
+	}
+	return &filtered, nil
+}
+
+// sameTag reports whether a tag given to a filter names the tag of an
+// operation
+func sameTag(filter, tag string) bool {
+	return filter == tag || DeriveGroupName(filter) == DeriveGroupName(tag)
+}
+
+// hasPathPrefix reports whether path starts with the segments of prefix, so
+// /admin matches /admin and /admin/users but not /administrators
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
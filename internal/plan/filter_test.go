@@ -0,0 +1,78 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/crunchloop/opencligen/internal/spec"
+)
+
+func filterTestSpec() *spec.Spec {
+	return &spec.Spec{
+		Services: []spec.Service{{Name: "billing"}, {Name: "admin"}},
+		Operations: []spec.Operation{
+			{Service: "billing", Tag: "User Accounts", Method: "GET", Path: "/accounts", OperationID: "listAccounts"},
+			{Service: "billing", Tag: "invoices", Method: "GET", Path: "/invoices", OperationID: "listInvoices"},
+			{Service: "billing", Tag: "invoices", Method: "POST", Path: "/invoices", OperationID: "createInvoice"},
+			{Service: "billing", Tag: "invoices", Method: "DELETE", Path: "/administrators/invoices", OperationID: "purgeInvoices"},
+			{Service: "admin", Tag: "users", Method: "GET", Path: "/admin/users", OperationID: "listUsers"},
+		},
+	}
+}
+
+func TestFilter_Apply(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   string
+	}{
+		{"zero", Filter{}, "listAccounts listInvoices createInvoice purgeInvoices listUsers"},
+		{"include tag by group name", Filter{IncludeTags: []string{"user-accounts"}}, "listAccounts"},
+		{"include tag and operation", Filter{IncludeTags: []string{"User Accounts"}, IncludeOperations: []string{"listInvoices"}}, "listAccounts listInvoices"},
+		{"exclude wins", Filter{IncludeTags: []string{"invoices"}, ExcludePathPrefixes: []string{"/administrators/"}}, "listInvoices createInvoice"},
+		{"exclude tag", Filter{ExcludeTags: []string{"invoices"}}, "listAccounts listUsers"},
+		{"path prefix matches whole segments", Filter{ExcludePathPrefixes: []string{"/admin"}}, "listAccounts listInvoices createInvoice purgeInvoices"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := filterTestSpec()
+			filtered, err := tt.filter.Apply(s)
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			var ids []string
+			for _, op := range filtered.Operations {
+				ids = append(ids, op.OperationID)
+			}
+			if got := strings.Join(ids, " "); got != tt.want {
+				t.Errorf("operations = %s, want %s", got, tt.want)
+			}
+			if len(s.Operations) != 5 {
+				t.Errorf("expected the spec to be left as is, got %d operations", len(s.Operations))
+			}
+		})
+	}
+}
+
+func TestFilter_ApplyDropsEmptyServices(t *testing.T) {
+	filtered, err := Filter{ExcludePathPrefixes: []string{"/admin"}}.Apply(filterTestSpec())
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(filtered.Services) != 1 || filtered.Services[0].Name != "billing" {
+		t.Errorf("expected only the billing service, got %+v", filtered.Services)
+	}
+}
+
+func TestFilter_ApplyErrors(t *testing.T) {
+	tests := map[string]Filter{
+		`included tag "invoice" matches no operations`:          {IncludeTags: []string{"invoice"}},
+		`included operation "getInvoice" matches no operations`: {IncludeOperations: []string{"getInvoice"}},
+		"the filters exclude every operation":                   {IncludeTags: []string{"users"}, ExcludeTags: []string{"users"}},
+	}
+	for want, filter := range tests {
+		if _, err := filter.Apply(filterTestSpec()); err == nil || err.Error() != want {
+			t.Errorf("expected %q, got %v", want, err)
+		}
+	}
+}
@@ -56,6 +56,18 @@ func DeriveFlagName(paramName, in string) string {
 	return toKebabCase(name)
 }
 
+// DeriveServerName derives a --server name from a server description: its
+// first word in lower case, e.g. "sandbox" for "Sandbox environment"
+func DeriveServerName(description string) string {
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	return words[0]
+}
+
 // toKebabCase converts a string to kebab-case
 func toKebabCase(s string) string {
 	if s == "" {
@@ -1,22 +1,121 @@
 package plan
 
+import "time"
+
 // Plan represents the full command plan for the generated CLI
 type Plan struct {
 	AppName    string
 	ModuleName string
+	Spec       SpecInfo
+	Templates  HelpTemplates
+	Auth       AuthPlan
+	Servers    []Server
+	Services   []ServicePlan // set when the plan merges several specs
 	Groups     []GroupPlan
+
+	// Warnings are problems found building the plan that leave parts of the
+	// spec out of the CLI, or unreachable
+	Warnings []string
+}
+
+// ServicePlan is a top-level command holding the groups of one service's
+// spec, in a CLI merged from several specs
+type ServicePlan struct {
+	Name        string
+	Description string
+}
+
+// Server is an API server the generated CLI can select with --server
+type Server struct {
+	Name        string
+	URL         string
+	Description string
+}
+
+// AuthPlan describes how the generated CLI authenticates its requests
+type AuthPlan struct {
+	// Bearer adds a --token setting sent as an Authorization: Bearer header,
+	// for specs that declare an http bearer security scheme
+	Bearer       bool
+	BearerFormat string // e.g. JWT, shown in help
+
+	// ClientCredentials adds --client-id and --client-secret settings
+	// exchanged for access tokens, for specs that declare an oauth2 client
+	// credentials flow
+	ClientCredentials *OAuth2Flow
+
+	// DeviceCode adds auth login, logout and status commands that obtain
+	// tokens with the device authorization grant, for specs that declare an
+	// x-deviceAuthorization flow
+	DeviceCode *OAuth2Flow
+
+	// Signing signs every request, from the document-level x-cli signing
+	Signing *Signing
+}
+
+// Signing types
+const (
+	SigningHMACSHA256 = "hmac-sha256"
+	SigningAWSSigV4   = "aws-sigv4"
+)
+
+// Signing describes how the generated CLI signs requests
+type Signing struct {
+	Type string // hmac-sha256 or aws-sigv4
+
+	Region  string // aws-sigv4; empty to read AWS_REGION at run time
+	Service string // aws-sigv4
+
+	SignatureHeader string // hmac-sha256; empty for the runtime default
+	TimestampHeader string // hmac-sha256; empty for the runtime default
+	KeyIDHeader     string // hmac-sha256; empty for the runtime default
+	Base64          bool   // hmac-sha256: base64 rather than hex signatures
+}
+
+// OAuth2Flow is an OAuth2 flow the generated CLI can obtain tokens with
+type OAuth2Flow struct {
+	TokenURL string
+	Scopes   []string // scopes to request
+
+	DeviceAuthorizationURL string // device code flow only
+	ClientID               string // default client ID, empty if not declared
+}
+
+// HelpTemplates are custom cobra templates installed on the generated root
+// command. Empty fields keep cobra's defaults.
+type HelpTemplates struct {
+	Usage string
+	Help  string
+}
+
+// SpecInfo identifies the API revision a plan was built from
+type SpecInfo struct {
+	Title   string
+	Version string
+	SHA256  string
+	Source  []byte // the source document, embedded into the generated CLI
 }
 
 // GroupPlan represents a command group (typically one per tag)
 type GroupPlan struct {
+	Service     string // the service command holding the group, if any
 	Name        string
 	Description string
+	Section     string // title of the root help section listing the group
 	Operations  []OpPlan
 }
 
+// Help sections for command groups without an x-cli section, and for the
+// services of a merged CLI
+const (
+	SectionResources = "Resource Commands"
+	SectionStreaming = "Streaming"
+	SectionServices  = "Services"
+)
+
 // OpPlan represents a single operation/command plan
 type OpPlan struct {
-	CommandPath   []string // e.g. ["tasks", "create"]
+	CommandPath   []string // e.g. ["tasks", "create"], or ["billing", "invoices", "list"] in a service
 	Method        string
 	Path          string
 	OperationID   string
@@ -25,9 +124,49 @@ type OpPlan struct {
 	Positionals   []ParamPlan
 	Flags         []ParamPlan
 	HasJSONBody   bool
+	SetFlag       bool // --set is added to change fields of the JSON body
 	IsEventStream bool
+	EventFlags    bool // --event, --event-metadata and --exec are added for the event stream
 	Hidden        bool
 	Aliases       []string
+	SuggestFor    []string         // names that suggest this command when mistyped
+	Envelope      *Envelope        // set when responses are paginated envelopes
+	Pagination    *Pagination      // set when following pages can be fetched (--all)
+	Columns       []string         // default columns of table output
+	Accept        []string         // response content types --accept chooses from, when there are several
+	Example       *ExampleResponse // canned successful response, nil if none is documented
+	Hints         Hints
+}
+
+// ExampleResponse is the response a generated mock server returns for an
+// operation
+type ExampleResponse struct {
+	Status      int
+	ContentType string // empty when the response has no body
+	Body        []byte
+}
+
+// Hints carries operational metadata such as rate-limit cost and expected
+// latency, shown in help and used to pace bulk execution
+type Hints struct {
+	RateCost        float64
+	ExpectedLatency time.Duration
+}
+
+// Envelope locates the items, total count and next cursor in a paginated
+// response body using dotted paths
+type Envelope struct {
+	Items string
+	Total string
+	Next  string
+}
+
+// Pagination tells the runtime how to request the page after a response:
+// through a cursor or page number query parameter, or a Link header
+type Pagination struct {
+	CursorParam string
+	PageParam   string
+	LinkHeader  bool
 }
 
 // ParamPlan represents a parameter plan for a command
@@ -41,8 +180,26 @@ type ParamPlan struct {
 	Default     interface{}
 	Min         *float64
 	Max         *float64
+	Enum        []interface{}
+	Pattern     string
 	Description string
 	EnvVar      string
 	ConfigKey   string
 	In          string // path, query, header
+
+	// Complete is set when the values complete from a list operation
+	Complete *Completion
+
+	// ETagAuto is set on the If-Match header of an operation whose path
+	// also has a GET operation, which "auto" takes the current ETag from
+	ETagAuto bool
+}
+
+// Completion is the list operation whose items complete a parameter's
+// values in the shell
+type Completion struct {
+	OperationID string
+	Path        string    // of the list operation, which is a GET
+	Envelope    *Envelope // of the list operation's responses, if any
+	Field       string    // dotted path into each item
 }
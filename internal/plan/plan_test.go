@@ -2,7 +2,10 @@ package plan
 
 import (
 	"context"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/crunchloop/opencligen/internal/spec"
 )
@@ -269,3 +272,421 @@ func TestParseCommandPath(t *testing.T) {
 		})
 	}
 }
+
+func TestBuild_RecordsSpecInfo(t *testing.T) {
+	s := loadTestSpec(t)
+	plan := Build(s, "dap", "github.com/example/dap")
+
+	if plan.Spec.Title != "DAP API" {
+		t.Errorf("expected spec title 'DAP API', got '%s'", plan.Spec.Title)
+	}
+	if plan.Spec.Version != "1.0.0" {
+		t.Errorf("expected spec version '1.0.0', got '%s'", plan.Spec.Version)
+	}
+	if plan.Spec.SHA256 == "" || plan.Spec.SHA256 != s.SHA256 {
+		t.Errorf("expected spec hash %q, got %q", s.SHA256, plan.Spec.SHA256)
+	}
+	if len(plan.Spec.Source) == 0 {
+		t.Error("expected the source document to be carried into the plan")
+	}
+}
+
+func TestBuild_CarriesPaginationEnvelope(t *testing.T) {
+	s, err := spec.Load(context.Background(), "../testdata/openapi30.yaml")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+	plan := Build(s, "bookmarks", "github.com/example/bookmarks")
+
+	var listOp *OpPlan
+	for _, group := range plan.Groups {
+		for i := range group.Operations {
+			if group.Operations[i].OperationID == "listBookmarks" {
+				listOp = &group.Operations[i]
+			}
+		}
+	}
+
+	if listOp == nil {
+		t.Fatal("expected to find listBookmarks operation")
+	}
+	if listOp.Envelope == nil || listOp.Envelope.Items != "data" || listOp.Envelope.Next != "next_cursor" {
+		t.Errorf("unexpected envelope: %+v", listOp.Envelope)
+	}
+	if listOp.Pagination == nil || listOp.Pagination.CursorParam != "cursor" {
+		t.Errorf("unexpected pagination: %+v", listOp.Pagination)
+	}
+}
+
+func TestBuild_CarriesOperationHints(t *testing.T) {
+	s, err := spec.Load(context.Background(), "../testdata/openapi30.yaml")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+	plan := Build(s, "bookmarks", "github.com/example/bookmarks")
+
+	for _, group := range plan.Groups {
+		for i := range group.Operations {
+			op := &group.Operations[i]
+			if op.OperationID != "getExport" {
+				continue
+			}
+			if op.Hints.RateCost != 10 {
+				t.Errorf("expected rate cost 10, got %v", op.Hints.RateCost)
+			}
+			if op.Hints.ExpectedLatency != 30*time.Second {
+				t.Errorf("expected latency 30s, got %v", op.Hints.ExpectedLatency)
+			}
+			return
+		}
+	}
+	t.Fatal("expected to find getExport operation")
+}
+
+func TestBuild_AssignsHelpSections(t *testing.T) {
+	s, err := spec.Load(context.Background(), "../testdata/openapi31.yaml")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+	plan := Build(s, "notes", "github.com/example/notes")
+
+	expected := map[string]string{
+		"notes": SectionResources,
+		"sync":  SectionStreaming,
+		"tags":  "Organization",
+	}
+	for _, group := range plan.Groups {
+		want, ok := expected[group.Name]
+		if !ok {
+			continue
+		}
+		if group.Section != want {
+			t.Errorf("expected group %s in section %q, got %q", group.Name, want, group.Section)
+		}
+	}
+}
+
+func TestBuild_SuggestForIncludesAliasesAndOldNames(t *testing.T) {
+	s, err := spec.Load(context.Background(), "../testdata/openapi31.yaml")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+	plan := Build(s, "notes", "github.com/example/notes")
+
+	var share *OpPlan
+	for gi := range plan.Groups {
+		for oi := range plan.Groups[gi].Operations {
+			if plan.Groups[gi].Operations[oi].OperationID == "shareNote" {
+				share = &plan.Groups[gi].Operations[oi]
+			}
+		}
+	}
+	if share == nil {
+		t.Fatal("expected to find shareNote operation")
+	}
+
+	if len(share.SuggestFor) != 2 || share.SuggestFor[0] != "sh" || share.SuggestFor[1] != "publish" {
+		t.Errorf("expected SuggestFor [sh publish], got %v", share.SuggestFor)
+	}
+}
+
+func TestBuild_PicksExampleResponse(t *testing.T) {
+	s, err := spec.Load(context.Background(), "../testdata/openapi30.yaml")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+	plan := Build(s, "bookmarks", "github.com/example/bookmarks")
+
+	examples := map[string]*ExampleResponse{}
+	for _, group := range plan.Groups {
+		for i := range group.Operations {
+			examples[group.Operations[i].OperationID] = group.Operations[i].Example
+		}
+	}
+
+	if ex := examples["getBookmark"]; ex == nil || ex.Status != 200 || ex.ContentType != "application/json" || len(ex.Body) == 0 {
+		t.Errorf("expected a 200 JSON example for getBookmark, got %+v", ex)
+	}
+	if ex := examples["deleteBookmark"]; ex == nil || ex.Status != 204 || len(ex.Body) != 0 {
+		t.Errorf("expected an empty 204 example for deleteBookmark, got %+v", ex)
+	}
+}
+
+func TestBuild_BearerAuth(t *testing.T) {
+	s, err := spec.Load(context.Background(), "../testdata/openapi30.yaml")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	plan := Build(s, "bookmarks", "github.com/example/bookmarks")
+	if !plan.Auth.Bearer || plan.Auth.BearerFormat != "JWT" {
+		t.Errorf("expected bearer auth with a JWT format, got %+v", plan.Auth)
+	}
+
+	// Other schemes don't enable bearer tokens
+	s.Security = []spec.SecurityScheme{{Name: "basic", Type: spec.SecurityTypeHTTP, Scheme: "basic"}}
+	if plan := Build(s, "bookmarks", "github.com/example/bookmarks"); plan.Auth.Bearer {
+		t.Errorf("expected no bearer auth for a basic scheme, got %+v", plan.Auth)
+	}
+}
+
+func TestBuild_ClientCredentials(t *testing.T) {
+	s := &spec.Spec{Security: []spec.SecurityScheme{{
+		Name: "oauth",
+		Type: spec.SecurityTypeOAuth2,
+		ClientCredentials: &spec.OAuthFlow{
+			TokenURL: "https://auth.example.com/token",
+			Scopes:   []string{"admin", "read"},
+		},
+		Scopes: []string{"read"},
+	}}}
+
+	plan := Build(s, "acme", "github.com/example/acme")
+	flow := plan.Auth.ClientCredentials
+	if flow == nil || flow.TokenURL != "https://auth.example.com/token" || len(flow.Scopes) != 1 || flow.Scopes[0] != "read" {
+		t.Errorf("expected the client credentials flow with the required scopes, got %+v", flow)
+	}
+}
+
+func TestBuild_DeviceCode(t *testing.T) {
+	s := &spec.Spec{Security: []spec.SecurityScheme{{
+		Name: "oauth",
+		Type: spec.SecurityTypeOAuth2,
+		DeviceAuthorization: &spec.OAuthFlow{
+			TokenURL:               "https://auth.example.com/token",
+			DeviceAuthorizationURL: "https://auth.example.com/device",
+			ClientID:               "acme-cli",
+			Scopes:                 []string{"admin", "read"},
+		},
+		Scopes: []string{"read"},
+	}}}
+
+	plan := Build(s, "acme", "github.com/example/acme")
+	want := &OAuth2Flow{
+		TokenURL:               "https://auth.example.com/token",
+		Scopes:                 []string{"read"},
+		DeviceAuthorizationURL: "https://auth.example.com/device",
+		ClientID:               "acme-cli",
+	}
+	if !reflect.DeepEqual(plan.Auth.DeviceCode, want) {
+		t.Errorf("device code flow = %+v, want %+v", plan.Auth.DeviceCode, want)
+	}
+	if plan.Auth.ClientCredentials != nil {
+		t.Errorf("expected no client credentials flow, got %+v", plan.Auth.ClientCredentials)
+	}
+}
+
+func TestBuildOpPlan_EventFlags(t *testing.T) {
+	op := spec.Operation{
+		Method:      "GET",
+		Path:        "/events",
+		OperationID: "subscribeEvents",
+		Responses:   []spec.Response{{StatusCode: "200", ContentTypes: []string{"text/event-stream"}}},
+	}
+	if p := buildOpPlan("events", op); !p.EventFlags {
+		t.Error("expected --event, --event-metadata and --exec on an event stream")
+	}
+
+	op.Params = []spec.Param{{Name: "event", In: "query", Type: "string"}}
+	if p := buildOpPlan("events", op); p.EventFlags {
+		t.Error("expected no event flags when a parameter is named event")
+	}
+}
+
+func TestBuildOpPlan_SetFlag(t *testing.T) {
+	op := spec.Operation{
+		Method:      "POST",
+		Path:        "/tasks",
+		OperationID: "createTask",
+		RequestBody: &spec.RequestBody{ContentTypes: []string{"application/json"}},
+	}
+	if p := buildOpPlan("tasks", op); !p.SetFlag {
+		t.Error("expected --set on an operation with a JSON body")
+	}
+
+	op.Params = []spec.Param{{Name: "set", In: "query", Type: "string"}}
+	if p := buildOpPlan("tasks", op); p.SetFlag {
+		t.Error("expected no --set when a parameter is named set")
+	}
+
+	op.Params, op.RequestBody = nil, nil
+	if p := buildOpPlan("tasks", op); p.SetFlag {
+		t.Error("expected no --set without a JSON body")
+	}
+}
+
+func TestBuild_Completions(t *testing.T) {
+	s := &spec.Spec{Operations: []spec.Operation{
+		{
+			Method:      "GET",
+			Path:        "/tasks",
+			OperationID: "listTasks",
+			Tag:         "tasks",
+			Envelope:    &spec.Envelope{Items: "data"},
+		},
+		{
+			Method:      "GET",
+			Path:        "/tasks/{taskId}",
+			OperationID: "getTask",
+			Tag:         "tasks",
+			Params: []spec.Param{{
+				Name:     "taskId",
+				In:       "path",
+				Required: true,
+				Type:     "string",
+				Cli:      &spec.ParamCliOverrides{Complete: &spec.Completion{OperationID: "listTasks"}},
+			}},
+		},
+	}}
+
+	p := Build(s, "mycli", "example.com/mycli")
+	for _, op := range p.Groups[0].Operations {
+		if op.OperationID != "getTask" {
+			continue
+		}
+		want := &Completion{OperationID: "listTasks", Path: "/tasks", Envelope: &Envelope{Items: "data"}, Field: "id"}
+		if got := op.Positionals[0].Complete; !reflect.DeepEqual(got, want) {
+			t.Errorf("completion = %+v, want %+v", got, want)
+		}
+		return
+	}
+	t.Fatal("expected to find getTask")
+}
+
+func TestBuild_Warnings(t *testing.T) {
+	complete := &spec.ParamCliOverrides{Complete: &spec.Completion{OperationID: "listTasks"}}
+	s := &spec.Spec{Operations: []spec.Operation{
+		{Method: "GET", Path: "/tasks/{taskId}", OperationID: "getTask", Tag: "tasks",
+			Params: []spec.Param{{Name: "taskId", In: "path", Required: true, Type: "string", Cli: complete}}},
+		{Method: "GET", Path: "/v2/tasks/{taskId}", OperationID: "getTaskV2", Tag: "tasks",
+			Cli: &spec.CliOverrides{Name: "tasks get"}},
+	}}
+
+	p := Build(s, "mycli", "example.com/mycli")
+	want := []string{
+		"tasks get: taskId completes from listTasks, which is not in the CLI",
+		`GET /v2/tasks/{taskId} and GET /tasks/{taskId} are both the command "tasks get"; name one with x-cli name`,
+	}
+	if !reflect.DeepEqual(p.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", p.Warnings, want)
+	}
+	if p.Groups[0].Operations[0].Positionals[0].Complete != nil {
+		t.Error("expected the completion to be left out")
+	}
+}
+
+func TestBuild_ETagAuto(t *testing.T) {
+	ifMatch := spec.Param{Name: "If-Match", In: "header", Type: "string"}
+	s := &spec.Spec{Operations: []spec.Operation{
+		{Method: "GET", Path: "/tasks/{taskId}", OperationID: "getTask", Tag: "tasks"},
+		{Method: "PUT", Path: "/tasks/{taskId}", OperationID: "updateTask", Tag: "tasks", Params: []spec.Param{ifMatch}},
+		{Method: "DELETE", Path: "/tasks/{taskId}", OperationID: "deleteTask", Tag: "tasks", Params: []spec.Param{{Name: "if-match", In: "header", Type: "string"}}},
+		{Method: "POST", Path: "/tasks/{taskId}/archive", OperationID: "archiveTask", Tag: "tasks", Params: []spec.Param{ifMatch}},
+	}}
+
+	p := Build(s, "mycli", "example.com/mycli")
+	got := map[string]bool{}
+	for _, op := range p.Groups[0].Operations {
+		for _, f := range op.Flags {
+			got[op.OperationID] = got[op.OperationID] || f.ETagAuto
+		}
+	}
+	want := map[string]bool{"updateTask": true, "deleteTask": true, "archiveTask": false}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ETagAuto = %v, want %v", got, want)
+	}
+}
+
+func TestBuild_Accept(t *testing.T) {
+	ok := func(contentTypes ...string) spec.Response {
+		return spec.Response{StatusCode: "200", ContentTypes: contentTypes}
+	}
+	s := &spec.Spec{Operations: []spec.Operation{
+		{Method: "GET", Path: "/reports", OperationID: "exportReport", Tag: "reports", Responses: []spec.Response{
+			ok("text/csv", "application/json"),
+			{StatusCode: "202", ContentTypes: []string{"application/pdf", "application/json"}},
+			{StatusCode: "404", ContentTypes: []string{"application/problem+json"}},
+		}},
+		{Method: "GET", Path: "/reports/{id}", OperationID: "getReport", Tag: "reports", Responses: []spec.Response{
+			ok("application/json"),
+			{StatusCode: "default", ContentTypes: []string{"text/plain"}},
+		}},
+		{Method: "GET", Path: "/reports/{id}/raw", OperationID: "rawReport", Tag: "reports", Responses: []spec.Response{ok("text/csv", "application/json")},
+			Params: []spec.Param{{Name: "Accept", In: "header", Type: "string"}}},
+	}}
+
+	p := Build(s, "mycli", "example.com/mycli")
+	got := map[string][]string{}
+	for _, op := range p.Groups[0].Operations {
+		got[op.OperationID] = op.Accept
+	}
+	want := map[string][]string{
+		"exportReport": {"application/json", "application/pdf", "text/csv"},
+		"getReport":    nil,
+		"rawReport":    nil, // the Accept header parameter is --accept already
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Accept = %v, want %v", got, want)
+	}
+}
+
+func TestBuildServers(t *testing.T) {
+	servers := []spec.Server{
+		{URL: "https://api.example.com", Description: "Production server"},
+		{URL: "https://sandbox.example.com", Description: "Sandbox"},
+		{URL: "https://eu.example.com", Description: "Production (EU)"},
+		{URL: "https://other.example.com"},
+		{URL: "/v1", Description: "Relative"},
+		{URL: "https://test.example.com", Name: "test"},
+		{URL: "https://test2.example.com", Name: "test"},
+	}
+
+	want := []Server{
+		{Name: "production", URL: "https://api.example.com", Description: "Production server"},
+		{Name: "sandbox", URL: "https://sandbox.example.com", Description: "Sandbox"},
+		{Name: "server3", URL: "https://eu.example.com", Description: "Production (EU)"},
+		{Name: "server4", URL: "https://other.example.com"},
+		{Name: "test", URL: "https://test.example.com"},
+	}
+	got, warnings := buildServers(servers)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildServers() = %+v, want %+v", got, want)
+	}
+	wantWarnings := []string{
+		"server /v1 is relative and cannot be selected with --server",
+		`server https://test2.example.com is left out: its x-cli name "test" is taken`,
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("buildServers() warnings = %q, want %q", warnings, wantWarnings)
+	}
+}
+
+func TestBuild_Services(t *testing.T) {
+	complete := &spec.ParamCliOverrides{Complete: &spec.Completion{OperationID: "listItems"}}
+	s := &spec.Spec{
+		Services: []spec.Service{{Name: "billing", Title: "Billing API"}, {Name: "users"}},
+		Operations: []spec.Operation{
+			{Service: "users", Tag: "items", Method: "GET", Path: "/users/items", OperationID: "listItems"},
+			{Service: "billing", Tag: "items", Method: "GET", Path: "/billing/items", OperationID: "listItems"},
+			{Service: "billing", Tag: "items", Method: "GET", Path: "/billing/items/{id}", OperationID: "getItem",
+				Params: []spec.Param{{Name: "id", In: "path", Required: true, Type: "string", Cli: complete}}},
+		},
+	}
+
+	p := Build(s, "test", "github.com/example/test")
+	if len(p.Services) != 2 || p.Services[0].Description != "Billing API" {
+		t.Errorf("unexpected services %+v", p.Services)
+	}
+	if len(p.Groups) != 2 || p.Groups[0].Service != "billing" || p.Groups[1].Service != "users" {
+		t.Fatalf("expected an items group per service, got %+v", p.Groups)
+	}
+
+	get := p.Groups[0].Operations[1]
+	if got := strings.Join(get.CommandPath, " "); got != "billing items get" {
+		t.Errorf("expected the service to lead the command path, got %q", got)
+	}
+	// Completions resolve within the service
+	if c := get.Positionals[0].Complete; c == nil || c.Path != "/billing/items" {
+		t.Errorf("expected the completion from the billing list operation, got %+v", c)
+	}
+}
@@ -0,0 +1,239 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crunchloop/opencligen/internal/spec"
+)
+
+// Suggestion is the x-cli annotation proposed for an operation by
+// opencligen init. Cli only holds what the operation's x-cli does not
+// already set.
+type Suggestion struct {
+	Method string
+	Path   string
+	Cli    spec.CliOverrides
+}
+
+// suggestedAliases are the short aliases proposed for common commands
+var suggestedAliases = map[string][]string{
+	"list":   {"ls"},
+	"delete": {"rm"},
+}
+
+// internalWords mark operations that look internal when they appear in the
+// path, tag or operationId; such operations are proposed as hidden
+var internalWords = map[string]bool{
+	"internal": true,
+	"admin":    true,
+	"debug":    true,
+	"private":  true,
+	"healthz":  true,
+	"readyz":   true,
+	"livez":    true,
+	"metrics":  true,
+}
+
+// Suggest proposes x-cli names, groups, aliases and hidden flags for the
+// operations of s, as a starting point for customizing the generated CLI.
+// Names follow the generated defaults where those are good, and otherwise
+// come from the method and path; they are unique within each group.
+// Operations whose x-cli already sets everything get no suggestion.
+func Suggest(s *spec.Spec) []Suggestion {
+	// Command paths already chosen with x-cli name are kept
+	taken := make(map[string]bool)
+	for i := range s.Operations {
+		op := &s.Operations[i]
+		if op.Cli != nil && op.Cli.Name != "" {
+			taken[strings.Join(buildOpPlan(op.Tag, *op).CommandPath, " ")] = true
+		}
+	}
+
+	var suggestions []Suggestion
+	for i := range s.Operations {
+		op := &s.Operations[i]
+		var existing spec.CliOverrides
+		if op.Cli != nil {
+			existing = *op.Cli
+		}
+		suggestion := Suggestion{Method: op.Method, Path: op.Path}
+
+		command := ""
+		if existing.Name == "" {
+			group := existing.Group
+			if group == "" {
+				group = suggestGroup(*op)
+				suggestion.Cli.Group = group
+			}
+			group = DeriveGroupName(group)
+			command = uniqueCommand(taken, group, suggestCommand(*op, group), *op)
+			suggestion.Cli.Name = group + " " + command
+		} else {
+			path := ParseCommandPath(existing.Name)
+			command = path[len(path)-1]
+		}
+
+		if len(existing.Aliases) == 0 {
+			suggestion.Cli.Aliases = suggestedAliases[command]
+		}
+		if !existing.Hidden && looksInternal(*op) {
+			suggestion.Cli.Hidden = true
+		}
+
+		if suggestion.Cli.Name != "" || suggestion.Cli.Group != "" || len(suggestion.Cli.Aliases) > 0 || suggestion.Cli.Hidden {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions
+}
+
+// suggestGroup returns the group of op: its tag, or else the first segment
+// of its path that is not a version or API prefix
+func suggestGroup(op spec.Operation) string {
+	if op.Tag != "" && op.Tag != "default" {
+		return op.Tag
+	}
+	for _, segment := range pathSegments(op.Path) {
+		if isParam(segment) || segment == "api" || isVersion(segment) {
+			continue
+		}
+		return segment
+	}
+	return "default"
+}
+
+// suggestCommand returns the command name of op within group: the generated
+// default when the operationId starts with a known verb, the CRUD verb of
+// the method and path, or else the operationId or last path segment without
+// the group's name
+func suggestCommand(op spec.Operation, group string) string {
+	if op.OperationID != "" {
+		if name := DeriveCommandName(op.OperationID); name != toKebabCase(op.OperationID) {
+			return name
+		}
+	}
+
+	segments := pathSegments(op.Path)
+	item := len(segments) > 0 && isParam(segments[len(segments)-1])
+	// A path ending in the group is a collection, and a plural segment after
+	// an item, as in /folders/{id}/items, a collection of the item;
+	// /bookmarks/{id}/archive is an action on an item
+	collection, subcollection := false, ""
+	if len(segments) > 0 && !item {
+		last := segments[len(segments)-1]
+		if len(segments) == 1 || strings.EqualFold(last, group) {
+			collection = true
+		} else if isParam(segments[len(segments)-2]) && strings.HasSuffix(last, "s") {
+			subcollection = toKebabCase(last)
+		}
+	}
+	switch {
+	case op.Method == "GET" && subcollection != "":
+		return "list-" + subcollection
+	case op.Method == "POST" && subcollection != "":
+		return "create-" + strings.TrimSuffix(subcollection, "s")
+	case op.Method == "GET" && item:
+		return "get"
+	case op.Method == "GET" && collection:
+		return "list"
+	case op.Method == "POST" && collection:
+		return "create"
+	case (op.Method == "PUT" || op.Method == "PATCH") && item:
+		return "update"
+	case op.Method == "DELETE" && item:
+		return "delete"
+	}
+
+	name := toKebabCase(op.OperationID)
+	if name == "" {
+		for i := len(segments) - 1; i >= 0; i-- {
+			if !isParam(segments[i]) {
+				name = toKebabCase(segments[i])
+				break
+			}
+		}
+	}
+	if trimmed := withoutGroup(name, group); trimmed != "" {
+		return trimmed
+	}
+	if name != "" {
+		return name
+	}
+	return strings.ToLower(op.Method)
+}
+
+// uniqueCommand returns command, or a variant of it that no other
+// operation in group uses, and marks it taken
+func uniqueCommand(taken map[string]bool, group, command string, op spec.Operation) string {
+	candidates := []string{command}
+	if id := toKebabCase(op.OperationID); id != "" && id != command {
+		candidates = append(candidates, id)
+	}
+	candidates = append(candidates, command+"-"+strings.ToLower(op.Method))
+	for _, candidate := range candidates {
+		if !taken[group+" "+candidate] {
+			taken[group+" "+candidate] = true
+			return candidate
+		}
+	}
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", command, n)
+		if !taken[group+" "+candidate] {
+			taken[group+" "+candidate] = true
+			return candidate
+		}
+	}
+}
+
+// withoutGroup removes the words of a kebab-case name that repeat the
+// group, e.g. "export-bookmarks" in group "bookmarks" becomes "export"
+func withoutGroup(name, group string) string {
+	singular := strings.TrimSuffix(group, "s")
+	var kept []string
+	for _, word := range strings.Split(name, "-") {
+		if word != group && word != singular {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, "-")
+}
+
+// looksInternal reports whether op looks like an internal operation that
+// users of the CLI should not see by default
+func looksInternal(op spec.Operation) bool {
+	for _, segment := range pathSegments(op.Path) {
+		if strings.HasPrefix(segment, "_") || internalWords[strings.ToLower(segment)] {
+			return true
+		}
+	}
+	for _, name := range []string{op.Tag, op.OperationID} {
+		for _, word := range strings.Split(toKebabCase(name), "-") {
+			if internalWords[word] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func pathSegments(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// isVersion reports whether a path segment is an API version such as v1
+func isVersion(segment string) bool {
+	if len(segment) < 2 || (segment[0] != 'v' && segment[0] != 'V') {
+		return false
+	}
+	for _, r := range segment[1:] {
+		if (r < '0' || r > '9') && r != '.' {
+			return false
+		}
+	}
+	return true
+}
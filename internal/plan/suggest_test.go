@@ -0,0 +1,69 @@
+package plan
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/crunchloop/opencligen/internal/spec"
+)
+
+func TestSuggest(t *testing.T) {
+	s := &spec.Spec{Operations: []spec.Operation{
+		{Tag: "bookmarks", Method: "GET", Path: "/bookmarks", OperationID: "listBookmarks"},
+		{Tag: "bookmarks", Method: "POST", Path: "/bookmarks/{id}/archive", OperationID: "archiveBookmark"},
+		{Tag: "default", Method: "GET", Path: "/api/v2/folders"},
+		{Tag: "default", Method: "DELETE", Path: "/api/v2/folders/{id}"},
+		{Tag: "default", Method: "GET", Path: "/api/v2/folders/{id}/items"},
+		{Tag: "default", Method: "GET", Path: "/internal/metrics", OperationID: "getMetrics"},
+		// The operationId of both maps to "get"; the second one gets another name
+		{Tag: "users", Method: "GET", Path: "/users/{id}", OperationID: "getUser"},
+		{Tag: "users", Method: "GET", Path: "/users/me", OperationID: "getCurrentUser"},
+	}}
+
+	want := []spec.CliOverrides{
+		{Name: "bookmarks list", Group: "bookmarks", Aliases: []string{"ls"}},
+		{Name: "bookmarks archive", Group: "bookmarks"},
+		{Name: "folders list", Group: "folders", Aliases: []string{"ls"}},
+		{Name: "folders delete", Group: "folders", Aliases: []string{"rm"}},
+		{Name: "folders list-items", Group: "folders"},
+		{Name: "internal get", Group: "internal", Hidden: true},
+		{Name: "users get", Group: "users"},
+		{Name: "users get-current-user", Group: "users"},
+	}
+
+	got := Suggest(s)
+	if len(got) != len(want) {
+		t.Fatalf("expected %d suggestions, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i].Cli, want[i]) {
+			t.Errorf("%s %s: expected %+v, got %+v", got[i].Method, got[i].Path, want[i], got[i].Cli)
+		}
+	}
+}
+
+func TestSuggest_KeepsExistingAnnotations(t *testing.T) {
+	s := &spec.Spec{Operations: []spec.Operation{
+		// Fully annotated: nothing to suggest
+		{Tag: "tasks", Method: "GET", Path: "/tasks", OperationID: "listTasks",
+			Cli: &spec.CliOverrides{Name: "tasks ls", Aliases: []string{"l"}}},
+		// Only the group is set: the name uses it
+		{Tag: "tasks", Method: "DELETE", Path: "/tasks/{id}", OperationID: "deleteTask",
+			Cli: &spec.CliOverrides{Group: "jobs"}},
+		// The name is set: aliases follow its command
+		{Tag: "tasks", Method: "GET", Path: "/tasks/{id}/runs", OperationID: "fetchRuns",
+			Cli: &spec.CliOverrides{Name: "tasks list"}},
+		// Command paths already in use are not reused
+		{Tag: "tasks", Method: "GET", Path: "/_debug/tasks", OperationID: "ls"},
+	}}
+
+	got := Suggest(s)
+	want := []Suggestion{
+		{Method: "DELETE", Path: "/tasks/{id}", Cli: spec.CliOverrides{Name: "jobs delete", Aliases: []string{"rm"}}},
+		{Method: "GET", Path: "/tasks/{id}/runs", Cli: spec.CliOverrides{Aliases: []string{"ls"}}},
+		{Method: "GET", Path: "/_debug/tasks", Cli: spec.CliOverrides{Name: "tasks list-get", Group: "tasks", Hidden: true}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
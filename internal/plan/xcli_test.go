@@ -2,6 +2,7 @@ package plan
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
 	"github.com/crunchloop/opencligen/internal/spec"
@@ -185,6 +186,28 @@ func TestXCli_ParamShorthand(t *testing.T) {
 	}
 }
 
+func TestXCli_ParamShorthandReserved(t *testing.T) {
+	p := buildParamPlan(spec.Param{
+		Name: "quota",
+		In:   "query",
+		Type: "integer",
+		Cli:  &spec.ParamCliOverrides{Shorthand: "q"},
+	})
+	if p.Shorthand != "" {
+		t.Errorf("expected -q to be left to --quiet, got shorthand %q", p.Shorthand)
+	}
+
+	p = buildParamPlan(spec.Param{
+		Name: "host",
+		In:   "query",
+		Type: "string",
+		Cli:  &spec.ParamCliOverrides{Shorthand: "H"},
+	})
+	if p.Shorthand != "" {
+		t.Errorf("expected -H to be left to --header, got shorthand %q", p.Shorthand)
+	}
+}
+
 func TestXCli_PositionalFalse(t *testing.T) {
 	s := loadAnnotatedSpec(t)
 	plan := Build(s, "test", "github.com/example/test")
@@ -222,3 +245,39 @@ func TestXCli_PositionalFalse(t *testing.T) {
 		t.Fatal("expected userId to be converted to --user flag")
 	}
 }
+
+func TestXCli_HelpTemplates(t *testing.T) {
+	s := &spec.Spec{
+		Title: "Branded API",
+		GlobalCli: &spec.CliOverrides{
+			UsageTemplate: "Usage: {{.UseLine}}\n",
+			HelpTemplate:  "Acme CLI\n{{.Long}}\n",
+		},
+	}
+	plan := Build(s, "test", "github.com/example/test")
+
+	if plan.Templates.Usage != "Usage: {{.UseLine}}\n" {
+		t.Errorf("expected usage template from x-cli, got %q", plan.Templates.Usage)
+	}
+	if plan.Templates.Help != "Acme CLI\n{{.Long}}\n" {
+		t.Errorf("expected help template from x-cli, got %q", plan.Templates.Help)
+	}
+}
+
+func TestXCli_Signing(t *testing.T) {
+	s := &spec.Spec{
+		GlobalCli: &spec.CliOverrides{
+			Signing: &spec.Signing{Type: spec.SigningHMACSHA256, SignatureHeader: "X-Sig", Encoding: "base64"},
+		},
+	}
+	plan := Build(s, "test", "github.com/example/test")
+
+	want := &Signing{Type: spec.SigningHMACSHA256, SignatureHeader: "X-Sig", Base64: true}
+	if !reflect.DeepEqual(plan.Auth.Signing, want) {
+		t.Errorf("signing = %+v, want %+v", plan.Auth.Signing, want)
+	}
+
+	if plan := Build(&spec.Spec{}, "test", "github.com/example/test"); plan.Auth.Signing != nil {
+		t.Errorf("expected no signing without x-cli signing, got %+v", plan.Auth.Signing)
+	}
+}
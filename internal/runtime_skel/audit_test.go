@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog_RecordAndVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log := &AuditLog{Path: path, Command: "app tasks list"}
+
+	if err := log.Record("GET", "https://user:pw@api.example.com/v1/tasks?token=secret", 200, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := log.Record("DELETE", "https://api.example.com/v1/tasks/1", 0, errors.New("connection refused")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	count, err := VerifyAuditLog(path)
+	if err != nil {
+		t.Fatalf("unexpected verification error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 entries, got %d", count)
+	}
+
+	content, _ := os.ReadFile(path)
+	if strings.Contains(string(content), "secret") || strings.Contains(string(content), "pw@") {
+		t.Errorf("expected credentials and query to be redacted, got %s", content)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected audit log permissions 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestVerifyAuditLog_DetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log := &AuditLog{Path: path, Command: "app tasks get"}
+
+	for i := 0; i < 3; i++ {
+		if err := log.Record("GET", fmt.Sprintf("https://api.example.com/v1/tasks/%d", i), 200, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	content, _ := os.ReadFile(path)
+	tampered := strings.Replace(string(content), `"status":200`, `"status":404`, 1)
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := VerifyAuditLog(path)
+	if !errors.Is(err, ErrAuditChainBroken) {
+		t.Fatalf("expected ErrAuditChainBroken, got %v", err)
+	}
+	if count != 0 || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected failure on line 1, got count=%d err=%v", count, err)
+	}
+
+	// Removing an entry breaks the chain too
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if err := os.WriteFile(path, []byte(lines[0]+"\n"+lines[2]+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyAuditLog(path); !errors.Is(err, ErrAuditChainBroken) {
+		t.Errorf("expected ErrAuditChainBroken after removing an entry, got %v", err)
+	}
+}
+
+func TestRuntime_DoRecordsAudit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	rt := New(server.URL, 5*time.Second)
+	rt.ErrOutput = &strings.Builder{}
+	rt.Audit = &AuditLog{Path: path, Command: "app tasks get"}
+
+	_ = rt.Do(context.Background(), NewRequest("GET", "/v1/tasks/1"))
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected audit log to be written: %v", err)
+	}
+	if !strings.Contains(string(content), `"status":404`) || !strings.Contains(string(content), `"command":"app tasks get"`) {
+		t.Errorf("unexpected audit entry: %s", content)
+	}
+}
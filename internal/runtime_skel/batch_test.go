@@ -0,0 +1,188 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// getThing is a BatchFunc that gets the thing named by the line's "id" flag
+func getThing(ctx context.Context, rt *Runtime, line BatchLine) error {
+	var id string
+	if err := json.Unmarshal(line.Flags["id"], &id); err != nil {
+		return err
+	}
+	return rt.Do(ctx, NewRequest("GET", "/things/"+id))
+}
+
+func TestRuntime_Batch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/things/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": "not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"path": "` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+	rt.Format = FormatTable
+
+	in := `{"id": "a"}` + "\n\n" + `{"id": "missing"}` + "\n" + `not json` + "\n" + `{"id": "b"}`
+	err := rt.Batch(context.Background(), strings.NewReader(in), 2, getThing)
+	if err == nil || err.Error() != "2 of 4 lines failed" {
+		t.Errorf("expected the failed lines to be counted, got %v", err)
+	}
+
+	want := `{"line":1,"status":200,"response":{"path":"/things/a"}}` + "\n" +
+		`{"line":3,"status":404,"response":{"error":"not found"},"error":"request failed with status 404"}` + "\n" +
+		`{"line":4,"error":"line 4 is not a JSON object"}` + "\n" +
+		`{"line":5,"status":200,"response":{"path":"/things/b"}}` + "\n"
+	if out.String() != want {
+		t.Errorf("unexpected results:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestRuntime_BatchConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, most := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		most = max(most, inFlight)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &bytes.Buffer{}
+	in := strings.Repeat(`{"id": "x"}`+"\n", 12)
+	if err := rt.Batch(context.Background(), strings.NewReader(in), 3, getThing); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if most < 2 || most > 3 {
+		t.Errorf("expected up to 3 concurrent requests, got %d", most)
+	}
+
+	if err := rt.Batch(context.Background(), strings.NewReader(in), 0, getThing); err == nil {
+		t.Error("expected a concurrency below 1 to be rejected")
+	}
+}
+
+// fakePacerClock stops the clock of batch pacing and records how long the
+// pacer waits instead of waiting, returning the waits so far
+func fakePacerClock(t *testing.T) func() []time.Duration {
+	var mu sync.Mutex
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var waits []time.Duration
+	origNow, origWait := pacerNow, pacerWait
+	pacerNow = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	pacerWait = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { pacerNow, pacerWait = origNow, origWait })
+	return func() []time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]time.Duration(nil), waits...)
+	}
+}
+
+func TestRuntime_BatchRate(t *testing.T) {
+	waits := fakePacerClock(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	// Each request costs 5 of 100 per second, so each waits 50ms longer
+	// than the one before, whichever line it belongs to
+	costly := func(ctx context.Context, rt *Runtime, line BatchLine) error {
+		req := NewRequest("GET", "/things")
+		req.SetHints(5, 0)
+		return rt.Do(ctx, req)
+	}
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &bytes.Buffer{}
+	rt.BatchRate = 100
+	in := strings.Repeat(`{}`+"\n", 4)
+	if err := rt.Batch(context.Background(), strings.NewReader(in), 4, costly); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := waits()
+	sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+	want := []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 150 * time.Millisecond}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("waits = %v, want %v", got, want)
+	}
+
+	rt.BatchRate = -1
+	if err := rt.Batch(context.Background(), strings.NewReader(in), 1, getThing); err == nil {
+		t.Error("expected a negative rate to be rejected")
+	}
+}
+
+func TestBatchPacer(t *testing.T) {
+	waits := fakePacerClock(t)
+	pacer := &batchPacer{rate: 10}
+	for _, cost := range []float64{0, 0.5, 2} {
+		if err := pacer.wait(context.Background(), cost); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// The first request is sent at once, a request without a cost counts
+	// as 1, and each waits for the cost of those before it
+	want := []time.Duration{100 * time.Millisecond, 150 * time.Millisecond}
+	if got := waits(); !reflect.DeepEqual(got, want) {
+		t.Errorf("waits = %v, want %v", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := pacer.wait(ctx, 1); err == nil {
+		t.Error("expected a canceled wait to fail")
+	}
+}
+
+func TestParseBatchLine(t *testing.T) {
+	line, err := parseBatchLine(2, []byte(`{"args": ["b1"], "tag": "go", "data": {"url": "x"}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if line.Number != 2 || len(line.Args) != 1 || line.Args[0] != "b1" {
+		t.Errorf("unexpected line %+v", line)
+	}
+	if _, ok := line.Flags["args"]; ok || string(line.Flags["data"]) != `{"url": "x"}` {
+		t.Errorf("expected the flags without args, got %v", line.Flags)
+	}
+
+	for _, data := range []string{`[]`, `null`, `{"args": "b1"}`} {
+		if _, err := parseBatchLine(1, []byte(data)); err == nil {
+			t.Errorf("expected %s to be rejected", data)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package runtime
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -79,3 +80,16 @@ func TestLoadBody_ComplexJSON(t *testing.T) {
 		t.Errorf("expected %q, got %q", input, string(body))
 	}
 }
+
+func TestReadValue(t *testing.T) {
+	value, err := ReadValue(strings.NewReader("  b1\n"))
+	if err != nil || value != "b1" {
+		t.Errorf("expected the trimmed value, got %q, %v", value, err)
+	}
+
+	for _, input := range []string{"", " \n", "b1\nb2\n"} {
+		if _, err := ReadValue(strings.NewReader(input)); err == nil {
+			t.Errorf("expected %q to be rejected", input)
+		}
+	}
+}
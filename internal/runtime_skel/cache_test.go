@@ -0,0 +1,151 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestResponseCache_Revalidates(t *testing.T) {
+	var requests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"id": 1}`))
+	}))
+	defer server.Close()
+
+	cache := &ResponseCache{Dir: t.TempDir()}
+	var out bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+	rt.Use(cache.Middleware())
+
+	for i := 0; i < 2; i++ {
+		if err := rt.Do(context.Background(), NewRequest("GET", "/things/1")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if requests != 2 || notModified != 1 {
+		t.Errorf("expected the second request to be revalidated, got %d requests and %d 304s", requests, notModified)
+	}
+	want := "{\n  \"id\": 1\n}\n"
+	if out.String() != want+want {
+		t.Errorf("expected the cached body for the 304, got %q", out.String())
+	}
+}
+
+func TestResponseCache_LastModified(t *testing.T) {
+	const modified = "Wed, 21 Oct 2015 07:28:00 GMT"
+	var gotSince string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSince = r.Header.Get("If-Modified-Since")
+		w.Header().Set("Last-Modified", modified)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &bytes.Buffer{}
+	rt.Use((&ResponseCache{Dir: t.TempDir()}).Middleware())
+
+	for i := 0; i < 2; i++ {
+		if err := rt.Do(context.Background(), NewRequest("GET", "/things")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if gotSince != modified {
+		t.Errorf("expected If-Modified-Since %q, got %q", modified, gotSince)
+	}
+}
+
+func TestResponseCache_NotStored(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+	}{
+		{"no validator", "GET", http.Header{}},
+		{"no-store", "GET", http.Header{"Etag": {`"v1"`}, "Cache-Control": {"no-store"}}},
+		{"not a GET", "POST", http.Header{"Etag": {`"v1"`}}},
+	}
+
+	for _, tt := range tests {
+		var conditional bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conditional = conditional || r.Header.Get("If-None-Match") != ""
+			for name, values := range tt.header {
+				w.Header()[name] = values
+			}
+			_, _ = w.Write([]byte(`{}`))
+		}))
+
+		rt := New(server.URL, 5*time.Second)
+		rt.Output = &bytes.Buffer{}
+		rt.Use((&ResponseCache{Dir: t.TempDir()}).Middleware())
+		for i := 0; i < 2; i++ {
+			if err := rt.Do(context.Background(), NewRequest(tt.method, "/things")); err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+		}
+		server.Close()
+
+		if conditional {
+			t.Errorf("%s: expected the response not to be cached", tt.name)
+		}
+	}
+}
+
+func TestResponseCache_KeyedByCredentials(t *testing.T) {
+	var conditional int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			conditional++
+		}
+		w.Header().Set("ETag", `"`+r.Header.Get("Authorization")+`"`)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	cache := &ResponseCache{Dir: t.TempDir()}
+	for _, token := range []string{"alice", "bob"} {
+		rt := New(server.URL, 5*time.Second)
+		rt.Output = &bytes.Buffer{}
+		rt.AddHeader("Authorization", "Bearer "+token)
+		rt.Use(cache.Middleware())
+		if err := rt.Do(context.Background(), NewRequest("GET", "/me")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if conditional != 0 {
+		t.Errorf("expected a separate entry per credential, got %d conditional requests", conditional)
+	}
+}
+
+func TestResponseCache_Clear(t *testing.T) {
+	cache := &ResponseCache{Dir: t.TempDir()}
+	if err := cache.save("key", &cachedResponse{StatusCode: 200}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(cache.Dir); !os.IsNotExist(err) {
+		t.Errorf("expected the cache directory to be removed, got %v", err)
+	}
+	if err := cache.Clear(); err != nil {
+		t.Errorf("expected clearing an empty cache to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package runtime
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Check is a constraint from the spec on the value of a parameter
+type Check func(value string) error
+
+// CheckValue checks a parameter value against the constraints the spec
+// gives for it, so a bad value fails before the request is sent. Empty
+// values, for parameters that were not given, are not checked.
+func CheckValue(value string, checks ...Check) error {
+	if value == "" {
+		return nil
+	}
+	for _, check := range checks {
+		if err := check(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Minimum checks that a value is a number of at least min
+func Minimum(min float64) Check {
+	return func(value string) error {
+		n, err := parseNumber(value)
+		if err != nil {
+			return err
+		}
+		if n < min {
+			return fmt.Errorf("%s is below the minimum of %v", value, min)
+		}
+		return nil
+	}
+}
+
+// Maximum checks that a value is a number of at most max
+func Maximum(max float64) Check {
+	return func(value string) error {
+		n, err := parseNumber(value)
+		if err != nil {
+			return err
+		}
+		if n > max {
+			return fmt.Errorf("%s is above the maximum of %v", value, max)
+		}
+		return nil
+	}
+}
+
+// OneOf checks that a value is one of the values of an enum
+func OneOf(allowed ...string) Check {
+	return func(value string) error {
+		for _, a := range allowed {
+			if value == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of: %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+// Matches checks that a value matches a regular expression. Patterns Go
+// cannot compile, e.g. with lookaheads, accept any value.
+func Matches(pattern string) Check {
+	re, err := regexp.Compile(pattern)
+	return func(value string) error {
+		if err == nil && !re.MatchString(value) {
+			return fmt.Errorf("%q does not match the pattern %s", value, pattern)
+		}
+		return nil
+	}
+}
+
+// parseNumber parses a value checked against a numeric range
+func parseNumber(value string) (float64, error) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	return n, nil
+}
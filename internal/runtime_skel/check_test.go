@@ -0,0 +1,32 @@
+package runtime
+
+import "testing"
+
+func TestCheckValue(t *testing.T) {
+	tests := []struct {
+		value  string
+		checks []Check
+		want   string
+	}{
+		{"", []Check{Minimum(1)}, ""},
+		{"25", []Check{Minimum(1), Maximum(100)}, ""},
+		{"0", []Check{Minimum(1), Maximum(100)}, "0 is below the minimum of 1"},
+		{"100.5", []Check{Minimum(1), Maximum(100)}, "100.5 is above the maximum of 100"},
+		{"ten", []Check{Minimum(1)}, `"ten" is not a number`},
+		{"csv", []Check{OneOf("json", "csv")}, ""},
+		{"xml", []Check{OneOf("json", "csv")}, `"xml" is not one of: json, csv`},
+		{"AB-12", []Check{Matches(`^[A-Z]+-\d+$`)}, ""},
+		{"ab-12", []Check{Matches(`^[A-Z]+-\d+$`)}, `"ab-12" does not match the pattern ^[A-Z]+-\d+$`},
+		{"anything", []Check{Matches(`^(?!x)`)}, ""},
+	}
+	for _, tt := range tests {
+		err := CheckValue(tt.value, tt.checks...)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("CheckValue(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package runtime
+
+import (
+	"context"
+	"io"
+)
+
+// Complete sends a list request for shell completion and returns the value
+// at field, a dotted path, of each item of the response, e.g. the IDs of
+// live resources. Items are found through req's envelope, or are the
+// response itself when it is a list. Only the first page is read, and
+// nothing is printed, so errors do not end up in the shell's completions.
+func (r *Runtime) Complete(ctx context.Context, req *Request, field string) ([]string, error) {
+	if r.DryRun {
+		return nil, nil
+	}
+	quiet := r.fork(io.Discard, io.Discard)
+	httpReq, err := quiet.build(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	items, _, _, err := quiet.fetchPage(httpReq, req.Envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, item := range items {
+		v, ok := lookupPath(item, field)
+		if !ok {
+			continue
+		}
+		if s := scalarString(v); s != "" {
+			values = append(values, s)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,49 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRuntime_Complete(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tasks":
+			_, _ = w.Write([]byte(`{"data": [{"id": "t1"}, {"id": 2}, {"name": "no id"}, {"id": "t3"}]}`))
+		case "/users":
+			_, _ = w.Write([]byte(`[{"profile": {"login": "ann"}}, {"profile": {"login": "bob"}}]`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error": "unauthorized"}`))
+		}
+	}))
+	defer server.Close()
+
+	var errOut bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.ErrOutput = &errOut
+
+	req := NewRequest("GET", "/tasks")
+	req.SetEnvelope("data", "", "")
+	values, err := rt.Complete(context.Background(), req, "id")
+	if err != nil || !reflect.DeepEqual(values, []string{"t1", "2", "t3"}) {
+		t.Errorf("expected the IDs of the items, got %v, %v", values, err)
+	}
+
+	values, err = rt.Complete(context.Background(), NewRequest("GET", "/users"), "profile.login")
+	if err != nil || !reflect.DeepEqual(values, []string{"ann", "bob"}) {
+		t.Errorf("expected the nested field of a bare list, got %v, %v", values, err)
+	}
+
+	if _, err := rt.Complete(context.Background(), NewRequest("GET", "/secret"), "id"); err == nil {
+		t.Error("expected an error status to fail")
+	}
+	if errOut.Len() > 0 {
+		t.Errorf("expected nothing to be printed, got %q", errOut.String())
+	}
+}
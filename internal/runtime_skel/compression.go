@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding is the Accept-Encoding sent when responses may be
+// compressed
+const acceptEncoding = "gzip, deflate"
+
+// compressionTransport sets the Accept-Encoding of requests sent through
+// next: "gzip, deflate" when compress is true, decoding compressed responses
+// so callers see the body as the API meant it, and "identity" otherwise, so
+// the body arrives exactly as the server stores it. Requests that set their
+// own Accept-Encoding, e.g. with --header, get the response as sent.
+func compressionTransport(next http.RoundTripper, compress bool) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Accept-Encoding") != "" {
+			return next.RoundTrip(req)
+		}
+		// A byte range of a compressed body cannot be decoded on its own
+		compress := compress && req.Header.Get("Range") == ""
+
+		req = req.Clone(req.Context())
+		if compress {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		} else {
+			req.Header.Set("Accept-Encoding", "identity")
+		}
+
+		resp, err := next.RoundTrip(req)
+		if err != nil || !compress {
+			return resp, err
+		}
+		encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+		if encoding != "gzip" && encoding != "deflate" {
+			return resp, nil
+		}
+		resp.Body = &decodedBody{body: resp.Body, encoding: encoding}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+		return resp, nil
+	})
+}
+
+// decodedBody decodes a compressed response body. The decoder is created on
+// the first read, as reading the compression header may block on a slow
+// response.
+type decodedBody struct {
+	body     io.ReadCloser
+	encoding string
+	r        io.Reader
+	err      error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.r == nil && b.err == nil {
+		b.r, b.err = b.decoder()
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.r.Read(p)
+}
+
+func (b *decodedBody) decoder() (io.Reader, error) {
+	if b.encoding == "gzip" {
+		return gzip.NewReader(b.body)
+	}
+	// "deflate" is zlib-wrapped (RFC 9110), but some servers send a raw
+	// deflate stream, which never starts with a valid zlib header
+	br := bufio.NewReader(b.body)
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (int(header[0])<<8|int(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+func (b *decodedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,127 @@
+package runtime
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// compressingServer serves body encoded with encoding when the request
+// accepts it, recording the Accept-Encoding it got
+func compressingServer(encoding string, body []byte, gotAccept *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*gotAccept = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Accept-Encoding") == "identity" {
+			_, _ = w.Write(body)
+			return
+		}
+
+		var buf bytes.Buffer
+		var enc io.WriteCloser
+		switch encoding {
+		case "gzip":
+			enc = gzip.NewWriter(&buf)
+		case "zlib":
+			enc, encoding = zlib.NewWriter(&buf), "deflate"
+		case "deflate":
+			enc, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+		}
+		_, _ = enc.Write(body)
+		enc.Close()
+		w.Header().Set("Content-Encoding", encoding)
+		_, _ = w.Write(buf.Bytes())
+	}))
+}
+
+func TestRuntime_DecodesCompressedResponses(t *testing.T) {
+	for _, encoding := range []string{"gzip", "zlib", "deflate"} {
+		var gotAccept string
+		server := compressingServer(encoding, []byte(`{"id": 1}`), &gotAccept)
+
+		var out bytes.Buffer
+		rt := New(server.URL, 5*time.Second)
+		rt.Output = &out
+		err := rt.Do(context.Background(), NewRequest("GET", "/things/1"))
+		server.Close()
+
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", encoding, err)
+		}
+		if gotAccept != acceptEncoding {
+			t.Errorf("%s: expected Accept-Encoding %q, got %q", encoding, acceptEncoding, gotAccept)
+		}
+		if out.String() != "{\n  \"id\": 1\n}\n" {
+			t.Errorf("%s: expected the decoded body, got %q", encoding, out.String())
+		}
+	}
+}
+
+func TestRuntime_DisableCompression(t *testing.T) {
+	var gotAccept string
+	server := compressingServer("gzip", []byte(`{"id": 1}`), &gotAccept)
+	defer server.Close()
+
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &bytes.Buffer{}
+	rt.DisableCompression = true
+	if err := rt.Do(context.Background(), NewRequest("GET", "/things/1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAccept != "identity" {
+		t.Errorf("expected an uncompressed response to be requested, got %q", gotAccept)
+	}
+}
+
+func TestRuntime_DownloadsUncompressed(t *testing.T) {
+	body := bytes.Repeat([]byte("data"), 1000)
+	var gotAccept string
+	server := compressingServer("gzip", body, &gotAccept)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	rt := New(server.URL, 5*time.Second)
+	rt.ErrOutput = io.Discard
+	rt.OutputFile = path
+	if err := rt.Do(context.Background(), NewRequest("GET", "/data.bin")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAccept != "identity" {
+		t.Errorf("expected a download to be requested as stored, got %q", gotAccept)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, body) {
+		t.Errorf("expected the body byte for byte, got %d bytes", len(got))
+	}
+}
+
+func TestRuntime_ExplicitAcceptEncoding(t *testing.T) {
+	var gotAccept string
+	server := compressingServer("gzip", []byte(`{"id": 1}`), &gotAccept)
+	defer server.Close()
+
+	var out bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+	rt.Raw = true
+	rt.AddHeader("Accept-Encoding", "gzip")
+	if err := rt.Do(context.Background(), NewRequest("GET", "/things/1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAccept != "gzip" {
+		t.Errorf("expected the explicit Accept-Encoding, got %q", gotAccept)
+	}
+	if r, err := gzip.NewReader(&out); err != nil {
+		t.Errorf("expected the body as sent, compressed, got %q", out.String())
+	} else if decoded, _ := io.ReadAll(r); string(decoded) != `{"id": 1}` {
+		t.Errorf("unexpected body %q", decoded)
+	}
+}
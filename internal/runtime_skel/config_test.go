@@ -3,6 +3,7 @@ package runtime
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -199,3 +200,299 @@ func TestLoadConfig_YmlExtension(t *testing.T) {
 		t.Errorf("expected BaseURL from .yml file, got %q", config.BaseURL)
 	}
 }
+
+func TestInitConfig_WritesStarterFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+
+	path, err := InitConfig("testapp", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if path != filepath.Join(tmpDir, "testapp", "config.yaml") {
+		t.Errorf("unexpected config path %q", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("expected config file to exist: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected permissions 0600, got %o", info.Mode().Perm())
+	}
+
+	// The starter file is entirely commented out and must load cleanly
+	config, err := LoadConfig("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error loading starter config: %v", err)
+	}
+	if config.BaseURL != "" {
+		t.Errorf("expected empty BaseURL from starter config, got %q", config.BaseURL)
+	}
+}
+
+func TestInitConfig_RefusesOverwriteWithoutForce(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+
+	if _, err := InitConfig("testapp", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := InitConfig("testapp", false); err == nil {
+		t.Error("expected error when config file already exists")
+	}
+
+	if _, err := InitConfig("testapp", true); err != nil {
+		t.Errorf("expected --force to overwrite, got: %v", err)
+	}
+}
+
+func TestConfigDirFor(t *testing.T) {
+	home, appData := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AppData", appData)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	tests := []struct {
+		goos, want string
+	}{
+		{"linux", filepath.Join(home, ".config", "testapp")},
+		{"freebsd", filepath.Join(home, ".config", "testapp")},
+		{"darwin", filepath.Join(home, "Library", "Application Support", "testapp")},
+		{"windows", filepath.Join(appData, "testapp")},
+	}
+	for _, tt := range tests {
+		if got, err := configDirFor(tt.goos, "testapp"); err != nil || got != tt.want {
+			t.Errorf("%s: configDirFor = %q, %v; want %q", tt.goos, got, err, tt.want)
+		}
+	}
+
+	// An existing ~/.config directory is kept on every platform
+	legacy := filepath.Join(home, ".config", "testapp")
+	if err := os.MkdirAll(legacy, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, goos := range []string{"linux", "darwin", "windows"} {
+		if got, err := configDirFor(goos, "testapp"); err != nil || got != legacy {
+			t.Errorf("%s: expected the existing %s, got %q, %v", goos, legacy, got, err)
+		}
+	}
+	// unless the platform directory exists too
+	if err := os.MkdirAll(filepath.Join(appData, "testapp"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := configDirFor("windows", "testapp"); got != filepath.Join(appData, "testapp") {
+		t.Errorf("windows: expected %%AppData%% to take precedence, got %q", got)
+	}
+
+	// XDG_CONFIG_HOME takes precedence everywhere
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	for _, goos := range []string{"linux", "darwin", "windows"} {
+		if got, err := configDirFor(goos, "testapp"); err != nil || got != filepath.Join(xdg, "testapp") {
+			t.Errorf("%s: expected XDG_CONFIG_HOME to be used, got %q, %v", goos, got, err)
+		}
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("AppData", "")
+	if _, err := configDirFor("windows", "testapp"); err == nil {
+		t.Error("windows: expected an error without %AppData%")
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if got := ExpandHome("~/logs/audit.log"); got != filepath.Join(home, "logs", "audit.log") {
+		t.Errorf("unexpected expansion: %q", got)
+	}
+	if got := ExpandHome("/var/log/audit.log"); got != "/var/log/audit.log" {
+		t.Errorf("expected absolute path to be unchanged, got %q", got)
+	}
+}
+
+func TestLoadConfig_ReadOnlyFromEnv(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("TESTAPP_READ_ONLY", "true")
+
+	config, err := LoadConfig("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.ReadOnly {
+		t.Error("expected ReadOnly to be enabled from environment")
+	}
+}
+
+func TestLoadConfig_Token(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	if err := os.MkdirAll(filepath.Join(tmpDir, "testapp"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "testapp", "config.yaml"), []byte("token: from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Token != "from-file" {
+		t.Errorf("expected token from file, got %q", config.Token)
+	}
+
+	t.Setenv("TESTAPP_TOKEN", "from-env")
+	config, err = LoadConfig("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Token != "from-env" {
+		t.Errorf("expected token from env to override the file, got %q", config.Token)
+	}
+}
+
+func TestLoadConfig_ExitCodes(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	if err := os.MkdirAll(filepath.Join(tmpDir, "testapp"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	content := "exit_codes:\n  404: 10\n  5xx: 3\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "testapp", "config.yaml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.ExitCodes["404"] != 10 || config.ExitCodes["5xx"] != 3 {
+		t.Errorf("expected exit codes from file, got %v", config.ExitCodes)
+	}
+}
+
+func TestLoadConfig_HeaderTemplates(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	if err := os.MkdirAll(filepath.Join(tmpDir, "testapp"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(tmpDir, "testapp", "config.yaml"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`headers:
+  X-Team: '{{ envOr "TEAM" "platform" }}'
+  X-Literal: plain {value}
+profiles:
+  prod:
+    headers:
+      Authorization: 'Bearer {{ env "PROD_TOKEN" }}'
+      X-Env: '{{ .Profile | upper }}'
+otel_headers:
+  x-api-key: '{{ env "COLLECTOR_KEY" }}'
+`)
+	t.Setenv("PROD_TOKEN", "s3cret")
+	t.Setenv("COLLECTOR_KEY", "collector")
+
+	config, err := LoadProfile("testapp", "prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"X-Team":        "platform",
+		"X-Literal":     "plain {value}",
+		"Authorization": "Bearer s3cret",
+		"X-Env":         "PROD",
+	}
+	for name, value := range want {
+		if config.Headers[name] != value {
+			t.Errorf("expected %s: %s, got %q", name, value, config.Headers[name])
+		}
+	}
+	if config.OtelHeaders["x-api-key"] != "collector" {
+		t.Errorf("expected the collector header to be expanded, got %q", config.OtelHeaders["x-api-key"])
+	}
+
+	// A variable that is not set is an error rather than an empty credential
+	os.Unsetenv("PROD_TOKEN")
+	if _, err := LoadProfile("testapp", "prod"); err == nil || !strings.Contains(err.Error(), "environment variable PROD_TOKEN is not set") {
+		t.Errorf("expected an error for an unset variable, got %v", err)
+	}
+
+	write("headers:\n  X-Broken: '{{ env \"A\" '\n")
+	if _, err := LoadConfig("testapp"); err == nil || !strings.Contains(err.Error(), "invalid template in header X-Broken") {
+		t.Errorf("expected an error for an invalid template, got %v", err)
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+	if err := os.MkdirAll(filepath.Join(tmpDir, "testapp"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	content := `base_url: https://api.example.com
+token: top-level
+headers:
+  X-Org-Id: org-1
+  X-Team: team-1
+profiles:
+  staging:
+    base_url: https://staging.example.com
+    headers:
+      x-org-id: org-2
+  prod:
+    token: prod-token
+  sandbox:
+    server: sandbox
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "testapp", "config.yaml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadProfile("testapp", "staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Profile != "staging" || config.BaseURL != "https://staging.example.com" || config.Token != "top-level" {
+		t.Errorf("expected the profile's base URL over the top-level settings, got %+v", config)
+	}
+	if len(config.Headers) != 2 || config.Headers["x-org-id"] != "org-2" || config.Headers["X-Team"] != "team-1" {
+		t.Errorf("expected the profile's headers merged into the top-level ones, got %v", config.Headers)
+	}
+
+	// A profile's server replaces the top-level base URL
+	config, err = LoadProfile("testapp", "sandbox")
+	if err != nil || config.Server != "sandbox" || config.BaseURL != "" {
+		t.Errorf("expected the sandbox server without the top-level base URL, got %+v, %v", config, err)
+	}
+
+	// The environment selects a profile when the flag does not
+	t.Setenv("TESTAPP_PROFILE", "prod")
+	config, err = LoadConfig("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Profile != "prod" || config.Token != "prod-token" || config.BaseURL != "https://api.example.com" {
+		t.Errorf("expected the prod profile from the environment, got %+v", config)
+	}
+
+	// ...and environment settings override the profile
+	t.Setenv("TESTAPP_TOKEN", "from-env")
+	if config, err = LoadConfig("testapp"); err != nil || config.Token != "from-env" {
+		t.Errorf("expected the token from the environment, got %q, %v", config.Token, err)
+	}
+
+	_, err = LoadProfile("testapp", "dev")
+	if err == nil || !strings.Contains(err.Error(), `unknown profile "dev" (must be one of: prod, sandbox, staging)`) {
+		t.Errorf("expected an unknown profile error naming the profiles, got %v", err)
+	}
+}
@@ -0,0 +1,372 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is a config file opened for editing by the config get, set,
+// unset and list commands. Editing keeps the file's comments and key order.
+// Keys are dotted paths, e.g. base_url, headers.X-Org-Id or
+// profiles.staging.token.
+type ConfigFile struct {
+	Path string
+
+	doc    yaml.Node
+	prefix []byte // the comments of a file without settings, which yaml drops
+}
+
+// ConfigSetting is a setting in a config file, as listed by Settings
+type ConfigSetting struct {
+	Key   string
+	Value string
+}
+
+// OpenConfigFile opens the config file of appName for editing. A missing
+// file is created by Save.
+func OpenConfigFile(appName string) (*ConfigFile, error) {
+	path := getConfigPath(appName)
+	if path == "" {
+		var err error
+		if path, err = DefaultConfigPath(appName); err != nil {
+			return nil, fmt.Errorf("failed to determine config path: %w", err)
+		}
+	}
+	f := &ConfigFile{Path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &f.doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if f.doc.Kind == 0 {
+		f.prefix = data
+		f.doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if f.root() == nil {
+		return nil, fmt.Errorf("config file %s does not hold a mapping of settings", path)
+	}
+	return f, nil
+}
+
+// root returns the top-level mapping of the file
+func (f *ConfigFile) root() *yaml.Node {
+	if f.doc.Kind != yaml.DocumentNode || len(f.doc.Content) == 0 || f.doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	return f.doc.Content[0]
+}
+
+// Get returns the value of key, in YAML for a list or mapping
+func (f *ConfigFile) Get(key string) (string, error) {
+	_, value := f.find(configLookupPath(key))
+	if value == nil {
+		return "", fmt.Errorf("%s is not set in %s", key, f.Path)
+	}
+	return nodeString(value, false)
+}
+
+// Set sets key to value. The key must be a setting of the config file, and
+// the value must suit it: a string is taken as is, while other settings
+// take YAML, e.g. true, 10 or ["vault", "read"]. Parent keys are added as
+// needed.
+func (f *ConfigFile) Set(key, value string) error {
+	path, leaf, err := configKeyPath(key)
+	if err != nil {
+		return err
+	}
+
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if leaf.Kind() != reflect.String {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &doc); err != nil || len(doc.Content) == 0 {
+			return fmt.Errorf("invalid value for %s: %q is not YAML", key, value)
+		}
+		node = doc.Content[0]
+		if err := node.Decode(reflect.New(leaf).Interface()); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
+
+	mapping := f.root()
+	for i, segment := range path {
+		index := findKey(mapping, segment)
+		if i == len(path)-1 {
+			if index < 0 {
+				mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: segment.name}, node)
+			} else {
+				// Keep comments on the old value, e.g. one explaining it
+				node.HeadComment, node.LineComment = mapping.Content[index+1].HeadComment, mapping.Content[index+1].LineComment
+				mapping.Content[index+1] = node
+			}
+			return nil
+		}
+		if index < 0 {
+			child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: segment.name}, child)
+			mapping = child
+			continue
+		}
+		if child := mapping.Content[index+1]; child.Kind != yaml.MappingNode {
+			// An empty section, e.g. "profiles:" with nothing under it
+			if child.Kind != yaml.ScalarNode || child.Tag != "!!null" {
+				return fmt.Errorf("cannot set %s: %s is not a mapping", key, strings.Join(segmentNames(path[:i+1]), "."))
+			}
+			mapping.Content[index+1] = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		mapping = mapping.Content[index+1]
+	}
+	return nil
+}
+
+// Unset removes key from the file. Any key can be removed, so misspelt ones
+// can be cleaned up.
+func (f *ConfigFile) Unset(key string) error {
+	mapping, value := f.find(configLookupPath(key))
+	if value == nil {
+		return fmt.Errorf("%s is not set in %s", key, f.Path)
+	}
+	for i := 1; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i] == value {
+			mapping.Content = append(mapping.Content[:i-1], mapping.Content[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Settings returns every setting in the file, in file order, with lists in
+// flow style and credentials masked
+func (f *ConfigFile) Settings() ([]ConfigSetting, error) {
+	var settings []ConfigSetting
+	var walk func(prefix string, mapping *yaml.Node) error
+	walk = func(prefix string, mapping *yaml.Node) error {
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			name, value := mapping.Content[i].Value, mapping.Content[i+1]
+			key := prefix + name
+			if value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+				if err := walk(key+".", value); err != nil {
+					return err
+				}
+				continue
+			}
+			s, err := nodeString(value, true)
+			if err != nil {
+				return err
+			}
+			settings = append(settings, ConfigSetting{Key: key, Value: redactSetting(prefix, name, s)})
+		}
+		return nil
+	}
+	if err := walk("", f.root()); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// Save writes the file with 0600 permissions, since it may hold
+// credentials. The file is replaced in one step so an interrupted write
+// cannot lose settings; a symlinked file is written through the link.
+func (f *ConfigFile) Save() error {
+	var buf bytes.Buffer
+	buf.Write(f.prefix)
+	if len(f.root().Content) > 0 {
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&f.doc); err != nil {
+			return fmt.Errorf("failed to encode config: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("failed to encode config: %w", err)
+		}
+	}
+
+	path := f.Path
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	// CreateTemp uses 0600, but make the permissions explicit
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return fmt.Errorf("failed to set config file permissions: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// find returns the value of path and the mapping holding it, or nils
+func (f *ConfigFile) find(path []keySegment) (mapping, value *yaml.Node) {
+	mapping = f.root()
+	for i, segment := range path {
+		index := findKey(mapping, segment)
+		if index < 0 {
+			return nil, nil
+		}
+		value = mapping.Content[index+1]
+		if i == len(path)-1 {
+			return mapping, value
+		}
+		if value.Kind != yaml.MappingNode {
+			return nil, nil
+		}
+		mapping = value
+	}
+	return nil, nil
+}
+
+// keySegment is a part of a dotted config key. Header names and other
+// map keys of string settings match whatever their case, as they do when
+// the config is used.
+type keySegment struct {
+	name string
+	fold bool
+}
+
+func segmentNames(path []keySegment) []string {
+	names := make([]string, len(path))
+	for i, s := range path {
+		names[i] = s.name
+	}
+	return names
+}
+
+// findKey returns the index of segment's key in mapping, or -1
+func findKey(mapping *yaml.Node, segment keySegment) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		name := mapping.Content[i].Value
+		if name == segment.name || (segment.fold && strings.EqualFold(name, segment.name)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// configKeyPath checks key against the settings of Config and returns its
+// segments and the type of its value
+func configKeyPath(key string) ([]keySegment, reflect.Type, error) {
+	names := strings.Split(key, ".")
+	path := make([]keySegment, len(names))
+	t := reflect.TypeOf(Config{})
+	for i, name := range names {
+		if name == "" {
+			return nil, nil, fmt.Errorf("invalid config key %q", key)
+		}
+		path[i].name = name
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := yamlField(t, name)
+			if !ok {
+				if i == 0 {
+					return nil, nil, fmt.Errorf("unknown config key %q (must be one of: %s)", key, strings.Join(yamlFields(t), ", "))
+				}
+				return nil, nil, fmt.Errorf("unknown config key %q (keys under %s are: %s)", key, strings.Join(names[:i], "."), strings.Join(yamlFields(t), ", "))
+			}
+			t = field
+		case reflect.Map:
+			path[i].fold = t.Elem().Kind() != reflect.Struct
+			t = t.Elem()
+		default:
+			return nil, nil, fmt.Errorf("unknown config key %q (%s has no keys under it)", key, strings.Join(names[:i], "."))
+		}
+	}
+	return path, t, nil
+}
+
+// configLookupPath returns the segments of key for reading or removing it, which
+// also works for keys that are not settings
+func configLookupPath(key string) []keySegment {
+	if path, _, err := configKeyPath(key); err == nil {
+		return path
+	}
+	names := strings.Split(key, ".")
+	path := make([]keySegment, len(names))
+	for i, name := range names {
+		path[i].name = name
+	}
+	return path
+}
+
+// ConfigKeys returns the top-level settings of the config file
+func ConfigKeys() []string {
+	return yamlFields(reflect.TypeOf(Config{}))
+}
+
+// yamlField returns the type of the field of struct type t with YAML key name
+func yamlField(t reflect.Type, name string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ","); tag == name && tag != "-" {
+			return t.Field(i).Type, true
+		}
+	}
+	return nil, false
+}
+
+// yamlFields returns the sorted YAML keys of struct type t
+func yamlFields(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ","); tag != "" && tag != "-" {
+			names = append(names, tag)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nodeString returns a scalar's value, or the YAML of a list or mapping, on
+// one line when flow is set
+func nodeString(node *yaml.Node, flow bool) (string, error) {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	if flow {
+		copied := *node
+		copied.Style = yaml.FlowStyle
+		node = &copied
+	}
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode config value: %w", err)
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// redactSetting masks the value of a credential setting, or of a header
+// that carries one
+func redactSetting(prefix, name, value string) string {
+	for _, key := range SecretKeys {
+		if name == key {
+			return "***"
+		}
+	}
+	if strings.HasSuffix(prefix, "headers.") {
+		return redactHeader(name, value)
+	}
+	return value
+}
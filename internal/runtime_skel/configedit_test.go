@@ -0,0 +1,223 @@
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigFile_SetGetUnset(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	f, err := OpenConfigFile("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, kv := range [][2]string{
+		{"base_url", "https://api.example.com"},
+		{"read_only", "true"},
+		{"headers.X-Org-Id", "my-org"},
+		{"headers.x-org-id", "other-org"},
+		{"profiles.staging.token", "s3cret"},
+		{"exit_codes.404", "10"},
+		{"auth_command", `["vault", "read", "-field=token"]`},
+		{"user_agent", "true"},
+	} {
+		if err := f.Set(kv[0], kv[1]); err != nil {
+			t.Fatalf("Set(%q, %q): unexpected error: %v", kv[0], kv[1], err)
+		}
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected 0600 permissions, got %o", info.Mode().Perm())
+	}
+
+	// The file is loaded as the config
+	config, err := LoadProfile("testapp", "staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.BaseURL != "https://api.example.com" || !config.ReadOnly || config.Token != "s3cret" ||
+		config.Headers["X-Org-Id"] != "other-org" || len(config.Headers) != 1 ||
+		config.ExitCodes["404"] != 10 || len(config.AuthCommand) != 3 || config.UserAgent != "true" {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	f, err = OpenConfigFile("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, err := f.Get("profiles.staging.token"); err != nil || value != "s3cret" {
+		t.Errorf("Get = %q, %v; want s3cret", value, err)
+	}
+	if value, err := f.Get("headers.X-ORG-ID"); err != nil || value != "other-org" {
+		t.Errorf("expected header names to match any case, got %q, %v", value, err)
+	}
+	if value, err := f.Get("auth_command"); err != nil || value != `["vault", "read", "-field=token"]` {
+		t.Errorf("expected a list in YAML, got %q, %v", value, err)
+	}
+
+	if err := f.Unset("read_only"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := f.Get("read_only"); err == nil {
+		t.Error("expected an unset key to be missing")
+	}
+	if err := f.Unset("read_only"); err == nil {
+		t.Error("expected unsetting a missing key to fail")
+	}
+}
+
+func TestConfigFile_SetRejectsInvalid(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	f, err := OpenConfigFile("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		key, value, wantErr string
+	}{
+		{"base_ulr", "x", `unknown config key "base_ulr" (must be one of:`},
+		{"profiles.staging.read_only", "true", "keys under profiles.staging are:"},
+		{"base_url.path", "x", "base_url has no keys under it"},
+		{"headers.", "x", "invalid config key"},
+		{"read_only", "maybe", "invalid value for read_only"},
+		{"exit_codes.404", "ten", "invalid value for exit_codes.404"},
+	}
+	for _, tt := range tests {
+		err := f.Set(tt.key, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Set(%q, %q): expected an error containing %q, got %v", tt.key, tt.value, tt.wantErr, err)
+		}
+	}
+}
+
+func TestConfigFile_KeepsComments(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	path, err := InitConfig("testapp", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f, err := OpenConfigFile("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.Set("base_url", "https://api.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# Configuration for testapp\n") || !strings.HasSuffix(string(data), "\nbase_url: https://api.example.com\n") {
+		t.Errorf("expected the starter comments followed by the setting, got\n%s", data)
+	}
+
+	// Comments next to settings survive later edits
+	if err := os.WriteFile(path, []byte("# The API\nbase_url: https://old.example.com # prod\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f, err = OpenConfigFile("testapp"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.Set("base_url", "https://api.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ = os.ReadFile(path); string(data) != "# The API\nbase_url: https://api.example.com # prod\n" {
+		t.Errorf("expected the comments to be kept, got\n%s", data)
+	}
+}
+
+func TestConfigFile_SaveFixesPermissions(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	path := filepath.Join(dir, "testapp", "config.yml")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("base_url: https://api.example.com\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := OpenConfigFile("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.Path != path {
+		t.Errorf("expected the existing config.yml to be edited, got %s", f.Path)
+	}
+	if err := f.Set("cache", "true"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected 0600 permissions, got %v, %v", info.Mode(), err)
+	}
+}
+
+func TestConfigFile_Settings(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	path := filepath.Join(dir, "testapp", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content := `base_url: https://api.example.com
+token: s3cret
+headers:
+  Authorization: Bearer abc
+  X-Org-Id: my-org
+profiles:
+  staging:
+    client_secret: hush
+    auth_command: [vault, read]
+  empty: {}
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := OpenConfigFile("testapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settings, err := f.Settings()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, s := range settings {
+		got = append(got, s.Key+"="+s.Value)
+	}
+	want := []string{
+		"base_url=https://api.example.com",
+		"token=***",
+		"headers.Authorization=Bearer ***",
+		"headers.X-Org-Id=my-org",
+		"profiles.staging.client_secret=***",
+		"profiles.staging.auth_command=[vault, read]",
+		"profiles.empty={}",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,108 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sessionServer sets a session cookie on POST /login, requires it elsewhere,
+// and expires it on POST /logout
+func sessionServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/", HttpOnly: true})
+			w.WriteHeader(http.StatusNoContent)
+		case "/logout":
+			http.SetCookie(w, &http.Cookie{Name: "session", Path: "/", MaxAge: -1})
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			if c, err := r.Cookie("session"); err != nil || c.Value != "s1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+}
+
+// invoke sends a request with a new runtime and jar on path, as a separate
+// invocation of a CLI would
+func invoke(t *testing.T, serverURL, jarPath, method, path string) error {
+	t.Helper()
+	rt := New(serverURL, 5*time.Second)
+	rt.Output = &bytes.Buffer{}
+	rt.ErrOutput = &bytes.Buffer{}
+	rt.Use((&CookieJar{Path: jarPath}).Middleware())
+	return rt.Do(context.Background(), NewRequest(method, path))
+}
+
+func TestCookieJar_PersistsSession(t *testing.T) {
+	server := sessionServer()
+	defer server.Close()
+	jarPath := filepath.Join(t.TempDir(), "cookies.json")
+
+	if err := invoke(t, server.URL, jarPath, "GET", "/me"); err == nil {
+		t.Fatal("expected the request to fail before logging in")
+	}
+	if err := invoke(t, server.URL, jarPath, "POST", "/login"); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if info, err := os.Stat(jarPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the jar to be saved with 0600 permissions, got %v", err)
+	}
+	if err := invoke(t, server.URL, jarPath, "GET", "/me"); err != nil {
+		t.Errorf("expected the session cookie from the earlier invocation, got %v", err)
+	}
+
+	if err := invoke(t, server.URL, jarPath, "POST", "/logout"); err != nil {
+		t.Fatalf("logout failed: %v", err)
+	}
+	if err := invoke(t, server.URL, jarPath, "GET", "/me"); err == nil {
+		t.Error("expected the expired cookie to be removed from the jar")
+	}
+}
+
+func TestCookieJar_OnlySendsMatchingCookies(t *testing.T) {
+	server := sessionServer()
+	defer server.Close()
+	jarPath := filepath.Join(t.TempDir(), "cookies.json")
+
+	if err := invoke(t, server.URL, jarPath, "POST", "/login"); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	other := sessionServer()
+	defer other.Close()
+	// 127.0.0.1 on another port is the same host to cookies, so use the
+	// name localhost for a different one
+	otherURL := "http://localhost:" + other.URL[len("http://127.0.0.1:"):]
+	if err := invoke(t, otherURL, jarPath, "GET", "/me"); err == nil {
+		t.Error("expected the cookie not to be sent to another host")
+	}
+}
+
+func TestCookieJar_Clear(t *testing.T) {
+	server := sessionServer()
+	defer server.Close()
+	jar := &CookieJar{Path: filepath.Join(t.TempDir(), "cookies.json")}
+
+	if err := invoke(t, server.URL, jar.Path, "POST", "/login"); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if err := jar.Clear(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(jar.Path); !os.IsNotExist(err) {
+		t.Errorf("expected the jar to be removed, got %v", err)
+	}
+	if err := jar.Clear(); err != nil {
+		t.Errorf("expected clearing an empty jar to succeed, got %v", err)
+	}
+}
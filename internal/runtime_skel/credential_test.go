@@ -0,0 +1,60 @@
+package runtime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunAuthCommand(t *testing.T) {
+	credential, err := RunAuthCommand(context.Background(), []string{"sh", "-c", "printf '  s3cret\\n'"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if credential != "s3cret" {
+		t.Errorf("expected the trimmed output, got %q", credential)
+	}
+}
+
+func TestRunAuthCommand_Errors(t *testing.T) {
+	tests := []struct {
+		argv []string
+		want string
+	}{
+		{nil, "auth_command is empty"},
+		{[]string{"sh", "-c", "exit 3"}, "auth_command sh failed: exit status 3"},
+		{[]string{"sh", "-c", "echo"}, "auth_command sh printed no credential"},
+		{[]string{filepath.Join(os.TempDir(), "no-such-helper")}, "auth_command " + filepath.Join(os.TempDir(), "no-such-helper") + " failed"},
+	}
+
+	for _, tt := range tests {
+		_, err := RunAuthCommand(context.Background(), tt.argv)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("RunAuthCommand(%q) = %v, want %q", tt.argv, err, tt.want)
+		}
+	}
+}
+
+func TestLoadConfig_AuthCommand(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	dir := filepath.Join(configHome, "helperapp")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	content := `auth_command: ["vault", "read", "-field=token", "secret/api"]`
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig("helperapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(config.AuthCommand, " ") != "vault read -field=token secret/api" {
+		t.Errorf("unexpected auth_command: %q", config.AuthCommand)
+	}
+}
@@ -0,0 +1,156 @@
+package runtime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCredentialsFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	f, err := NewCredentialsFile("credapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := f.Get("token"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound before anything is stored, got %v", err)
+	}
+	if err := f.Set("token", "s3cret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.Set("staging:token", "staging-s3cret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, err := f.Get("staging:token"); err != nil || value != "staging-s3cret" {
+		t.Errorf("Get = %q, %v; want staging-s3cret", value, err)
+	}
+
+	info, err := os.Stat(f.Path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the file with 0600 permissions, got %v", err)
+	}
+
+	if err := f.Delete("token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := f.Get("token"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("expected a deleted secret to be gone, got %v", err)
+	}
+	if err := f.Delete("token"); err != nil {
+		t.Errorf("expected deleting a missing secret to succeed, got %v", err)
+	}
+
+	// A file others can read is refused
+	if err := os.Chmod(f.Path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Get("staging:token"); err == nil || !strings.Contains(err.Error(), "accessible by other users") {
+		t.Errorf("expected insecure permissions to be refused, got %v", err)
+	}
+}
+
+func TestLoadConfig_CredentialsFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if err := os.MkdirAll(filepath.Join(dir, "credapp"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	config := "credential_store: credentials_file\nprofiles:\n  staging:\n    base_url: https://staging.example.com\n"
+	if err := os.WriteFile(filepath.Join(dir, "credapp", "config.yaml"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := ConfiguredSecretStore("credapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := store.(*CredentialsFile); !ok {
+		t.Fatalf("expected the credentials file, got %T", store)
+	}
+	if err := store.Set("token", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("staging:client_secret", "hush"); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadProfile("credapp", "staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.Token != "s3cret" || loaded.ClientSecret != "hush" {
+		t.Errorf("expected secrets from the credentials file, got token %q and client secret %q", loaded.Token, loaded.ClientSecret)
+	}
+}
+
+func TestSecretSetting(t *testing.T) {
+	tests := []struct {
+		key, want string
+		ok        bool
+	}{
+		{"token", "token", true},
+		{"profiles.staging.client_secret", "staging:client_secret", true},
+		{"base_url", "", false},
+		{"profiles.staging.base_url", "", false},
+		{"headers.token", "", false},
+		{"profiles..token", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := SecretSetting(tt.key)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("SecretSetting(%q) = %q, %v; want %q, %v", tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFixPermissions(t *testing.T) {
+	configHome, cacheHome := t.TempDir(), t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+
+	configDir := filepath.Join(configHome, "permapp")
+	responses := filepath.Join(cacheHome, "permapp", "responses")
+	for _, dir := range []string{configDir, responses} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]os.FileMode{
+		filepath.Join(configDir, "config.yaml"):      0644,
+		filepath.Join(configDir, "credentials.yaml"): 0600,
+		filepath.Join(configDir, "token.json"):       0666,
+		filepath.Join(responses, "abc.json"):         0644,
+	}
+	for path, mode := range files {
+		if err := os.WriteFile(path, []byte("{}"), mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fixed, err := FixPermissions("permapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Both directories under the cache, the config directory and three files
+	if len(fixed) != 6 {
+		t.Errorf("expected 6 paths to be fixed, got %d:\n%s", len(fixed), strings.Join(fixed, "\n"))
+	}
+	for path := range files {
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("expected %s to have 0600 permissions, got %v", path, info.Mode())
+		}
+	}
+	if info, err := os.Stat(configDir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("expected the config directory to have 0700 permissions, got %v", info.Mode())
+	}
+
+	if fixed, err := FixPermissions("permapp"); err != nil || len(fixed) != 0 {
+		t.Errorf("expected nothing left to fix, got %v, %v", fixed, err)
+	}
+}
@@ -0,0 +1,87 @@
+package runtime
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// localDateTime is a date and time without a zone, read as local time
+const localDateTime = "2006-01-02T15:04:05"
+
+// ParseDateTime parses a value for a date-time parameter and returns it in
+// RFC 3339, as the API expects. Besides RFC 3339, it accepts a date or a
+// date and time without a zone, in local time, and a time relative to now,
+// like "now", "now-24h" or "now+7d".
+func ParseDateTime(value string) (string, error) {
+	// Relative times are sent to the second, as most APIs expect
+	t, err := parseTime(value, time.Now().Truncate(time.Second))
+	if err != nil {
+		return "", fmt.Errorf("invalid date-time %q (must be RFC 3339 like 2024-01-01T12:00:00Z, a date like 2024-01-01, or relative like now-24h)", value)
+	}
+	return t.UTC().Format(time.RFC3339Nano), nil
+}
+
+// ParseDate parses a value for a date parameter and returns it as
+// YYYY-MM-DD, as the API expects. It accepts the same values as
+// ParseDateTime; a time is reduced to its date in its own zone, which for
+// relative times is local.
+func ParseDate(value string) (string, error) {
+	t, err := parseTime(value, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q (must be a date like 2024-01-01, or relative like now-7d)", value)
+	}
+	return t.Format(time.DateOnly), nil
+}
+
+// parseTime parses the values ParseDateTime accepts, with relative times
+// counted from now
+func parseTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if rest, ok := strings.CutPrefix(value, "now"); ok {
+		if rest == "" {
+			return now, nil
+		}
+		if rest[0] != '+' && rest[0] != '-' {
+			return time.Time{}, fmt.Errorf("invalid relative time %q", value)
+		}
+		d, err := parseRelative(rest[1:])
+		if err != nil {
+			return time.Time{}, err
+		}
+		if rest[0] == '-' {
+			d = -d
+		}
+		return now.Add(d), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{localDateTime, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// parseRelative parses the offset of a relative time: a Go duration like
+// "1h30m", or a number of days ("7d") or weeks ("2w")
+func parseRelative(s string) (time.Duration, error) {
+	for unit, length := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, unit); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid offset %q", s)
+			}
+			return time.Duration(count) * length, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid offset %q", s)
+	}
+	return d, nil
+}
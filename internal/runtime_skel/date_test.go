@@ -0,0 +1,61 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"now", now},
+		{"now-24h", now.Add(-24 * time.Hour)},
+		{"now+1h30m", now.Add(90 * time.Minute)},
+		{"now-7d", now.AddDate(0, 0, -7)},
+		{"now-2w", now.AddDate(0, 0, -14)},
+		{"2024-01-01T08:30:00Z", time.Date(2024, 1, 1, 8, 30, 0, 0, time.UTC)},
+		{"2024-01-01T08:30:00.5+02:00", time.Date(2024, 1, 1, 6, 30, 0, 5e8, time.UTC)},
+		{"2024-01-01", time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)},
+		{"2024-01-01T08:30:00", time.Date(2024, 1, 1, 8, 30, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTime(tt.value, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "now-", "now24h", "now-1x", "now--1h", "now-1.5d", "2024-13-01", "01/02/2024"} {
+		if _, err := parseTime(value, now); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	got, err := ParseDateTime("2024-01-01T08:30:00+02:00")
+	if err != nil || got != "2024-01-01T06:30:00Z" {
+		t.Errorf("expected the time in UTC, got %q, %v", got, err)
+	}
+	if _, err := ParseDateTime("tomorrow"); err == nil || err.Error() != `invalid date-time "tomorrow" (must be RFC 3339 like 2024-01-01T12:00:00Z, a date like 2024-01-01, or relative like now-24h)` {
+		t.Errorf("expected an error naming the accepted values, got %v", err)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	for value, want := range map[string]string{
+		"2024-01-01":                "2024-01-01",
+		"2024-01-01T23:30:00-05:00": "2024-01-01",
+	} {
+		if got, err := ParseDate(value); err != nil || got != want {
+			t.Errorf("ParseDate(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+	if _, err := ParseDate("2024-02-30"); err == nil {
+		t.Error("expected an invalid date to be rejected")
+	}
+}
@@ -0,0 +1,153 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLoggedBody is the largest request body DebugLog prints in full
+const maxLoggedBody = 64 << 10
+
+// DebugLog writes each request and response to Out as it is sent, in the
+// style of curl -v: the method, URL and headers of the request, and the
+// status and headers of the response. Credentials in headers and query
+// parameters are masked.
+type DebugLog struct {
+	Out    io.Writer
+	Bodies bool // also log request bodies
+}
+
+// transport logs requests and responses around next
+func (d *DebugLog) transport(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		d.logRequest(req)
+
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			fmt.Fprintf(d.Out, "* %s %s failed after %s: %v\n", req.Method, redactQuery(req.URL), time.Since(start).Round(time.Millisecond), err)
+			return resp, err
+		}
+
+		fmt.Fprintf(d.Out, "< %s %s (%s)\n", resp.Proto, resp.Status, time.Since(start).Round(time.Millisecond))
+		writeHeaders(d.Out, "< ", resp.Header)
+		fmt.Fprintln(d.Out, "<")
+		return resp, nil
+	})
+}
+
+func (d *DebugLog) logRequest(req *http.Request) {
+	fmt.Fprintf(d.Out, "> %s %s\n", req.Method, redactQuery(req.URL))
+	header := req.Header.Clone()
+	if header.Get("Host") == "" && req.Host != "" {
+		header.Set("Host", req.Host)
+	}
+	writeHeaders(d.Out, "> ", header)
+	fmt.Fprintln(d.Out, ">")
+	if hints, ok := hintsOf(req); ok {
+		fmt.Fprintf(d.Out, "* %s\n", hints)
+	}
+
+	if !d.Bodies || req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+	if req.GetBody == nil {
+		fmt.Fprintln(d.Out, "* (request body is streamed and not shown)")
+		return
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		fmt.Fprintf(d.Out, "* (request body not shown: %v)\n", err)
+		return
+	}
+	defer rc.Close()
+	body, err := io.ReadAll(io.LimitReader(rc, maxLoggedBody+1))
+	if err != nil {
+		fmt.Fprintf(d.Out, "* (request body not shown: %v)\n", err)
+		return
+	}
+
+	switch {
+	case !utf8.Valid(body):
+		fmt.Fprintln(d.Out, "* (binary request body not shown)")
+	case len(body) > maxLoggedBody:
+		fmt.Fprintf(d.Out, "%s\n* (request body truncated at %d bytes)\n", body[:maxLoggedBody], maxLoggedBody)
+	default:
+		fmt.Fprintf(d.Out, "%s\n", strings.TrimRight(string(body), "\n"))
+	}
+}
+
+// writeHeaders writes header sorted by name, one per line after prefix,
+// masking credentials
+func writeHeaders(w io.Writer, prefix string, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(w, "%s%s: %s\n", prefix, name, redactHeader(name, value))
+		}
+	}
+}
+
+// redactHeader masks the value of headers that carry credentials. The
+// scheme of an Authorization header is kept, so "Bearer abc" becomes
+// "Bearer ***".
+func redactHeader(name, value string) string {
+	lower := strings.ToLower(name)
+	switch lower {
+	case "authorization", "proxy-authorization":
+		if scheme, _, ok := strings.Cut(value, " "); ok {
+			return scheme + " ***"
+		}
+		return "***"
+	case "cookie", "set-cookie":
+		return "***"
+	}
+	if isSensitiveName(lower) {
+		return "***"
+	}
+	return value
+}
+
+// redactQuery returns u with the values of credential query parameters
+// masked
+func redactQuery(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+	pairs := strings.Split(u.RawQuery, "&")
+	for i, pair := range pairs {
+		raw, _, _ := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(raw)
+		if err != nil {
+			name = raw
+		}
+		if isSensitiveName(strings.ToLower(name)) {
+			pairs[i] = raw + "=***"
+		}
+	}
+	redacted := *u
+	redacted.RawQuery = strings.Join(pairs, "&")
+	return redacted.String()
+}
+
+// isSensitiveName reports whether a lower-case header or parameter name
+// looks like it carries a credential, e.g. X-Api-Key or access_token
+func isSensitiveName(name string) bool {
+	name = strings.NewReplacer("-", "", "_", "").Replace(name)
+	for _, word := range []string{"apikey", "token", "secret", "password", "signature", "credential"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return name == "key"
+}
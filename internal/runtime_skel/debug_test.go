@@ -0,0 +1,128 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 1}`))
+	}))
+	defer server.Close()
+
+	var log bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = io.Discard
+	rt.Debug = &DebugLog{Out: &log, Bodies: true}
+	rt.AddHeader("Authorization", "Bearer s3cret-token")
+	rt.AddHeader("X-Api-Key", "s3cret-key")
+
+	req := NewRequest("POST", "/things")
+	req.SetQueryParam("api_key", "s3cret-query")
+	req.SetQueryParam("limit", "10")
+	req.SetBody([]byte(`{"name": "widget"}`))
+	if err := rt.Do(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := log.String()
+	for _, want := range []string{
+		"> POST " + server.URL + "/things?api_key=***&limit=10\n",
+		"> Authorization: Bearer ***\n",
+		"> X-Api-Key: ***\n",
+		"> Content-Type: application/json\n",
+		"{\"name\": \"widget\"}\n",
+		"< HTTP/1.1 201 Created (",
+		"< Set-Cookie: ***\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected log to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "s3cret") {
+		t.Errorf("expected credentials to be masked, got:\n%s", out)
+	}
+}
+
+func TestDebugLog_Hints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var log bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = io.Discard
+	rt.Debug = &DebugLog{Out: &log}
+
+	req := NewRequest("POST", "/reports")
+	req.SetHints(2.5, 1500*time.Millisecond)
+	if err := rt.Do(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := ">\n* rate cost 2.5, expected latency 1.5s\n"; !strings.Contains(log.String(), want) {
+		t.Errorf("expected log to contain %q, got:\n%s", want, log.String())
+	}
+
+	log.Reset()
+	if err := rt.Do(context.Background(), NewRequest("POST", "/reports")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(log.String(), "* rate cost") {
+		t.Errorf("expected no hints for a request without them, got:\n%s", log.String())
+	}
+}
+
+func TestDebugLog_NoBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var log bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = io.Discard
+	rt.Debug = &DebugLog{Out: &log}
+
+	req := NewRequest("PUT", "/things/1")
+	req.SetBody([]byte(`{"name": "widget"}`))
+	if err := rt.Do(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(log.String(), "> PUT ") || strings.Contains(log.String(), "widget") {
+		t.Errorf("expected the request without its body, got:\n%s", log.String())
+	}
+}
+
+func TestRedactHeader(t *testing.T) {
+	tests := []struct {
+		name, value, want string
+	}{
+		{"Authorization", "Basic dXNlcjpwYXNz", "Basic ***"},
+		{"Authorization", "opaque", "***"},
+		{"Proxy-Authorization", "Bearer abc", "Bearer ***"},
+		{"Cookie", "a=b", "***"},
+		{"X-Auth-Token", "abc", "***"},
+		{"Api-Key", "abc", "***"},
+		{"X-Signature", "abc", "***"},
+		{"Accept", "application/json", "application/json"},
+		{"X-Request-Id", "abc", "abc"},
+	}
+
+	for _, tt := range tests {
+		if got := redactHeader(tt.name, tt.value); got != tt.want {
+			t.Errorf("redactHeader(%q, %q) = %q, want %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+)
+
+const describeSpec = `openapi: 3.0.3
+info:
+  title: Test
+  version: "1.0"
+paths:
+  /tasks/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+      - name: X-Trace
+        in: header
+        schema:
+          type: string
+    put:
+      summary: Update a task
+      parameters:
+        - name: X-Trace
+          in: header
+          required: true
+          description: Trace identifier
+          schema:
+            type: string
+        - $ref: '#/components/parameters/Tags'
+      requestBody:
+        required: true
+        content:
+          text/plain:
+            schema:
+              type: string
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Task'
+      responses:
+        "200":
+          description: Updated
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Task'
+        "404":
+          description: Not found
+components:
+  parameters:
+    Tags:
+      name: tags
+      in: query
+      schema:
+        type: array
+        items:
+          type: string
+  schemas:
+    Task:
+      type: object
+      required: [title]
+      properties:
+        title:
+          type: string
+        parent:
+          $ref: '#/components/schemas/Task'
+`
+
+func TestDescribeOperation(t *testing.T) {
+	op, err := DescribeOperation([]byte(describeSpec), "PUT", "/tasks/{id}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if op.Method != "PUT" || op.Summary != "Update a task" {
+		t.Errorf("unexpected operation: %+v", op)
+	}
+
+	want := []Parameter{
+		{Name: "id", In: "path", Type: "string", Required: true},
+		{Name: "X-Trace", In: "header", Type: "string", Required: true, Description: "Trace identifier"},
+		{Name: "tags", In: "query", Type: "array of string"},
+	}
+	if len(op.Parameters) != len(want) {
+		t.Fatalf("expected %d parameters, got %+v", len(want), op.Parameters)
+	}
+	for i := range want {
+		if op.Parameters[i] != want[i] {
+			t.Errorf("parameter %d = %+v, want %+v", i, op.Parameters[i], want[i])
+		}
+	}
+
+	body := op.RequestBody
+	if body == nil || body.ContentType != "application/json" || !body.Required {
+		t.Fatalf("unexpected request body: %+v", body)
+	}
+	// The recursive parent reference is left unresolved
+	wantSchema := `{"type":"object","required":["title"],"properties":{"title":{"type":"string"},"parent":{"$ref":"#/components/schemas/Task"}}}`
+	if string(body.Schema) != wantSchema {
+		t.Errorf("schema = %s, want %s", body.Schema, wantSchema)
+	}
+
+	if len(op.Responses) != 2 || op.Responses[0].Status != "200" || op.Responses[0].ContentTypes[0] != "application/json" ||
+		op.Responses[1].Status != "404" || op.Responses[1].ContentTypes != nil {
+		t.Errorf("unexpected responses: %+v", op.Responses)
+	}
+}
+
+func TestDescribeOperation_Swagger2(t *testing.T) {
+	doc := `{
+  "swagger": "2.0",
+  "info": {"title": "Test", "version": "1.0"},
+  "consumes": ["application/json"],
+  "paths": {
+    "/tasks": {
+      "post": {
+        "parameters": [
+          {"name": "body", "in": "body", "required": true, "schema": {"type": "object"}},
+          {"name": "dry_run", "in": "query", "type": "boolean"}
+        ],
+        "responses": {"201": {"description": "Created", "schema": {"type": "object"}}}
+      }
+    }
+  }
+}`
+
+	op, err := DescribeOperation([]byte(doc), "post", "/tasks")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(op.Parameters) != 1 || op.Parameters[0].Type != "boolean" {
+		t.Errorf("unexpected parameters: %+v", op.Parameters)
+	}
+	if op.RequestBody == nil || string(op.RequestBody.Schema) != `{"type":"object"}` || !op.RequestBody.Required {
+		t.Errorf("unexpected request body: %+v", op.RequestBody)
+	}
+	if len(op.Responses) != 1 || op.Responses[0].ContentTypes[0] != "application/json" {
+		t.Errorf("unexpected responses: %+v", op.Responses)
+	}
+}
+
+func TestDescribeOperation_NotFound(t *testing.T) {
+	_, err := DescribeOperation([]byte(describeSpec), "DELETE", "/tasks/{id}")
+	if err == nil || !strings.Contains(err.Error(), "DELETE /tasks/{id} not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestOperation_WriteText(t *testing.T) {
+	op, err := DescribeOperation([]byte(describeSpec), "PUT", "/tasks/{id}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out strings.Builder
+	if err := op.WriteText(&out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"PUT /tasks/{id}\nUpdate a task\n",
+		"  NAME     IN      TYPE             REQUIRED  DESCRIPTION\n",
+		"  X-Trace  header  string           yes       Trace identifier\n",
+		"Request body (application/json, required):\n  type: object\n",
+		"  properties:\n    title:\n      type: string\n",
+		"Responses:\n  200  Updated    application/json\n  404  Not found\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// noPollWait makes Poll retry immediately for the duration of the test
+func noPollWait(t *testing.T) {
+	t.Helper()
+	orig := pollWait
+	pollWait = func(ctx context.Context, d time.Duration) error { return ctx.Err() }
+	t.Cleanup(func() { pollWait = orig })
+}
+
+// newDeviceServer returns an authorization server that approves the device
+// code after the given number of polls, answering earlier polls with
+// pending or, when errorCode is set, that error
+func newDeviceServer(t *testing.T, pendingPolls int, errorCode string) *httptest.Server {
+	t.Helper()
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "cli" || r.FormValue("scope") != "read" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid_client"}`)
+			return
+		}
+		fmt.Fprint(w, `{"device_code": "dev-1", "user_code": "ABCD-EFGH", "verification_uri": "https://example.com/activate", "expires_in": 600, "interval": 5}`)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != deviceCodeGrantType || r.FormValue("device_code") != "dev-1" || r.FormValue("client_id") != "cli" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid_grant"}`)
+			return
+		}
+		polls++
+		if polls <= pendingPolls {
+			w.WriteHeader(http.StatusBadRequest)
+			code := "authorization_pending"
+			if errorCode != "" {
+				code = errorCode
+			}
+			fmt.Fprintf(w, `{"error": %q}`, code)
+			return
+		}
+		fmt.Fprint(w, `{"access_token": "device-token", "token_type": "Bearer", "refresh_token": "refresh-1", "expires_in": 3600}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDeviceFlow_Login(t *testing.T) {
+	noPollWait(t)
+	server := newDeviceServer(t, 2, "")
+
+	flow := &DeviceFlow{DeviceAuthorizationURL: server.URL + "/device", TokenURL: server.URL + "/token", ClientID: "cli", Scopes: []string{"read"}}
+	auth, err := flow.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if auth.UserCode != "ABCD-EFGH" || auth.VerificationURI != "https://example.com/activate" || auth.Interval != 5 {
+		t.Errorf("unexpected device authorization: %+v", auth)
+	}
+
+	token, err := flow.Poll(context.Background(), auth)
+	if err != nil {
+		t.Fatalf("Poll failed: %v", err)
+	}
+	if token.AccessToken != "device-token" || token.RefreshToken != "refresh-1" || token.Expiry.IsZero() {
+		t.Errorf("unexpected token: %+v", token)
+	}
+}
+
+func TestDeviceFlow_StartError(t *testing.T) {
+	server := newDeviceServer(t, 0, "")
+
+	flow := &DeviceFlow{DeviceAuthorizationURL: server.URL + "/device", TokenURL: server.URL + "/token", ClientID: "other"}
+	_, err := flow.Start(context.Background())
+
+	var oauthErr *OAuth2Error
+	if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_client" {
+		t.Errorf("expected an invalid_client error, got %v", err)
+	}
+}
+
+func TestDeviceFlow_PollErrors(t *testing.T) {
+	noPollWait(t)
+
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"access_denied", "the login was denied"},
+		{"expired_token", ErrDeviceCodeExpired.Error()},
+		{"invalid_grant", "oauth2: invalid_grant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			server := newDeviceServer(t, 1, tt.code)
+			flow := &DeviceFlow{TokenURL: server.URL + "/token", ClientID: "cli"}
+			_, err := flow.Poll(context.Background(), &DeviceAuthorization{DeviceCode: "dev-1", Interval: 1})
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestDeviceFlow_PollExpires(t *testing.T) {
+	noPollWait(t)
+
+	// The next poll would happen after the code expires
+	flow := &DeviceFlow{TokenURL: "http://127.0.0.1:0/token", ClientID: "cli"}
+	_, err := flow.Poll(context.Background(), &DeviceAuthorization{DeviceCode: "dev-1", ExpiresIn: 5, Interval: 10})
+	if !errors.Is(err, ErrDeviceCodeExpired) {
+		t.Errorf("expected ErrDeviceCodeExpired, got %v", err)
+	}
+}
+
+func TestLoginToken(t *testing.T) {
+	var seen string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get("Authorization")
+	}))
+	defer api.Close()
+
+	store := &TokenCache{Path: filepath.Join(t.TempDir(), "token.json")}
+	client := &http.Client{Transport: (&LoginToken{Store: store}).Middleware()(http.DefaultTransport)}
+	get := func(header string) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, api.URL, nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		return seen
+	}
+
+	if got := get(""); got != "" {
+		t.Errorf("expected no Authorization without a stored token, got %q", got)
+	}
+
+	if err := store.Save(&Token{AccessToken: "stored", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := get(""); got != "Bearer stored" {
+		t.Errorf("expected the stored token, got %q", got)
+	}
+	if got := get("Basic abc"); got != "Basic abc" {
+		t.Errorf("expected the explicit header to be kept, got %q", got)
+	}
+
+	if err := store.Save(&Token{AccessToken: "stored", Expiry: time.Now().Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := get(""); got != "" {
+		t.Errorf("expected an expired token not to be sent, got %q", got)
+	}
+}
+
+func TestLoginToken_Refresh(t *testing.T) {
+	var refreshes int
+	var refreshErr string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "refresh-1" || r.FormValue("client_id") != "cli" || refreshErr != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error": %q}`, refreshErr)
+			return
+		}
+		refreshes++
+		fmt.Fprintf(w, `{"access_token": "refreshed-%d", "expires_in": 3600}`, refreshes)
+	})
+	var seen []string
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") == "Bearer revoked" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	store := &TokenCache{Path: filepath.Join(t.TempDir(), "token.json")}
+	login := &LoginToken{Store: store, Flow: &DeviceFlow{TokenURL: server.URL + "/token", ClientID: "cli"}, LoginCommand: "myapp auth login"}
+	client := &http.Client{Transport: login.Middleware()(http.DefaultTransport)}
+	get := func() error {
+		t.Helper()
+		seen = nil
+		resp, err := client.Get(server.URL + "/api")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// An expired token is refreshed before the request, and stored with the
+	// refresh token kept
+	if err := store.Save(&Token{AccessToken: "expired", RefreshToken: "refresh-1", Expiry: time.Now().Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen) != 1 || seen[0] != "Bearer refreshed-1" {
+		t.Errorf("expected the refreshed token to be sent, got %q", seen)
+	}
+	stored, _ := store.Load()
+	if stored.AccessToken != "refreshed-1" || stored.RefreshToken != "refresh-1" || !stored.Valid(time.Now()) {
+		t.Errorf("expected the refreshed token to be stored, got %+v", stored)
+	}
+
+	// A token the API rejects is refreshed and the request retried
+	if err := store.Save(&Token{AccessToken: "revoked", RefreshToken: "refresh-1", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen) != 2 || seen[1] != "Bearer refreshed-2" {
+		t.Errorf("expected a retry with the refreshed token, got %q", seen)
+	}
+
+	// When the refresh fails, the user is told to log in again
+	refreshErr = "invalid_grant"
+	if err := store.Save(&Token{AccessToken: "expired", RefreshToken: "refresh-1", Expiry: time.Now().Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	err := get()
+	if err == nil || !strings.Contains(err.Error(), "could not be refreshed (oauth2: invalid_grant); run 'myapp auth login' to log in again") {
+		t.Errorf("expected a prompt to log in again, got %v", err)
+	}
+	if len(seen) != 0 {
+		t.Errorf("expected no request to be sent, got %q", seen)
+	}
+}
@@ -0,0 +1,124 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document formats accepted by ConvertDocument
+const (
+	DocumentJSON = "json"
+	DocumentYAML = "yaml"
+)
+
+// ConvertDocument re-encodes a JSON or YAML document as format, preserving
+// the order of object keys
+func ConvertDocument(doc []byte, format string) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(doc, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+
+	switch format {
+	case DocumentJSON:
+		var buf bytes.Buffer
+		if err := writeJSONNode(&buf, &node); err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+		return out.Bytes(), nil
+
+	case DocumentYAML:
+		// JSON input parses as flow style; render everything block style
+		resetStyle(&node)
+		var out bytes.Buffer
+		enc := yaml.NewEncoder(&out)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return nil, fmt.Errorf("failed to encode YAML: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+
+	default:
+		return nil, fmt.Errorf("unknown document format %q (valid: %s, %s)", format, DocumentJSON, DocumentYAML)
+	}
+}
+
+// writeJSONNode writes a YAML node tree as compact JSON
+func writeJSONNode(buf *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeJSONNode(buf, n.Content[0])
+
+	case yaml.AliasNode:
+		return writeJSONNode(buf, n.Alias)
+
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSONNode(buf, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONNode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+
+	default:
+		var value interface{}
+		if err := n.Decode(&value); err != nil {
+			return err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		buf.Write(data)
+		return nil
+	}
+}
+
+// resetStyle clears the formatting style of every node so it is encoded in
+// the default block style. The encoder still quotes strings that would
+// otherwise read back as another type.
+func resetStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetStyle(c)
+	}
+}
@@ -0,0 +1,79 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertDocument_JSONToYAML(t *testing.T) {
+	doc := []byte(`{"openapi": "3.0.3", "info": {"title": "Test", "version": "1.0"}, "paths": {"/b": {}, "/a": {}}, "x-code": "200", "x-flag": "true", "x-num": 2}`)
+
+	out, err := ConvertDocument(doc, DocumentYAML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `openapi: 3.0.3
+info:
+  title: Test
+  version: "1.0"
+paths:
+  /b: {}
+  /a: {}
+x-code: "200"
+x-flag: "true"
+x-num: 2
+`
+	if string(out) != expected {
+		t.Errorf("unexpected YAML:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestConvertDocument_YAMLToJSON(t *testing.T) {
+	doc := []byte(`openapi: 3.0.3
+paths:
+  /z:
+    get:
+      responses:
+        200:
+          description: OK
+  /a: {}
+tags: [b, a]
+`)
+
+	out, err := ConvertDocument(doc, DocumentJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/z": {
+      "get": {
+        "responses": {
+          "200": {
+            "description": "OK"
+          }
+        }
+      }
+    },
+    "/a": {}
+  },
+  "tags": [
+    "b",
+    "a"
+  ]
+}
+`
+	if string(out) != expected {
+		t.Errorf("unexpected JSON:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestConvertDocument_UnknownFormat(t *testing.T) {
+	_, err := ConvertDocument([]byte(`{}`), "toml")
+	if err == nil || !strings.Contains(err.Error(), "unknown document format") {
+		t.Errorf("expected unknown format error, got %v", err)
+	}
+}
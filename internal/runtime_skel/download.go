@@ -0,0 +1,148 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// OutputFileAuto names the downloaded file after the response's
+// Content-Disposition header
+const OutputFileAuto = "auto"
+
+// download writes the body of resp to r.OutputFile ("-" for Output), or with
+// OutputFileAuto to a new file in the current directory named by the
+// Content-Disposition header or, failing that, the last segment of the URL
+// path. While the file is written, a progress bar is drawn on ErrOutput when
+// it is a terminal and the size of the body is known.
+func (r *Runtime) download(resp *http.Response) error {
+	errOut := outputOptions{ErrOut: r.ErrOutput}.errOut()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		return checkStatus(resp, body, errOut)
+	}
+
+	if r.OutputFile == "-" {
+		_, err := io.Copy(r.Output, resp.Body)
+		return err
+	}
+
+	name := ExpandHome(r.OutputFile)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if r.OutputFile == OutputFileAuto {
+		if name = downloadName(resp); name == "" {
+			return errors.New("the response does not name a file; pass a path to --output-file instead of auto")
+		}
+		// Like curl -J, never overwrite a file the server named
+		flags = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+
+	f, err := os.OpenFile(name, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	var body io.Reader = resp.Body
+	var bar *progressBar
+	if resp.ContentLength > 0 && !NoInput && isTerminal(errOut) {
+		bar = &progressBar{out: errOut, total: resp.ContentLength}
+		body = io.TeeReader(resp.Body, bar)
+	}
+
+	n, err := io.Copy(f, body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if bar != nil {
+		bar.finish()
+	}
+	if err != nil {
+		os.Remove(name)
+		if resp.Request != nil && errors.Is(resp.Request.Context().Err(), context.Canceled) {
+			return fmt.Errorf("%w; removed the incomplete %s", ErrInterrupted, name)
+		}
+		return fmt.Errorf("failed to download to %s: %w", name, err)
+	}
+
+	fmt.Fprintf(errOut, "Saved %s to %s\n", formatBytes(n), name)
+	return nil
+}
+
+// downloadName returns the file name for resp from its Content-Disposition
+// header or URL, reduced to a base name so a server cannot choose the
+// directory; "" when there is no usable name
+func downloadName(resp *http.Response) string {
+	var name string
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	if name == "" && resp.Request != nil {
+		name = path.Base(resp.Request.URL.Path)
+	}
+
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if name == "." || name == ".." || name == "/" || strings.HasPrefix(name, ".") {
+		return ""
+	}
+	return name
+}
+
+// progressBar draws download progress on a terminal line; it counts the
+// bytes written to it
+type progressBar struct {
+	out     io.Writer
+	total   int64
+	written int64
+	drawn   time.Time
+}
+
+const progressBarWidth = 30
+
+func (p *progressBar) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if time.Since(p.drawn) >= 100*time.Millisecond || p.written >= p.total {
+		p.draw()
+	}
+	return len(b), nil
+}
+
+func (p *progressBar) draw() {
+	p.drawn = time.Now()
+	done := p.written
+	if done > p.total {
+		done = p.total
+	}
+	filled := int(done * progressBarWidth / p.total)
+	fmt.Fprintf(p.out, "\r[%s%s] %3d%% %s / %s", strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled),
+		done*100/p.total, formatBytes(p.written), formatBytes(p.total))
+}
+
+func (p *progressBar) finish() {
+	p.draw()
+	fmt.Fprintln(p.out)
+}
+
+// formatBytes returns n in binary units, e.g. 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,121 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuntime_Download(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/reports/1":
+			w.Header().Set("Content-Disposition", `attachment; filename="report 2024.csv"`)
+			_, _ = w.Write([]byte("a,b\n1,2\n"))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": "not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var out, errOut bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+	rt.ErrOutput = &errOut
+	rt.OutputFile = filepath.Join(dir, "out.csv")
+
+	if err := rt.Do(context.Background(), NewRequest("GET", "/reports/1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(rt.OutputFile); string(data) != "a,b\n1,2\n" {
+		t.Errorf("unexpected file contents %q", data)
+	}
+	if out.Len() != 0 || !strings.Contains(errOut.String(), "Saved 8 B to "+rt.OutputFile) {
+		t.Errorf("unexpected output %q, stderr %q", out.String(), errOut.String())
+	}
+
+	// Errors are reported and leave no file behind
+	rt.OutputFile = filepath.Join(dir, "missing.json")
+	if err := rt.Do(context.Background(), NewRequest("GET", "/missing")); err == nil {
+		t.Error("expected an error for a 404")
+	}
+	if _, err := os.Stat(rt.OutputFile); !os.IsNotExist(err) {
+		t.Errorf("expected no file for a failed request, got %v", err)
+	}
+
+	// auto takes the name from Content-Disposition and never overwrites
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+	rt.OutputFile = OutputFileAuto
+	if err := rt.Do(context.Background(), NewRequest("GET", "/reports/1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "report 2024.csv")); string(data) != "a,b\n1,2\n" {
+		t.Errorf("unexpected file contents %q", data)
+	}
+	if err := rt.Do(context.Background(), NewRequest("GET", "/reports/1")); err == nil || !strings.Contains(err.Error(), "exists") {
+		t.Errorf("expected an existing file not to be overwritten, got %v", err)
+	}
+}
+
+func TestDownloadName(t *testing.T) {
+	tests := []struct {
+		disposition string
+		path        string
+		want        string
+	}{
+		{`attachment; filename="a.zip"`, "/x", "a.zip"},
+		{`attachment; filename*=UTF-8''na%C3%AFve.txt`, "/x", "naïve.txt"},
+		{`attachment; filename="../../etc/passwd"`, "/x", "passwd"},
+		{`attachment; filename="..\\evil.exe"`, "/x", "evil.exe"},
+		{"", "/files/data.bin", "data.bin"},
+		{`attachment; filename=".bashrc"`, "/x", ""},
+		{"", "/", ""},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{
+			Header:  http.Header{"Content-Disposition": {tt.disposition}},
+			Request: &http.Request{URL: &url.URL{Path: tt.path}},
+		}
+		if got := downloadName(resp); got != tt.want {
+			t.Errorf("downloadName(%q, %q) = %q, want %q", tt.disposition, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	var buf bytes.Buffer
+	bar := &progressBar{out: &buf, total: 2048}
+	_, _ = bar.Write(make([]byte, 1024))
+	_, _ = bar.Write(make([]byte, 1024))
+	bar.finish()
+
+	out := buf.String()
+	if !strings.Contains(out, "\r[===============               ]  50% 1.0 KiB / 2.0 KiB") {
+		t.Errorf("expected a half-full bar, got %q", out)
+	}
+	if !strings.HasSuffix(out, "\r[==============================] 100% 2.0 KiB / 2.0 KiB\n") {
+		t.Errorf("expected a full bar, got %q", out)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 5 << 20: "5.0 MiB", 3 << 30: "3.0 GiB"} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuntime_CurrentETag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		switch r.URL.Path {
+		case "/tasks/t1":
+			w.Header().Set("ETag", `W/"v7"`)
+			_, _ = w.Write([]byte(`{"id": "t1"}`))
+		case "/tasks/t2":
+			_, _ = w.Write([]byte(`{"id": "t2"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	var out, errOut bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+	rt.ErrOutput = &errOut
+
+	update := func(id string) *Request {
+		req := NewRequest("PUT", "/tasks/{taskId}")
+		req.SetPathParam("taskId", id)
+		req.SetBody([]byte(`{"title": "a"}`))
+		return req
+	}
+
+	if etag, err := rt.CurrentETag(context.Background(), update("t1")); err != nil || etag != `W/"v7"` {
+		t.Errorf("expected the resource's ETag, got %q, %v", etag, err)
+	}
+	if out.Len() > 0 {
+		t.Errorf("expected the GET response not to be printed, got %q", out.String())
+	}
+	if _, err := rt.CurrentETag(context.Background(), update("t2")); err == nil || !strings.Contains(err.Error(), "no ETag") {
+		t.Errorf("expected an error for a response without an ETag, got %v", err)
+	}
+	if _, err := rt.CurrentETag(context.Background(), update("t3")); err == nil {
+		t.Error("expected an error status to fail")
+	}
+
+	rt.DryRun = true
+	out.Reset()
+	if etag, err := rt.CurrentETag(context.Background(), update("t1")); err != nil || etag != dryRunETag {
+		t.Errorf("expected a placeholder in a dry run, got %q, %v", etag, err)
+	}
+	if !strings.HasPrefix(out.String(), "GET "+server.URL+"/tasks/t1\n") {
+		t.Errorf("expected the GET to be printed, got %q", out.String())
+	}
+}
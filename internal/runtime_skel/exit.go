@@ -0,0 +1,124 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrInterrupted is returned when a request is canceled through its context,
+// e.g. by Ctrl-C
+var ErrInterrupted = errors.New("interrupted")
+
+// StatusError is returned for a response with a status other than 2xx
+type StatusError struct {
+	StatusCode int
+	RequestID  string // returned by the server, or else sent with the request
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("request failed with status %d (request ID %s)", e.StatusCode, e.RequestID)
+	}
+	return fmt.Sprintf("request failed with status %d", e.StatusCode)
+}
+
+// NetworkError is returned when a request got no response, e.g. because the
+// host could not be resolved or the connection failed
+type NetworkError struct {
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return "request failed: " + e.Err.Error()
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// Exit codes of generated CLIs
+const (
+	ExitOK          = 0
+	ExitError       = 1
+	ExitInterrupted = 130 // as shells report a process ended by SIGINT
+)
+
+// ExitCodes maps request outcomes to process exit codes, so scripts can tell
+// them apart. Keys are an HTTP status ("404"), a status class ("4xx"), or
+// "network" for requests that got no response.
+type ExitCodes map[string]int
+
+// ExitCodeNetwork is the ExitCodes key for requests that got no response
+const ExitCodeNetwork = "network"
+
+// DefaultExitCodes are the exit codes used unless configured otherwise
+var DefaultExitCodes = ExitCodes{"4xx": 2, "5xx": 3, ExitCodeNetwork: 4}
+
+// ExitCode returns the process exit code for the error a command returned,
+// using DefaultExitCodes
+func ExitCode(err error) int {
+	return ExitCodes(nil).ExitCode(err)
+}
+
+// ExitCode returns the process exit code for the error a command returned.
+// An exact status takes precedence over its class, and c over
+// DefaultExitCodes.
+func (c ExitCodes) ExitCode(err error) int {
+	var keys []string
+	var statusErr *StatusError
+	var networkErr *NetworkError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrInterrupted):
+		return ExitInterrupted
+	case errors.As(err, &statusErr):
+		keys = []string{strconv.Itoa(statusErr.StatusCode), fmt.Sprintf("%dxx", statusErr.StatusCode/100)}
+	case errors.As(err, &networkErr):
+		keys = []string{ExitCodeNetwork}
+	}
+
+	for _, codes := range []ExitCodes{c, DefaultExitCodes} {
+		for _, key := range keys {
+			if code, ok := codes[key]; ok {
+				return code
+			}
+		}
+	}
+	return ExitError
+}
+
+// Validate checks the keys and codes of c
+func (c ExitCodes) Validate() error {
+	for key, code := range c {
+		if !validExitCodeKey(key) {
+			return fmt.Errorf("invalid exit_codes key %q (must be a status like 404, a class like 4xx, or %s)", key, ExitCodeNetwork)
+		}
+		if code < 0 || code > 255 {
+			return fmt.Errorf("invalid exit code %d for %s (must be 0 to 255)", code, key)
+		}
+	}
+	return nil
+}
+
+func validExitCodeKey(key string) bool {
+	if key == ExitCodeNetwork {
+		return true
+	}
+	digit := func(c byte) bool { return '0' <= c && c <= '9' }
+	return len(key) == 3 && '1' <= key[0] && key[0] <= '5' &&
+		(key[1:] == "xx" || digit(key[1]) && digit(key[2]))
+}
+
+// interruption reports err as ErrInterrupted when ctx was canceled, since
+// the cancellation is what made sending the request or printing its response
+// fail. Output already written is then incomplete.
+func interruption(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.Canceled) || errors.Is(err, ErrInterrupted) {
+		return err
+	}
+	return fmt.Errorf("%w; the output is incomplete", ErrInterrupted)
+}
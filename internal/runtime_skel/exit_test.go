@@ -0,0 +1,184 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{errors.New("boom"), ExitError},
+		{ErrInterrupted, ExitInterrupted},
+		{fmt.Errorf("%w; the output is incomplete", ErrInterrupted), ExitInterrupted},
+		{&StatusError{StatusCode: 404}, 2},
+		{fmt.Errorf("page 2: %w", &StatusError{StatusCode: 503}), 3},
+		{&StatusError{StatusCode: 304}, ExitError},
+		{&NetworkError{Err: errors.New("connection refused")}, 4},
+	}
+
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestExitCodes_ExitCode(t *testing.T) {
+	codes := ExitCodes{"404": 10, "4xx": 20, ExitCodeNetwork: 0}
+
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{&StatusError{StatusCode: 404}, 10},
+		{&StatusError{StatusCode: 403}, 20},
+		{&StatusError{StatusCode: 500}, 3},
+		{&NetworkError{Err: errors.New("timeout")}, 0},
+		{ErrInterrupted, ExitInterrupted},
+	}
+
+	for _, tt := range tests {
+		if got := codes.ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestExitCodes_Validate(t *testing.T) {
+	if err := (ExitCodes{"404": 10, "5xx": 3, "network": 4}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, codes := range []ExitCodes{{"4x": 2}, {"600": 2}, {"+40": 2}, {"timeout": 2}, {"404": 256}} {
+		if err := codes.Validate(); err == nil {
+			t.Errorf("expected %v to be invalid", codes)
+		}
+	}
+}
+
+func TestRuntime_ErrorTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	rt := New(server.URL, 5*time.Second)
+	rt.ErrOutput = io.Discard
+
+	var statusErr *StatusError
+	if err := rt.Do(context.Background(), NewRequest("GET", "/missing")); !errors.As(err, &statusErr) || statusErr.StatusCode != 404 {
+		t.Errorf("expected a StatusError for 404, got %v", err)
+	}
+
+	server.Close()
+	var networkErr *NetworkError
+	if err := rt.Do(context.Background(), NewRequest("GET", "/missing")); !errors.As(err, &networkErr) {
+		t.Errorf("expected a NetworkError for a closed server, got %v", err)
+	}
+}
+
+// syncWriter lets a test read output while a request is writing it
+type syncWriter struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *syncWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestRuntime_InterruptedStream(t *testing.T) {
+	server := slowStream(100, 20*time.Millisecond)
+	defer server.Close()
+
+	out := &syncWriter{}
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = out
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for !strings.Contains(out.String(), "2\n") {
+			time.Sleep(5 * time.Millisecond)
+		}
+		cancel()
+	}()
+
+	req := NewRequest("GET", "/events")
+	req.SetStream()
+	err := rt.Do(ctx, req)
+	if !errors.Is(err, ErrInterrupted) || !strings.Contains(err.Error(), "the output is incomplete") {
+		t.Errorf("expected an interruption with a partial output notice, got %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "1\n2\n") {
+		t.Errorf("expected the events received before the interruption, got %q", out.String())
+	}
+}
+
+func TestRuntime_InterruptedBeforeResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	rt := New(server.URL, 5*time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	err := rt.Do(ctx, NewRequest("GET", "/things"))
+	if err != ErrInterrupted {
+		t.Errorf("expected ErrInterrupted, got %v", err)
+	}
+}
+
+func TestRuntime_InterruptedDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000000")
+		_, _ = w.Write(make([]byte, 1000))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "big.bin")
+	rt := New(server.URL, 5*time.Second)
+	rt.ErrOutput = io.Discard
+	rt.OutputFile = path
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	err := rt.Do(ctx, NewRequest("GET", "/big.bin"))
+	if !errors.Is(err, ErrInterrupted) || !strings.Contains(err.Error(), "removed the incomplete "+path) {
+		t.Errorf("expected an interruption naming the removed file, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the partial download to be removed, got %v", err)
+	}
+}
+
+func TestStatusError_Error(t *testing.T) {
+	if got := (&StatusError{StatusCode: 500}).Error(); got != "request failed with status 500" {
+		t.Errorf("unexpected message %q", got)
+	}
+	if got := (&StatusError{StatusCode: 500, RequestID: "abc"}).Error(); got != "request failed with status 500 (request ID abc)" {
+		t.Errorf("expected the request ID in the message, got %q", got)
+	}
+}
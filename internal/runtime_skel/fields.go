@@ -0,0 +1,212 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BuildBody builds a JSON object request body from httpie-style field
+// arguments, as an alternative to writing the JSON:
+//
+//   - name=value sets a string
+//   - name:=json sets any JSON value, like 3, true, null, [1, 2] or {"a": 1}
+//   - name[key]=value sets a key of a nested object
+//   - name[]=value appends to a list
+//
+// Fields are applied in order; a later field replaces an earlier one of the
+// same name.
+func BuildBody(fields []string) ([]byte, error) {
+	body := map[string]interface{}{}
+	for _, field := range fields {
+		path, value, err := parseField(field)
+		if err != nil {
+			return nil, err
+		}
+		if err := setField(body, path, value); err != nil {
+			return nil, fmt.Errorf("invalid field %q: %w", field, err)
+		}
+	}
+	return json.Marshal(body)
+}
+
+// parseField splits a field argument into its key path and its value. An
+// empty key in the path ("[]") appends to a list.
+func parseField(field string) ([]string, interface{}, error) {
+	invalid := fmt.Errorf("invalid field %q (must be name=value or name:=json)", field)
+	name, value, ok, err := splitField(field)
+	if !ok {
+		return nil, nil, invalid
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON in field %q: %w", field, err)
+	}
+
+	// name[key][]... to ["name", "key", ""]
+	first, rest, _ := strings.Cut(name, "[")
+	if first == "" {
+		return nil, nil, invalid
+	}
+	path := []string{first}
+	if rest != "" {
+		if !strings.HasSuffix(rest, "]") {
+			return nil, nil, invalid
+		}
+		path = append(path, strings.Split(rest[:len(rest)-1], "][")...)
+		for _, key := range path[1 : len(path)-1] {
+			if key == "" {
+				// Appending in the middle of a path is ambiguous
+				return nil, nil, invalid
+			}
+		}
+	}
+	return path, value, nil
+}
+
+// splitField splits name=value, with a string value, or name:=json. ok is
+// false when field has neither form.
+func splitField(field string) (name string, value interface{}, ok bool, err error) {
+	i := strings.IndexByte(field, '=')
+	if i <= 0 {
+		return "", nil, false, nil
+	}
+	name, raw := field[:i], field[i+1:]
+	if !strings.HasSuffix(name, ":") {
+		return name, raw, true, nil
+	}
+	name = name[:len(name)-1]
+	value, err = decodeJSON([]byte(raw))
+	return name, value, name != "", err
+}
+
+// decodeJSON decodes data, keeping numbers as written so large IDs do not
+// lose digits
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// setField sets the value at path in obj, creating the objects and lists
+// on the way
+func setField(obj map[string]interface{}, path []string, value interface{}) error {
+	key := path[0]
+	if len(path) == 1 {
+		obj[key] = value
+		return nil
+	}
+
+	if path[1] == "" {
+		list, ok := obj[key].([]interface{})
+		if !ok && obj[key] != nil {
+			return fmt.Errorf("%s is not a list", key)
+		}
+		obj[key] = append(list, value)
+		return nil
+	}
+
+	nested, ok := obj[key].(map[string]interface{})
+	if !ok {
+		if obj[key] != nil {
+			return fmt.Errorf("%s is not an object", key)
+		}
+		nested = map[string]interface{}{}
+		obj[key] = nested
+	}
+	return setField(nested, path[1:], value)
+}
+
+// PatchBody applies --set changes to a JSON request body, so one field of a
+// template payload can be changed without editing it. Each change is
+// path=value, setting a string, or path:=json, setting any JSON value. The
+// path is dotted (items.0.name) or a JSON Pointer (/items/0/name); a list
+// index one past the end, or "-", appends. Missing objects on the way are
+// created, and an empty body is an empty object.
+func PatchBody(body []byte, changes []string) ([]byte, error) {
+	var doc interface{} = map[string]interface{}{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		var err error
+		if doc, err = decodeJSON(body); err != nil {
+			return nil, fmt.Errorf("cannot apply --set to a body that is not JSON: %w", err)
+		}
+	}
+
+	for _, change := range changes {
+		name, value, ok, err := splitField(change)
+		if !ok {
+			return nil, fmt.Errorf("invalid --set %q (must be path=value or path:=json)", change)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON in --set %q: %w", change, err)
+		}
+		if doc, err = setPath(doc, patchPath(name), value); err != nil {
+			return nil, fmt.Errorf("invalid --set %q: %w", change, err)
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// patchPath splits a dotted path or a JSON Pointer into its keys
+func patchPath(path string) []string {
+	if !strings.HasPrefix(path, "/") {
+		return strings.Split(path, ".")
+	}
+	keys := strings.Split(path[1:], "/")
+	for i, key := range keys {
+		keys[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+	}
+	return keys
+}
+
+// setPath returns doc with value set at path
+func setPath(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	key, rest := path[0], path[1:]
+
+	switch d := doc.(type) {
+	case nil:
+		child, err := setPath(nil, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{key: child}, nil
+	case map[string]interface{}:
+		child, err := setPath(d[key], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		d[key] = child
+		return d, nil
+	case []interface{}:
+		i := len(d)
+		if key != "-" {
+			var err error
+			if i, err = strconv.Atoi(key); err != nil || i < 0 || i > len(d) {
+				return nil, fmt.Errorf("no index %s in a list of %d", key, len(d))
+			}
+		}
+		if i == len(d) {
+			d = append(d, nil)
+		}
+		child, err := setPath(d[i], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		d[i] = child
+		return d, nil
+	default:
+		return nil, fmt.Errorf("cannot set %s in a value that is not an object or a list", key)
+	}
+}
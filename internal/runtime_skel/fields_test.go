@@ -0,0 +1,95 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildBody(t *testing.T) {
+	tests := []struct {
+		fields []string
+		want   string
+	}{
+		{[]string{`title=Do thing`, `priority:=3`, `done:=true`}, `{"done":true,"priority":3,"title":"Do thing"}`},
+		{[]string{`note=a=b`, `empty=`}, `{"empty":"","note":"a=b"}`},
+		{[]string{`tags:=["a", "b"]`, `meta:={"x": null}`}, `{"meta":{"x":null},"tags":["a","b"]}`},
+		{[]string{`owner[name]=Ann`, `owner[address][city]=Oslo`, `owner[id]:=7`}, `{"owner":{"address":{"city":"Oslo"},"id":7,"name":"Ann"}}`},
+		{[]string{`tags[]=a`, `tags[]:=2`}, `{"tags":["a",2]}`},
+		{[]string{`title=one`, `title=two`}, `{"title":"two"}`},
+		{nil, `{}`},
+	}
+
+	for _, tt := range tests {
+		got, err := BuildBody(tt.fields)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("BuildBody(%q) = %s, %v, want %s", tt.fields, got, err, tt.want)
+		}
+	}
+}
+
+func TestBuildBody_Invalid(t *testing.T) {
+	tests := []struct {
+		fields []string
+		want   string
+	}{
+		{[]string{"title"}, `invalid field "title" (must be name=value or name:=json)`},
+		{[]string{"=x"}, `invalid field "=x"`},
+		{[]string{"[a]=x"}, `invalid field "[a]=x"`},
+		{[]string{"a[b=x"}, `invalid field "a[b=x"`},
+		{[]string{"a[][b]=x"}, `invalid field "a[][b]=x"`},
+		{[]string{"n:=three"}, `invalid JSON in field "n:=three"`},
+		{[]string{"a=x", "a[b]=y"}, `invalid field "a[b]=y": a is not an object`},
+		{[]string{"a=x", "a[]=y"}, `invalid field "a[]=y": a is not a list`},
+	}
+
+	for _, tt := range tests {
+		if _, err := BuildBody(tt.fields); err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("BuildBody(%q): expected an error starting %q, got %v", tt.fields, tt.want, err)
+		}
+	}
+}
+
+func TestPatchBody(t *testing.T) {
+	base := `{"title": "Draft", "id": 12345678901234567890, "owner": {"name": "Ann"}, "tags": ["a", "b"]}`
+	tests := []struct {
+		changes []string
+		want    string
+	}{
+		{[]string{"title=Final"}, `{"id":12345678901234567890,"owner":{"name":"Ann"},"tags":["a","b"],"title":"Final"}`},
+		{[]string{"owner.name=Bo", "owner.age:=40"}, `{"id":12345678901234567890,"owner":{"age":40,"name":"Bo"},"tags":["a","b"],"title":"Draft"}`},
+		{[]string{"tags.1=c", "tags.2=d", "/tags/-:=null"}, `{"id":12345678901234567890,"owner":{"name":"Ann"},"tags":["a","c","d",null],"title":"Draft"}`},
+		{[]string{"meta.source.kind=cli"}, `{"id":12345678901234567890,"meta":{"source":{"kind":"cli"}},"owner":{"name":"Ann"},"tags":["a","b"],"title":"Draft"}`},
+		{[]string{"/a~1b=x", "/c~0d=y"}, `{"a/b":"x","c~d":"y","id":12345678901234567890,"owner":{"name":"Ann"},"tags":["a","b"],"title":"Draft"}`},
+	}
+
+	for _, tt := range tests {
+		got, err := PatchBody([]byte(base), tt.changes)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("PatchBody(%q) = %s, %v, want %s", tt.changes, got, err, tt.want)
+		}
+	}
+
+	if got, err := PatchBody(nil, []string{"done:=true"}); err != nil || string(got) != `{"done":true}` {
+		t.Errorf("expected an empty body to be patched as an object, got %s, %v", got, err)
+	}
+}
+
+func TestPatchBody_Invalid(t *testing.T) {
+	tests := []struct {
+		body    string
+		changes []string
+		want    string
+	}{
+		{`{}`, []string{"title"}, `invalid --set "title" (must be path=value or path:=json)`},
+		{`{}`, []string{"n:=three"}, `invalid JSON in --set "n:=three"`},
+		{`{"tags": []}`, []string{"tags.3=x"}, `invalid --set "tags.3=x": no index 3 in a list of 0`},
+		{`{"title": "x"}`, []string{"title.en=y"}, `invalid --set "title.en=y": cannot set en in a value that is not an object or a list`},
+		{`title: x`, []string{"a=b"}, `cannot apply --set to a body that is not JSON`},
+	}
+
+	for _, tt := range tests {
+		if _, err := PatchBody([]byte(tt.body), tt.changes); err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("PatchBody(%s, %q): expected an error starting %q, got %v", tt.body, tt.changes, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package runtime
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// FuzzyMatch is a candidate that matched a fuzzy query
+type FuzzyMatch struct {
+	Index int // position of the candidate in the input slice
+	Score int
+}
+
+// FuzzyScore reports whether every character of query appears in candidate in
+// order (ignoring case and whitespace) and scores the match. Consecutive
+// characters and characters at the start of a word score higher, so "tl"
+// ranks "tasks list" above "settle" and "tasks get" ranks "tasks get" above
+// "tasks list GET".
+func FuzzyScore(query, candidate string) (int, bool) {
+	q := []rune(strings.ToLower(strings.Join(strings.Fields(query), "")))
+	c := []rune(strings.ToLower(candidate))
+	if len(q) == 0 {
+		return 0, true
+	}
+
+	score := 0
+	qi := 0
+	consecutive := false
+	for ci := 0; ci < len(c) && qi < len(q); ci++ {
+		if unicode.IsSpace(c[ci]) {
+			continue
+		}
+		if c[ci] != q[qi] {
+			consecutive = false
+			continue
+		}
+		score++
+		if consecutive {
+			score += 3
+		}
+		if ci == 0 || !unicode.IsLetter(c[ci-1]) && !unicode.IsDigit(c[ci-1]) {
+			score += 2
+		}
+		consecutive = true
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+
+	// Prefer shorter candidates when the match quality is otherwise equal
+	return score*100 - len(c), true
+}
+
+// FuzzyFind returns the candidates matching query, best match first
+func FuzzyFind(query string, candidates []string) []FuzzyMatch {
+	var matches []FuzzyMatch
+	for i, candidate := range candidates {
+		if score, ok := FuzzyScore(query, candidate); ok {
+			matches = append(matches, FuzzyMatch{Index: i, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
@@ -0,0 +1,70 @@
+package runtime
+
+import "testing"
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query     string
+		candidate string
+		match     bool
+	}{
+		{"", "tasks list", true},
+		{"tl", "tasks list", true},
+		{"tasks list", "tasks list", true},
+		{"TSKLST", "tasks list", true},
+		{"lt", "tasks list", true},
+		{"xyz", "tasks list", false},
+		{"tasks lists", "tasks list", false},
+	}
+
+	for _, tt := range tests {
+		_, ok := FuzzyScore(tt.query, tt.candidate)
+		if ok != tt.match {
+			t.Errorf("FuzzyScore(%q, %q) matched = %v, want %v", tt.query, tt.candidate, ok, tt.match)
+		}
+	}
+}
+
+func TestFuzzyFind_RanksWordStartsFirst(t *testing.T) {
+	candidates := []string{
+		"settle",
+		"tasks list",
+		"users create",
+		"users get",
+	}
+
+	matches := FuzzyFind("tl", candidates)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(matches))
+	}
+	if candidates[matches[0].Index] != "tasks list" {
+		t.Errorf("expected 'tasks list' first, got %q", candidates[matches[0].Index])
+	}
+	if candidates[matches[1].Index] != "settle" {
+		t.Errorf("expected 'settle' second, got %q", candidates[matches[1].Index])
+	}
+}
+
+func TestFuzzyFind_PrefersShorterCandidates(t *testing.T) {
+	candidates := []string{"tasks list-archived", "tasks list"}
+
+	matches := FuzzyFind("tasks list", candidates)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(matches))
+	}
+	if candidates[matches[0].Index] != "tasks list" {
+		t.Errorf("expected exact command first, got %q", candidates[matches[0].Index])
+	}
+}
+
+func TestFuzzyFind_PrefersMatchesAcrossWords(t *testing.T) {
+	candidates := []string{"tasks list GET /tasks", "tasks get GET /tasks/{id}"}
+
+	matches := FuzzyFind("tasks get", candidates)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(matches))
+	}
+	if candidates[matches[0].Index] != "tasks get GET /tasks/{id}" {
+		t.Errorf("expected 'tasks get' first, got %q", candidates[matches[0].Index])
+	}
+}
@@ -0,0 +1,51 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// JSONErrors is set when the user chose JSON output, so that errors are
+// written as JSON by WriteJSONError too. Failed requests then print nothing
+// themselves; their response body is kept in the StatusError instead.
+var JSONErrors bool
+
+// jsonError is the object WriteJSONError writes under "error"
+type jsonError struct {
+	Status    int         `json:"status,omitempty"`
+	Message   string      `json:"message"`
+	RequestID string      `json:"request_id,omitempty"`
+	Body      interface{} `json:"body,omitempty"`
+}
+
+// WriteJSONError writes err to w as a line of JSON, e.g.
+//
+//	{"error":{"status":404,"message":"request failed with status 404","body":{"error":"not found"}}}
+//
+// status, request_id and body are only set for error responses; the body is
+// included as JSON when it is JSON, and as a string otherwise.
+func WriteJSONError(w io.Writer, err error) {
+	out := jsonError{Message: err.Error()}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		out.Status = statusErr.StatusCode
+		out.RequestID = statusErr.RequestID
+		if len(statusErr.Body) > 0 {
+			var body interface{}
+			if json.Unmarshal(statusErr.Body, &body) == nil {
+				out.Body = body
+			} else {
+				out.Body = string(statusErr.Body)
+			}
+		}
+	}
+
+	line, jsonErr := json.Marshal(map[string]jsonError{"error": out})
+	if jsonErr != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return
+	}
+	fmt.Fprintln(w, string(line))
+}
@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteJSONError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{errors.New("missing required argument: id"), `{"error":{"message":"missing required argument: id"}}`},
+		{
+			fmt.Errorf("page 2: %w", &StatusError{StatusCode: 404, RequestID: "r1", Body: []byte(`{"error": "not found"}`)}),
+			`{"error":{"status":404,"message":"page 2: request failed with status 404 (request ID r1)","request_id":"r1","body":{"error":"not found"}}}`,
+		},
+		{&StatusError{StatusCode: 502, Body: []byte("Bad Gateway")}, `{"error":{"status":502,"message":"request failed with status 502","body":"Bad Gateway"}}`},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		WriteJSONError(&out, tt.err)
+		if out.String() != tt.want+"\n" {
+			t.Errorf("WriteJSONError(%v) = %s, want %s", tt.err, out.String(), tt.want)
+		}
+	}
+}
+
+func TestRuntime_Do_JSONErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error": "not found"}`))
+	}))
+	defer server.Close()
+
+	defer func() { JSONErrors = false }()
+	JSONErrors = true
+
+	var out, errOut bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+	rt.ErrOutput = &errOut
+	err := rt.Do(context.Background(), NewRequest("GET", "/tasks/t1"))
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || string(statusErr.Body) != `{"error": "not found"}` {
+		t.Fatalf("expected the body in the status error, got %v", err)
+	}
+	if out.Len() != 0 || errOut.Len() != 0 {
+		t.Errorf("expected nothing printed, got %q, %q", out.String(), errOut.String())
+	}
+}
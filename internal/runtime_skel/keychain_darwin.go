@@ -0,0 +1,71 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func init() {
+	osKeychain = securityKeychain{}
+}
+
+// securityKeychain stores secrets as generic passwords in the login keychain
+// with the security command
+type securityKeychain struct{}
+
+// errSecItemNotFound is the exit status of security for missing items
+const errSecItemNotFound = 44
+
+func (securityKeychain) get(service, key string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", key, "-w").Output()
+	if err != nil {
+		return "", securityError(err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (securityKeychain) set(service, key, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return errors.New("keychain values cannot contain line breaks")
+	}
+
+	// Pass the command on stdin so the secret never appears in the process
+	// list
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		shellQuote(service), shellQuote(key), shellQuote(value)))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("security: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (securityKeychain) delete(service, key string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", service, "-a", key).Run()
+	if err = securityError(err); errors.Is(err, ErrSecretNotFound) {
+		return nil
+	}
+	return err
+}
+
+// securityError maps the exit status for missing items to ErrSecretNotFound
+func securityError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == errSecItemNotFound {
+		return ErrSecretNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("security: %w", err)
+	}
+	return nil
+}
+
+// shellQuote quotes s for the shell-like command parser of security -i
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,163 @@
+package runtime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeSecretTool installs a secret-tool on PATH that keeps secrets as files
+// in a temporary directory
+func fakeSecretTool(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	store := filepath.Join(dir, "secrets")
+	if err := os.Mkdir(store, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	script := `#!/bin/sh
+cmd=$1; shift
+while [ $# -gt 0 ]; do
+	case $1 in
+	service) svc=$2; shift 2 ;;
+	account) acct=$2; shift 2 ;;
+	*) shift ;;
+	esac
+done
+f="` + store + `/$svc.$acct"
+case $cmd in
+store) cat > "$f" ;;
+lookup) [ -f "$f" ] || exit 1; cat "$f" ;;
+clear) [ -f "$f" ] || exit 1; rm "$f" ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "secret-tool"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return store
+}
+
+func TestKeychain_SecretTool(t *testing.T) {
+	store := fakeSecretTool(t)
+	keychain := &Keychain{Service: "testapp"}
+
+	if _, err := keychain.Get("token"); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("expected ErrSecretNotFound, got %v", err)
+	}
+
+	if err := keychain.Set("token", "s3cret"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(store, "testapp.token")); err != nil {
+		t.Errorf("expected the secret under the service and account: %v", err)
+	}
+	if value, err := keychain.Get("token"); err != nil || value != "s3cret" {
+		t.Errorf("Get = %q, %v; want s3cret", value, err)
+	}
+
+	if err := keychain.Delete("token"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := keychain.Delete("token"); err != nil {
+		t.Errorf("expected deleting a missing secret to succeed, got %v", err)
+	}
+	if _, err := keychain.Get("token"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("expected ErrSecretNotFound after Delete, got %v", err)
+	}
+}
+
+func TestLoadConfig_Keychain(t *testing.T) {
+	fakeSecretTool(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("KEYAPP_CREDENTIAL_STORE", "keychain")
+	t.Setenv("KEYAPP_CLIENT_SECRET", "from-env")
+
+	keychain := &Keychain{Service: "keyapp"}
+	for key, value := range map[string]string{"token": "from-keychain", "client_secret": "ignored"} {
+		if err := keychain.Set(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config, err := LoadConfig("keyapp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Token != "from-keychain" {
+		t.Errorf("expected the token from the keychain, got %q", config.Token)
+	}
+	// The environment takes precedence over the keychain
+	if config.ClientSecret != "from-env" {
+		t.Errorf("expected the client secret from the environment, got %q", config.ClientSecret)
+	}
+
+	// The login token is kept in the keychain too
+	login, err := NewLoginStore("keyapp", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := login.Save(&Token{AccessToken: "login", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if value, err := keychain.Get(loginTokenKey); err != nil || value == "" {
+		t.Errorf("expected the login token in the keychain, got %q, %v", value, err)
+	}
+	if token, err := login.Load(); err != nil || token.AccessToken != "login" {
+		t.Errorf("Load = %+v, %v", token, err)
+	}
+	if err := login.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if token, err := login.Load(); err != nil || token != nil {
+		t.Errorf("expected no token after Clear, got %+v, %v", token, err)
+	}
+}
+
+func TestLoadConfig_KeychainProfile(t *testing.T) {
+	fakeSecretTool(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("KEYAPP_CREDENTIAL_STORE", "keychain")
+
+	keychain := &Keychain{Service: "keyapp"}
+	for key, value := range map[string]string{"token": "default", "staging:token": "staging", "client_secret": "shared"} {
+		if err := keychain.Set(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := &Config{CredentialStore: CredentialStoreKeychain, Profile: "staging"}
+	if err := config.loadSecrets("keyapp"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Token != "staging" || config.ClientSecret != "shared" {
+		t.Errorf("expected the profile's token and the shared client secret, got %q and %q", config.Token, config.ClientSecret)
+	}
+
+	// Each profile has its own login token
+	login, err := NewLoginStore("keyapp", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := login.Save(&Token{AccessToken: "login", Expiry: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := keychain.Get("staging:" + loginTokenKey); err != nil {
+		t.Errorf("expected the login token under the profile, got %v", err)
+	}
+	if _, err := keychain.Get(loginTokenKey); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("expected no login token without a profile, got %v", err)
+	}
+}
+
+func TestLoadConfig_InvalidCredentialStore(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("KEYAPP_CREDENTIAL_STORE", "vault")
+
+	if _, err := LoadConfig("keyapp"); err == nil {
+		t.Error("expected an error for an unknown credential store")
+	}
+}
@@ -0,0 +1,68 @@
+package runtime
+
+import "net/http"
+
+// Middleware wraps the transport used to send requests. It can inspect or
+// modify requests and responses to add custom auth, logging or tracing.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts an ordinary function to an http.RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Use registers middleware around the HTTP transport. Middleware registered
+// first is outermost: it sees each request first and each response last.
+func (r *Runtime) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// BaseClient returns a client for the requests the runtime sends on its own
+// behalf, like token exchanges: through its transport and with its timeout,
+// but without the middleware of API requests
+func (r *Runtime) BaseClient() *http.Client {
+	return &http.Client{Transport: r.HTTPClient.Transport, Timeout: r.HTTPClient.Timeout}
+}
+
+// client returns the HTTP client to send requests with, its transport
+// wrapped in the registered middleware, the signer, compression, the debug
+// log and timing
+func (r *Runtime) client() *http.Client {
+	transport := r.HTTPClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	// Time innermost, so only the network is timed
+	if r.Timing != nil {
+		transport = r.Timing.transport(transport)
+	}
+	// Log innermost, so the logs show the request as it is sent
+	if r.Debug != nil {
+		transport = r.Debug.transport(transport)
+	}
+	if r.Log != nil {
+		transport = r.Log.transport(transport)
+	}
+	// Downloads are saved exactly as the server stores them, as with curl
+	// without --compressed
+	transport = compressionTransport(transport, !r.DisableCompression && r.OutputFile == "")
+	// Sign next, so the signature covers headers added by middleware
+	if r.Signer != nil {
+		transport = signingTransport(r.Signer, transport)
+	}
+	// Trace outside signing, so the signature covers the traceparent header
+	if r.Tracer != nil {
+		transport = r.Tracer.transport(transport)
+	}
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		transport = r.middleware[i](transport)
+	}
+
+	client := *r.HTTPClient
+	client.Transport = transport
+	client.CheckRedirect = r.checkRedirect
+	return &client
+}
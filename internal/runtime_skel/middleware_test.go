@@ -0,0 +1,84 @@
+package runtime
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRuntime_UseWrapsTransport(t *testing.T) {
+	var gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("X-Trace-Id")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+" request")
+				req.Header.Set("X-Trace-Id", "abc123")
+				resp, err := next.RoundTrip(req)
+				order = append(order, name+" response")
+				return resp, err
+			})
+		}
+	}
+
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = io.Discard
+	rt.Use(trace("outer"), trace("inner"))
+
+	if err := rt.Do(context.Background(), NewRequest("GET", "/ping")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotHeader != "abc123" {
+		t.Errorf("expected middleware to set header, got %q", gotHeader)
+	}
+
+	want := []string{"outer request", "inner request", "inner response", "outer response"}
+	if len(order) != len(want) {
+		t.Fatalf("expected %v, got %v", want, order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, order)
+			break
+		}
+	}
+}
+
+func TestRuntime_BaseClient(t *testing.T) {
+	var gotHeader string
+	rt := New("http://api.example.invalid", 5*time.Second)
+	rt.HTTPClient.Transport = RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		gotHeader = req.Header.Get("X-Middleware")
+		return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody, Request: req}, nil
+	})
+	rt.Use(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Middleware", "yes")
+			return next.RoundTrip(req)
+		})
+	})
+
+	client := rt.BaseClient()
+	if client.Timeout != 5*time.Second {
+		t.Errorf("expected the runtime's timeout, got %s", client.Timeout)
+	}
+	resp, err := client.Get("http://auth.example.invalid/token")
+	if err != nil {
+		t.Fatalf("expected the request to go through the runtime's transport: %v", err)
+	}
+	resp.Body.Close()
+
+	if gotHeader != "" {
+		t.Errorf("expected no API middleware, got X-Middleware %q", gotHeader)
+	}
+}
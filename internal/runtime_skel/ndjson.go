@@ -0,0 +1,84 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+)
+
+// isNDJSON checks if content type indicates newline-delimited JSON
+func isNDJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines", "application/jsonlines":
+		return true
+	}
+	return false
+}
+
+// handleNDJSON prints each JSON document of a newline-delimited stream as it
+// arrives: pretty-printed by default, compact with the jsonl format, or
+// through the query, template or quiet mode one document at a time. The
+// table format needs every row to align the columns, so it waits for the
+// end of the stream.
+func handleNDJSON(body io.Reader, out io.Writer, opts outputOptions) error {
+	reader := bufio.NewReader(body)
+	var rows []interface{}
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return fmt.Errorf("error reading stream: %w", readErr)
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var doc interface{}
+			if err := json.Unmarshal(line, &doc); err != nil {
+				return fmt.Errorf("invalid JSON in stream: %w", err)
+			}
+			if opts.Query != nil {
+				var err error
+				if doc, err = opts.Query.Search(doc); err != nil {
+					return err
+				}
+			}
+
+			switch {
+			case opts.Quiet:
+				if id := itemID(doc); id != "" {
+					fmt.Fprintln(out, id)
+				}
+			case opts.Format == FormatTable:
+				rows = append(rows, doc)
+			case opts.Format == FormatJSONL:
+				compact, err := json.Marshal(doc)
+				if err != nil {
+					return fmt.Errorf("failed to encode output: %w", err)
+				}
+				fmt.Fprintln(out, string(compact))
+			default:
+				if err := writeParsed(doc, nil, opts, out); err != nil {
+					return err
+				}
+			}
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	if opts.Format == FormatTable && !opts.Quiet {
+		if rows == nil {
+			rows = []interface{}{}
+		}
+		return writeTable(rows, nil, opts.Columns, out)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsNDJSON(t *testing.T) {
+	for contentType, want := range map[string]bool{
+		"application/x-ndjson":                true,
+		"application/x-ndjson; charset=utf-8": true,
+		"application/jsonl":                   true,
+		"application/json":                    false,
+		"text/event-stream":                   false,
+		"":                                    false,
+	} {
+		if got := isNDJSON(contentType); got != want {
+			t.Errorf("isNDJSON(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func TestHandleNDJSON(t *testing.T) {
+	stream := "{\"id\": 1, \"name\": \"a\"}\n\n{\"id\": 2, \"name\": \"b\"}\r\n{\"id\": 3}"
+	query, _ := CompileQuery("name")
+
+	tests := []struct {
+		name string
+		opts outputOptions
+		want string
+	}{
+		{"json", outputOptions{Format: FormatJSON}, "{\n  \"id\": 1,\n  \"name\": \"a\"\n}\n{\n  \"id\": 2,\n  \"name\": \"b\"\n}\n{\n  \"id\": 3\n}\n"},
+		{"jsonl", outputOptions{Format: FormatJSONL}, "{\"id\":1,\"name\":\"a\"}\n{\"id\":2,\"name\":\"b\"}\n{\"id\":3}\n"},
+		{"query", outputOptions{Format: FormatJSONL, Query: query}, "\"a\"\n\"b\"\nnull\n"},
+		{"quiet", outputOptions{Quiet: true}, "1\n2\n3\n"},
+		{"table", outputOptions{Format: FormatTable}, "ID  NAME\n1   a\n2   b\n3   \n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := handleNDJSON(strings.NewReader(stream), &out, tt.opts); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+
+	if err := handleNDJSON(strings.NewReader("{\"id\": 1}\nnot json\n"), io.Discard, outputOptions{}); err == nil {
+		t.Error("expected an error for an invalid line")
+	}
+}
+
+func TestHandleNDJSON_PrintsAsItArrives(t *testing.T) {
+	pr, pw := io.Pipe()
+	lines := make(chan string, 2)
+	done := make(chan error)
+	go func() {
+		done <- handleNDJSON(pr, writerFunc(func(p []byte) (int, error) {
+			lines <- string(p)
+			return len(p), nil
+		}), outputOptions{Format: FormatJSONL})
+	}()
+
+	_, _ = io.WriteString(pw, "{\"n\": 1}\n")
+	select {
+	case line := <-lines:
+		if line != "{\"n\":1}\n" {
+			t.Errorf("unexpected line %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the first document before the stream ended")
+	}
+
+	pw.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
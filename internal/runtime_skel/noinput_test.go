@@ -0,0 +1,48 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheckInput(t *testing.T) {
+	// /dev/null is a character device, so it stands in for a terminal
+	tty, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Skipf("no %s: %v", os.DevNull, err)
+	}
+	defer tty.Close()
+
+	defer func() { NoInput = false }()
+
+	NoInput = false
+	if err := CheckInput(tty, "the value"); err != nil {
+		t.Errorf("expected a terminal to be read without --no-input, got %v", err)
+	}
+
+	NoInput = true
+	if err := CheckInput(strings.NewReader("v1\n"), "the value"); err != nil {
+		t.Errorf("expected piped input to be read with --no-input, got %v", err)
+	}
+	err = CheckInput(tty, "the value")
+	if !errors.Is(err, ErrNoInput) || !strings.Contains(err.Error(), "the value must be piped to stdin") {
+		t.Errorf("expected a terminal not to be read with --no-input, got %v", err)
+	}
+}
+
+func TestRunAuthCommand_NoInput(t *testing.T) {
+	defer func() { NoInput = false }()
+	NoInput = true
+
+	// The helper gets no stdin to prompt on, so its read ends at once
+	credential, err := RunAuthCommand(context.Background(), []string{"sh", "-c", "read line; echo token${line}"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if credential != "token" {
+		t.Errorf("expected the helper to read nothing, got %q", credential)
+	}
+}
@@ -0,0 +1,311 @@
+package runtime
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// expiryLeeway renews tokens this long before they expire, so a token does
+// not lapse while a request is in flight
+const expiryLeeway = 30 * time.Second
+
+// Token is an OAuth2 access token
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// Valid reports whether the token can still be used at now. Tokens without
+// an expiry never expire.
+func (t *Token) Valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || now.Add(expiryLeeway).Before(t.Expiry))
+}
+
+// tokenResponse is a token endpoint response (RFC 6749 sections 5.1, 5.2)
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestToken posts form to a token endpoint, authenticating with HTTP
+// basic auth when clientID is set
+func requestToken(ctx context.Context, client *http.Client, tokenURL string, form url.Values, clientID, clientSecret string) (*Token, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if tr.Error != "" {
+		if tr.ErrorDescription != "" {
+			return nil, &OAuth2Error{Code: tr.Error, Description: tr.ErrorDescription}
+		}
+		return nil, &OAuth2Error{Code: tr.Error}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %s", resp.Status)
+	}
+	if tr.AccessToken == "" {
+		return nil, errors.New("token response has no access_token")
+	}
+
+	token := &Token{AccessToken: tr.AccessToken, TokenType: tr.TokenType, RefreshToken: tr.RefreshToken}
+	if tr.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// OAuth2Error is an error response from a token endpoint
+type OAuth2Error struct {
+	Code        string // e.g. invalid_client
+	Description string
+}
+
+func (e *OAuth2Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth2: %s: %s", e.Code, e.Description)
+	}
+	return "oauth2: " + e.Code
+}
+
+// TokenCache stores a token in a file readable only by the user
+type TokenCache struct {
+	Path string
+}
+
+// NewTokenCache returns a cache for the token named name in appName's
+// directory under the user cache dir ($XDG_CACHE_HOME on Linux)
+func NewTokenCache(appName, name string) (*TokenCache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine cache directory: %w", err)
+	}
+	return &TokenCache{Path: filepath.Join(dir, appName, name+".json")}, nil
+}
+
+// Load returns the cached token, or nil when there is none
+func (c *TokenCache) Load() (*Token, error) {
+	data, err := os.ReadFile(c.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		// A corrupt cache is as good as an empty one
+		return nil, nil
+	}
+	return &token, nil
+}
+
+// Save replaces the cached token
+func (c *TokenCache) Save(token *Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so concurrent readers never see a
+	// partial token
+	tmp, err := os.CreateTemp(filepath.Dir(c.Path), ".token-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.Path)
+}
+
+// Clear removes the cached token
+func (c *TokenCache) Clear() error {
+	if err := os.Remove(c.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// ClientCredentials obtains access tokens with the OAuth2 client credentials
+// grant (RFC 6749 section 4.4) and sends them as bearer tokens. Tokens are
+// reused until they expire, across runs when Cache is set.
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	Cache        *TokenCache  // optional
+	HTTPClient   *http.Client // for token requests; defaults to http.DefaultClient
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// CacheName returns a cache file name unique to the token URL, client and
+// scopes, so changing any of them never reuses a token issued for another
+func (c *ClientCredentials) CacheName() string {
+	sum := sha256.Sum256([]byte(c.TokenURL + "\n" + c.ClientID + "\n" + strings.Join(c.Scopes, " ")))
+	return "client-credentials-" + hex.EncodeToString(sum[:8])
+}
+
+// Token returns a valid access token, requesting a new one when the current
+// token is missing or expired
+func (c *ClientCredentials) Token(ctx context.Context) (*Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.token.Valid(now) {
+		return c.token, nil
+	}
+	if c.Cache != nil {
+		if cached, err := c.Cache.Load(); err == nil && cached.Valid(now) {
+			c.token = cached
+			return cached, nil
+		}
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	token, err := requestToken(ctx, c.HTTPClient, c.TokenURL, form, c.ClientID, c.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	c.token = token
+	if c.Cache != nil {
+		// Caching is an optimization; a read-only cache dir must not fail
+		// the request
+		_ = c.Cache.Save(token)
+	}
+	return token, nil
+}
+
+// Invalidate discards the current token, including the cached copy
+func (c *ClientCredentials) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = nil
+	if c.Cache != nil {
+		_ = c.Cache.Clear()
+	}
+}
+
+// Middleware returns middleware that authorizes requests with an access
+// token. Requests that already carry an Authorization header are sent
+// unchanged. When the API rejects a token with 401 Unauthorized (e.g. it was
+// revoked before expiring), a new token is requested and the request retried
+// once.
+func (c *ClientCredentials) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") != "" {
+				return next.RoundTrip(req)
+			}
+
+			token, err := c.Token(req.Context())
+			if err != nil {
+				return nil, err
+			}
+			return retryUnauthorized(next, req, token, func() (*Token, error) {
+				c.Invalidate()
+				return c.Token(req.Context())
+			})
+		})
+	}
+}
+
+// retryUnauthorized sends req with token, and when the API rejects it with
+// 401 Unauthorized, sends it once more with the token renew returns. The
+// first response is returned when renew fails or returns no token, or when
+// the request body cannot be sent again.
+func retryUnauthorized(next http.RoundTripper, req *http.Request, token *Token, renew func() (*Token, error)) (*http.Response, error) {
+	resp, err := next.RoundTrip(withBearer(req, token.AccessToken))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// Retry only requests whose body can be sent again
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	retry := req
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry = req.Clone(req.Context())
+		retry.Body = body
+	}
+
+	token, err = renew()
+	if err != nil || token == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+	return next.RoundTrip(withBearer(retry, token.AccessToken))
+}
+
+// withBearer returns a copy of req authorized with an access token
+func withBearer(req *http.Request, accessToken string) *http.Request {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return req
+}
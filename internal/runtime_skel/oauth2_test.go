@@ -0,0 +1,175 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenServer returns a token endpoint that issues "token-<n>" for the
+// client "id"/"secret" and counts the requests it serves
+func newTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *int32) {
+	t.Helper()
+	var issued int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "id" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_client", "error_description": "bad credentials"}`)
+			return
+		}
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("scope") != "read write" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid_request"}`)
+			return
+		}
+		n := atomic.AddInt32(&issued, 1)
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "Bearer", "expires_in": %d}`, n, expiresIn)
+	}))
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func TestClientCredentials_CachesToken(t *testing.T) {
+	server, issued := newTokenServer(t, 3600)
+	cache := &TokenCache{Path: filepath.Join(t.TempDir(), "app", "token.json")}
+
+	cc := &ClientCredentials{TokenURL: server.URL, ClientID: "id", ClientSecret: "secret", Scopes: []string{"read", "write"}, Cache: cache}
+	for i := 0; i < 2; i++ {
+		token, err := cc.Token(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if token.AccessToken != "token-1" {
+			t.Errorf("expected token-1, got %q", token.AccessToken)
+		}
+	}
+
+	info, err := os.Stat(cache.Path)
+	if err != nil {
+		t.Fatalf("expected the token to be cached: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected cache permissions 0600, got %o", info.Mode().Perm())
+	}
+
+	// A later run reuses the cached token
+	next := &ClientCredentials{TokenURL: server.URL, ClientID: "id", ClientSecret: "secret", Scopes: []string{"read", "write"}, Cache: cache}
+	token, err := next.Token(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token.AccessToken != "token-1" || atomic.LoadInt32(issued) != 1 {
+		t.Errorf("expected the cached token without a new request, got %q after %d requests", token.AccessToken, *issued)
+	}
+}
+
+func TestClientCredentials_RenewsExpiredToken(t *testing.T) {
+	// Tokens expiring within the leeway are already considered expired
+	server, issued := newTokenServer(t, 10)
+
+	cc := &ClientCredentials{TokenURL: server.URL, ClientID: "id", ClientSecret: "secret", Scopes: []string{"read", "write"}}
+	for i := 1; i <= 2; i++ {
+		token, err := cc.Token(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := fmt.Sprintf("token-%d", i); token.AccessToken != want {
+			t.Errorf("expected %s, got %q", want, token.AccessToken)
+		}
+	}
+	if atomic.LoadInt32(issued) != 2 {
+		t.Errorf("expected 2 token requests, got %d", *issued)
+	}
+}
+
+func TestClientCredentials_ErrorResponse(t *testing.T) {
+	server, _ := newTokenServer(t, 3600)
+
+	cc := &ClientCredentials{TokenURL: server.URL, ClientID: "id", ClientSecret: "wrong"}
+	_, err := cc.Token(context.Background())
+
+	var oauthErr *OAuth2Error
+	if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_client" {
+		t.Fatalf("expected an invalid_client error, got %v", err)
+	}
+	if err.Error() != "oauth2: invalid_client: bad credentials" {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
+
+func TestClientCredentials_Middleware(t *testing.T) {
+	tokenServer, issued := newTokenServer(t, 3600)
+
+	// The API rejects the first token, as if it had been revoked
+	var seen []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer api.Close()
+
+	cc := &ClientCredentials{TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret", Scopes: []string{"read", "write"}}
+	client := &http.Client{Transport: cc.Middleware()(http.DefaultTransport)}
+
+	req, _ := http.NewRequest(http.MethodPost, api.URL, strings.NewReader(`{"a": 1}`))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected the retried request to succeed, got %d", resp.StatusCode)
+	}
+	if strings.Join(seen, ",") != "Bearer token-1,Bearer token-2" || atomic.LoadInt32(issued) != 2 {
+		t.Errorf("expected a retry with a new token, got %v", seen)
+	}
+
+	// An explicit Authorization header is left alone
+	seen = nil
+	req, _ = http.NewRequest(http.MethodGet, api.URL, nil)
+	req.Header.Set("Authorization", "Basic abc")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if len(seen) != 1 || seen[0] != "Basic abc" {
+		t.Errorf("expected the explicit header to be kept, got %v", seen)
+	}
+}
+
+func TestToken_Valid(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		token *Token
+		want  bool
+	}{
+		{nil, false},
+		{&Token{}, false},
+		{&Token{AccessToken: "a"}, true},
+		{&Token{AccessToken: "a", Expiry: now.Add(time.Hour)}, true},
+		{&Token{AccessToken: "a", Expiry: now.Add(10 * time.Second)}, false},
+		{&Token{AccessToken: "a", Expiry: now.Add(-time.Hour)}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.token.Valid(now); got != tt.want {
+			t.Errorf("Valid(%+v) = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package runtime
 
 import (
 	"bytes"
+	"io"
 	"net/http"
 	"strings"
 	"testing"
@@ -118,7 +119,7 @@ func TestHandleResponse_Success(t *testing.T) {
 	}
 
 	buf := new(bytes.Buffer)
-	err := handleResponse(resp, buf)
+	err := handleResponse(resp, buf, outputOptions{ErrOut: io.Discard})
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -139,7 +140,7 @@ func TestHandleResponse_Error(t *testing.T) {
 	}
 
 	buf := new(bytes.Buffer)
-	err := handleResponse(resp, buf)
+	err := handleResponse(resp, buf, outputOptions{ErrOut: io.Discard})
 
 	if err == nil {
 		t.Fatal("expected error for 404 response")
@@ -158,7 +159,7 @@ func TestHandleResponse_EmptyBody(t *testing.T) {
 	}
 
 	buf := new(bytes.Buffer)
-	err := handleResponse(resp, buf)
+	err := handleResponse(resp, buf, outputOptions{ErrOut: io.Discard})
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -179,7 +180,7 @@ func TestHandleResponse_NonJSONBody(t *testing.T) {
 	}
 
 	buf := new(bytes.Buffer)
-	err := handleResponse(resp, buf)
+	err := handleResponse(resp, buf, outputOptions{ErrOut: io.Discard})
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -200,7 +201,7 @@ func TestHandleResponse_ServerError(t *testing.T) {
 	}
 
 	buf := new(bytes.Buffer)
-	err := handleResponse(resp, buf)
+	err := handleResponse(resp, buf, outputOptions{ErrOut: io.Discard})
 
 	if err == nil {
 		t.Fatal("expected error for 500 response")
@@ -210,3 +211,185 @@ func TestHandleResponse_ServerError(t *testing.T) {
 		t.Errorf("expected error to mention status code, got: %v", err)
 	}
 }
+
+func TestHandleResponse_JSONLinesArray(t *testing.T) {
+	body := []byte(`[{"id": 1}, {"id": 2}]`)
+	resp := &http.Response{
+		StatusCode: 200,
+		Status:     "200 OK",
+		Body:       &mockResponseBody{bytes.NewReader(body)},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := handleResponse(resp, buf, outputOptions{Format: FormatJSONL}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if buf.String() != "{\"id\":1}\n{\"id\":2}\n" {
+		t.Errorf("unexpected jsonl output: %q", buf.String())
+	}
+}
+
+func TestHandleResponse_EnvelopeUnwrapped(t *testing.T) {
+	body := []byte(`{"items": [{"id": "a"}, {"id": "b"}], "meta": {"total": 42, "next_cursor": "c2"}}`)
+	resp := &http.Response{
+		StatusCode: 200,
+		Status:     "200 OK",
+		Body:       &mockResponseBody{bytes.NewReader(body)},
+	}
+
+	buf := new(bytes.Buffer)
+	errBuf := new(bytes.Buffer)
+	opts := outputOptions{
+		Format:   FormatJSONL,
+		Envelope: &Envelope{Items: "items", Total: "meta.total", Next: "meta.next_cursor"},
+		ErrOut:   errBuf,
+	}
+	if err := handleResponse(resp, buf, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if buf.String() != "{\"id\":\"a\"}\n{\"id\":\"b\"}\n" {
+		t.Errorf("expected items to be unwrapped, got %q", buf.String())
+	}
+	if errBuf.String() != "# total: 42, next cursor: c2\n" {
+		t.Errorf("unexpected trailer: %q", errBuf.String())
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	if err := ValidateFormat(FormatJSONL); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateFormat("xml"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestHandleResponse_Query(t *testing.T) {
+	body := []byte(`{"items": [{"id": "a", "ok": true}, {"id": "b", "ok": false}], "meta": {"total": 2}}`)
+	query, err := CompileQuery("items[?ok].id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for format, want := range map[string]string{
+		FormatJSON:  "[\n  \"a\"\n]\n",
+		FormatJSONL: "\"a\"\n",
+	} {
+		resp := &http.Response{
+			StatusCode: 200,
+			Status:     "200 OK",
+			Body:       &mockResponseBody{bytes.NewReader(body)},
+		}
+		buf := new(bytes.Buffer)
+		errBuf := new(bytes.Buffer)
+		opts := outputOptions{
+			Format:   format,
+			Envelope: &Envelope{Items: "items", Total: "meta.total"},
+			Query:    query,
+			ErrOut:   errBuf,
+		}
+		if err := handleResponse(resp, buf, opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if buf.String() != want {
+			t.Errorf("%s: got %q, want %q", format, buf.String(), want)
+		}
+		if errBuf.String() != "# total: 2\n" {
+			t.Errorf("%s: unexpected trailer %q", format, errBuf.String())
+		}
+	}
+}
+
+func TestHandleResponse_Raw(t *testing.T) {
+	body := []byte("{\"id\":1,  \"name\":\"a\"}")
+	resp := &http.Response{
+		StatusCode: 200,
+		Status:     "200 OK",
+		Body:       &mockResponseBody{bytes.NewReader(body)},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := handleResponse(resp, buf, outputOptions{Format: FormatJSONL, Raw: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != string(body) {
+		t.Errorf("expected the body as received, got %q", buf.String())
+	}
+}
+
+func TestHandleResponse_Quiet(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"id": 42, "name": "a"}`, "42\n"},
+		{`{"uuid": "u1", "name": "a"}`, "u1\n"},
+		{`{"items": [{"id": "a"}, {"name": "b"}, {"other": 1}], "meta": {"total": 3}}`, "a\nb\n"},
+		{`[{"id": "x"}]`, "x\n"},
+		{`{"ok": true}`, ""},
+		{`not json`, ""},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{
+			StatusCode: 200,
+			Status:     "200 OK",
+			Body:       &mockResponseBody{bytes.NewReader([]byte(tt.body))},
+		}
+		buf := new(bytes.Buffer)
+		errBuf := new(bytes.Buffer)
+		opts := outputOptions{
+			Envelope: &Envelope{Items: "items", Total: "meta.total"},
+			Quiet:    true,
+			ErrOut:   errBuf,
+		}
+		if err := handleResponse(resp, buf, opts); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.body, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.body, buf.String(), tt.want)
+		}
+		if errBuf.Len() != 0 {
+			t.Errorf("%s: expected nothing on stderr, got %q", tt.body, errBuf.String())
+		}
+	}
+}
+
+func TestBinaryBody(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        bool
+	}{
+		{"application/json; charset=utf-8", `{}`, false},
+		{"application/problem+json", `{}`, false},
+		{"text/csv", "a,b", false},
+		{"application/xml", "<a/>", false},
+		{"application/zip", "PK\x03\x04", true},
+		{"image/png", "\x89PNG", true},
+		{"application/octet-stream", "abc", true},
+		{"", `{"id": 1}`, false},
+		{"", "\x00\x01\x02binary", true},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{
+			Header: http.Header{},
+			Body:   &mockResponseBody{bytes.NewReader([]byte(tt.body))},
+		}
+		if tt.contentType != "" {
+			resp.Header.Set("Content-Type", tt.contentType)
+		}
+		if got, _ := binaryBody(resp); got != tt.want {
+			t.Errorf("binaryBody(%q, %q) = %v, want %v", tt.contentType, tt.body, got, tt.want)
+		}
+
+		// Sniffing must not consume the body
+		if rest, _ := io.ReadAll(resp.Body); string(rest) != tt.body {
+			t.Errorf("expected the body to be intact, got %q", rest)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Envelope describes a paginated response body that wraps its items in an
+// object alongside metadata, e.g. {"items": [...], "meta": {"total": 42}}.
+// Each field is a dotted path into the response object; empty paths are
+// ignored.
+type Envelope struct {
+	Items string
+	Total string
+	Next  string
+}
+
+// unwrap returns the items array from a decoded envelope body
+func (e *Envelope) unwrap(body interface{}) ([]interface{}, bool) {
+	if e == nil || e.Items == "" {
+		return nil, false
+	}
+	v, ok := lookupPath(body, e.Items)
+	if !ok {
+		return nil, false
+	}
+	items, ok := v.([]interface{})
+	return items, ok
+}
+
+// writeTrailer prints the total count and next cursor found in body, if any.
+// It is written to stderr so that it never mixes with piped output.
+func (e *Envelope) writeTrailer(body interface{}, out io.Writer) {
+	if e == nil {
+		return
+	}
+
+	var parts []string
+	if e.Total != "" {
+		if total, ok := lookupPath(body, e.Total); ok && total != nil {
+			parts = append(parts, fmt.Sprintf("total: %v", total))
+		}
+	}
+	if e.Next != "" {
+		if next, ok := lookupPath(body, e.Next); ok && next != nil && next != "" {
+			parts = append(parts, fmt.Sprintf("next cursor: %v", next))
+		}
+	}
+
+	if len(parts) > 0 {
+		fmt.Fprintf(out, "# %s\n", strings.Join(parts, ", "))
+	}
+}
+
+// lookupPath resolves a dotted path like "meta.total" in a decoded JSON value
+func lookupPath(v interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok = obj[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// Pagination describes how to request the page after a response of a list
+// operation: through the query parameter taking the envelope's next cursor,
+// a page number parameter, or the rel="next" URL of a Link header
+type Pagination struct {
+	CursorParam string
+	PageParam   string
+	LinkHeader  bool
+}
+
+// DoAll sends a paginated list request and follows req.Pagination through
+// the following pages. The items of all pages are printed together: as one
+// JSON array or table, or with the jsonl format one per line as each page
+// arrives. A query is applied to the array of all items, so with a query
+// the jsonl output is written once the last page arrives. --quiet prints
+// the identifier of each item.
+// When maxItems is positive, it stops after that many items. Pages of a
+// page-numbered list with a total count are fetched PageConcurrency at a
+// time, and printed in order.
+func (r *Runtime) DoAll(ctx context.Context, req *Request, maxItems int) (err error) {
+	defer func() { err = interruption(ctx, err) }()
+
+	if req.Pagination == nil {
+		return r.Do(ctx, req)
+	}
+	pagination := req.Pagination
+	// Every page is requested with the hints of req
+	ctx = req.withHints(ctx)
+	if r.Raw {
+		return errors.New("--raw prints a single response as received and cannot be combined with --all")
+	}
+	if r.OutputFile != "" {
+		return errors.New("--output-file saves a single response and cannot be combined with --all")
+	}
+
+	httpReq, err := r.build(ctx, req)
+	if err != nil {
+		return err
+	}
+	if r.DryRun {
+		writeDryRun(r.Output, httpReq, req.Body)
+		return nil
+	}
+
+	page := 1
+	if pagination.PageParam != "" {
+		if v := req.QueryParams[pagination.PageParam]; v != "" {
+			if page, err = strconv.Atoi(v); err != nil {
+				return fmt.Errorf("invalid %s %q: %w", pagination.PageParam, v, err)
+			}
+		}
+	}
+
+	var all []interface{}
+	count, fetched := 0, 0
+	seen := map[string]bool{}
+	opts := r.outputOptions(req)
+	errOut := opts.errOut()
+	stream := (r.Format == FormatJSONL || r.Quiet) && r.Query == nil
+
+	// emit prints or collects the items of a page, and reports whether the
+	// item limit was reached; more tells whether pages follow
+	emit := func(items []interface{}, more bool) (bool, error) {
+		limited := false
+		for _, item := range items {
+			if maxItems > 0 && count == maxItems {
+				limited = true
+				break
+			}
+			count++
+			if stream && r.Quiet {
+				if id := itemID(item); id != "" {
+					fmt.Fprintln(r.Output, id)
+				}
+			} else if stream {
+				line, err := json.Marshal(item)
+				if err != nil {
+					return false, fmt.Errorf("failed to encode output: %w", err)
+				}
+				fmt.Fprintln(r.Output, string(line))
+			} else {
+				all = append(all, item)
+			}
+		}
+		if maxItems > 0 && count == maxItems && more {
+			limited = true
+		}
+		if limited && !r.Quiet {
+			fmt.Fprintf(errOut, "# stopped after %d items; raise --max-items to fetch more\n", count)
+		}
+		return limited, nil
+	}
+
+	first := true
+	for {
+		items, parsed, header, err := r.fetchPage(httpReq, req.Envelope)
+		if err != nil {
+			return err
+		}
+		fetched += len(items)
+
+		// Find the next page before printing, to tell whether the item
+		// limit cut the results short
+		var next *url.URL
+		byPage := false
+		if link := nextLink(header.Values("Link")); link != "" {
+			if next, err = httpReq.URL.Parse(link); err != nil {
+				return fmt.Errorf("invalid Link header: %w", err)
+			}
+		} else if pagination.CursorParam != "" && req.Envelope != nil && req.Envelope.Next != "" {
+			if cursor := cursorString(parsed, req.Envelope.Next); cursor != "" && !seen[cursor] {
+				seen[cursor] = true
+				next = withQuery(httpReq.URL, pagination.CursorParam, cursor)
+			}
+		} else if pagination.PageParam != "" && len(items) > 0 && !reachedTotal(parsed, req.Envelope, fetched) {
+			page++
+			next = withQuery(httpReq.URL, pagination.PageParam, strconv.Itoa(page))
+			byPage = true
+		}
+
+		limited, err := emit(items, next != nil)
+		if err != nil {
+			return err
+		}
+		if limited || next == nil {
+			break
+		}
+
+		// The total count on the first page of a page-numbered list tells
+		// how many pages follow, so they can be fetched at once
+		if first && byPage && r.PageConcurrency > 1 {
+			if pages := pagesLeft(parsed, req.Envelope, len(items), page-1); pages > 1 {
+				more := false
+				if maxItems > 0 {
+					if needed := (maxItems - count + len(items) - 1) / len(items); needed < pages {
+						pages, more = needed, true
+					}
+				}
+				if err := r.fetchPages(ctx, httpReq, req.Envelope, pagination.PageParam, page, pages, more, emit); err != nil {
+					return err
+				}
+				break
+			}
+		}
+		first = false
+
+		httpReq = httpReq.Clone(ctx)
+		httpReq.URL = next
+		httpReq.Host = next.Host
+	}
+
+	if stream {
+		return nil
+	}
+	var result interface{} = all
+	if all == nil {
+		result = []interface{}{}
+	}
+	if r.Query != nil {
+		if result, err = r.Query.Search(result); err != nil {
+			return err
+		}
+	}
+	if r.Quiet {
+		writeIDs(result, nil, r.Output)
+		return nil
+	}
+	return writeParsed(result, nil, opts, r.Output)
+}
+
+// fetchPages fetches count pages of a page-numbered list from page first
+// on, with up to PageConcurrency requests at once, and passes the items of
+// each page to emit in page order, so the output is the same as when they
+// are fetched one by one. At most PageConcurrency pages are held before
+// they are emitted. more tells whether pages follow the last one.
+func (r *Runtime) fetchPages(ctx context.Context, httpReq *http.Request, envelope *Envelope, param string, first, count int, more bool, emit func([]interface{}, bool) (bool, error)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		items []interface{}
+		err   error
+	}
+	results := make([]chan result, count)
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	slots := make(chan struct{}, r.PageConcurrency)
+	go func() {
+		for i := range results {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(i int) {
+				pageReq := httpReq.Clone(ctx)
+				pageReq.URL = withQuery(httpReq.URL, param, strconv.Itoa(first+i))
+				items, _, _, err := r.fetchPage(pageReq, envelope)
+				results[i] <- result{items, err}
+			}(i)
+		}
+	}()
+
+	for i := range results {
+		res := <-results[i]
+		<-slots
+		if res.err != nil {
+			return res.err
+		}
+		if limited, err := emit(res.items, i < count-1 || more); err != nil || limited {
+			return err
+		}
+	}
+	return nil
+}
+
+// pagesLeft returns how many pages follow page of a list split into pages
+// of pageSize items, from the total count in body, or 0 when it has none
+func pagesLeft(body interface{}, envelope *Envelope, pageSize, page int) int {
+	if envelope == nil || envelope.Total == "" || pageSize == 0 {
+		return 0
+	}
+	total, ok := lookupPath(body, envelope.Total)
+	if !ok {
+		return 0
+	}
+	n, ok := total.(float64)
+	if !ok {
+		return 0
+	}
+	last := (int(n) + pageSize - 1) / pageSize
+	if last <= page {
+		return 0
+	}
+	return last - page
+}
+
+// fetchPage sends httpReq and returns the items of the response, the
+// decoded body and the response headers
+func (r *Runtime) fetchPage(httpReq *http.Request, envelope *Envelope) ([]interface{}, interface{}, http.Header, error) {
+	resp, err := r.send(httpReq, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if err := checkStatus(resp, body, outputOptions{ErrOut: r.ErrOutput}.errOut()); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, nil, nil, fmt.Errorf("response from %s is not JSON: %w", redactQuery(httpReq.URL), err)
+	}
+	items, ok := envelope.unwrap(parsed)
+	if !ok {
+		if items, ok = parsed.([]interface{}); !ok {
+			return nil, nil, nil, fmt.Errorf("response from %s is not a list of items", redactQuery(httpReq.URL))
+		}
+	}
+	return items, parsed, resp.Header, nil
+}
+
+// nextLink returns the rel="next" target of Link header values (RFC 8288),
+// e.g. <https://api.example.com/items?page=2>; rel="next"
+func nextLink(values []string) string {
+	for _, value := range values {
+		for _, link := range strings.Split(value, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, rel, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+				for _, r := range strings.Fields(strings.Trim(rel, `"`)) {
+					if strings.EqualFold(r, "next") {
+						return strings.Trim(target, "<>")
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// cursorString returns the cursor at path in body, or "" when there is none
+func cursorString(body interface{}, path string) string {
+	v, ok := lookupPath(body, path)
+	if !ok {
+		return ""
+	}
+	return scalarString(v)
+}
+
+// scalarString returns a string or number as text, and "" for anything else
+func scalarString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// reachedTotal reports whether fetched items cover the total count in body
+func reachedTotal(body interface{}, envelope *Envelope, fetched int) bool {
+	if envelope == nil || envelope.Total == "" {
+		return false
+	}
+	total, ok := lookupPath(body, envelope.Total)
+	if !ok {
+		return false
+	}
+	n, ok := total.(float64)
+	return ok && float64(fetched) >= n
+}
+
+// withQuery returns a copy of u with the query parameter name set to value
+func withQuery(u *url.URL, name, value string) *url.URL {
+	next := *u
+	query := next.Query()
+	query.Set(name, value)
+	next.RawQuery = query.Encode()
+	return &next
+}
@@ -0,0 +1,381 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLookupPath(t *testing.T) {
+	body := map[string]interface{}{
+		"meta": map[string]interface{}{"total": 3.0},
+		"data": []interface{}{},
+	}
+
+	if v, ok := lookupPath(body, "meta.total"); !ok || v != 3.0 {
+		t.Errorf("expected meta.total to be 3, got %v (ok=%v)", v, ok)
+	}
+	if _, ok := lookupPath(body, "meta.missing"); ok {
+		t.Error("expected missing path to not be found")
+	}
+	if _, ok := lookupPath(body, "data.nested"); ok {
+		t.Error("expected lookup through an array to fail")
+	}
+}
+
+func TestEnvelope_Unwrap(t *testing.T) {
+	body := map[string]interface{}{
+		"data": []interface{}{"a", "b"},
+	}
+
+	items, ok := (&Envelope{Items: "data"}).unwrap(body)
+	if !ok || len(items) != 2 {
+		t.Errorf("expected 2 items, got %v (ok=%v)", items, ok)
+	}
+
+	var nilEnvelope *Envelope
+	if _, ok := nilEnvelope.unwrap(body); ok {
+		t.Error("expected nil envelope to not unwrap")
+	}
+}
+
+func TestEnvelope_WriteTrailer_OmitsEmptyCursor(t *testing.T) {
+	body := map[string]interface{}{
+		"total":       10.0,
+		"next_cursor": "",
+	}
+
+	var buf bytes.Buffer
+	(&Envelope{Items: "items", Total: "total", Next: "next_cursor"}).writeTrailer(body, &buf)
+
+	if buf.String() != "# total: 10\n" {
+		t.Errorf("unexpected trailer: %q", buf.String())
+	}
+}
+
+func TestRuntime_DoAll(t *testing.T) {
+	tests := []struct {
+		name       string
+		pagination Pagination
+		envelope   *Envelope
+		handler    func(w http.ResponseWriter, r *http.Request)
+	}{
+		{
+			name:       "cursor",
+			pagination: Pagination{CursorParam: "cursor"},
+			envelope:   &Envelope{Items: "data", Next: "meta.next_cursor"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Query().Get("cursor") {
+				case "":
+					fmt.Fprint(w, `{"data": [1, 2], "meta": {"next_cursor": "c2"}}`)
+				case "c2":
+					fmt.Fprint(w, `{"data": [3, 4], "meta": {"next_cursor": "c3"}}`)
+				default:
+					fmt.Fprint(w, `{"data": [5], "meta": {"next_cursor": null}}`)
+				}
+			},
+		},
+		{
+			name:       "page",
+			pagination: Pagination{PageParam: "page"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Query().Get("page") {
+				case "":
+					fmt.Fprint(w, `[1, 2]`)
+				case "2":
+					fmt.Fprint(w, `[3, 4]`)
+				case "3":
+					fmt.Fprint(w, `[5]`)
+				default:
+					fmt.Fprint(w, `[]`)
+				}
+			},
+		},
+		{
+			name:       "page with total",
+			pagination: Pagination{PageParam: "page"},
+			envelope:   &Envelope{Items: "items", Total: "total"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Query().Get("page") {
+				case "":
+					fmt.Fprint(w, `{"items": [1, 2, 3], "total": 5}`)
+				case "2":
+					fmt.Fprint(w, `{"items": [4, 5], "total": 5}`)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			},
+		},
+		{
+			name:       "link header",
+			pagination: Pagination{LinkHeader: true},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Query().Get("after") {
+				case "":
+					w.Header().Set("Link", `</v1/items?after=2>; rel="next", </v1/items>; rel="first"`)
+					fmt.Fprint(w, `[1, 2]`)
+				case "2":
+					w.Header().Set("Link", `<?after=4>; rel="next"`)
+					fmt.Fprint(w, `[3, 4]`)
+				default:
+					fmt.Fprint(w, `[5]`)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(tt.handler))
+			defer server.Close()
+
+			var out bytes.Buffer
+			rt := New(server.URL, 5*time.Second)
+			rt.Output = &out
+			rt.Format = FormatJSONL
+
+			req := NewRequest("GET", "/v1/items")
+			req.Envelope = tt.envelope
+			req.SetPagination(tt.pagination.CursorParam, tt.pagination.PageParam, tt.pagination.LinkHeader)
+			if err := rt.DoAll(context.Background(), req, 0); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != "1\n2\n3\n4\n5\n" {
+				t.Errorf("unexpected output: %q", out.String())
+			}
+		})
+	}
+}
+
+func TestRuntime_DoAll_ConcurrentPages(t *testing.T) {
+	var mu sync.Mutex
+	var requested []int
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		mu.Lock()
+		requested = append(requested, page)
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		// Earlier pages answer last, so they arrive out of order
+		if page > 1 {
+			time.Sleep(time.Duration(8-page) * 10 * time.Millisecond)
+		}
+		if page == 6 && r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		items := []string{strconv.Itoa(page*2 - 1), strconv.Itoa(page * 2)}
+		if page == 6 {
+			items = items[:1]
+		}
+		fmt.Fprintf(w, `{"items": [%s], "total": 11}`, strings.Join(items, ", "))
+	}))
+	defer server.Close()
+
+	run := func(maxItems int, query map[string]string) (string, string, error) {
+		requested, maxInFlight = nil, 0
+		var out, errOut bytes.Buffer
+		rt := New(server.URL, 5*time.Second)
+		rt.Output = &out
+		rt.ErrOutput = &errOut
+		rt.Format = FormatJSONL
+		rt.PageConcurrency = 3
+
+		req := NewRequest("GET", "/v1/items")
+		req.QueryParams = query
+		req.SetEnvelope("items", "total", "")
+		req.SetPagination("", "page", false)
+		err := rt.DoAll(context.Background(), req, maxItems)
+		return out.String(), errOut.String(), err
+	}
+
+	output, _, err := run(0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n" {
+		t.Errorf("expected the items in page order, got %q", output)
+	}
+	if len(requested) != 6 || maxInFlight < 2 || maxInFlight > 3 {
+		t.Errorf("expected 6 pages with up to 3 at once, got %v with %d at once", requested, maxInFlight)
+	}
+
+	// Only the pages needed for the limit are fetched
+	output, errOutput, err := run(5, nil)
+	if err != nil || output != "1\n2\n3\n4\n5\n" || !strings.Contains(errOutput, "stopped after 5 items") {
+		t.Errorf("expected the limit to stop the output, got %v: %q %q", err, output, errOutput)
+	}
+	if len(requested) != 3 {
+		t.Errorf("expected 3 pages to be fetched, got %v", requested)
+	}
+
+	// A failed page fails the command after the pages before it
+	output, _, err = run(0, map[string]string{"fail": "1"})
+	if err == nil || output != "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n" {
+		t.Errorf("expected the pages before the failed one, got %v: %q", err, output)
+	}
+}
+
+func TestRuntime_DoAll_MaxItems(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		fmt.Fprintf(w, `[%d, %d]`, page*2+1, page*2+2)
+	}))
+	defer server.Close()
+
+	var out, errOut bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+	rt.ErrOutput = &errOut
+
+	req := NewRequest("GET", "/v1/items")
+	req.SetQueryParam("page", "0")
+	req.SetPagination("", "page", false)
+	if err := rt.DoAll(context.Background(), req, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if out.String() != "[\n  1,\n  2,\n  3\n]\n" {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+	if !strings.Contains(errOut.String(), "stopped after 3 items") {
+		t.Errorf("expected a notice about the limit, got %q", errOut.String())
+	}
+}
+
+func TestRuntime_DoAll_Query(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page > 1 {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprintf(w, `[{"n": %d}, {"n": %d}]`, page*2+1, page*2+2)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+	rt.Format = FormatJSONL
+	rt.Query, _ = CompileQuery("[?n > `1`].n | reverse(@)")
+
+	req := NewRequest("GET", "/v1/items")
+	req.SetQueryParam("page", "0")
+	req.SetPagination("", "page", false)
+	if err := rt.DoAll(context.Background(), req, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The query sees the items of every page at once
+	if out.String() != "4\n3\n2\n" {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
+
+func TestRuntime_DoAll_Quiet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		fmt.Fprintf(w, `[{"id": "i%d"}, {"id": "i%d"}]`, page*2+1, page*2+2)
+	}))
+	defer server.Close()
+
+	var out, errOut bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+	rt.ErrOutput = &errOut
+	rt.Quiet = true
+
+	req := NewRequest("GET", "/v1/items")
+	req.SetQueryParam("page", "0")
+	req.SetPagination("", "page", false)
+	if err := rt.DoAll(context.Background(), req, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if out.String() != "i1\ni2\ni3\n" || errOut.Len() != 0 {
+		t.Errorf("unexpected output: %q, stderr %q", out.String(), errOut.String())
+	}
+
+	rt.Quiet, rt.Raw = false, true
+	if err := rt.DoAll(context.Background(), req, 3); err == nil || !strings.Contains(err.Error(), "--raw") {
+		t.Errorf("expected --raw to be rejected, got %v", err)
+	}
+}
+
+func TestRuntime_DoAll_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cursor") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"data": [1], "next": "c2"}`)
+	}))
+	defer server.Close()
+
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = io.Discard
+	rt.ErrOutput = io.Discard
+
+	req := NewRequest("GET", "/v1/items")
+	req.SetEnvelope("data", "", "next")
+	req.SetPagination("cursor", "", false)
+	if err := rt.DoAll(context.Background(), req, 0); err == nil || !strings.Contains(err.Error(), "status 500") {
+		t.Errorf("expected the failed page to be reported, got %v", err)
+	}
+
+	// Responses that are not lists cannot be paginated
+	req = NewRequest("GET", "/v1/items")
+	req.SetPagination("cursor", "", false)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 1}`)
+	})
+	if err := rt.DoAll(context.Background(), req, 0); err == nil || !strings.Contains(err.Error(), "not a list") {
+		t.Errorf("expected an error for a non-list response, got %v", err)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   string
+	}{
+		{[]string{`<https://api.example.com/items?page=2>; rel="next"`}, "https://api.example.com/items?page=2"},
+		{[]string{`<https://a/1>; rel="prev", <https://a/3>; rel="next last"`}, "https://a/3"},
+		{[]string{`<https://a/1>; rel="prev"`, `<https://a/2>; rel=next`}, "https://a/2"},
+		{[]string{`<https://a/1>; rel="prev"`}, ""},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := nextLink(tt.values); got != tt.want {
+			t.Errorf("nextLink(%q) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestQuery_Search(t *testing.T) {
+	body := `{
+		"data": [
+			{"id": "t1", "name": "Write docs", "status": "open", "points": 3, "tags": ["docs"], "owner": {"name": "ana"}},
+			{"id": "t2", "name": "Ship", "status": "done", "points": 5, "tags": ["release", "ops"], "owner": {"name": "bo"}},
+			{"id": "t3", "name": "Fix bug", "status": "open", "points": 1, "tags": [], "owner": null}
+		],
+		"meta": {"total": 3, "next": null},
+		"weird key": true
+	}`
+
+	tests := []struct {
+		expr string
+		want string // compact JSON
+	}{
+		{"meta.total", `3`},
+		{"data[0].id", `"t1"`},
+		{"data[-1].id", `"t3"`},
+		{"data[5]", `null`},
+		{`"weird key"`, `true`},
+		{"data[*].id", `["t1","t2","t3"]`},
+		{"data[].owner.name", `["ana","bo"]`},
+		{"data[1:].id", `["t2","t3"]`},
+		{"data[::-1].id", `["t3","t2","t1"]`},
+		{"data[].tags[]", `["docs","release","ops"]`},
+		{"meta.*", `[3]`},
+		{"data[?status=='open'].id", `["t1","t3"]`},
+		{"data[?points > `2` && status != 'done'].name", `["Write docs"]`},
+		{"data[?!owner].id", `["t3"]`},
+		{"data[?contains(tags, 'ops')].id", `["t2"]`},
+		{"data[0].{id: id, who: owner.name}", `{"id":"t1","who":"ana"}`},
+		{"data[0].[id, points]", `["t1",3]`},
+		{"data[*].id | [0]", `"t1"`},
+		{"length(data)", `3`},
+		{"sum(data[*].points)", `9`},
+		{"max_by(data, &points).id", `"t2"`},
+		{"sort_by(data, &name)[*].id", `["t3","t2","t1"]`},
+		{"sort(data[*].name)", `["Fix bug","Ship","Write docs"]`},
+		{"join(', ', data[*].id)", `"t1, t2, t3"`},
+		{"sort(keys(meta))", `["next","total"]`},
+		{"meta.next || 'none'", `"none"`},
+		{"map(&starts_with(name, 'S'), data)", `[false,true,false]`},
+		{"to_string(meta.total)", `"3"`},
+		{"@.meta.total", `3`},
+		{"`{\"a\": 1}`.a", `1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			// Functions such as sort_by may reorder the data they are given
+			var data interface{}
+			if err := json.Unmarshal([]byte(body), &data); err != nil {
+				t.Fatal(err)
+			}
+			q, err := CompileQuery(tt.expr)
+			if err != nil {
+				t.Fatalf("CompileQuery: %v", err)
+			}
+			result, err := q.Search(data)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			got, _ := json.Marshal(result)
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileQuery_Errors(t *testing.T) {
+	for _, expr := range []string{"", "data[", "data.", "a = b", "{a}", "'open", "data[?]", "a b"} {
+		if _, err := CompileQuery(expr); err == nil {
+			t.Errorf("CompileQuery(%q): expected an error", expr)
+		} else if !strings.Contains(err.Error(), "invalid query") {
+			t.Errorf("CompileQuery(%q): unexpected error %v", expr, err)
+		}
+	}
+}
+
+func TestQuery_SearchErrors(t *testing.T) {
+	for _, expr := range []string{"nope(a)", "length(`1`)", "abs('a')", "[1:2:0]"} {
+		q, err := CompileQuery(expr)
+		if err != nil {
+			t.Fatalf("CompileQuery(%q): %v", expr, err)
+		}
+		if _, err := q.Search([]interface{}{map[string]interface{}{"a": true}}); err == nil {
+			t.Errorf("Search(%q): expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxRedirects is how many redirects a request follows by default
+const DefaultMaxRedirects = 10
+
+// checkRedirect follows up to MaxRedirects redirects per request, and none
+// with NoFollow, which makes the redirect response the result. Go's client
+// keeps the method and body for 307 and 308, and sends a GET without the
+// body for 301, 302 and 303 after anything but GET or HEAD.
+func (r *Runtime) checkRedirect(req *http.Request, via []*http.Request) error {
+	if r.NoFollow {
+		return http.ErrUseLastResponse
+	}
+	if len(via) > r.MaxRedirects {
+		return fmt.Errorf("stopped after %d redirects (raise the limit with --max-redirects)", r.MaxRedirects)
+	}
+	return nil
+}
+
+// isRedirect reports whether resp redirects to another location
+func isRedirect(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return resp.Header.Get("Location") != ""
+	}
+	return false
+}
+
+// writeRedirect prints where a redirect that was not followed leads: the
+// resolved Location to out, for scripts, and the status to errOut
+func writeRedirect(resp *http.Response, out, errOut io.Writer) error {
+	location, err := resp.Location()
+	if err != nil {
+		return fmt.Errorf("invalid redirect location %q: %w", resp.Header.Get("Location"), err)
+	}
+	fmt.Fprintf(errOut, "Redirect not followed: %s\n", resp.Status)
+	fmt.Fprintln(out, location.String())
+	return nil
+}
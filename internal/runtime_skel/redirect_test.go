@@ -0,0 +1,96 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuntime_Redirects(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r.Method+" "+r.URL.Path+" "+string(body)+" "+r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/temporary":
+			http.Redirect(w, r, "/tasks", http.StatusTemporaryRedirect)
+		case "/permanent":
+			http.Redirect(w, r, "/tasks", http.StatusPermanentRedirect)
+		case "/found":
+			http.Redirect(w, r, "/tasks", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte(`{"id": "t1"}`))
+		}
+	}))
+	defer server.Close()
+
+	newRuntime := func() (*Runtime, *bytes.Buffer, *bytes.Buffer) {
+		var out, errOut bytes.Buffer
+		rt := New(server.URL, 5*time.Second)
+		rt.Output = &out
+		rt.ErrOutput = &errOut
+		rt.AddHeader("Authorization", "Bearer secret")
+		return rt, &out, &errOut
+	}
+	post := func(path string) *Request {
+		req := NewRequest("POST", path)
+		req.SetBody([]byte(`{"title": "a"}`))
+		return req
+	}
+
+	// 307 and 308 repeat the method and body; 302 switches to GET without it
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/temporary", `POST /tasks {"title": "a"} Bearer secret`},
+		{"/permanent", `POST /tasks {"title": "a"} Bearer secret`},
+		{"/found", `GET /tasks  Bearer secret`},
+	}
+	for _, tt := range tests {
+		received = nil
+		rt, out, _ := newRuntime()
+		if err := rt.Do(context.Background(), post(tt.path)); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.path, err)
+		}
+		if len(received) != 2 || received[1] != tt.want {
+			t.Errorf("%s: expected the redirect to send %q, got %q", tt.path, tt.want, received)
+		}
+		if !strings.Contains(out.String(), `"t1"`) {
+			t.Errorf("%s: expected the final response to be printed, got %q", tt.path, out.String())
+		}
+	}
+
+	// The limit counts redirects, not requests
+	received = nil
+	rt, _, _ := newRuntime()
+	rt.MaxRedirects = 3
+	err := rt.Do(context.Background(), NewRequest("GET", "/loop"))
+	if err == nil || !strings.Contains(err.Error(), "stopped after 3 redirects") {
+		t.Errorf("expected the redirect limit to stop the loop, got %v", err)
+	}
+	if len(received) != 4 {
+		t.Errorf("expected 3 redirects to be followed, got %d requests", len(received))
+	}
+
+	// Without following, the redirect's target is the result
+	received = nil
+	rt, out, errOut := newRuntime()
+	rt.NoFollow = true
+	if err := rt.Do(context.Background(), post("/temporary")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(received) != 1 {
+		t.Errorf("expected the redirect not to be followed, got %q", received)
+	}
+	if out.String() != server.URL+"/tasks\n" || errOut.String() != "Redirect not followed: 307 Temporary Redirect\n" {
+		t.Errorf("unexpected output %q, %q", out.String(), errOut.String())
+	}
+}
@@ -240,3 +240,22 @@ func TestRequest_Build_NoPathParams(t *testing.T) {
 		t.Errorf("expected URL %q, got %q", expectedURL, httpReq.URL.String())
 	}
 }
+
+func TestRequest_Build_QueryInPath(t *testing.T) {
+	req := NewRequest("GET", "/anything?x=1&y=two")
+	req.SetQueryParam("z", "3")
+
+	httpReq, err := req.Build(context.Background(), "https://api.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if httpReq.URL.Path != "/anything" {
+		t.Errorf("expected path /anything, got %s", httpReq.URL.Path)
+	}
+
+	query := httpReq.URL.Query()
+	if query.Get("x") != "1" || query.Get("y") != "two" || query.Get("z") != "3" {
+		t.Errorf("expected merged query parameters, got %s", httpReq.URL.RawQuery)
+	}
+}
@@ -0,0 +1,56 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestNewRequestID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first, err := NewRequestID()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := NewRequestID()
+	if !uuid.MatchString(first) || first == second {
+		t.Errorf("expected distinct version 4 UUIDs, got %q and %q", first, second)
+	}
+}
+
+func TestRuntime_StatusErrorRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		returned http.Header
+		want     string
+	}{
+		{"returned", http.Header{"X-Request-Id": {"server-id"}}, "server-id"},
+		{"other header", http.Header{"X-Correlation-Id": {"correlation-id"}}, "correlation-id"},
+		{"sent", http.Header{}, "sent-id"},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for name, values := range tt.returned {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+
+		rt := New(server.URL, 5*time.Second)
+		rt.ErrOutput = io.Discard
+		rt.AddHeader(RequestIDHeader, "sent-id")
+		err := rt.Do(context.Background(), NewRequest("GET", "/things"))
+		server.Close()
+
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || statusErr.RequestID != tt.want {
+			t.Errorf("%s: expected request ID %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRuntime_RequestLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "t1"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "logs", "requests.log")
+	f, err := OpenLogFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &bytes.Buffer{}
+	rt.ErrOutput = &bytes.Buffer{}
+	rt.Log = &RequestLog{Out: f, Command: "tasks tasks get"}
+	rt.AddHeader("Authorization", "Bearer secret")
+
+	if err := rt.Do(context.Background(), NewRequest("GET", "/tasks/t1?api_key=k1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = rt.Do(context.Background(), NewRequest("GET", "/missing"))
+	rt.BaseURL = "http://127.0.0.1:1"
+	_ = rt.Do(context.Background(), NewRequest("GET", "/down"))
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the log file to be private, got %o", info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []RequestLogEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var entry RequestLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected a line per request, got:\n%s", data)
+	}
+
+	first := entries[0]
+	if first.Command != "tasks tasks get" || first.Method != "GET" || first.URL != server.URL+"/tasks/t1?api_key=***" || first.Status != 200 {
+		t.Errorf("unexpected entry: %+v", first)
+	}
+	if first.RequestHeaders["Authorization"] != "Bearer ***" || first.ResponseHeaders["Set-Cookie"] != "***" {
+		t.Errorf("expected credentials to be masked, got %v and %v", first.RequestHeaders, first.ResponseHeaders)
+	}
+	if entries[1].Status != 404 {
+		t.Errorf("expected the error status to be logged, got %+v", entries[1])
+	}
+	if entries[2].Status != 0 || entries[2].Error == "" {
+		t.Errorf("expected the network error to be logged, got %+v", entries[2])
+	}
+}
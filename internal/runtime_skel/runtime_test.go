@@ -0,0 +1,283 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRuntime_ReadOnlyBlocksMutations(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	rt := New(server.URL, 5*time.Second)
+	rt.ReadOnly = true
+
+	err := rt.Do(context.Background(), NewRequest("DELETE", "/v1/tasks/1"))
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "DELETE /v1/tasks/1") {
+		t.Errorf("expected error to name the blocked request, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("expected no request to reach the server, got %d", calls)
+	}
+
+	if err := rt.Do(context.Background(), NewRequest("GET", "/v1/tasks")); err != nil {
+		t.Errorf("expected GET to be allowed, got %v", err)
+	}
+	if err := rt.Do(context.Background(), NewRequest("HEAD", "/v1/tasks")); err != nil {
+		t.Errorf("expected HEAD to be allowed, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 requests to reach the server, got %d", calls)
+	}
+}
+
+func TestRuntime_DryRun(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	var out strings.Builder
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+	rt.DryRun = true
+	rt.AddHeader("Authorization", "Bearer s3cret")
+
+	req := NewRequest("POST", "/v1/tasks/{id}")
+	req.SetPathParam("id", "1")
+	req.SetQueryParam("notify", "true")
+	req.SetBody([]byte(`{"title": "write tests"}`))
+	if err := rt.Do(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "POST " + server.URL + "/v1/tasks/1?notify=true\n" +
+		"Authorization: Bearer ***\n" +
+		"Content-Type: application/json\n" +
+		"\n" +
+		`{"title": "write tests"}` + "\n"
+	if out.String() != want {
+		t.Errorf("dry run output = %q, want %q", out.String(), want)
+	}
+	if calls != 0 {
+		t.Errorf("expected no request to reach the server, got %d", calls)
+	}
+
+	// Requests are still validated
+	if err := rt.Do(context.Background(), NewRequest("GET", "/v1/tasks/{id}")); err == nil {
+		t.Error("expected a missing path parameter to fail")
+	}
+}
+
+func TestRuntime_BinaryResponsePassedThrough(t *testing.T) {
+	body := []byte("\x89PNG\r\n\x1a\n\x00\x00")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+
+	if err := rt.Do(context.Background(), NewRequest("GET", "/logo.png")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), body) {
+		t.Errorf("expected the body byte for byte, got %q", out.Bytes())
+	}
+}
+
+func TestRuntime_Accept(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Accept") {
+		case "text/csv":
+			w.Header().Set("Content-Type", "text/csv")
+			_, _ = w.Write([]byte("id,title\nb1,Go\n"))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`[{"id":"b1","title":"Go"}]`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"text/csv", "id,title\nb1,Go\n"},
+		{"application/json", "[\n  {\n    \"id\": \"b1\",\n    \"title\": \"Go\"\n  }\n]\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		rt := New(server.URL, 5*time.Second)
+		rt.Output = &out
+		// A global Accept, e.g. from the config file's headers, does not
+		// override --accept
+		rt.AddHeader("accept", "application/xml")
+		req := NewRequest("GET", "/export")
+		req.SetAccept(tt.accept)
+		if err := rt.Do(context.Background(), req); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.accept, err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.accept, tt.want, out.String())
+		}
+	}
+}
+
+func TestRuntime_HeadAndOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		w.Header().Set("X-Ratelimit-Remaining", "42")
+		w.Header().Set("Date", "Wed, 01 May 2024 12:00:00 GMT")
+		switch {
+		case r.Method == http.MethodOptions && r.URL.Path == "/described":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"methods":["GET"]}`))
+		case r.Method == http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Content-Length", "1234")
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		method, path string
+		want         string
+	}{
+		{"HEAD", "/tasks", "HTTP/1.1 200 OK\nAllow: GET, HEAD, OPTIONS\nContent-Length: 1234\nDate: Wed, 01 May 2024 12:00:00 GMT\nX-Ratelimit-Remaining: 42\n"},
+		{"OPTIONS", "/tasks", "HTTP/1.1 204 No Content\nAllow: GET, HEAD, OPTIONS\nDate: Wed, 01 May 2024 12:00:00 GMT\nX-Ratelimit-Remaining: 42\n"},
+		{"OPTIONS", "/described", "HTTP/1.1 200 OK\nAllow: GET, HEAD, OPTIONS\nContent-Length: 19\nContent-Type: application/json\nDate: Wed, 01 May 2024 12:00:00 GMT\nX-Ratelimit-Remaining: 42\n\n{\n  \"methods\": [\n    \"GET\"\n  ]\n}\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		rt := New(server.URL, 5*time.Second)
+		rt.Output = &out
+		if err := rt.Do(context.Background(), NewRequest(tt.method, tt.path)); err != nil {
+			t.Fatalf("%s %s: unexpected error: %v", tt.method, tt.path, err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s %s: expected\n%s\ngot\n%s", tt.method, tt.path, tt.want, out.String())
+		}
+	}
+
+	// --quiet prints nothing for a response without a body
+	var out bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+	rt.Quiet = true
+	if err := rt.Do(context.Background(), NewRequest("HEAD", "/tasks")); err != nil || out.Len() != 0 {
+		t.Errorf("expected no output with --quiet, got %v, %q", err, out.String())
+	}
+}
+
+func TestRuntime_AddHeaderReplacesAnyCase(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Values("X-Feature")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	rt := New(server.URL, 5*time.Second)
+	rt.AddHeader("x-feature", "from-config")
+	rt.AddHeader("X-Feature", "from-flag")
+	if err := rt.Do(context.Background(), NewRequest("GET", "/")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "from-flag" {
+		t.Errorf("expected the later header to replace the earlier one, got %v", got)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		input, name, value string
+		wantErr            bool
+	}{
+		{input: "X-Trace-Id: abc123", name: "X-Trace-Id", value: "abc123"},
+		{input: "X-Empty:", name: "X-Empty", value: ""},
+		{input: "Accept:application/json", name: "Accept", value: "application/json"},
+		{input: "X-Url: https://example.com", name: "X-Url", value: "https://example.com"},
+		{input: "no-colon", wantErr: true},
+		{input: ": value", wantErr: true},
+		{input: "Bad Name: value", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		name, value, err := ParseHeader(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseHeader(%q): expected an error", tt.input)
+			}
+			continue
+		}
+		if err != nil || name != tt.name || value != tt.value {
+			t.Errorf("ParseHeader(%q) = %q, %q, %v; want %q, %q", tt.input, name, value, err, tt.name, tt.value)
+		}
+	}
+}
+
+func TestRuntime_AddQueryParam(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.RawQuery
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	rt := New(server.URL, 5*time.Second)
+	rt.AddQueryParam("tag", "b")
+	rt.AddQueryParam("debug", "")
+	req := NewRequest("GET", "/things")
+	req.SetQueryParam("tag", "a")
+	if err := rt.Do(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "debug=&tag=a&tag=b" {
+		t.Errorf("expected the extra parameters after the request's own, got %q", got)
+	}
+}
+
+func TestParseQueryParam(t *testing.T) {
+	tests := []struct {
+		input, name, value string
+		wantErr            bool
+	}{
+		{input: "beta=true", name: "beta", value: "true"},
+		{input: "empty=", name: "empty", value: ""},
+		{input: "filter=a=b", name: "filter", value: "a=b"},
+		{input: "no-equals", wantErr: true},
+		{input: "=value", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		name, value, err := ParseQueryParam(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseQueryParam(%q): expected an error", tt.input)
+			}
+			continue
+		}
+		if err != nil || name != tt.name || value != tt.value {
+			t.Errorf("ParseQueryParam(%q) = %q, %q, %v; want %q, %q", tt.input, name, value, err, tt.name, tt.value)
+		}
+	}
+}
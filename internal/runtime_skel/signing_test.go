@@ -0,0 +1,153 @@
+package runtime
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// AWS Signature Version 4 test suite credentials
+var sigV4TestSigner = &SigV4Signer{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	Region:          "us-east-1",
+	Service:         "service",
+	now:             func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+}
+
+func TestSigV4Signer_TestSuite(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", http.MethodGet, "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"post-vanilla", http.MethodPost, "https://example.amazonaws.com/", "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.url, nil)
+			if err := sigV4TestSigner.Sign(req, nil); err != nil {
+				t.Fatalf("Sign failed: %v", err)
+			}
+
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=" + tt.signature
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Errorf("Authorization = %q\nwant %q", got, want)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %q", got)
+			}
+		})
+	}
+}
+
+func TestSigV4Signer_CanonicalQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"b=2&a=1&a=0&s=x%2Fy", "a=0&a=1&b=2&s=x%2Fy"},
+		// Names sort before longer names they prefix, whatever follows
+		{"a0=2&a=1", "a=1&a0=2"},
+		{"foo.bar=2&foo=1", "foo=1&foo.bar=2"},
+		{"a-b=2&a=1", "a=1&a-b=2"},
+		{"a=b&a=a-&a=a", "a=a&a=a-&a=b"},
+		{"a%20b=1&a=2", "a=2&a%20b=1"},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		if got := canonicalQuery(query); got != tt.want {
+			t.Errorf("canonicalQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/a%20b/c", nil)
+	if got := sigV4TestSigner.canonicalPath(req.URL); got != "/a%2520b/c" {
+		t.Errorf("canonicalPath = %q", got)
+	}
+}
+
+func TestNewSigV4SignerFromEnv(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	if _, err := NewSigV4SignerFromEnv("us-east-1", "execute-api"); err == nil {
+		t.Error("expected an error without credentials")
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session")
+	t.Setenv("AWS_REGION", "eu-west-1")
+	signer, err := NewSigV4SignerFromEnv("", "execute-api")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if signer.Region != "eu-west-1" || signer.SessionToken != "session" {
+		t.Errorf("unexpected signer: %+v", signer)
+	}
+}
+
+func TestHMACSigner(t *testing.T) {
+	signer := &HMACSigner{
+		KeyID:  "key-1",
+		Secret: []byte("s3cret"),
+		now:    func() time.Time { return time.Unix(1700000000, 0) },
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "https://api.example.com/v1/orders?dry=1", nil)
+	body := []byte(`{"qty": 2}`)
+	if err := signer.Sign(req, body); err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte("POST\n/v1/orders?dry=1\n1700000000\n" + hex.EncodeToString(bodyHash[:])))
+	if got, want := req.Header.Get("X-Signature"), hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("X-Signature = %q, want %q", got, want)
+	}
+	if req.Header.Get("X-Timestamp") != "1700000000" || req.Header.Get("X-Key-Id") != "key-1" {
+		t.Errorf("unexpected headers: %v", req.Header)
+	}
+
+	if err := (&HMACSigner{}).Sign(req, nil); err == nil {
+		t.Error("expected an error without a secret")
+	}
+}
+
+func TestRuntime_Signer(t *testing.T) {
+	var signature, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get("X-Signature")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = new(strings.Builder)
+	rt.Signer = &HMACSigner{Secret: []byte("s3cret")}
+
+	err := rt.Do(context.Background(), &Request{Method: http.MethodPost, Path: "/things", Body: []byte(`{"a":1}`)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if signature == "" {
+		t.Error("expected the request to be signed")
+	}
+	if body != `{"a":1}` {
+		t.Errorf("expected the body to be sent after signing, got %q", body)
+	}
+}
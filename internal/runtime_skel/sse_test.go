@@ -2,6 +2,8 @@ package runtime
 
 import (
 	"bytes"
+	"io"
+	goruntime "runtime"
 	"strings"
 	"testing"
 )
@@ -16,7 +18,7 @@ data: {"message": "world"}
 	reader := strings.NewReader(input)
 	var out bytes.Buffer
 
-	err := handleSSE(reader, &out)
+	err := handleSSE(reader, &out, io.Discard, nil)
 	if err != nil {
 		t.Fatalf("handleSSE failed: %v", err)
 	}
@@ -39,7 +41,7 @@ data:  "continued": true}
 	reader := strings.NewReader(input)
 	var out bytes.Buffer
 
-	err := handleSSE(reader, &out)
+	err := handleSSE(reader, &out, io.Discard, nil)
 	if err != nil {
 		t.Fatalf("handleSSE failed: %v", err)
 	}
@@ -63,7 +65,7 @@ data: {"status": "done"}
 	reader := strings.NewReader(input)
 	var out bytes.Buffer
 
-	err := handleSSE(reader, &out)
+	err := handleSSE(reader, &out, io.Discard, nil)
 	if err != nil {
 		t.Fatalf("handleSSE failed: %v", err)
 	}
@@ -92,7 +94,7 @@ data: {"type": "test"}
 	reader := strings.NewReader(input)
 	var out bytes.Buffer
 
-	err := handleSSE(reader, &out)
+	err := handleSSE(reader, &out, io.Discard, nil)
 	if err != nil {
 		t.Fatalf("handleSSE failed: %v", err)
 	}
@@ -112,7 +114,7 @@ func TestHandleSSE_PlainTextData(t *testing.T) {
 	reader := strings.NewReader(input)
 	var out bytes.Buffer
 
-	err := handleSSE(reader, &out)
+	err := handleSSE(reader, &out, io.Discard, nil)
 	if err != nil {
 		t.Fatalf("handleSSE failed: %v", err)
 	}
@@ -144,3 +146,89 @@ func TestIsEventStream(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleSSE_EventFilterAndMetadata(t *testing.T) {
+	input := "id: 1\nevent: created\ndata: {\"n\": 1}\n\n" +
+		"event: deleted\ndata: {\"n\": 2}\n\n" +
+		"data: plain text\n\n" +
+		"id: 7\nevent: created\ndata: {\"n\": 3}\n\n"
+
+	tests := []struct {
+		name string
+		opts *EventOptions
+		want string
+	}{
+		{
+			name: "metadata",
+			opts: &EventOptions{Metadata: true},
+			want: `{"event":"created","id":"1","data":{"n":1}}` + "\n" +
+				`{"event":"deleted","id":"1","data":{"n":2}}` + "\n" +
+				`{"event":"message","id":"1","data":"plain text"}` + "\n" +
+				`{"event":"created","id":"7","data":{"n":3}}` + "\n",
+		},
+		{
+			name: "filtered",
+			opts: &EventOptions{Names: []string{"created"}, Metadata: true},
+			want: `{"event":"created","id":"1","data":{"n":1}}` + "\n" +
+				`{"event":"created","id":"7","data":{"n":3}}` + "\n",
+		},
+		{
+			name: "default event type",
+			opts: &EventOptions{Names: []string{"message"}},
+			want: "plain text\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := handleSSE(strings.NewReader(input), &out, io.Discard, tt.opts); err != nil {
+				t.Fatalf("handleSSE failed: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleSSE_LongLines(t *testing.T) {
+	data := strings.Repeat("x", 200*1024)
+	var out bytes.Buffer
+	if err := handleSSE(strings.NewReader("data: "+data+"\n\n"), &out, io.Discard, nil); err != nil {
+		t.Fatalf("handleSSE failed: %v", err)
+	}
+	if out.String() != data+"\n" {
+		t.Errorf("expected the whole event, got %d bytes", out.Len())
+	}
+}
+
+func TestHandleSSE_Exec(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell command")
+	}
+
+	input := "id: 1\nevent: created\ndata: {\"n\": 1}\n\n" +
+		"id: 2\nevent: deleted\ndata: {\"n\": 2}\n\n"
+	var out, errOut bytes.Buffer
+	opts := &EventOptions{Exec: `printf '%s %s ' "$EVENT_NAME" "$EVENT_ID"; cat; [ "$EVENT_NAME" != deleted ]`}
+	if err := handleSSE(strings.NewReader(input), &out, &errOut, opts); err != nil {
+		t.Fatalf("handleSSE failed: %v", err)
+	}
+
+	if out.String() != "created 1 {\"n\": 1}\ndeleted 2 {\"n\": 2}\n" {
+		t.Errorf("unexpected command output %q", out.String())
+	}
+	if !strings.Contains(errOut.String(), "exec: deleted event 2: exit status 1") {
+		t.Errorf("expected the failing command to be reported, got %q", errOut.String())
+	}
+
+	out.Reset()
+	opts = &EventOptions{Names: []string{"created"}, Metadata: true, Exec: "cat"}
+	if err := handleSSE(strings.NewReader(input), &out, io.Discard, opts); err != nil {
+		t.Fatalf("handleSSE failed: %v", err)
+	}
+	if out.String() != `{"event":"created","id":"1","data":{"n":1}}`+"\n" {
+		t.Errorf("expected the event with its metadata on stdin, got %q", out.String())
+	}
+}
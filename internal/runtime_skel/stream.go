@@ -0,0 +1,186 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// streamThreshold is the size above which a response body is streamed to
+// the output where the format allows it, instead of being held in memory
+var streamThreshold = 8 << 20
+
+// streamLarge writes a response body too large to buffer, of which head
+// has been read, without holding it in memory: JSON is pretty-printed
+// token by token in the server's key order, a JSON array with the jsonl
+// format is written one item per line, and text is copied as-is. It reports
+// false when the output needs the whole body, e.g. for a query or table.
+func streamLarge(head []byte, rest io.Reader, out io.Writer, opts outputOptions) (bool, error) {
+	if opts.Query != nil || opts.Quiet || opts.Envelope != nil {
+		return false, nil
+	}
+	body := io.MultiReader(bytes.NewReader(head), rest)
+	first := firstByte(head)
+
+	switch {
+	case opts.Format == FormatJSON && (first == '{' || first == '['):
+		return true, streamIndent(body, out)
+	case opts.Format == FormatJSONL && first == '[':
+		return true, streamJSONLines(body, out)
+	case opts.Format == FormatJSON:
+		w := &lastByteWriter{w: out}
+		if _, err := io.Copy(w, body); err != nil {
+			return true, fmt.Errorf("failed to read response body: %w", err)
+		}
+		if w.last != '\n' {
+			fmt.Fprintln(out)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// firstByte returns the first non-whitespace byte of data
+func firstByte(data []byte) byte {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 {
+		return 0
+	}
+	return trimmed[0]
+}
+
+// lastByteWriter remembers the last byte written through it
+type lastByteWriter struct {
+	w    io.Writer
+	last byte
+}
+
+func (l *lastByteWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		l.last = p[len(p)-1]
+	}
+	return l.w.Write(p)
+}
+
+// streamIndent pretty-prints the JSON values read from r with two-space
+// indentation, keeping only the open containers in memory
+func streamIndent(r io.Reader, out io.Writer) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	type container struct {
+		object   bool
+		count    int  // members written so far
+		valueDue bool // a key was written and its value is next
+	}
+	var stack []container
+
+	newline := func() {
+		w.WriteString("\n" + strings.Repeat("  ", len(stack)))
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid JSON in response: %w", err)
+		}
+
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			closed := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if closed.count > 0 {
+				newline()
+			}
+			w.WriteRune(rune(d))
+			if len(stack) == 0 {
+				w.WriteString("\n")
+			}
+			continue
+		}
+
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			switch {
+			case top.object && !top.valueDue:
+				// tok is a key
+				if top.count > 0 {
+					w.WriteString(",")
+				}
+				newline()
+				writeToken(w, tok)
+				w.WriteString(": ")
+				top.count++
+				top.valueDue = true
+				continue
+			case top.object:
+				top.valueDue = false
+			default:
+				if top.count > 0 {
+					w.WriteString(",")
+				}
+				newline()
+				top.count++
+			}
+		}
+
+		if d, ok := tok.(json.Delim); ok {
+			w.WriteRune(rune(d))
+			stack = append(stack, container{object: d == '{'})
+			continue
+		}
+		writeToken(w, tok)
+		if len(stack) == 0 {
+			w.WriteString("\n")
+		}
+	}
+}
+
+// writeToken writes a JSON string, number, boolean or null as it was in the
+// response, without escaping HTML characters
+func writeToken(w io.Writer, tok json.Token) {
+	if n, ok := tok.(json.Number); ok {
+		io.WriteString(w, n.String())
+		return
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(tok)
+	w.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+// streamJSONLines writes each item of the JSON array read from r as one
+// compact line
+func streamJSONLines(r io.Reader, out io.Writer) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("invalid JSON in response: %w", err)
+	}
+
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	for dec.More() {
+		var item json.RawMessage
+		if err := dec.Decode(&item); err != nil {
+			return fmt.Errorf("invalid JSON in response: %w", err)
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, item); err != nil {
+			return fmt.Errorf("invalid JSON in response: %w", err)
+		}
+		compact.WriteByte('\n')
+		if _, err := w.Write(compact.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
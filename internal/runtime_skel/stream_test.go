@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestStreamIndent(t *testing.T) {
+	tests := []string{
+		`{"b": 1, "a": [1, 2.50, {"x": null}], "c": {}, "d": [], "e": "<tag> é"}`,
+		`[]`,
+		`[{"id": 1}, {"id": 2}]`,
+		`{"nested": {"deep": {"deeper": [true, false]}}}`,
+	}
+
+	for _, body := range tests {
+		var out bytes.Buffer
+		if err := streamIndent(strings.NewReader(body), &out); err != nil {
+			t.Fatalf("streamIndent(%s): %v", body, err)
+		}
+
+		// Same layout as json.Indent, which keeps the server's key order
+		var want bytes.Buffer
+		if err := json.Indent(&want, []byte(body), "", "  "); err != nil {
+			t.Fatal(err)
+		}
+		want.WriteString("\n")
+		if out.String() != want.String() {
+			t.Errorf("streamIndent(%s) =\n%s\nwant\n%s", body, out.String(), want.String())
+		}
+	}
+
+	if err := streamIndent(strings.NewReader(`{"a": }`), io.Discard); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestHandleResponse_StreamsLargeBodies(t *testing.T) {
+	defer func(n int) { streamThreshold = n }(streamThreshold)
+	streamThreshold = 16
+
+	tests := []struct {
+		name   string
+		format string
+		body   string
+		want   string
+	}{
+		{"json", FormatJSON, `{"z": 1, "a": [1, 2]}`, "{\n  \"z\": 1,\n  \"a\": [\n    1,\n    2\n  ]\n}\n"},
+		{"jsonl", FormatJSONL, `[{"id": 1}, {"id": 2}, {"id": 3}]`, "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"},
+		{"text", FormatJSON, "a long line of plain text", "a long line of plain text\n"},
+		{"table falls back to buffering", FormatTable, `[{"id": 1}, {"id": 2}, {"id": 3}]`, "ID\n1\n2\n3\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: 200,
+				Status:     "200 OK",
+				Body:       &mockResponseBody{bytes.NewReader([]byte(tt.body))},
+			}
+			var out bytes.Buffer
+			if err := handleResponse(resp, &out, outputOptions{Format: tt.format, ErrOut: io.Discard}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
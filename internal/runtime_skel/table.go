@@ -0,0 +1,129 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// maxCellWidth is the width at which table cells are truncated
+const maxCellWidth = 60
+
+// maxDefaultColumns is the number of columns shown when none are chosen
+const maxDefaultColumns = 6
+
+// writeTable renders items as aligned columns under an upper-case header.
+// Columns are dotted paths into each item; without columns the scalar fields
+// of the first item are used. A single object is shown as a one-row table.
+func writeTable(parsed interface{}, envelope *Envelope, columns []string, out io.Writer) error {
+	items, ok := envelope.unwrap(parsed)
+	if !ok {
+		if arr, isArray := parsed.([]interface{}); isArray {
+			items = arr
+		} else {
+			items = []interface{}{parsed}
+		}
+	}
+
+	if len(columns) == 0 {
+		columns = defaultColumns(items)
+	}
+	if len(columns) == 0 {
+		// Not objects: one value per line
+		for _, item := range items {
+			fmt.Fprintln(out, cellText(item))
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = strings.ToUpper(col)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	row := make([]string, len(columns))
+	for _, item := range items {
+		for i, col := range columns {
+			v, _ := lookupPath(item, col)
+			row[i] = cellText(v)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// defaultColumns returns the scalar fields of the first object in items,
+// identifying fields first
+func defaultColumns(items []interface{}) []string {
+	if len(items) == 0 {
+		return nil
+	}
+	obj, ok := items[0].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var columns []string
+	for key, v := range obj {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			continue
+		}
+		columns = append(columns, key)
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		ri, rj := columnRank(columns[i]), columnRank(columns[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return columns[i] < columns[j]
+	})
+	if len(columns) > maxDefaultColumns {
+		columns = columns[:maxDefaultColumns]
+	}
+	return columns
+}
+
+// preferredColumns are shown first, in this order, when present
+var preferredColumns = []string{"id", "name", "title", "status", "state", "type"}
+
+func columnRank(column string) int {
+	for i, c := range preferredColumns {
+		if c == column {
+			return i
+		}
+	}
+	return len(preferredColumns)
+}
+
+// cellText formats a decoded JSON value for a table cell: strings as-is,
+// null as empty, and anything else as compact JSON, truncated to
+// maxCellWidth
+func cellText(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		s = v
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		s = string(data)
+	}
+
+	// Keep rows on one line
+	s = strings.NewReplacer("\n", " ", "\r", " ", "\t", " ").Replace(s)
+	if utf8.RuneCountInString(s) > maxCellWidth {
+		s = string([]rune(s)[:maxCellWidth-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,101 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteTable(t *testing.T) {
+	var parsed interface{}
+	body := `{"data": [
+		{"id": "t1", "name": "Write docs", "status": "open", "owner": {"name": "ana"}, "tags": ["a"]},
+		{"id": "t22", "name": "Ship", "status": null, "owner": {"name": "bo"}, "tags": []}
+	], "total": 2}`
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	envelope := &Envelope{Items: "data", Total: "total"}
+
+	tests := []struct {
+		name    string
+		columns []string
+		want    string
+	}{
+		{
+			name: "default columns",
+			want: "ID   NAME        STATUS\n" +
+				"t1   Write docs  open\n" +
+				"t22  Ship        \n",
+		},
+		{
+			name:    "chosen columns",
+			columns: []string{"owner.name", "id", "tags"},
+			want: "OWNER.NAME  ID   TAGS\n" +
+				"ana         t1   [\"a\"]\n" +
+				"bo          t22  []\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeTable(parsed, envelope, tt.columns, &buf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("unexpected table:\n%s\nwant:\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTable_SingleObjectAndScalars(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeTable(map[string]interface{}{"id": 1.0, "name": "x"}, nil, nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "ID  NAME\n1   x\n" {
+		t.Errorf("unexpected table for an object: %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeTable([]interface{}{"a", 2.0}, nil, nil, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "a\n2\n" {
+		t.Errorf("unexpected output for scalars: %q", buf.String())
+	}
+}
+
+func TestDefaultColumns(t *testing.T) {
+	item := map[string]interface{}{
+		"zeta": 1.0, "status": "ok", "alpha": "a", "id": "1", "name": "n", "beta": "b", "gamma": "g", "nested": map[string]interface{}{},
+	}
+	got := strings.Join(defaultColumns([]interface{}{item}), ",")
+	if want := "id,name,status,alpha,beta,gamma"; got != want {
+		t.Errorf("defaultColumns = %s, want %s", got, want)
+	}
+}
+
+func TestCellText(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, ""},
+		{"multi\nline", "multi line"},
+		{3.5, "3.5"},
+		{true, "true"},
+		{map[string]interface{}{"a": 1.0}, `{"a":1}`},
+		{long, strings.Repeat("x", maxCellWidth-1) + "…"},
+	}
+
+	for _, tt := range tests {
+		if got := cellText(tt.v); got != tt.want {
+			t.Errorf("cellText(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are available to go-template output in addition to the
+// text/template builtins
+var templateFuncs = template.FuncMap{
+	// json encodes a value as compact JSON, e.g. {{json .tags}}
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": func(sep string, items []interface{}) string {
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, sep)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+func parseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// writeTemplate executes tmpl with the decoded response. Whole numbers are
+// passed as integers, so IDs print as 1000000 rather than 1e+06 and compare
+// with integer constants.
+func writeTemplate(parsed interface{}, tmpl *template.Template, out io.Writer) error {
+	if tmpl == nil {
+		return fmt.Errorf("no output template given (use --output %s=TEMPLATE)", FormatGoTemplate)
+	}
+	if err := tmpl.Execute(out, templateData(parsed)); err != nil {
+		return fmt.Errorf("failed to render output template: %w", err)
+	}
+	return nil
+}
+
+// templateData converts the whole numbers in v to int64
+func templateData(v interface{}) interface{} {
+	switch v := v.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = templateData(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = templateData(item)
+		}
+		return out
+	}
+	return v
+}
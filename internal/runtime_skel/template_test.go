@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	for _, value := range []string{FormatJSON, FormatJSONL, FormatTable} {
+		format, tmpl, err := ParseFormat(value)
+		if err != nil || format != value || tmpl != nil {
+			t.Errorf("ParseFormat(%q) = %q, %v, %v", value, format, tmpl, err)
+		}
+	}
+
+	format, tmpl, err := ParseFormat(`go-template={{.id}}`)
+	if err != nil || format != FormatGoTemplate || tmpl == nil {
+		t.Errorf("unexpected go-template result: %q, %v, %v", format, tmpl, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.tmpl")
+	if err := os.WriteFile(path, []byte(`{{.name}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if format, tmpl, err = ParseFormat("go-template-file=" + path); err != nil || format != FormatGoTemplate || tmpl == nil {
+		t.Errorf("unexpected go-template-file result: %q, %v, %v", format, tmpl, err)
+	}
+
+	for value, want := range map[string]string{
+		"go-template={{.id":                "invalid output template",
+		"go-template-file=/does/not/exist": "failed to read template",
+		"yaml":                             "unsupported output format",
+	} {
+		if _, _, err := ParseFormat(value); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseFormat(%q): expected %q error, got %v", value, want, err)
+		}
+	}
+}
+
+func TestWriteTemplate(t *testing.T) {
+	var parsed interface{}
+	body := `{"items": [{"id": 1000000, "name": "a", "tags": ["x", "y"]}, {"id": 2, "name": "b", "score": 1.5}]}`
+	if err := json.Unmarshal([]byte(body), &parsed); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{`{{range .items}}{{.id}}{{"\n"}}{{end}}`, "1000000\n2\n"},
+		{`{{range .items}}{{if gt .id 5}}{{upper .name}}{{end}}{{end}}`, "A"},
+		{`{{(index .items 0).tags | json}} {{join "," (index .items 0).tags}}`, `["x","y"] x,y`},
+		{`{{(index .items 1).score}}`, "1.5"},
+	}
+
+	for _, tt := range tests {
+		_, tmpl, err := ParseFormat("go-template=" + tt.template)
+		if err != nil {
+			t.Fatalf("ParseFormat(%q): %v", tt.template, err)
+		}
+		var buf bytes.Buffer
+		if err := writeTemplate(parsed, tmpl, &buf); err != nil {
+			t.Fatalf("writeTemplate(%q): %v", tt.template, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("writeTemplate(%q) = %q, want %q", tt.template, buf.String(), tt.want)
+		}
+	}
+}
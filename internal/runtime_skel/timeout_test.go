@@ -0,0 +1,103 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowStream serves an event stream that sends an event every interval until
+// it has sent count, then waits for the client to go away
+func slowStream(count int, interval time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 1; i <= count; i++ {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+			select {
+			case <-time.After(interval):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		<-r.Context().Done()
+	}))
+}
+
+func TestRuntime_StreamOutlivesTimeout(t *testing.T) {
+	server := slowStream(3, 100*time.Millisecond)
+	defer server.Close()
+
+	var out bytes.Buffer
+	rt := New(server.URL, 50*time.Millisecond)
+	rt.Output = &out
+	rt.IdleTimeout = 500 * time.Millisecond
+
+	req := NewRequest("GET", "/events")
+	req.SetStream()
+	err := rt.Do(context.Background(), req)
+	if err == nil || !strings.Contains(err.Error(), "no data received for 500ms") {
+		t.Errorf("expected the stream to end on the idle timeout, got %v", err)
+	}
+	if out.String() != "1\n2\n3\n" {
+		t.Errorf("expected every event despite the request timeout, got %q", out.String())
+	}
+}
+
+func TestRuntime_StreamTimeout(t *testing.T) {
+	server := slowStream(100, 20*time.Millisecond)
+	defer server.Close()
+
+	var out bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.Output = &out
+	rt.StreamTimeout = 150 * time.Millisecond
+
+	req := NewRequest("GET", "/events")
+	req.SetStream()
+	start := time.Now()
+	if err := rt.Do(context.Background(), req); err == nil {
+		t.Error("expected the stream to be ended by the stream timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the stream to end after about 150ms, took %s", elapsed)
+	}
+	if !strings.HasPrefix(out.String(), "1\n") {
+		t.Errorf("expected events before the timeout, got %q", out.String())
+	}
+}
+
+func TestRuntime_IdleTimeoutWaitingForResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	rt := New(server.URL, 0)
+	rt.IdleTimeout = 50 * time.Millisecond
+
+	req := NewRequest("GET", "/events")
+	req.SetStream()
+	err := rt.Do(context.Background(), req)
+	if err == nil || !strings.Contains(err.Error(), "no data received for 50ms") {
+		t.Errorf("expected the idle timeout to cover the wait for a response, got %v", err)
+	}
+}
+
+func TestRuntime_RequestTimeoutAppliesToOtherRequests(t *testing.T) {
+	server := slowStream(3, 100*time.Millisecond)
+	defer server.Close()
+
+	var out bytes.Buffer
+	rt := New(server.URL, 50*time.Millisecond)
+	rt.Output = &out
+
+	if err := rt.Do(context.Background(), NewRequest("GET", "/events")); err == nil {
+		t.Error("expected the request timeout to end a request not marked as a stream")
+	}
+}
@@ -0,0 +1,37 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestRuntime_Timing(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id": "t1"}`))
+	}))
+	defer server.Close()
+
+	var timing bytes.Buffer
+	rt := New(server.URL, 5*time.Second)
+	rt.HTTPClient = server.Client()
+	rt.Output = &bytes.Buffer{}
+	rt.Timing = &Timing{Out: &timing}
+
+	for i := 0; i < 2; i++ {
+		if err := rt.Do(context.Background(), NewRequest("GET", "/tasks/t1?token=secret")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// A new connection, then a reused one; credentials in the URL are masked
+	want := regexp.MustCompile(`^\* timing GET https://127\.0\.0\.1:\d+/tasks/t1\?token=\*\*\*: connect [^,]+, tls [^,]+, ttfb [^,]+, total \S+\n` +
+		`\* timing GET \S+: reused connection, ttfb [^,]+, total \S+\n$`)
+	if !want.MatchString(timing.String()) {
+		t.Errorf("unexpected timing output:\n%s", timing.String())
+	}
+}
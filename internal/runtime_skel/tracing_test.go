@@ -0,0 +1,226 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracer(t *testing.T) {
+	var traceParents []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParents = append(traceParents, r.Header.Get(TraceParentHeader))
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = w.Write([]byte(`{"id": "t1"}`))
+	}))
+	defer api.Close()
+
+	var exported []byte
+	var apiKey string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		apiKey = r.Header.Get("X-Api-Key")
+		exported, _ = io.ReadAll(r.Body)
+	}))
+	defer collector.Close()
+
+	parent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	tracer, err := NewTracer(collector.URL+"/", "tasks", "1.2.0", "tasks tasks get", parent)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tracer.Headers = map[string]string{"X-Api-Key": "k1"}
+
+	rt := New(api.URL, 5*time.Second)
+	rt.Output = &bytes.Buffer{}
+	rt.ErrOutput = &bytes.Buffer{}
+	rt.Tracer = tracer
+	if err := rt.Do(context.Background(), NewRequest("GET", "/tasks/t1?token=secret")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reqErr := rt.Do(context.Background(), NewRequest("GET", "/missing"))
+	if reqErr == nil {
+		t.Fatal("expected an error status to fail")
+	}
+	if err := tracer.End(reqErr); err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+
+	// Each request carries its own span ID in the given trace
+	if len(traceParents) != 2 || traceParents[0] == traceParents[1] {
+		t.Fatalf("expected a different traceparent per request, got %v", traceParents)
+	}
+	for _, tp := range traceParents {
+		if !strings.HasPrefix(tp, "00-0af7651916cd43dd8448eb211c80319c-") || !strings.HasSuffix(tp, "-01") {
+			t.Errorf("expected the traceparent to continue the trace, got %q", tp)
+		}
+	}
+	if apiKey != "k1" {
+		t.Errorf("expected the export to carry the collector headers, got %q", apiKey)
+	}
+
+	var payload struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []struct {
+					Key   string
+					Value map[string]string
+				}
+			}
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string
+					SpanID       string
+					ParentSpanID string
+					Name         string
+					Kind         int
+					Attributes   []struct {
+						Key   string
+						Value map[string]string
+					}
+					Status *struct {
+						Code    int
+						Message string
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(exported, &payload); err != nil {
+		t.Fatalf("failed to decode export: %v\n%s", err, exported)
+	}
+	if got := payload.ResourceSpans[0].Resource.Attributes; len(got) != 2 || got[0].Value["stringValue"] != "tasks" || got[1].Value["stringValue"] != "1.2.0" {
+		t.Errorf("unexpected resource attributes: %+v", got)
+	}
+
+	spans := payload.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("expected the command span and a span per request, got %d", len(spans))
+	}
+	command := spans[0]
+	if command.Name != "tasks tasks get" || command.ParentSpanID != "b7ad6b7169203331" || command.Status == nil || command.Status.Code != 2 {
+		t.Errorf("unexpected command span: %+v", command)
+	}
+	for i, s := range spans[1:] {
+		if s.TraceID != "0af7651916cd43dd8448eb211c80319c" || s.ParentSpanID != command.SpanID || s.Kind != spanKindClient || s.Name != "GET" {
+			t.Errorf("unexpected request span: %+v", s)
+		}
+		if !strings.Contains(traceParents[i], s.SpanID) {
+			t.Errorf("expected span %s to be sent in %q", s.SpanID, traceParents[i])
+		}
+	}
+	attributes := map[string]string{}
+	for _, a := range spans[1].Attributes {
+		attributes[a.Key] = a.Value["stringValue"] + a.Value["intValue"]
+	}
+	if attributes["url.full"] != api.URL+"/tasks/t1?token=***" || attributes["http.response.status_code"] != "200" {
+		t.Errorf("unexpected request span attributes: %v", attributes)
+	}
+	if spans[1].Status != nil || spans[2].Status == nil {
+		t.Errorf("expected only the 404 to fail, got %+v and %+v", spans[1].Status, spans[2].Status)
+	}
+
+	// A collector that rejects the trace is reported
+	tracer, _ = NewTracer(collector.URL+"/wrong", "tasks", "", "tasks", "")
+	if err := tracer.End(errors.New("boom")); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected the failed export to be reported, got %v", err)
+	}
+}
+
+// signerFunc adapts a function to a Signer
+type signerFunc func(req *http.Request, body []byte) error
+
+func (f signerFunc) Sign(req *http.Request, body []byte) error {
+	return f(req, body)
+}
+
+func TestTracer_Signing(t *testing.T) {
+	var sent string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = r.Header.Get(TraceParentHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer api.Close()
+
+	tracer, err := NewTracer("http://collector.invalid", "tasks", "", "tasks", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var signed string
+	rt := New(api.URL, 5*time.Second)
+	rt.Output = &bytes.Buffer{}
+	rt.Tracer = tracer
+	rt.Signer = signerFunc(func(req *http.Request, body []byte) error {
+		signed = req.Header.Get(TraceParentHeader)
+		return nil
+	})
+	if err := rt.Do(context.Background(), NewRequest("GET", "/tasks")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sent == "" || signed != sent {
+		t.Errorf("expected the signer to see the traceparent %q that is sent, got %q", sent, signed)
+	}
+}
+
+func TestTracer_HTTPClient(t *testing.T) {
+	var exportedVia string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exportedVia = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	rt := New("http://api.example.invalid", 5*time.Second)
+	if err := rt.SetProxy(proxy.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tracer, err := NewTracer("http://collector.example.invalid", "tasks", "", "tasks", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tracer.HTTPClient = rt.BaseClient()
+	if err := tracer.End(nil); err != nil {
+		t.Fatalf("unexpected export error: %v", err)
+	}
+
+	if exportedVia != "http://collector.example.invalid/v1/traces" {
+		t.Errorf("expected the trace to be exported through the runtime's proxy, got %q", exportedVia)
+	}
+}
+
+func TestParseTraceParent(t *testing.T) {
+	for _, value := range []string{
+		"",
+		"01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b716920333-01",
+		"00-zzf7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+	} {
+		if _, _, ok := parseTraceParent(value); ok {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+	if _, _, ok := parseTraceParent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00"); !ok {
+		t.Error("expected a valid traceparent to be accepted")
+	}
+}
+
+func TestOTLPHeaders(t *testing.T) {
+	got := OTLPHeaders("x-api-key=k1, authorization=Basic%20abc=,bad")
+	if len(got) != 2 || got["x-api-key"] != "k1" || got["authorization"] != "Basic abc=" {
+		t.Errorf("unexpected headers: %v", got)
+	}
+}
@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const bookmarkSchema = `{
+	"type": "object",
+	"required": ["url", "id"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "readOnly": true},
+		"url": {"type": "string", "minLength": 8, "pattern": "^https?://"},
+		"title": {"type": "string", "nullable": true, "maxLength": 5},
+		"rank": {"type": "integer", "minimum": 1, "exclusiveMaximum": 10},
+		"status": {"type": "string", "enum": ["draft", "published"]},
+		"tags": {"type": "array", "maxItems": 2, "items": {"type": "string"}},
+		"owner": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}},
+		"meta": {"type": "object", "additionalProperties": {"type": "number"}},
+		"link": {"oneOf": [{"type": "string"}, {"type": "object", "required": ["href"]}]},
+		"parent": {"$ref": "#/components/schemas/Bookmark"}
+	}
+}`
+
+func TestValidateBody(t *testing.T) {
+	valid := []string{
+		`{"url": "https://example.com"}`,
+		`{"url": "https://example.com", "title": null, "rank": 9, "status": "draft", "tags": ["go"]}`,
+		`{"url": "https://example.com", "meta": {"score": 1.5}, "link": {"href": "x"}, "parent": 1}`,
+		`{"url": "https://example.com", "rank": 2.0, "link": "x"}`,
+	}
+	for _, body := range valid {
+		if err := ValidateBody([]byte(bookmarkSchema), []byte(body)); err != nil {
+			t.Errorf("expected %s to be valid, got %v", body, err)
+		}
+	}
+
+	tests := []struct {
+		body     string
+		problems []string
+	}{
+		{`{}`, []string{"missing required property 'url'"}},
+		{`[]`, []string{"expected object, got array"}},
+		{`{"url": "ftp://example.com", "extra": 1}`, []string{"unknown property 'extra'", "url: must match the pattern ^https?://"}},
+		{`{"url": "http://", "title": "too long"}`, []string{"title: must be at most 5 characters", "url: must be at least 8 characters"}},
+		{`{"url": "https://example.com", "rank": 1.5}`, []string{"rank: expected integer, got number"}},
+		{`{"url": "https://example.com", "rank": 10}`, []string{"rank: must be less than 10"}},
+		{`{"url": "https://example.com", "rank": 0}`, []string{"rank: must be at least 1"}},
+		{`{"url": "https://example.com", "status": "gone"}`, []string{`status: must be one of "draft", "published" (got "gone")`}},
+		{`{"url": "https://example.com", "tags": ["a", 2, "c"]}`, []string{"tags: must have at most 2 items", "tags[1]: expected string, got integer"}},
+		{`{"url": "https://example.com", "owner": {}}`, []string{"owner: missing required property 'name'"}},
+		{`{"url": "https://example.com", "meta": {"score": "high"}}`, []string{"meta.score: expected number, got string"}},
+		{`{"url": "https://example.com", "link": {}}`, []string{"link: does not match any of the allowed schemas"}},
+		{`{"url": `, []string{"not valid JSON: unexpected EOF"}},
+	}
+	for _, tt := range tests {
+		err := ValidateBody([]byte(bookmarkSchema), []byte(tt.body))
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("expected %s to be invalid, got %v", tt.body, err)
+			continue
+		}
+		if !reflect.DeepEqual(validationErr.Problems, tt.problems) {
+			t.Errorf("problems of %s = %q, want %q", tt.body, validationErr.Problems, tt.problems)
+		}
+	}
+}
+
+func TestValidateBody_TypeLists(t *testing.T) {
+	// OpenAPI 3.1 lists types and gives exclusive bounds as numbers
+	schema := []byte(`{"type": ["integer", "null"], "exclusiveMinimum": 0}`)
+	for _, body := range []string{`1`, `null`} {
+		if err := ValidateBody(schema, []byte(body)); err != nil {
+			t.Errorf("expected %s to be valid, got %v", body, err)
+		}
+	}
+	if err := ValidateBody(schema, []byte(`0`)); err == nil || err.Error() != "invalid request body:\n  must be greater than 0" {
+		t.Errorf("unexpected error for 0: %v", err)
+	}
+	if err := ValidateBody(schema, []byte(`"1"`)); err == nil || err.Error() != "invalid request body:\n  expected integer or null, got string" {
+		t.Errorf("unexpected error for a string: %v", err)
+	}
+}
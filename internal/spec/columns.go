@@ -0,0 +1,70 @@
+package spec
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxColumns is the number of default table columns
+const maxColumns = 6
+
+// preferredColumns are listed first, in this order, when present
+var preferredColumns = []string{"id", "name", "title", "status", "state", "type"}
+
+// detectColumns picks the default table columns for a response schema: the
+// scalar properties of its items (or of the object itself), identifying
+// properties first. The items of an envelope are found at envelope.Items.
+func detectColumns(schema *openapi3.Schema, envelope *Envelope) []string {
+	if schema == nil {
+		return nil
+	}
+	if envelope != nil && envelope.Items != "" {
+		for _, key := range strings.Split(envelope.Items, ".") {
+			if schema = property(schema, key); schema == nil {
+				return nil
+			}
+		}
+	}
+	if schema.Type.Is(openapi3.TypeArray) {
+		if schema.Items == nil || schema.Items.Value == nil {
+			return nil
+		}
+		schema = schema.Items.Value
+	}
+	if !schema.Type.Is(openapi3.TypeObject) {
+		return nil
+	}
+
+	var columns []string
+	for name, prop := range schema.Properties {
+		if prop == nil || prop.Value == nil || prop.Value.Type == nil {
+			continue
+		}
+		if prop.Value.Type.Includes(openapi3.TypeObject) || prop.Value.Type.Includes(openapi3.TypeArray) {
+			continue
+		}
+		columns = append(columns, name)
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		ri, rj := columnRank(columns[i]), columnRank(columns[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return columns[i] < columns[j]
+	})
+	if len(columns) > maxColumns {
+		columns = columns[:maxColumns]
+	}
+	return columns
+}
+
+func columnRank(column string) int {
+	for i, c := range preferredColumns {
+		if c == column {
+			return i
+		}
+	}
+	return len(preferredColumns)
+}
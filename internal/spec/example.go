@@ -0,0 +1,150 @@
+package spec
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxExampleDepth bounds schema recursion when synthesizing examples
+const maxExampleDepth = 6
+
+// mediaExample returns an example body for a response media type: the
+// media type's own example, else its first named example, else one
+// synthesized from the schema. JSON bodies are indented; other content types
+// only use string examples.
+func mediaExample(contentType string, media *openapi3.MediaType) []byte {
+	if media == nil {
+		return nil
+	}
+
+	value := media.Example
+	if value == nil && len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if ref := media.Examples[names[0]]; ref != nil && ref.Value != nil {
+			value = ref.Value.Value
+		}
+	}
+
+	if !isJSONContentType(contentType) {
+		if s, ok := value.(string); ok {
+			return []byte(s)
+		}
+		return nil
+	}
+
+	if value == nil && media.Schema != nil {
+		value = schemaExample(media.Schema.Value, 0)
+	}
+	if value == nil {
+		return nil
+	}
+
+	body, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil
+	}
+	return body
+}
+
+// schemaExample builds a value matching schema from its example, default or
+// enum, falling back to a placeholder for its type
+func schemaExample(schema *openapi3.Schema, depth int) interface{} {
+	if schema == nil || depth > maxExampleDepth {
+		return nil
+	}
+
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	}
+
+	for _, alternatives := range []openapi3.SchemaRefs{schema.OneOf, schema.AnyOf} {
+		if len(alternatives) > 0 && alternatives[0] != nil {
+			return schemaExample(alternatives[0].Value, depth+1)
+		}
+	}
+
+	if len(schema.AllOf) > 0 {
+		merged := map[string]interface{}{}
+		for _, ref := range schema.AllOf {
+			if ref == nil {
+				continue
+			}
+			if obj, ok := schemaExample(ref.Value, depth+1).(map[string]interface{}); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+
+	switch {
+	case schema.Type.Is(openapi3.TypeObject) || len(schema.Properties) > 0:
+		obj := map[string]interface{}{}
+		for name, ref := range schema.Properties {
+			if ref != nil {
+				obj[name] = schemaExample(ref.Value, depth+1)
+			}
+		}
+		return obj
+	case schema.Type.Is(openapi3.TypeArray):
+		if schema.Items == nil || depth == maxExampleDepth {
+			return []interface{}{}
+		}
+		return []interface{}{schemaExample(schema.Items.Value, depth+1)}
+	case schema.Type.Is(openapi3.TypeString):
+		switch schema.Format {
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "date":
+			return "2024-01-01"
+		case "uuid":
+			return "00000000-0000-0000-0000-000000000000"
+		case "email":
+			return "user@example.com"
+		case "uri", "url":
+			return "https://example.com"
+		}
+		return "string"
+	case schema.Type.Is(openapi3.TypeInteger), schema.Type.Is(openapi3.TypeNumber):
+		if schema.Min != nil {
+			return *schema.Min
+		}
+		return 0
+	case schema.Type.Is(openapi3.TypeBoolean):
+		return true
+	}
+	return nil
+}
+
+// exampleContentType picks the content type to take a response example from:
+// the first JSON type, else the first type listed
+func exampleContentType(contentTypes []string) string {
+	for _, ct := range contentTypes {
+		if isJSONContentType(ct) {
+			return ct
+		}
+	}
+	if len(contentTypes) > 0 {
+		return contentTypes[0]
+	}
+	return ""
+}
+
+// isJSONContentType reports whether ct is application/json or a +json type
+func isJSONContentType(ct string) bool {
+	ct = strings.ToLower(strings.TrimSpace(strings.SplitN(ct, ";", 2)[0]))
+	return ct == "application/json" || strings.HasSuffix(ct, "+json")
+}
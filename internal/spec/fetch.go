@@ -0,0 +1,109 @@
+package spec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	// fetchTimeout bounds each request for a remote document
+	fetchTimeout = time.Minute
+
+	// maxRemoteSize bounds the size of a remote document
+	maxRemoteSize = 64 << 20
+)
+
+// IsURL reports whether a spec location is an http or https URL rather than
+// a file path
+func IsURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// LoadURL loads and validates an OpenAPI spec from an http or https URL,
+// such as a spec served behind an SSO or internal gateway. header is sent
+// with the request, and with requests for documents it references on the
+// same host, but never to other hosts, also not on redirects. Proxies are
+// taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+func LoadURL(ctx context.Context, rawURL string, header http.Header) (*Spec, error) {
+	base, err := url.Parse(rawURL)
+	if err != nil || !IsURL(rawURL) || base.Host == "" {
+		return nil, fmt.Errorf("invalid spec URL %q", rawURL)
+	}
+
+	client := &http.Client{
+		Timeout: fetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			if req.URL.Host != via[0].URL.Host {
+				for name := range header {
+					req.Header.Del(name)
+				}
+			}
+			return nil
+		},
+	}
+	read := func(_ *openapi3.Loader, location *url.URL) ([]byte, error) {
+		// A remote spec can't refer to local files
+		if location.Scheme != "http" && location.Scheme != "https" {
+			return nil, fmt.Errorf("%s: only http and https references are supported in a remote spec", location)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if location.Host == base.Host {
+			req.Header = header.Clone()
+			if req.Header == nil {
+				req.Header = make(http.Header)
+			}
+		}
+		if req.Header.Get("Accept") == "" {
+			req.Header.Set("Accept", "application/json, application/yaml, */*;q=0.8")
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("GET %s: %s", location, resp.Status)
+		}
+		// A gateway that wants a login answers with its login page
+		if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "text/html" {
+			return nil, fmt.Errorf("GET %s: got an HTML page instead of a spec (missing credentials?)", location)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("GET %s: %w", location, err)
+		}
+		if len(data) > maxRemoteSize {
+			return nil, fmt.Errorf("GET %s: larger than %d MiB", location, maxRemoteSize>>20)
+		}
+		return data, nil
+	}
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = openapi3.URIMapCache(read)
+
+	raw, err := read(loader, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch spec: %w", err)
+	}
+	doc, err := loader.LoadFromDataWithPath(raw, base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load spec: %w", err)
+	}
+	return fromDocument(ctx, doc, raw)
+}
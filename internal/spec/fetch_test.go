@@ -0,0 +1,93 @@
+package spec
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const remoteSpec = `openapi: 3.0.3
+info:
+  title: Remote API
+  version: 1.0.0
+paths:
+  /items:
+    get:
+      operationId: listItems
+      responses:
+        "200":
+          description: Items
+          content:
+            application/json:
+              schema:
+                $ref: 'schemas.yaml#/Item'
+  /owners:
+    get:
+      operationId: listOwners
+      responses:
+        "200":
+          description: Owners
+          content:
+            application/json:
+              schema:
+                $ref: '%s/owner.yaml#/Owner'
+`
+
+func TestLoadURL(t *testing.T) {
+	// Another host, which must not see the credentials
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no credentials on another host, got %q", r.Header.Get("Authorization"))
+		}
+		_, _ = w.Write([]byte("Owner:\n  type: object\n"))
+	}))
+	defer other.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/specs/api.yaml":
+			_, _ = w.Write([]byte(strings.Replace(remoteSpec, "%s", other.URL, 1)))
+		case "/specs/schemas.yaml":
+			_, _ = w.Write([]byte("Item:\n  type: object\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	header := http.Header{"Authorization": {"Bearer secret"}}
+	s, err := LoadURL(context.Background(), server.URL+"/specs/api.yaml", header)
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+	if s.Title != "Remote API" || len(s.Operations) != 2 {
+		t.Errorf("unexpected spec %s with %d operations", s.Title, len(s.Operations))
+	}
+	if !strings.HasPrefix(string(s.Source), "openapi: 3.0.3") {
+		t.Errorf("expected the fetched document as the source, got %q", s.Source)
+	}
+
+	_, err = LoadURL(context.Background(), server.URL+"/specs/api.yaml", nil)
+	if err == nil || !strings.Contains(err.Error(), "401 Unauthorized") {
+		t.Errorf("expected an unauthorized error, got %v", err)
+	}
+}
+
+func TestLoadURL_LoginPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<html>Sign in</html>"))
+	}))
+	defer server.Close()
+
+	_, err := LoadURL(context.Background(), server.URL+"/openapi.json", nil)
+	if err == nil || !strings.Contains(err.Error(), "got an HTML page instead of a spec") {
+		t.Errorf("expected a login page error, got %v", err)
+	}
+}
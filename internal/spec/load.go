@@ -2,10 +2,15 @@ package spec
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"os"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 )
@@ -19,12 +24,32 @@ func Load(ctx context.Context, path string) (*Spec, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to load spec: %w", err)
 	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec: %w", err)
+	}
+	return fromDocument(ctx, doc, raw)
+}
 
+// fromDocument validates a loaded OpenAPI document and converts it to our
+// model, with raw the source document it was loaded from
+func fromDocument(ctx context.Context, doc *openapi3.T, raw []byte) (*Spec, error) {
 	if err := doc.Validate(ctx); err != nil {
 		return nil, fmt.Errorf("spec validation failed: %w", err)
 	}
 
-	return normalize(doc)
+	spec, err := normalize(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fingerprint the source document so generated CLIs can report exactly
+	// which revision they were built from
+	sum := sha256.Sum256(raw)
+	spec.SHA256 = hex.EncodeToString(sum[:])
+	spec.Source = raw
+
+	return spec, nil
 }
 
 // normalize converts an OpenAPI document to our internal model
@@ -41,9 +66,24 @@ func normalize(doc *openapi3.T) (*Spec, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse global x-cli: %w", err)
 		}
+		if err := validateSigning(overrides.Signing); err != nil {
+			return nil, fmt.Errorf("invalid x-cli signing: %w", err)
+		}
 		spec.GlobalCli = overrides
 	}
 
+	security, err := securitySchemes(doc.Components, doc.Security)
+	if err != nil {
+		return nil, err
+	}
+	spec.Security = security
+
+	servers, err := extractServers(doc.Servers)
+	if err != nil {
+		return nil, err
+	}
+	spec.Servers = servers
+
 	// Extract operations from paths
 	// Sort paths for deterministic output
 	paths := make([]string, 0, len(doc.Paths.Map()))
@@ -54,19 +94,210 @@ func normalize(doc *openapi3.T) (*Spec, error) {
 
 	for _, path := range paths {
 		pathItem := doc.Paths.Map()[path]
-		ops, err := extractOperations(path, pathItem)
+		ops, warnings, err := extractOperations(path, pathItem)
 		if err != nil {
 			return nil, fmt.Errorf("failed to extract operations for path %s: %w", path, err)
 		}
 		spec.Operations = append(spec.Operations, ops...)
+		spec.Warnings = append(spec.Warnings, warnings...)
+	}
+
+	if err := validateCompletions(spec.Operations); err != nil {
+		return nil, err
+	}
+
+	for _, op := range spec.Operations {
+		if op.OperationID == "" && (op.Cli == nil || op.Cli.Name == "") {
+			spec.Warnings = append(spec.Warnings, fmt.Sprintf("%s %s has neither an operationId nor an x-cli name to name its command after", op.Method, op.Path))
+		}
 	}
 
 	return spec, nil
 }
 
-// extractOperations extracts all operations from a path item
-func extractOperations(path string, pathItem *openapi3.PathItem) ([]Operation, error) {
+// validateCompletions checks that each x-cli complete names a list operation
+// the CLI can call for completion: a GET without path parameters
+func validateCompletions(ops []Operation) error {
+	byID := make(map[string]*Operation, len(ops))
+	for i := range ops {
+		byID[ops[i].OperationID] = &ops[i]
+	}
+
+	for _, op := range ops {
+		for _, p := range op.Params {
+			if p.Cli == nil || p.Cli.Complete == nil {
+				continue
+			}
+			id := p.Cli.Complete.OperationID
+			list, ok := byID[id]
+			if id == "" || !ok {
+				return fmt.Errorf("parameter %s of %s: x-cli complete: unknown operationId %q", p.Name, op.OperationID, id)
+			}
+			if list.Method != "GET" || strings.Contains(list.Path, "{") {
+				return fmt.Errorf("parameter %s of %s: x-cli complete: %s must be a GET without path parameters", p.Name, op.OperationID, id)
+			}
+		}
+	}
+	return nil
+}
+
+// extractServers extracts the document-level servers, in declared order
+func extractServers(servers openapi3.Servers) ([]Server, error) {
+	var result []Server
+	for _, s := range servers {
+		server := Server{URL: s.URL, Description: s.Description}
+		// Server variables take their defaults
+		for name, v := range s.Variables {
+			server.URL = strings.ReplaceAll(server.URL, "{"+name+"}", v.Default)
+		}
+		if cli, ok := s.Extensions["x-cli"]; ok {
+			overrides, err := parseCliOverrides(cli)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse x-cli of server %s: %w", s.URL, err)
+			}
+			server.Name = overrides.Name
+		}
+		result = append(result, server)
+	}
+	return result, nil
+}
+
+// securitySchemes extracts the declared security schemes, sorted by name,
+// with the scopes the document-level requirements ask for
+func securitySchemes(components *openapi3.Components, requirements openapi3.SecurityRequirements) ([]SecurityScheme, error) {
+	if components == nil {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(components.SecuritySchemes))
+	for name := range components.SecuritySchemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var schemes []SecurityScheme
+	for _, name := range names {
+		ref := components.SecuritySchemes[name]
+		if ref == nil || ref.Value == nil {
+			continue
+		}
+		scheme := SecurityScheme{
+			Name:         name,
+			Type:         ref.Value.Type,
+			Scheme:       strings.ToLower(ref.Value.Scheme),
+			BearerFormat: ref.Value.BearerFormat,
+			Description:  ref.Value.Description,
+		}
+		if flows := ref.Value.Flows; flows != nil {
+			scheme.ClientCredentials = oauthFlow(flows.ClientCredentials)
+			if ext, ok := flows.Extensions["x-deviceAuthorization"]; ok {
+				flow, err := parseDeviceAuthorization(ext)
+				if err != nil {
+					return nil, fmt.Errorf("security scheme %s: invalid x-deviceAuthorization: %w", name, err)
+				}
+				scheme.DeviceAuthorization = flow
+			}
+		}
+		for _, req := range requirements {
+			for _, scope := range req[name] {
+				if !slices.Contains(scheme.Scopes, scope) {
+					scheme.Scopes = append(scheme.Scopes, scope)
+				}
+			}
+		}
+		schemes = append(schemes, scheme)
+	}
+	return schemes, nil
+}
+
+// oauthFlow converts an OAuth2 flow, or returns nil when it is not declared
+func oauthFlow(flow *openapi3.OAuthFlow) *OAuthFlow {
+	if flow == nil {
+		return nil
+	}
+
+	scopes := make([]string, 0, len(flow.Scopes))
+	for scope := range flow.Scopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	return &OAuthFlow{
+		TokenURL:   flow.TokenURL,
+		RefreshURL: flow.RefreshURL,
+		Scopes:     scopes,
+	}
+}
+
+// validateSigning checks the signing type and its settings
+func validateSigning(signing *Signing) error {
+	if signing == nil {
+		return nil
+	}
+	switch signing.Type {
+	case SigningHMACSHA256:
+		if signing.Encoding != "" && signing.Encoding != "hex" && signing.Encoding != "base64" {
+			return fmt.Errorf("unknown encoding %q (must be hex or base64)", signing.Encoding)
+		}
+	case SigningAWSSigV4:
+		if signing.Service == "" {
+			return fmt.Errorf("aws-sigv4 signing requires a service")
+		}
+	default:
+		return fmt.Errorf("unknown type %q (must be %s or %s)", signing.Type, SigningHMACSHA256, SigningAWSSigV4)
+	}
+	return nil
+}
+
+// parseDeviceAuthorization parses the x-deviceAuthorization flow extension,
+// which mirrors the deviceAuthorization flow of OpenAPI 3.2:
+//
+//	flows:
+//	  x-deviceAuthorization:
+//	    deviceAuthorizationUrl: https://auth.example.com/device
+//	    tokenUrl: https://auth.example.com/token
+//	    x-client-id: my-cli
+//	    scopes:
+//	      read: Read access
+func parseDeviceAuthorization(ext interface{}) (*OAuthFlow, error) {
+	data, err := json.Marshal(ext)
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		DeviceAuthorizationURL string            `json:"deviceAuthorizationUrl"`
+		TokenURL               string            `json:"tokenUrl"`
+		RefreshURL             string            `json:"refreshUrl"`
+		ClientID               string            `json:"x-client-id"`
+		Scopes                 map[string]string `json:"scopes"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.DeviceAuthorizationURL == "" || raw.TokenURL == "" {
+		return nil, fmt.Errorf("deviceAuthorizationUrl and tokenUrl are required")
+	}
+
+	scopes := make([]string, 0, len(raw.Scopes))
+	for scope := range raw.Scopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	return &OAuthFlow{
+		TokenURL:               raw.TokenURL,
+		RefreshURL:             raw.RefreshURL,
+		Scopes:                 scopes,
+		DeviceAuthorizationURL: raw.DeviceAuthorizationURL,
+		ClientID:               raw.ClientID,
+	}, nil
+}
+
+// extractOperations extracts all operations from a path item, with the
+// warnings about them
+func extractOperations(path string, pathItem *openapi3.PathItem) ([]Operation, []string, error) {
 	var ops []Operation
+	var warnings []string
 
 	methods := []struct {
 		method string
@@ -86,18 +317,19 @@ func extractOperations(path string, pathItem *openapi3.PathItem) ([]Operation, e
 			continue
 		}
 
-		op, err := extractOperation(path, m.method, m.op, pathItem.Parameters)
+		op, opWarnings, err := extractOperation(path, m.method, m.op, pathItem.Parameters)
 		if err != nil {
-			return nil, fmt.Errorf("failed to extract %s operation: %w", m.method, err)
+			return nil, nil, fmt.Errorf("failed to extract %s operation: %w", m.method, err)
 		}
 		ops = append(ops, *op)
+		warnings = append(warnings, opWarnings...)
 	}
 
-	return ops, nil
+	return ops, warnings, nil
 }
 
-// extractOperation extracts a single operation
-func extractOperation(path, method string, op *openapi3.Operation, pathParams openapi3.Parameters) (*Operation, error) {
+// extractOperation extracts a single operation, with the warnings about it
+func extractOperation(path, method string, op *openapi3.Operation, pathParams openapi3.Parameters) (*Operation, []string, error) {
 	tag := "default"
 	if len(op.Tags) > 0 {
 		tag = op.Tags[0]
@@ -116,11 +348,20 @@ func extractOperation(path, method string, op *openapi3.Operation, pathParams op
 	if cli, ok := op.Extensions["x-cli"]; ok {
 		overrides, err := parseCliOverrides(cli)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse operation x-cli: %w", err)
+			return nil, nil, fmt.Errorf("failed to parse operation x-cli: %w", err)
 		}
 		operation.Cli = overrides
 	}
 
+	// Hints only inform, so a malformed one is dropped rather than failing
+	// the spec
+	var warnings []string
+	hints, problems := extractHints(op.Extensions)
+	for _, problem := range problems {
+		warnings = append(warnings, fmt.Sprintf("%s %s: %s", method, path, problem))
+	}
+	operation.Hints = hints
+
 	// Extract parameters (path-level + operation-level)
 	allParams := make([]*openapi3.ParameterRef, 0, len(pathParams)+len(op.Parameters))
 	allParams = append(allParams, pathParams...)
@@ -131,7 +372,7 @@ func extractOperation(path, method string, op *openapi3.Operation, pathParams op
 		}
 		param, err := extractParam(paramRef.Value)
 		if err != nil {
-			return nil, fmt.Errorf("failed to extract param %s: %w", paramRef.Value.Name, err)
+			return nil, nil, fmt.Errorf("failed to extract param %s: %w", paramRef.Value.Name, err)
 		}
 		operation.Params = append(operation.Params, *param)
 	}
@@ -151,6 +392,8 @@ func extractOperation(path, method string, op *openapi3.Operation, pathParams op
 	}
 
 	// Extract responses
+	linkHeader := false
+	var resultSchema *openapi3.Schema
 	if op.Responses != nil {
 		// Sort status codes for deterministic output
 		codes := make([]string, 0, len(op.Responses.Map()))
@@ -176,11 +419,77 @@ func extractOperation(path, method string, op *openapi3.Operation, pathParams op
 			}
 			sort.Strings(response.ContentTypes)
 
+			response.ExampleType = exampleContentType(response.ContentTypes)
+			if response.ExampleType != "" {
+				response.Example = mediaExample(response.ExampleType, resp.Content[response.ExampleType])
+			}
+
 			operation.Responses = append(operation.Responses, response)
+
+			// Detect pagination envelopes on the first successful JSON
+			// response, whose schema also gives the table columns
+			if strings.HasPrefix(code, "2") {
+				if media := resp.Content.Get("application/json"); media != nil && media.Schema != nil {
+					if resultSchema == nil {
+						resultSchema = media.Schema.Value
+					}
+					if operation.Envelope == nil {
+						operation.Envelope = detectEnvelope(media.Schema.Value)
+					}
+				}
+			}
+			if strings.HasPrefix(code, "2") && hasHeader(resp.Headers, "Link") {
+				linkHeader = true
+			}
+		}
+	}
+
+	// An explicit x-cli envelope always wins over detection
+	if operation.Cli != nil && operation.Cli.Envelope != nil {
+		operation.Envelope = operation.Cli.Envelope
+	}
+
+	operation.Pagination = detectPagination(operation, linkHeader)
+	if operation.Cli != nil && operation.Cli.Pagination != nil {
+		operation.Pagination = operation.Cli.Pagination
+	}
+
+	operation.Columns = detectColumns(resultSchema, operation.Envelope)
+	if operation.Cli != nil && len(operation.Cli.Columns) > 0 {
+		operation.Columns = operation.Cli.Columns
+	}
+
+	return operation, warnings, nil
+}
+
+// extractHints reads x-rate-cost and x-expected-latency extensions. Latency
+// may be a Go duration string ("1.5s") or a number of milliseconds. A
+// malformed hint is left out, and described in problems.
+func extractHints(ext map[string]interface{}) (hints Hints, problems []string) {
+	switch v := ext["x-rate-cost"].(type) {
+	case nil:
+	case float64:
+		hints.RateCost = v
+	default:
+		problems = append(problems, fmt.Sprintf("ignoring x-rate-cost, which must be a number, not %T", v))
+	}
+
+	switch v := ext["x-expected-latency"].(type) {
+	case nil:
+	case float64:
+		hints.ExpectedLatency = time.Duration(v * float64(time.Millisecond))
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("ignoring invalid x-expected-latency: %v", err))
+			break
 		}
+		hints.ExpectedLatency = d
+	default:
+		problems = append(problems, fmt.Sprintf("ignoring x-expected-latency, which must be a duration string or milliseconds, not %T", v))
 	}
 
-	return operation, nil
+	return hints, problems
 }
 
 // extractParam extracts a parameter definition
@@ -205,6 +514,8 @@ func extractParam(p *openapi3.Parameter) (*Param, error) {
 		if schema.Max != nil {
 			param.Max = schema.Max
 		}
+		param.Enum = schema.Enum
+		param.Pattern = schema.Pattern
 	}
 
 	// Parse parameter-level x-cli
@@ -1,8 +1,19 @@
 package spec
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
 )
 
 func TestLoad(t *testing.T) {
@@ -182,6 +193,25 @@ func TestLoad_PaginationParams(t *testing.T) {
 	}
 }
 
+func TestLoad_ParamEnum(t *testing.T) {
+	spec, err := Load(context.Background(), "../testdata/openapi31.yaml")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	for _, op := range spec.Operations {
+		for _, p := range op.Params {
+			if p.Name == "status" && p.In == "query" {
+				if len(p.Enum) != 3 || p.Enum[0] != "draft" {
+					t.Errorf("expected the status enum, got %v", p.Enum)
+				}
+				return
+			}
+		}
+	}
+	t.Fatal("expected to find the status parameter")
+}
+
 func TestOperation_HasJSONBody(t *testing.T) {
 	ctx := context.Background()
 	spec, err := Load(ctx, "../testdata/dap.json")
@@ -205,3 +235,629 @@ func TestOperation_HasJSONBody(t *testing.T) {
 		t.Error("expected createTask to have JSON body")
 	}
 }
+
+func TestLoad_ComputesSHA256(t *testing.T) {
+	ctx := context.Background()
+	spec, err := Load(ctx, "../testdata/dap.json")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	raw, err := os.ReadFile("../testdata/dap.json")
+	if err != nil {
+		t.Fatalf("failed to read spec: %v", err)
+	}
+	sum := sha256.Sum256(raw)
+
+	if spec.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected SHA256 %x, got %s", sum, spec.SHA256)
+	}
+	if !bytes.Equal(spec.Source, raw) {
+		t.Error("expected Source to hold the raw document")
+	}
+}
+
+func TestLoad_DetectsPaginationEnvelope(t *testing.T) {
+	ctx := context.Background()
+	spec, err := Load(ctx, "../testdata/openapi30.yaml")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	var listOp *Operation
+	for i := range spec.Operations {
+		if spec.Operations[i].OperationID == "listBookmarks" {
+			listOp = &spec.Operations[i]
+			break
+		}
+	}
+
+	if listOp == nil {
+		t.Fatal("expected to find listBookmarks operation")
+	}
+
+	if listOp.Envelope == nil {
+		t.Fatal("expected listBookmarks to have a pagination envelope")
+	}
+	if listOp.Envelope.Items != "data" {
+		t.Errorf("expected items path 'data', got '%s'", listOp.Envelope.Items)
+	}
+	if listOp.Envelope.Next != "next_cursor" {
+		t.Errorf("expected next path 'next_cursor', got '%s'", listOp.Envelope.Next)
+	}
+
+	if listOp.Pagination == nil || listOp.Pagination.CursorParam != "cursor" {
+		t.Errorf("expected the cursor parameter to take the next cursor, got %+v", listOp.Pagination)
+	}
+}
+
+func TestLoad_DetectsPagination(t *testing.T) {
+	content := `openapi: "3.0.3"
+info:
+  title: Pagination API
+  version: "1.0.0"
+paths:
+  /pages:
+    get:
+      operationId: listPages
+      parameters:
+        - {name: page, in: query, schema: {type: integer}}
+      responses:
+        "200":
+          description: Pages
+          content:
+            application/json:
+              schema: {type: array, items: {type: object}}
+  /links:
+    get:
+      operationId: listLinks
+      responses:
+        "200":
+          description: Links
+          headers:
+            link:
+              schema: {type: string}
+  /tokens:
+    get:
+      operationId: listTokens
+      parameters:
+        - {name: page_token, in: query, schema: {type: string}}
+      responses:
+        "200":
+          description: Tokens
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  items: {type: array, items: {type: object}}
+                  next_page_token: {type: string}
+  /custom:
+    get:
+      operationId: listCustom
+      x-cli:
+        pagination:
+          pageParam: p
+      parameters:
+        - {name: p, in: query, schema: {type: integer}}
+      responses:
+        "200":
+          description: Custom
+  /plain:
+    get:
+      operationId: listPlain
+      responses:
+        "200":
+          description: Plain
+`
+	path := filepath.Join(t.TempDir(), "pagination.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+
+	spec, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	want := map[string]*Pagination{
+		"listPages":  {PageParam: "page"},
+		"listLinks":  {LinkHeader: true},
+		"listTokens": {CursorParam: "page_token"},
+		"listCustom": {PageParam: "p"},
+		"listPlain":  nil,
+	}
+	for _, op := range spec.Operations {
+		if !reflect.DeepEqual(op.Pagination, want[op.OperationID]) {
+			t.Errorf("%s: pagination = %+v, want %+v", op.OperationID, op.Pagination, want[op.OperationID])
+		}
+	}
+}
+
+func TestLoad_DetectsColumns(t *testing.T) {
+	content := `openapi: "3.0.3"
+info:
+  title: Columns API
+  version: "1.0.0"
+paths:
+  /tasks:
+    get:
+      operationId: listTasks
+      responses:
+        "200":
+          description: Tasks
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  items:
+                    type: array
+                    items:
+                      type: object
+                      properties:
+                        created_at: {type: string}
+                        status: {type: string}
+                        labels: {type: array, items: {type: string}}
+                        owner: {type: object}
+                        id: {type: integer}
+                  total: {type: integer}
+  /tasks/{id}:
+    get:
+      operationId: getTask
+      x-cli:
+        columns: [id, owner.name]
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+      responses:
+        "200":
+          description: Task
+          content:
+            application/json:
+              schema: {type: object}
+`
+	path := filepath.Join(t.TempDir(), "columns.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+
+	spec, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	want := map[string][]string{
+		"listTasks": {"id", "status", "created_at"},
+		"getTask":   {"id", "owner.name"},
+	}
+	for _, op := range spec.Operations {
+		if !reflect.DeepEqual(op.Columns, want[op.OperationID]) {
+			t.Errorf("%s: columns = %v, want %v", op.OperationID, op.Columns, want[op.OperationID])
+		}
+	}
+}
+
+func TestLoad_EnvelopeFromXCli(t *testing.T) {
+	content := `openapi: "3.0.3"
+info:
+  title: Envelope API
+  version: "1.0.0"
+paths:
+  /widgets:
+    get:
+      operationId: listWidgets
+      x-cli:
+        envelope:
+          items: widgets
+          total: paging.count
+      responses:
+        "200":
+          description: Widgets
+          content:
+            application/json:
+              schema:
+                type: object
+`
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+
+	spec, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	env := spec.Operations[0].Envelope
+	if env == nil {
+		t.Fatal("expected envelope from x-cli")
+	}
+	if env.Items != "widgets" || env.Total != "paging.count" {
+		t.Errorf("unexpected envelope: %+v", env)
+	}
+}
+
+func TestExtractHints(t *testing.T) {
+	hints, problems := extractHints(map[string]interface{}{
+		"x-rate-cost":        5.0,
+		"x-expected-latency": "1.5s",
+	})
+	if len(problems) > 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	if hints.RateCost != 5 {
+		t.Errorf("expected rate cost 5, got %v", hints.RateCost)
+	}
+	if hints.ExpectedLatency != 1500*time.Millisecond {
+		t.Errorf("expected latency 1.5s, got %v", hints.ExpectedLatency)
+	}
+
+	hints, problems = extractHints(map[string]interface{}{"x-expected-latency": 250.0})
+	if len(problems) > 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	if hints.ExpectedLatency != 250*time.Millisecond {
+		t.Errorf("expected numeric latency as milliseconds, got %v", hints.ExpectedLatency)
+	}
+
+	hints, problems = extractHints(map[string]interface{}{"x-rate-cost": "high", "x-expected-latency": "soon"})
+	if len(problems) != 2 {
+		t.Errorf("expected a problem for each malformed hint, got %v", problems)
+	}
+	if hints != (Hints{}) {
+		t.Errorf("expected malformed hints to be ignored, got %+v", hints)
+	}
+}
+
+func TestLoad_WarnsAboutMalformedHints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	doc := `openapi: 3.0.3
+info: {title: Hints, version: "1.0"}
+paths:
+  /tasks:
+    get:
+      operationId: listTasks
+      x-rate-cost: high
+      x-expected-latency: 2s
+      responses:
+        "200": {description: OK}
+`
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("expected a malformed hint not to fail the spec, got %v", err)
+	}
+	want := []string{"GET /tasks: ignoring x-rate-cost, which must be a number, not string"}
+	if !reflect.DeepEqual(s.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", s.Warnings, want)
+	}
+	if hints := s.Operations[0].Hints; hints.RateCost != 0 || hints.ExpectedLatency != 2*time.Second {
+		t.Errorf("expected only the valid hint to be kept, got %+v", hints)
+	}
+}
+
+func TestLoad_ResponseExamples(t *testing.T) {
+	spec, err := Load(context.Background(), "../testdata/openapi30.yaml")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	examples := map[string]Response{}
+	for i := range spec.Operations {
+		op := &spec.Operations[i]
+		if len(op.Responses) > 0 {
+			examples[op.OperationID] = op.Responses[0]
+		}
+	}
+
+	// An explicit example is used as is
+	if got := examples["getBookmark"]; got.ExampleType != "application/json" || !bytes.Contains(got.Example, []byte(`"id": "bm_123"`)) {
+		t.Errorf("expected the documented example, got %s %s", got.ExampleType, got.Example)
+	}
+	// Otherwise one is synthesized from the schema
+	if got := examples["listBookmarks"]; !bytes.Contains(got.Example, []byte(`"next_cursor": "string"`)) {
+		t.Errorf("expected an example synthesized from the schema, got %s", got.Example)
+	}
+	// JSON is preferred over other content types
+	if got := examples["getExport"]; got.ExampleType != "application/json" {
+		t.Errorf("expected the JSON example, got %s", got.ExampleType)
+	}
+}
+
+func TestLoad_SecuritySchemes(t *testing.T) {
+	spec, err := Load(context.Background(), "../testdata/openapi30.yaml")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	want := []SecurityScheme{{
+		Name:         "bearerAuth",
+		Type:         SecurityTypeHTTP,
+		Scheme:       HTTPSchemeBearer,
+		BearerFormat: "JWT",
+		Description:  "Personal access token",
+	}}
+	if !reflect.DeepEqual(spec.Security, want) {
+		t.Errorf("security schemes = %+v, want %+v", spec.Security, want)
+	}
+}
+
+func TestLoad_OAuth2ClientCredentials(t *testing.T) {
+	content := `openapi: 3.0.3
+info:
+  title: Test
+  version: "1.0"
+paths: {}
+components:
+  securitySchemes:
+    oauth:
+      type: oauth2
+      flows:
+        clientCredentials:
+          tokenUrl: https://auth.example.com/token
+          scopes:
+            write: Write access
+            read: Read access
+security:
+  - oauth: [read]
+  - oauth: [read, write]
+`
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+
+	spec, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	want := []SecurityScheme{{
+		Name: "oauth",
+		Type: SecurityTypeOAuth2,
+		ClientCredentials: &OAuthFlow{
+			TokenURL: "https://auth.example.com/token",
+			Scopes:   []string{"read", "write"},
+		},
+		Scopes: []string{"read", "write"},
+	}}
+	if !reflect.DeepEqual(spec.Security, want) {
+		t.Errorf("security schemes = %+v, want %+v", spec.Security, want)
+	}
+}
+
+func TestLoad_Servers(t *testing.T) {
+	content := `openapi: 3.0.3
+info:
+  title: Test
+  version: "1.0"
+servers:
+  - url: https://api.example.com
+    description: Production
+  - url: https://{region}.sandbox.example.com/{version}
+    description: Sandbox environment
+    x-cli:
+      name: test
+    variables:
+      region:
+        default: eu
+      version:
+        default: v2
+paths: {}
+`
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+
+	spec, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	want := []Server{
+		{URL: "https://api.example.com", Description: "Production"},
+		{URL: "https://eu.sandbox.example.com/v2", Description: "Sandbox environment", Name: "test"},
+	}
+	if !reflect.DeepEqual(spec.Servers, want) {
+		t.Errorf("servers = %+v, want %+v", spec.Servers, want)
+	}
+}
+
+func TestLoad_XCliSigning(t *testing.T) {
+	tests := []struct {
+		signing string
+		wantErr string
+	}{
+		{"{type: aws-sigv4, region: us-east-1, service: execute-api}", ""},
+		{"{type: hmac-sha256, encoding: base64}", ""},
+		{"{type: aws-sigv4}", "requires a service"},
+		{"{type: hmac-sha256, encoding: base32}", "unknown encoding"},
+		{"{type: rsa}", "unknown type"},
+	}
+
+	for i, tt := range tests {
+		content := `openapi: 3.0.3
+info:
+  title: Test
+  version: "1.0"
+x-cli:
+  signing: ` + tt.signing + `
+paths: {}
+`
+		path := filepath.Join(t.TempDir(), fmt.Sprintf("spec%d.yaml", i))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write spec: %v", err)
+		}
+
+		spec, err := Load(context.Background(), path)
+		if tt.wantErr == "" {
+			if err != nil || spec.GlobalCli.Signing == nil {
+				t.Errorf("%s: expected signing settings, got %v", tt.signing, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.signing, tt.wantErr, err)
+		}
+	}
+}
+
+func TestLoad_XCliComplete(t *testing.T) {
+	tests := []struct {
+		operationID string
+		wantErr     string
+	}{
+		{"listTasks", ""},
+		{"listMissing", `unknown operationId "listMissing"`},
+		{"getTask", "getTask must be a GET without path parameters"},
+	}
+
+	for i, tt := range tests {
+		content := `openapi: 3.0.3
+info:
+  title: Test
+  version: "1.0"
+paths:
+  /tasks:
+    get:
+      operationId: listTasks
+      responses:
+        "200":
+          description: OK
+  /tasks/{taskId}:
+    get:
+      operationId: getTask
+      parameters:
+        - name: taskId
+          in: path
+          required: true
+          schema:
+            type: string
+          x-cli:
+            complete:
+              operationId: ` + tt.operationID + `
+      responses:
+        "200":
+          description: OK
+`
+		path := filepath.Join(t.TempDir(), fmt.Sprintf("spec%d.yaml", i))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write spec: %v", err)
+		}
+
+		_, err := Load(context.Background(), path)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.operationID, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.operationID, tt.wantErr, err)
+		}
+	}
+}
+
+func TestLoad_DeviceAuthorization(t *testing.T) {
+	content := `openapi: 3.0.3
+info:
+  title: Test
+  version: "1.0"
+paths: {}
+components:
+  securitySchemes:
+    oauth:
+      type: oauth2
+      flows:
+        x-deviceAuthorization:
+          deviceAuthorizationUrl: https://auth.example.com/device
+          tokenUrl: https://auth.example.com/token
+          x-client-id: test-cli
+          scopes:
+            read: Read access
+`
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+
+	spec, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+
+	want := &OAuthFlow{
+		TokenURL:               "https://auth.example.com/token",
+		Scopes:                 []string{"read"},
+		DeviceAuthorizationURL: "https://auth.example.com/device",
+		ClientID:               "test-cli",
+	}
+	if len(spec.Security) != 1 || !reflect.DeepEqual(spec.Security[0].DeviceAuthorization, want) {
+		t.Errorf("security schemes = %+v, want a device authorization flow %+v", spec.Security, want)
+	}
+
+	// Both endpoints are required
+	content = strings.Replace(content, "          tokenUrl: https://auth.example.com/token\n", "", 1)
+	path = filepath.Join(t.TempDir(), "invalid.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+	if _, err := Load(context.Background(), path); err == nil || !strings.Contains(err.Error(), "x-deviceAuthorization") {
+		t.Errorf("expected an invalid x-deviceAuthorization error, got %v", err)
+	}
+}
+
+func TestSchemaExample(t *testing.T) {
+	min := 1.0
+	schema := &openapi3.Schema{
+		Type: &openapi3.Types{openapi3.TypeObject},
+		Properties: openapi3.Schemas{
+			"id":      openapi3.NewUUIDSchema().NewRef(),
+			"status":  &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, Enum: []interface{}{"open", "closed"}}},
+			"count":   &openapi3.SchemaRef{Value: &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeInteger}, Min: &min}},
+			"tags":    openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema()).NewRef(),
+			"created": openapi3.NewDateTimeSchema().NewRef(),
+		},
+	}
+
+	got := schemaExample(schema, 0)
+	want := map[string]interface{}{
+		"id":      "00000000-0000-0000-0000-000000000000",
+		"status":  "open",
+		"count":   1.0,
+		"tags":    []interface{}{"string"},
+		"created": "2024-01-01T00:00:00Z",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("schemaExample() = %#v, want %#v", got, want)
+	}
+}
+
+func TestLoad_WarnsAboutUnnamedOperations(t *testing.T) {
+	s := loadMergeSpec(t, "api.yaml", `openapi: 3.0.3
+info:
+  title: Unnamed API
+  version: 1.0.0
+paths:
+  /health:
+    get:
+      responses:
+        "200":
+          description: OK
+  /ready:
+    get:
+      x-cli:
+        name: ready
+      responses:
+        "200":
+          description: OK
+`)
+	want := []string{"GET /health has neither an operationId nor an x-cli name to name its command after"}
+	if !reflect.DeepEqual(s.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", s.Warnings, want)
+	}
+}
@@ -0,0 +1,478 @@
+package spec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NamedSpec is a spec to merge and the name of its service
+type NamedSpec struct {
+	Name string
+	Spec *Spec
+}
+
+// componentSections are the sections of components that are referenced
+// with $ref
+var componentSections = []string{
+	"schemas", "responses", "parameters", "examples", "requestBodies",
+	"headers", "securitySchemes", "links", "callbacks", "pathItems",
+}
+
+// operationMethods are the keys of a path item that hold operations
+var operationMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Merge combines the specs of several services into one, for a CLI with a
+// top-level command per service. The services are expected to share a base
+// URL, such as an API gateway: the path of each spec's first server becomes
+// a prefix of its operation paths, and the merged servers keep only their
+// scheme and host. The merged Source is a single OpenAPI document.
+//
+// Collisions are resolved in the order of specs, so the same specs always
+// merge the same way:
+//   - a component that a later spec defines differently is renamed to
+//     <service>.<name> in that spec, along with its references
+//   - an operationId already used by an earlier spec becomes
+//     <service>.<operationId> in the merged document; commands are named
+//     within their service, so their names are unaffected
+//   - tags do not collide, as each service has its own groups
+//   - two specs defining an operation with the same method and path is an
+//     error
+func Merge(specs []NamedSpec) (*Spec, error) {
+	m := &merger{
+		root:       &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"},
+		paths:      &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"},
+		components: make(map[string]*yaml.Node),
+		owners:     make(map[string]string),
+		opIDs:      make(map[string]bool),
+		tags:       &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"},
+		tagNames:   make(map[string]bool),
+	}
+	merged := &Spec{}
+	var titles, versions []string
+	schemes := make(map[string]bool)
+	servers := make(map[string]bool)
+
+	for _, ns := range specs {
+		s := ns.Spec
+		renamed, err := m.add(ns.Name, s)
+		if err != nil {
+			return nil, err
+		}
+
+		merged.Services = append(merged.Services, Service{
+			Name:        ns.Name,
+			Title:       s.Title,
+			Version:     s.Version,
+			Description: s.Description,
+		})
+		titles = append(titles, s.Title)
+		versions = append(versions, s.Version)
+
+		prefix := serverPrefix(s.Servers)
+		for _, op := range s.Operations {
+			op.Service = ns.Name
+			op.Path = prefix + op.Path
+			merged.Operations = append(merged.Operations, op)
+		}
+		for _, scheme := range s.Security {
+			if name, ok := renamed["securitySchemes/"+scheme.Name]; ok {
+				scheme.Name = name
+			} else if schemes[scheme.Name] {
+				continue
+			}
+			schemes[scheme.Name] = true
+			merged.Security = append(merged.Security, scheme)
+		}
+		for _, server := range s.Servers {
+			server.URL = serverHost(server.URL)
+			if server.URL == "" || servers[server.URL] {
+				continue
+			}
+			servers[server.URL] = true
+			merged.Servers = append(merged.Servers, server)
+		}
+		if merged.GlobalCli == nil {
+			merged.GlobalCli = s.GlobalCli
+		}
+		for _, warning := range s.Warnings {
+			merged.Warnings = append(merged.Warnings, ns.Name+": "+warning)
+		}
+	}
+	sort.Slice(merged.Security, func(i, j int) bool { return merged.Security[i].Name < merged.Security[j].Name })
+
+	merged.Title = strings.Join(titles, ", ")
+	merged.Version = strings.Join(versions, ", ")
+
+	source, err := m.document(merged)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(source)
+	merged.SHA256 = hex.EncodeToString(sum[:])
+	merged.Source = source
+	return merged, nil
+}
+
+// merger builds the merged OpenAPI document
+type merger struct {
+	root       *yaml.Node
+	openapi    string
+	paths      *yaml.Node
+	components map[string]*yaml.Node // section → mapping of components
+	owners     map[string]string     // merged path → service that added it
+	opIDs      map[string]bool
+	tags       *yaml.Node
+	tagNames   map[string]bool
+	cli        *yaml.Node // the first document-level x-cli
+}
+
+// add merges the source document of the service's spec, and returns the
+// components it renamed, as "<section>/<name>" → new name
+func (m *merger) add(service string, s *Spec) (map[string]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(s.Source, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse the spec of %s: %w", service, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("the spec of %s is not an OpenAPI document", service)
+	}
+	src := doc.Content[0]
+	if trimmed := bytes.TrimSpace(s.Source); len(trimmed) > 0 && trimmed[0] == '{' {
+		// JSON is parsed as flow style, which would make the merged YAML
+		// hard to read
+		clearStyle(src)
+	}
+
+	if m.openapi == "" {
+		if v := mapValue(src, "openapi"); v != nil {
+			m.openapi = v.Value
+		}
+	}
+	if m.cli == nil {
+		m.cli = mapValue(src, "x-cli")
+	}
+
+	renamed := m.addComponents(service, mapValue(src, "components"))
+	rewriteRefs(src, renamed)
+	renameSecurity(mapValue(src, "security"), renamed)
+
+	prefix := serverPrefix(s.Servers)
+	security := mapValue(src, "security")
+	paths := mapValue(src, "paths")
+	for i := 0; paths != nil && i+1 < len(paths.Content); i += 2 {
+		path, item := prefix+paths.Content[i].Value, paths.Content[i+1]
+		for _, method := range operationMethods {
+			op := mapValue(item, method)
+			if op == nil || op.Kind != yaml.MappingNode {
+				continue
+			}
+			// Document-level security moves to the operations it applies to
+			if opSecurity := mapValue(op, "security"); opSecurity != nil {
+				renameSecurity(opSecurity, renamed)
+			} else if security != nil {
+				op.Content = append(op.Content, stringNode("security"), security)
+			}
+			if id := mapValue(op, "operationId"); id != nil {
+				if m.opIDs[id.Value] {
+					id.Value = service + "." + id.Value
+				}
+				m.opIDs[id.Value] = true
+			}
+		}
+
+		existing := mapValue(m.paths, path)
+		if existing == nil {
+			m.paths.Content = append(m.paths.Content, stringNode(path), item)
+			m.owners[path] = service
+			continue
+		}
+		if err := mergePathItem(existing, item); err != nil {
+			return nil, fmt.Errorf("path %s of %s: %w in %s", path, service, err, m.owners[path])
+		}
+	}
+
+	tags := mapValue(src, "tags")
+	for i := 0; tags != nil && i < len(tags.Content); i++ {
+		name := mapValue(tags.Content[i], "name")
+		if name == nil || m.tagNames[name.Value] {
+			continue
+		}
+		m.tagNames[name.Value] = true
+		m.tags.Content = append(m.tags.Content, tags.Content[i])
+	}
+	return renamed, nil
+}
+
+// addComponents adds the components of a service, renaming the ones that
+// differ from a component of the same name added before
+func (m *merger) addComponents(service string, components *yaml.Node) map[string]string {
+	type component struct {
+		section, name string
+		value         *yaml.Node
+	}
+
+	renamed := make(map[string]string)
+	var added, shared []component
+	for _, section := range componentSections {
+		entries := mapValue(components, section)
+		for i := 0; entries != nil && i+1 < len(entries.Content); i += 2 {
+			c := component{section, entries.Content[i].Value, entries.Content[i+1]}
+			if existing := mapValue(m.components[section], c.name); existing != nil {
+				if equalNodes(existing, c.value) {
+					shared = append(shared, c)
+					continue
+				}
+				renamed[section+"/"+c.name] = service + "." + c.name
+			}
+			added = append(added, c)
+		}
+	}
+
+	// A component that matches one added before, but refers to a renamed
+	// one, differs after all
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(shared); i++ {
+			c := shared[i]
+			if !refersTo(c.value, renamed) {
+				continue
+			}
+			renamed[c.section+"/"+c.name] = service + "." + c.name
+			added = append(added, c)
+			shared = append(shared[:i], shared[i+1:]...)
+			changed = true
+			i--
+		}
+	}
+
+	for _, c := range added {
+		name := c.name
+		if newName, ok := renamed[c.section+"/"+c.name]; ok {
+			name = newName
+		}
+		if m.components[c.section] == nil {
+			m.components[c.section] = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		m.components[c.section].Content = append(m.components[c.section].Content, stringNode(name), c.value)
+	}
+	return renamed
+}
+
+// document encodes the merged document
+func (m *merger) document(merged *Spec) ([]byte, error) {
+	add := func(key string, value *yaml.Node) {
+		m.root.Content = append(m.root.Content, stringNode(key), value)
+	}
+
+	openapi := m.openapi
+	if openapi == "" {
+		openapi = "3.0.3"
+	}
+	add("openapi", stringNode(openapi))
+	info := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	info.Content = append(info.Content,
+		stringNode("title"), stringNode(merged.Title),
+		stringNode("version"), stringNode(merged.Version))
+	add("info", info)
+
+	if len(merged.Servers) > 0 {
+		servers := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, s := range merged.Servers {
+			server := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			server.Content = append(server.Content, stringNode("url"), stringNode(s.URL))
+			if s.Description != "" {
+				server.Content = append(server.Content, stringNode("description"), stringNode(s.Description))
+			}
+			servers.Content = append(servers.Content, server)
+		}
+		add("servers", servers)
+	}
+	if len(m.tags.Content) > 0 {
+		add("tags", m.tags)
+	}
+	add("paths", m.paths)
+
+	components := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, section := range componentSections {
+		if entries := m.components[section]; entries != nil {
+			components.Content = append(components.Content, stringNode(section), entries)
+		}
+	}
+	if len(components.Content) > 0 {
+		add("components", components)
+	}
+	if m.cli != nil {
+		add("x-cli", m.cli)
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(m.root); err != nil {
+		return nil, fmt.Errorf("failed to encode the merged spec: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode the merged spec: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// mergePathItem adds the operations of from to the path item into, which
+// must not define them already
+func mergePathItem(into, from *yaml.Node) error {
+	for i := 0; i+1 < len(from.Content); i += 2 {
+		key, value := from.Content[i], from.Content[i+1]
+		existing := mapValue(into, key.Value)
+		switch {
+		case existing == nil:
+			into.Content = append(into.Content, key, value)
+		case slices.Contains(operationMethods, key.Value):
+			return fmt.Errorf("%s is already defined", strings.ToUpper(key.Value))
+		case !equalNodes(existing, value):
+			return fmt.Errorf("%s differs from the one", key.Value)
+		}
+	}
+	return nil
+}
+
+// serverPrefix returns the path of the first server, which prefixes the
+// operation paths of a merged spec
+func serverPrefix(servers []Server) string {
+	if len(servers) == 0 {
+		return ""
+	}
+	u, err := url.Parse(servers[0].URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// serverHost returns the server URL without its path, or "" for a relative
+// URL
+func serverHost(serverURL string) string {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return (&url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host}).String()
+}
+
+// rewriteRefs points the $refs of node at renamed components
+func rewriteRefs(node *yaml.Node, renamed map[string]string) {
+	if len(renamed) == 0 || node == nil {
+		return
+	}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "$ref" {
+				if ref, ok := renamedRef(node.Content[i+1].Value, renamed); ok {
+					node.Content[i+1].Value = ref
+				}
+			}
+		}
+	}
+	for _, child := range node.Content {
+		rewriteRefs(child, renamed)
+	}
+}
+
+// refersTo reports whether node has a $ref to a renamed component
+func refersTo(node *yaml.Node, renamed map[string]string) bool {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "$ref" {
+				if _, ok := renamedRef(node.Content[i+1].Value, renamed); ok {
+					return true
+				}
+			}
+		}
+	}
+	for _, child := range node.Content {
+		if refersTo(child, renamed) {
+			return true
+		}
+	}
+	return false
+}
+
+// renamedRef returns ref pointed at its renamed component, if it refers to
+// one
+func renamedRef(ref string, renamed map[string]string) (string, bool) {
+	rest, ok := strings.CutPrefix(ref, "#/components/")
+	if !ok {
+		return "", false
+	}
+	section, name, _ := strings.Cut(rest, "/")
+	name, pointer, _ := strings.Cut(name, "/")
+	newName, ok := renamed[section+"/"+name]
+	if !ok {
+		return "", false
+	}
+	ref = "#/components/" + section + "/" + newName
+	if pointer != "" {
+		ref += "/" + pointer
+	}
+	return ref, true
+}
+
+// renameSecurity renames the schemes of a list of security requirements
+func renameSecurity(requirements *yaml.Node, renamed map[string]string) {
+	if requirements == nil {
+		return
+	}
+	for _, requirement := range requirements.Content {
+		for i := 0; i+1 < len(requirement.Content); i += 2 {
+			if name, ok := renamed["securitySchemes/"+requirement.Content[i].Value]; ok {
+				requirement.Content[i].Value = name
+			}
+		}
+	}
+}
+
+// equalNodes reports whether two nodes hold the same YAML value, ignoring
+// style and comments
+func equalNodes(a, b *yaml.Node) bool {
+	if a.Kind != b.Kind || a.ShortTag() != b.ShortTag() || a.Value != b.Value || len(a.Content) != len(b.Content) {
+		return false
+	}
+	for i := range a.Content {
+		if !equalNodes(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// clearStyle resets the style of node and its children to the default
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// mapValue returns the value of key in a mapping node, or nil
+func mapValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func stringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
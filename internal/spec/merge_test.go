@@ -0,0 +1,169 @@
+package spec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const billingSpec = `openapi: 3.0.3
+info:
+  title: Billing API
+  version: 1.0.0
+servers:
+  - url: https://api.example.com/billing
+security:
+  - bearer: []
+paths:
+  /invoices:
+    get:
+      operationId: listInvoices
+      tags: [invoices]
+      responses:
+        "200":
+          description: Invoices
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Invoice'
+  /health:
+    get:
+      operationId: getHealth
+      responses:
+        "200":
+          description: OK
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+  schemas:
+    Error:
+      type: object
+      properties:
+        message:
+          type: string
+    Invoice:
+      type: object
+      properties:
+        total:
+          type: number
+`
+
+const usersSpec = `{
+  "openapi": "3.0.3",
+  "info": {"title": "Users API", "version": "2.1.0"},
+  "servers": [{"url": "https://api.example.com/users"}],
+  "paths": {
+    "/users": {
+      "get": {
+        "operationId": "listInvoices",
+        "tags": ["invoices"],
+        "responses": {
+          "200": {
+            "description": "Users",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Invoice"}}}
+          },
+          "default": {
+            "description": "Error",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Error": {"type": "object", "properties": {"message": {"type": "string"}}},
+      "Invoice": {"type": "object", "properties": {"id": {"type": "string"}}}
+    }
+  }
+}
+`
+
+func loadMergeSpec(t *testing.T, name, content string) *Spec {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write spec: %v", err)
+	}
+	s, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+	return s
+}
+
+func TestMerge(t *testing.T) {
+	billing := loadMergeSpec(t, "billing.yaml", billingSpec)
+	users := loadMergeSpec(t, "users.json", usersSpec)
+
+	merged, err := Merge([]NamedSpec{{Name: "billing", Spec: billing}, {Name: "users", Spec: users}})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+
+	if merged.Title != "Billing API, Users API" || len(merged.Services) != 2 || merged.Services[1].Name != "users" {
+		t.Errorf("unexpected merged spec info: %q, %+v", merged.Title, merged.Services)
+	}
+	if len(merged.Servers) != 1 || merged.Servers[0].URL != "https://api.example.com" {
+		t.Errorf("expected the shared server without its path, got %+v", merged.Servers)
+	}
+
+	// Operations keep their names and tags, under the server path of their spec
+	var paths []string
+	for _, op := range merged.Operations {
+		paths = append(paths, op.Service+" "+op.Method+" "+op.Path+" "+op.OperationID)
+	}
+	want := "billing GET /billing/health getHealth, billing GET /billing/invoices listInvoices, users GET /users/users listInvoices"
+	if got := strings.Join(paths, ", "); got != want {
+		t.Errorf("operations = %s, want %s", got, want)
+	}
+
+	// The merged document is a valid spec of the same operations, where the
+	// differing Invoice schema and the duplicate operationId are renamed and
+	// the identical Error schema is shared
+	path := filepath.Join(t.TempDir(), "merged.yaml")
+	if err := os.WriteFile(path, merged.Source, 0644); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatalf("failed to load the merged document: %v\n%s", err, merged.Source)
+	}
+	if len(reloaded.Operations) != 3 {
+		t.Errorf("expected 3 operations in the merged document, got %d", len(reloaded.Operations))
+	}
+	for _, want := range []string{
+		"operationId: users.listInvoices",
+		"$ref: '#/components/schemas/users.Invoice'",
+		"    users.Invoice:\n",
+		// Document-level security applies to the billing operations only
+		"      operationId: getHealth\n      responses:\n        \"200\":\n          description: OK\n      security:\n        - bearer: []\n",
+	} {
+		if !strings.Contains(string(merged.Source), want) {
+			t.Errorf("expected the merged document to contain %q:\n%s", want, merged.Source)
+		}
+	}
+	if strings.Contains(string(merged.Source), "users.Error") {
+		t.Errorf("expected the identical Error schemas to be shared:\n%s", merged.Source)
+	}
+
+	// The same specs always merge the same way
+	again, err := Merge([]NamedSpec{{Name: "billing", Spec: billing}, {Name: "users", Spec: users}})
+	if err != nil || again.SHA256 != merged.SHA256 {
+		t.Errorf("expected a deterministic merge, got %v", err)
+	}
+}
+
+func TestMerge_ConflictingOperations(t *testing.T) {
+	billing := loadMergeSpec(t, "billing.yaml", billingSpec)
+	other := loadMergeSpec(t, "other.yaml", strings.Replace(billingSpec, "title: Billing API", "title: Other API", 1))
+
+	_, err := Merge([]NamedSpec{{Name: "billing", Spec: billing}, {Name: "other", Spec: other}})
+	if err == nil || !strings.Contains(err.Error(), "path /billing/invoices of other: GET is already defined in billing") {
+		t.Errorf("expected a conflicting operation error, got %v", err)
+	}
+}
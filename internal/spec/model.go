@@ -1,16 +1,88 @@
 package spec
 
+import "time"
+
 // Spec represents a normalized OpenAPI specification
 type Spec struct {
 	Title       string
 	Version     string
 	Description string
+	SHA256      string // hex-encoded SHA-256 of the source document
+	Source      []byte // the source document as loaded, JSON or YAML
 	Operations  []Operation
+	Security    []SecurityScheme // components.securitySchemes, sorted by name
+	Servers     []Server
 	GlobalCli   *CliOverrides
+
+	// Services are the specs a merged spec was built from, in order; nil
+	// for a single spec
+	Services []Service
+
+	// Warnings are problems of the spec that don't prevent generating a
+	// CLI from it, but likely make it not work as intended
+	Warnings []string
+}
+
+// Service is one of the specs merged into a spec by Merge
+type Service struct {
+	Name        string // top-level command of the service's operations
+	Title       string
+	Version     string
+	Description string
+}
+
+// Server is an entry of the document-level servers list
+type Server struct {
+	URL         string // with server variables replaced by their defaults
+	Description string
+	Name        string // from the server's x-cli name, empty if not declared
 }
 
+// SecurityScheme is a security scheme declared by the spec
+type SecurityScheme struct {
+	Name         string // key in components.securitySchemes
+	Type         string // http, apiKey, oauth2 or openIdConnect
+	Scheme       string // HTTP authorization scheme, e.g. bearer or basic
+	BearerFormat string // hint for the bearer token format, e.g. JWT
+	Description  string
+
+	// ClientCredentials is the oauth2 client credentials flow, if declared
+	ClientCredentials *OAuthFlow
+
+	// DeviceAuthorization is the oauth2 device authorization flow (RFC
+	// 8628), declared with the x-deviceAuthorization flow extension
+	DeviceAuthorization *OAuthFlow
+
+	// Scopes are the scopes the document-level security requirements ask
+	// for with this scheme
+	Scopes []string
+}
+
+// OAuthFlow is an OAuth2 flow of a security scheme
+type OAuthFlow struct {
+	TokenURL   string
+	RefreshURL string
+	Scopes     []string // declared scope names, sorted
+
+	// DeviceAuthorizationURL is the device authorization endpoint of a
+	// device authorization flow
+	DeviceAuthorizationURL string
+
+	// ClientID is the public client ID to use with the flow (x-client-id),
+	// for CLIs registered as a single OAuth2 client
+	ClientID string
+}
+
+// Security scheme types and HTTP schemes
+const (
+	SecurityTypeHTTP   = "http"
+	SecurityTypeOAuth2 = "oauth2"
+	HTTPSchemeBearer   = "bearer"
+)
+
 // Operation represents a single API operation extracted from the spec
 type Operation struct {
+	Service     string // name of the operation's service in a merged spec
 	Tag         string
 	Method      string
 	Path        string
@@ -20,9 +92,20 @@ type Operation struct {
 	Params      []Param
 	RequestBody *RequestBody
 	Responses   []Response
+	Envelope    *Envelope   // paginated response envelope, if any
+	Pagination  *Pagination // how to request the following pages, if known
+	Columns     []string    // default columns of table output
+	Hints       Hints
 	Cli         *CliOverrides
 }
 
+// Hints carries operational metadata from x-rate-cost and
+// x-expected-latency extensions
+type Hints struct {
+	RateCost        float64       // relative cost against the API's rate limit
+	ExpectedLatency time.Duration // typical time for the operation to complete
+}
+
 // Param represents a parameter for an operation
 type Param struct {
 	Name        string
@@ -33,6 +116,8 @@ type Param struct {
 	Default     interface{}
 	Min         *float64
 	Max         *float64
+	Enum        []interface{}
+	Pattern     string
 	Description string
 	Cli         *ParamCliOverrides
 }
@@ -49,6 +134,8 @@ type Response struct {
 	StatusCode   string
 	Description  string
 	ContentTypes []string
+	Example      []byte // example body, from the spec or synthesized from the schema
+	ExampleType  string // content type of Example
 }
 
 // CliOverrides represents x-cli overrides at the operation level
@@ -57,6 +144,71 @@ type CliOverrides struct {
 	Group   string   `json:"group,omitempty" yaml:"group,omitempty"`
 	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
 	Hidden  bool     `json:"hidden,omitempty" yaml:"hidden,omitempty"`
+	Section string   `json:"section,omitempty" yaml:"section,omitempty"`
+
+	// SuggestFor lists former or common mistyped names of the command
+	SuggestFor []string `json:"suggestFor,omitempty" yaml:"suggestFor,omitempty"`
+
+	Envelope   *Envelope   `json:"envelope,omitempty" yaml:"envelope,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty" yaml:"pagination,omitempty"`
+
+	// Columns are the default columns of table output, as dotted paths
+	// into each item
+	Columns []string `json:"columns,omitempty" yaml:"columns,omitempty"`
+
+	// UsageTemplate and HelpTemplate replace cobra's usage and help
+	// templates in the generated CLI. Only read from the document-level x-cli.
+	UsageTemplate string `json:"usageTemplate,omitempty" yaml:"usageTemplate,omitempty"`
+	HelpTemplate  string `json:"helpTemplate,omitempty" yaml:"helpTemplate,omitempty"`
+
+	// Signing makes the generated CLI sign every request. Only read from the
+	// document-level x-cli.
+	Signing *Signing `json:"signing,omitempty" yaml:"signing,omitempty"`
+}
+
+// Signing describes how requests are signed
+type Signing struct {
+	Type string `json:"type" yaml:"type"` // hmac-sha256 or aws-sigv4
+
+	// aws-sigv4: the signing region (empty to read AWS_REGION) and service
+	Region  string `json:"region,omitempty" yaml:"region,omitempty"`
+	Service string `json:"service,omitempty" yaml:"service,omitempty"`
+
+	// hmac-sha256: header names and the signature encoding (hex or base64)
+	SignatureHeader string `json:"signatureHeader,omitempty" yaml:"signatureHeader,omitempty"`
+	TimestampHeader string `json:"timestampHeader,omitempty" yaml:"timestampHeader,omitempty"`
+	KeyIDHeader     string `json:"keyIdHeader,omitempty" yaml:"keyIdHeader,omitempty"`
+	Encoding        string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+}
+
+// Signing types
+const (
+	SigningHMACSHA256 = "hmac-sha256"
+	SigningAWSSigV4   = "aws-sigv4"
+)
+
+// Envelope describes a paginated response that wraps its items in an object
+// with metadata. Fields are dotted paths into the response body
+// (e.g. "meta.total").
+type Envelope struct {
+	Items string `json:"items,omitempty" yaml:"items,omitempty"`
+	Total string `json:"total,omitempty" yaml:"total,omitempty"`
+	Next  string `json:"next,omitempty" yaml:"next,omitempty"`
+}
+
+// Pagination describes how to request the page after a response of a list
+// operation
+type Pagination struct {
+	// CursorParam is the query parameter that takes the envelope's next
+	// cursor
+	CursorParam string `json:"cursorParam,omitempty" yaml:"cursorParam,omitempty"`
+
+	// PageParam is the query parameter that takes a page number
+	PageParam string `json:"pageParam,omitempty" yaml:"pageParam,omitempty"`
+
+	// LinkHeader is set when the next page's URL is sent in a Link header
+	// with rel="next" (RFC 8288)
+	LinkHeader bool `json:"linkHeader,omitempty" yaml:"linkHeader,omitempty"`
 }
 
 // ParamCliOverrides represents x-cli overrides at the parameter level
@@ -66,4 +218,17 @@ type ParamCliOverrides struct {
 	Env        string `json:"env,omitempty" yaml:"env,omitempty"`
 	ConfigKey  string `json:"config,omitempty" yaml:"config,omitempty"`
 	Positional *bool  `json:"positional,omitempty" yaml:"positional,omitempty"`
+
+	// Complete completes the parameter's values in the shell from the items
+	// of a list operation, e.g. live resource IDs
+	Complete *Completion `json:"complete,omitempty" yaml:"complete,omitempty"`
+}
+
+// Completion names the list operation whose items complete a parameter's
+// values, and the field of each item to complete
+type Completion struct {
+	OperationID string `json:"operationId" yaml:"operationId"`
+
+	// Field is a dotted path into each item (default "id")
+	Field string `json:"field,omitempty" yaml:"field,omitempty"`
 }
@@ -0,0 +1,135 @@
+package spec
+
+import (
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Property names recognized when detecting pagination envelopes
+var (
+	envelopeItemsKeys = []string{"items", "data", "results", "records"}
+	envelopeMetaKeys  = []string{"meta", "pagination", "page_info", "pageInfo"}
+	envelopeTotalKeys = []string{"total", "total_count", "totalCount", "count"}
+	envelopeNextKeys  = []string{"next_cursor", "nextCursor", "next_page_token", "nextPageToken", "cursor", "next"}
+)
+
+// Query parameter names recognized when detecting how to request the next
+// page
+var (
+	cursorParamKeys = []string{"cursor", "page_token", "pageToken", "after", "starting_after", "continuation_token", "continuationToken"}
+	pageParamKeys   = []string{"page", "page_number", "pageNumber"}
+)
+
+// detectEnvelope recognizes response schemas shaped like
+// {"items": [...], "meta": {"total": 1, "next_cursor": "..."}}. An items
+// array alone is not enough: a total count or next cursor must be present
+// either beside it or inside a metadata object.
+func detectEnvelope(schema *openapi3.Schema) *Envelope {
+	if schema == nil || !schema.Type.Is(openapi3.TypeObject) {
+		return nil
+	}
+
+	env := &Envelope{}
+	for _, key := range envelopeItemsKeys {
+		if prop := property(schema, key); prop != nil && prop.Type.Is(openapi3.TypeArray) {
+			env.Items = key
+			break
+		}
+	}
+	if env.Items == "" {
+		return nil
+	}
+
+	env.Total = findProperty(schema, "", envelopeTotalKeys)
+	env.Next = findProperty(schema, "", envelopeNextKeys)
+	for _, metaKey := range envelopeMetaKeys {
+		meta := property(schema, metaKey)
+		if meta == nil {
+			continue
+		}
+		if env.Total == "" {
+			env.Total = findProperty(meta, metaKey+".", envelopeTotalKeys)
+		}
+		if env.Next == "" {
+			env.Next = findProperty(meta, metaKey+".", envelopeNextKeys)
+		}
+	}
+
+	if env.Total == "" && env.Next == "" {
+		return nil
+	}
+	return env
+}
+
+// findProperty returns prefix+key for the first key present in schema
+func findProperty(schema *openapi3.Schema, prefix string, keys []string) string {
+	for _, key := range keys {
+		if property(schema, key) != nil {
+			return prefix + key
+		}
+	}
+	return ""
+}
+
+// property returns the resolved schema of a named object property
+func property(schema *openapi3.Schema, name string) *openapi3.Schema {
+	ref, ok := schema.Properties[name]
+	if !ok || ref == nil {
+		return nil
+	}
+	return ref.Value
+}
+
+// detectPagination finds how to request the following pages of op: a
+// cursor query parameter fed from the envelope's next cursor, a page number
+// parameter, or a Link header declared on its successful responses. It
+// returns nil when op does not look paginated. Only GET operations are
+// considered.
+func detectPagination(op *Operation, linkHeader bool) *Pagination {
+	if op.Method != "GET" {
+		return nil
+	}
+	p := &Pagination{LinkHeader: linkHeader}
+
+	query := map[string]bool{}
+	for _, param := range op.Params {
+		if param.In == "query" {
+			query[param.Name] = true
+		}
+	}
+
+	if op.Envelope != nil && op.Envelope.Next != "" {
+		// A parameter named like the cursor field (next_page_token ->
+		// page_token) takes precedence over the common names
+		field := op.Envelope.Next[strings.LastIndex(op.Envelope.Next, ".")+1:]
+		candidates := []string{field, strings.TrimPrefix(field, "next_"), strings.TrimPrefix(field, "next")}
+		for _, name := range append(candidates, cursorParamKeys...) {
+			if query[name] {
+				p.CursorParam = name
+				break
+			}
+		}
+	}
+	for _, name := range pageParamKeys {
+		if query[name] {
+			p.PageParam = name
+			break
+		}
+	}
+
+	if p.CursorParam == "" && p.PageParam == "" && !p.LinkHeader {
+		return nil
+	}
+	return p
+}
+
+// hasHeader reports whether headers declares name, ignoring case
+func hasHeader(headers openapi3.Headers, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+// Package opencligen is the Go API of the opencligen generator, for tools
+// that generate CLIs from OpenAPI specs without running the opencligen
+// command. It loads a spec, builds the command plan from it, and generates
+// the project with a Generator configured by Options:
+//
+//	s, err := opencligen.LoadSpec(ctx, "openapi.yaml")
+//	if err != nil {
+//	    return err
+//	}
+//	p := opencligen.BuildPlan(s, "mycli", "github.com/acme/mycli")
+//	g := opencligen.New(p, "./mycli",
+//	    opencligen.WithEmitMode(opencligen.EmitPerGroup),
+//	    opencligen.WithFileHeader("SPDX-License-Identifier: Apache-2.0"),
+//	)
+//	err = g.Generate() // or g.Render() to keep the files in memory
+//
+// Generators share no state, so several can run with different options in
+// the same process.
+package opencligen
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/crunchloop/opencligen/internal/gen"
+	"github.com/crunchloop/opencligen/internal/plan"
+	"github.com/crunchloop/opencligen/internal/spec"
+)
+
+type (
+	// Spec is a loaded OpenAPI spec
+	Spec = spec.Spec
+
+	// Filter selects the operations of a spec to generate commands for
+	Filter = plan.Filter
+
+	// Plan is the command surface of a CLI, built from a spec
+	Plan = plan.Plan
+
+	// Generator generates a CLI project from a plan
+	Generator = gen.Generator
+
+	// Options configures a Generator
+	Options = gen.Options
+
+	// Option configures a Generator created with New
+	Option = gen.Option
+
+	// FileSet holds generated files by slash-separated path
+	FileSet = gen.FileSet
+)
+
+// Command file layouts for Options.Emit
+const (
+	EmitPerOperation = gen.EmitPerOperation
+	EmitPerGroup     = gen.EmitPerGroup
+)
+
+// ErrModifiedFiles is returned by Generator.Generate when generated files
+// were edited since the last generation, unless WithForce is given
+var ErrModifiedFiles = gen.ErrModifiedFiles
+
+// LoadSpec loads and validates the OpenAPI spec at location, a file path or
+// an http(s) URL
+func LoadSpec(ctx context.Context, location string) (*Spec, error) {
+	return LoadSpecWithHeader(ctx, location, nil)
+}
+
+// LoadSpecWithHeader is LoadSpec with header sent when location is a URL,
+// e.g. for authorization. The header is not sent to other hosts.
+func LoadSpecWithHeader(ctx context.Context, location string, header http.Header) (*Spec, error) {
+	if spec.IsURL(location) {
+		return spec.LoadURL(ctx, location, header)
+	}
+	return spec.Load(ctx, location)
+}
+
+// BuildPlan builds the command plan of a CLI named appName, with the Go
+// module path moduleName, from s, which Filter.Apply can narrow down first
+func BuildPlan(s *Spec, appName, moduleName string) *Plan {
+	return plan.Build(s, appName, moduleName)
+}
+
+// New creates a Generator writing p's project to outDir
+func New(p *Plan, outDir string, opts ...Option) *Generator {
+	return gen.New(p, outDir, opts...)
+}
+
+// WithOptions replaces all options with o
+func WithOptions(o Options) Option {
+	return gen.WithOptions(o)
+}
+
+// WithSharedRuntime imports the shared runtime module at version instead of
+// vendoring the runtime. An empty version resolves the latest.
+func WithSharedRuntime(version string) Option {
+	return gen.WithSharedRuntime(version)
+}
+
+// WithMockServer adds a cmd/<app>-mock server
+func WithMockServer() Option {
+	return gen.WithMockServer()
+}
+
+// WithReleaseFiles adds a .goreleaser.yaml and a Dockerfile
+func WithReleaseFiles() Option {
+	return gen.WithReleaseFiles()
+}
+
+// WithoutGoMod skips go.mod for output inside an existing module
+func WithoutGoMod() Option {
+	return gen.WithoutGoMod()
+}
+
+// WithEmitMode sets the command file layout
+func WithEmitMode(mode string) Option {
+	return gen.WithEmitMode(mode)
+}
+
+// WithFileHeader places header at the top of every generated Go file
+func WithFileHeader(header string) Option {
+	return gen.WithFileHeader(header)
+}
+
+// WithBuildTags adds a build constraint to every generated Go file
+func WithBuildTags(expr string) Option {
+	return gen.WithBuildTags(expr)
+}
+
+// WithStreamBuildTags adds a build constraint to event-stream command files
+func WithStreamBuildTags(expr string) Option {
+	return gen.WithStreamBuildTags(expr)
+}
+
+// WithTemplates reads usage.tmpl and help.tmpl from dir
+func WithTemplates(dir string) Option {
+	return gen.WithTemplates(dir)
+}
+
+// WithForce overwrites generated files even if they were edited
+func WithForce() Option {
+	return gen.WithForce()
+}
+
+// WithConcurrency bounds the number of files rendered in parallel
+func WithConcurrency(n int) Option {
+	return gen.WithConcurrency(n)
+}
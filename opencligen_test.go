@@ -0,0 +1,51 @@
+package opencligen_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/crunchloop/opencligen"
+)
+
+func TestGenerator(t *testing.T) {
+	s, err := opencligen.LoadSpec(context.Background(), "internal/testdata/openapi30.yaml")
+	if err != nil {
+		t.Fatalf("failed to load spec: %v", err)
+	}
+	p := opencligen.BuildPlan(s, "mycli", "github.com/acme/mycli")
+
+	// Generators with different options side by side
+	perGroup, err := opencligen.New(p, "",
+		opencligen.WithEmitMode(opencligen.EmitPerGroup),
+		opencligen.WithFileHeader("SPDX-License-Identifier: Apache-2.0"),
+	).Render()
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	perOperation, err := opencligen.New(p, "", opencligen.WithoutGoMod()).Render()
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+
+	if _, ok := perGroup["go.mod"]; !ok {
+		t.Error("expected go.mod by default")
+	}
+	if _, ok := perOperation["go.mod"]; ok {
+		t.Error("expected no go.mod with WithoutGoMod")
+	}
+	if len(perGroup) >= len(perOperation) {
+		t.Errorf("expected fewer files with one file per group, got %d and %d", len(perGroup), len(perOperation))
+	}
+	for _, path := range perGroup.Paths() {
+		if strings.HasSuffix(path, ".go") && !strings.HasPrefix(string(perGroup[path]), "// SPDX-License-Identifier: Apache-2.0\n") {
+			t.Errorf("expected %s to start with the file header", path)
+		}
+	}
+}
+
+func TestLoadSpec_NotFound(t *testing.T) {
+	if _, err := opencligen.LoadSpec(context.Background(), "missing.yaml"); err == nil {
+		t.Error("expected an error for a missing spec")
+	}
+}
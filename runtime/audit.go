@@ -0,0 +1,186 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// AuditEntry is a single record in the audit log. Request and response
+// bodies are never recorded.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`
+	Command  string    `json:"command"`
+	Method   string    `json:"method"`
+	URL      string    `json:"url"`
+	Status   int       `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	PrevHash string    `json:"prev_hash"`
+	Hash     string    `json:"hash"`
+}
+
+// AuditLog appends hash-chained entries to a JSON lines file. Each entry's
+// hash covers its content and the previous entry's hash, so editing or
+// removing an entry breaks the chain for every entry after it.
+type AuditLog struct {
+	Path    string
+	Command string
+
+	// mu keeps concurrent requests, e.g. of --batch, from chaining their
+	// entries to the same previous entry
+	mu sync.Mutex
+}
+
+// ErrAuditChainBroken is returned by VerifyAuditLog when the log was altered
+var ErrAuditChainBroken = errors.New("audit log hash chain is broken")
+
+// Record appends an entry for a request to the audit log
+func (a *AuditLog) Record(method, rawURL string, status int, reqErr error) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry := AuditEntry{
+		Time:    time.Now().UTC(),
+		User:    currentUser(),
+		Command: a.Command,
+		Method:  method,
+		URL:     redactURL(rawURL),
+		Status:  status,
+	}
+	if reqErr != nil {
+		entry.Error = reqErr.Error()
+	}
+
+	prev, err := lastAuditHash(a.Path)
+	if err != nil {
+		return err
+	}
+	entry.PrevHash = prev
+	entry.Hash = hashAuditEntry(entry)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(a.Path), 0700); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(a.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// VerifyAuditLog checks the hash chain of the audit log at path and returns
+// the number of valid entries. The error wraps ErrAuditChainBroken and names
+// the first bad line when the log has been tampered with.
+func VerifyAuditLog(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	prev := ""
+	count := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		count++
+
+		var entry AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return count - 1, fmt.Errorf("%w: line %d is not valid JSON", ErrAuditChainBroken, count)
+		}
+		if entry.PrevHash != prev {
+			return count - 1, fmt.Errorf("%w: line %d does not follow the previous entry", ErrAuditChainBroken, count)
+		}
+		if hashAuditEntry(entry) != entry.Hash {
+			return count - 1, fmt.Errorf("%w: line %d was modified", ErrAuditChainBroken, count)
+		}
+		prev = entry.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return count, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	return count, nil
+}
+
+// hashAuditEntry computes the chained hash of an entry, excluding its own
+// Hash field
+func hashAuditEntry(entry AuditEntry) string {
+	entry.Hash = ""
+	data, _ := json.Marshal(entry)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// lastAuditHash returns the hash of the last entry in the log, or "" if the
+// log does not exist yet
+func lastAuditHash(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	last := lines[len(lines)-1]
+	if len(last) == 0 {
+		return "", nil
+	}
+
+	var entry AuditEntry
+	if err := json.Unmarshal(last, &entry); err != nil {
+		return "", fmt.Errorf("%w: last entry is not valid JSON", ErrAuditChainBroken)
+	}
+	return entry.Hash, nil
+}
+
+// redactURL drops credentials and the query string, which may carry secrets
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// currentUser returns the name of the user running the CLI
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
@@ -0,0 +1,61 @@
+package runtime
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LoadBody loads request body from a data string
+// Supports:
+// - @filename - reads from file
+// - @- - reads from stdin
+// - raw JSON string
+func LoadBody(data string) ([]byte, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	// Check for file reference
+	if strings.HasPrefix(data, "@") {
+		path := data[1:]
+
+		if path == "-" {
+			// Read from stdin
+			if err := CheckInput(os.Stdin, "the body for --data @-"); err != nil {
+				return nil, err
+			}
+			return io.ReadAll(os.Stdin)
+		}
+
+		// Read from file
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read body from file %s: %w", path, err)
+		}
+		return content, nil
+	}
+
+	// Treat as raw JSON
+	return []byte(data), nil
+}
+
+// ReadValue reads a single value from r for a "-" argument, so an ID printed
+// by a previous command can be piped in. Surrounding whitespace is trimmed,
+// and empty or multi-line input is rejected, since each value needs its own
+// request.
+func ReadValue(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read value from stdin: %w", err)
+	}
+	value := strings.TrimSpace(string(data))
+	switch {
+	case value == "":
+		return "", fmt.Errorf("no value on stdin for \"-\"")
+	case strings.ContainsAny(value, "\r\n"):
+		return "", fmt.Errorf("stdin has more than one line for \"-\", which takes a single value")
+	}
+	return value, nil
+}
@@ -0,0 +1,372 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Operation describes an operation of an OpenAPI document, as printed by
+// the generated describe command
+type Operation struct {
+	Method      string       `json:"method"`
+	Path        string       `json:"path"`
+	Summary     string       `json:"summary,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Parameters  []Parameter  `json:"parameters,omitempty"`
+	RequestBody *RequestBody `json:"requestBody,omitempty"`
+	Responses   []Response   `json:"responses,omitempty"`
+}
+
+// Parameter is a path, query, header or cookie parameter of an operation
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Type        string `json:"type,omitempty"`
+	Required    bool   `json:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+// RequestBody is the request body of an operation. Schema is the JSON
+// Schema of the body with local $refs resolved.
+type RequestBody struct {
+	ContentType string          `json:"contentType"`
+	Required    bool            `json:"required"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+}
+
+// Response is a documented response of an operation
+type Response struct {
+	Status       string   `json:"status"`
+	Description  string   `json:"description,omitempty"`
+	ContentTypes []string `json:"contentTypes,omitempty"`
+}
+
+// maxSchemaDepth bounds $ref resolution when inlining schemas
+const maxSchemaDepth = 16
+
+// DescribeOperation looks up the operation for method and path in an
+// OpenAPI 3.x or Swagger 2.0 document, in JSON or YAML
+func DescribeOperation(doc []byte, method, path string) (*Operation, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(doc, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+	if len(node.Content) == 0 {
+		return nil, fmt.Errorf("empty document")
+	}
+	d := describer{root: node.Content[0]}
+
+	item := d.resolve(mappingValue(mappingValue(d.root, "paths"), path))
+	opNode := d.resolve(mappingValue(item, strings.ToLower(method)))
+	if opNode == nil {
+		return nil, fmt.Errorf("operation %s %s not found in the spec", strings.ToUpper(method), path)
+	}
+
+	op := &Operation{
+		Method:      strings.ToUpper(method),
+		Path:        path,
+		Summary:     scalarValue(mappingValue(opNode, "summary")),
+		Description: scalarValue(mappingValue(opNode, "description")),
+	}
+
+	// Operation parameters override path item parameters with the same
+	// name and location
+	var params []*yaml.Node
+	for _, list := range []*yaml.Node{mappingValue(item, "parameters"), mappingValue(opNode, "parameters")} {
+		for _, p := range sequenceItems(list) {
+			p = d.resolve(p)
+			for i, existing := range params {
+				if scalarValue(mappingValue(existing, "name")) == scalarValue(mappingValue(p, "name")) &&
+					scalarValue(mappingValue(existing, "in")) == scalarValue(mappingValue(p, "in")) {
+					params = append(params[:i], params[i+1:]...)
+					break
+				}
+			}
+			params = append(params, p)
+		}
+	}
+
+	for _, p := range params {
+		if scalarValue(mappingValue(p, "in")) == "body" {
+			// Swagger 2.0 body parameter
+			body, err := d.body(d.mediaTypes(opNode, "consumes"), mappingValue(p, "schema"))
+			if err != nil {
+				return nil, err
+			}
+			body.Required = scalarValue(mappingValue(p, "required")) == "true"
+			op.RequestBody = body
+			continue
+		}
+		op.Parameters = append(op.Parameters, d.parameter(p))
+	}
+
+	if reqBody := d.resolve(mappingValue(opNode, "requestBody")); reqBody != nil {
+		content := mappingValue(reqBody, "content")
+		contentType := preferredContentType(mappingKeys(content))
+		body, err := d.body([]string{contentType}, mappingValue(mappingValue(content, contentType), "schema"))
+		if err != nil {
+			return nil, err
+		}
+		body.Required = scalarValue(mappingValue(reqBody, "required")) == "true"
+		op.RequestBody = body
+	}
+
+	responses := mappingValue(opNode, "responses")
+	for _, status := range mappingKeys(responses) {
+		resp := d.resolve(mappingValue(responses, status))
+		contentTypes := mappingKeys(mappingValue(resp, "content"))
+		if contentTypes == nil && mappingValue(resp, "schema") != nil {
+			contentTypes = d.mediaTypes(opNode, "produces")
+		}
+		op.Responses = append(op.Responses, Response{
+			Status:       status,
+			Description:  scalarValue(mappingValue(resp, "description")),
+			ContentTypes: contentTypes,
+		})
+	}
+
+	return op, nil
+}
+
+// WriteText writes the operation in a human-readable layout, with the
+// request body schema as YAML
+func (op *Operation) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", op.Method, op.Path)
+	if op.Summary != "" {
+		fmt.Fprintf(&b, "%s\n", op.Summary)
+	}
+	if op.Description != "" && op.Description != op.Summary {
+		fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(op.Description))
+	}
+
+	if len(op.Parameters) > 0 {
+		b.WriteString("\nParameters:\n")
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  NAME\tIN\tTYPE\tREQUIRED\tDESCRIPTION")
+		for _, p := range op.Parameters {
+			required := "no"
+			if p.Required {
+				required = "yes"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", p.Name, p.In, p.Type, required, firstLine(p.Description))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if body := op.RequestBody; body != nil {
+		required := ""
+		if body.Required {
+			required = ", required"
+		}
+		fmt.Fprintf(&b, "\nRequest body (%s%s):\n", body.ContentType, required)
+		if len(body.Schema) > 0 {
+			schema, err := ConvertDocument(body.Schema, DocumentYAML)
+			if err != nil {
+				return err
+			}
+			for _, line := range strings.Split(strings.TrimRight(string(schema), "\n"), "\n") {
+				fmt.Fprintf(&b, "  %s\n", line)
+			}
+		}
+	}
+
+	if len(op.Responses) > 0 {
+		b.WriteString("\nResponses:\n")
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		for _, r := range op.Responses {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", r.Status, firstLine(r.Description), strings.Join(r.ContentTypes, ", "))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	// Drop the padding tabwriter leaves after empty last columns
+	lines := strings.Split(b.String(), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// describer reads operations out of a parsed document
+type describer struct {
+	root *yaml.Node
+}
+
+// parameter converts a resolved parameter node
+func (d describer) parameter(p *yaml.Node) Parameter {
+	typ := scalarValue(mappingValue(p, "type"))
+	if schema := d.resolve(mappingValue(p, "schema")); schema != nil {
+		typ = scalarValue(mappingValue(schema, "type"))
+		if typ == "array" {
+			if items := d.resolve(mappingValue(schema, "items")); items != nil {
+				typ = "array of " + scalarValue(mappingValue(items, "type"))
+			}
+		}
+	}
+	return Parameter{
+		Name:        scalarValue(mappingValue(p, "name")),
+		In:          scalarValue(mappingValue(p, "in")),
+		Type:        typ,
+		Required:    scalarValue(mappingValue(p, "required")) == "true",
+		Description: scalarValue(mappingValue(p, "description")),
+	}
+}
+
+// body builds a request body from its content types and schema node
+func (d describer) body(contentTypes []string, schema *yaml.Node) (*RequestBody, error) {
+	body := &RequestBody{ContentType: preferredContentType(contentTypes)}
+	if schema == nil {
+		return body, nil
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSONNode(&buf, d.inline(schema, map[string]bool{}, 0)); err != nil {
+		return nil, err
+	}
+	body.Schema = buf.Bytes()
+	return body, nil
+}
+
+// mediaTypes returns a Swagger 2.0 consumes or produces list, from the
+// operation or else the document
+func (d describer) mediaTypes(op *yaml.Node, key string) []string {
+	var types []string
+	for _, n := range sequenceItems(mappingValue(op, key)) {
+		types = append(types, scalarValue(n))
+	}
+	if types == nil {
+		for _, n := range sequenceItems(mappingValue(d.root, key)) {
+			types = append(types, scalarValue(n))
+		}
+	}
+	if types == nil {
+		types = []string{"application/json"}
+	}
+	return types
+}
+
+// resolve follows a local $ref, returning n itself when it is not a
+// reference and nil when the reference cannot be resolved
+func (d describer) resolve(n *yaml.Node) *yaml.Node {
+	for i := 0; n != nil && i < maxSchemaDepth; i++ {
+		ref := scalarValue(mappingValue(n, "$ref"))
+		if ref == "" {
+			return n
+		}
+		n = d.lookup(ref)
+	}
+	return n
+}
+
+// lookup returns the node at a local JSON pointer reference such as
+// #/components/schemas/Task
+func (d describer) lookup(ref string) *yaml.Node {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	n := d.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		n = mappingValue(n, token)
+	}
+	return n
+}
+
+// inline returns a copy of n with local $refs replaced by their targets.
+// References back into a schema being expanded are left as they are.
+func (d describer) inline(n *yaml.Node, expanding map[string]bool, depth int) *yaml.Node {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if ref := scalarValue(mappingValue(n, "$ref")); ref != "" {
+		target := d.lookup(ref)
+		if target == nil || expanding[ref] || depth >= maxSchemaDepth {
+			return n
+		}
+		expanding[ref] = true
+		defer delete(expanding, ref)
+		return d.inline(target, expanding, depth+1)
+	}
+
+	out := *n
+	out.Content = make([]*yaml.Node, len(n.Content))
+	for i, c := range n.Content {
+		out.Content[i] = d.inline(c, expanding, depth+1)
+	}
+	return &out
+}
+
+// mappingValue returns the value for key in a mapping node, or nil
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mappingKeys returns the keys of a mapping node in document order
+func mappingKeys(n *yaml.Node) []string {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	var keys []string
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		keys = append(keys, n.Content[i].Value)
+	}
+	return keys
+}
+
+// sequenceItems returns the items of a sequence node, or nil
+func sequenceItems(n *yaml.Node) []*yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return n.Content
+}
+
+// scalarValue returns the value of a scalar node, or ""
+func scalarValue(n *yaml.Node) string {
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
+
+// preferredContentType returns the first JSON content type, else the first
+func preferredContentType(contentTypes []string) string {
+	for _, ct := range contentTypes {
+		base := strings.ToLower(strings.TrimSpace(strings.SplitN(ct, ";", 2)[0]))
+		if base == "application/json" || strings.HasSuffix(base, "+json") {
+			return ct
+		}
+	}
+	if len(contentTypes) > 0 {
+		return contentTypes[0]
+	}
+	return ""
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	return strings.TrimSpace(strings.SplitN(strings.TrimSpace(s), "\n", 2)[0])
+}
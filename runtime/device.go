@@ -0,0 +1,292 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// deviceCodeGrantType is the grant type for polling the token endpoint
+// (RFC 8628 section 3.4)
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// defaultPollInterval is the polling interval when the authorization server
+// does not specify one
+const defaultPollInterval = 5 * time.Second
+
+// ErrDeviceCodeExpired is returned by Poll when the user did not approve the
+// request before the device code expired
+var ErrDeviceCodeExpired = errors.New("the device code expired before the login was approved")
+
+// DeviceAuthorization is a device authorization response (RFC 8628 section
+// 3.2): the code the user enters at the verification URI
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// DeviceFlow obtains access tokens with the OAuth2 device authorization
+// grant (RFC 8628), for interactive logins from a terminal
+type DeviceFlow struct {
+	DeviceAuthorizationURL string
+	TokenURL               string
+	ClientID               string
+	Scopes                 []string
+	HTTPClient             *http.Client // defaults to http.DefaultClient
+}
+
+// Start requests a device code and the user code to show the user
+func (f *DeviceFlow) Start(ctx context.Context) (*DeviceAuthorization, error) {
+	form := url.Values{"client_id": {f.ClientID}}
+	if len(f.Scopes) > 0 {
+		form.Set("scope", strings.Join(f.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.DeviceAuthorizationURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := f.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("device authorization request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device authorization response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var tr tokenResponse
+		if json.Unmarshal(body, &tr) == nil && tr.Error != "" {
+			return nil, &OAuth2Error{Code: tr.Error, Description: tr.ErrorDescription}
+		}
+		return nil, fmt.Errorf("device authorization endpoint returned %s", resp.Status)
+	}
+
+	var auth DeviceAuthorization
+	if err := json.Unmarshal(body, &auth); err != nil {
+		return nil, fmt.Errorf("invalid device authorization response: %w", err)
+	}
+	if auth.DeviceCode == "" || auth.UserCode == "" || auth.VerificationURI == "" {
+		return nil, errors.New("device authorization response is missing device_code, user_code or verification_uri")
+	}
+	return &auth, nil
+}
+
+// Poll waits until the user approves auth and returns the issued token. It
+// polls the token endpoint at the interval the server asked for, backing
+// off when told to slow down.
+func (f *DeviceFlow) Poll(ctx context.Context, auth *DeviceAuthorization) (*Token, error) {
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	var expiry time.Time
+	if auth.ExpiresIn > 0 {
+		expiry = time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+	}
+
+	form := url.Values{
+		"grant_type":  {deviceCodeGrantType},
+		"device_code": {auth.DeviceCode},
+		"client_id":   {f.ClientID},
+	}
+	for {
+		if !expiry.IsZero() && time.Now().Add(interval).After(expiry) {
+			return nil, ErrDeviceCodeExpired
+		}
+		if err := pollWait(ctx, interval); err != nil {
+			return nil, err
+		}
+
+		token, err := requestToken(ctx, f.HTTPClient, f.TokenURL, form, "", "")
+		var oauthErr *OAuth2Error
+		if errors.As(err, &oauthErr) {
+			switch oauthErr.Code {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += 5 * time.Second
+				continue
+			case "expired_token":
+				return nil, ErrDeviceCodeExpired
+			case "access_denied":
+				return nil, errors.New("the login was denied")
+			}
+		}
+		return token, err
+	}
+}
+
+// pollWait sleeps for d or until ctx is done; tests replace it to poll
+// without delay
+var pollWait = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// loginTokenKey is the keychain key of the token stored by auth login
+const loginTokenKey = "oauth_token"
+
+// NewLoginStore returns the store for the token obtained with auth login:
+// the keychain when config selects it, and otherwise a file next to
+// appName's config file. Each profile has its own token.
+func NewLoginStore(appName string, config *Config) (TokenStore, error) {
+	secrets, err := config.SecretStore(appName)
+	if err != nil {
+		return nil, err
+	}
+	if secrets != nil {
+		return &SecretToken{Store: secrets, Key: SecretKey(config.Profile, loginTokenKey)}, nil
+	}
+
+	dir, err := configDir(appName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine config directory: %w", err)
+	}
+	name := "token.json"
+	if config.Profile != "" {
+		name = "token-" + config.Profile + ".json"
+	}
+	return &TokenCache{Path: filepath.Join(dir, name)}, nil
+}
+
+// Refresh exchanges refreshToken for a new access token (RFC 6749 section
+// 6). The new token keeps refreshToken when the server does not issue
+// another.
+func (f *DeviceFlow) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {f.ClientID},
+	}
+	token, err := requestToken(ctx, f.HTTPClient, f.TokenURL, form, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// LoginToken authorizes requests with the token stored by auth login. An
+// expired token is refreshed with Flow when the server issued a refresh
+// token, and the new token is stored for later runs.
+type LoginToken struct {
+	Store TokenStore
+	Flow  *DeviceFlow // optional; without it, expired tokens are not sent
+
+	// LoginCommand is suggested when the token cannot be refreshed, e.g.
+	// "myapp auth login"
+	LoginCommand string
+
+	mu sync.Mutex
+}
+
+// Token returns the stored token, refreshing it first when it has expired.
+// It returns nil when no token is stored, or when an expired one cannot be
+// refreshed for lack of a refresh token.
+func (l *LoginToken) Token(ctx context.Context) (*Token, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	token, err := l.Store.Load()
+	if err != nil || token == nil {
+		return nil, err
+	}
+	if token.Valid(time.Now()) {
+		return token, nil
+	}
+	if token.RefreshToken == "" || l.Flow == nil {
+		return nil, nil
+	}
+	return l.refresh(ctx, token)
+}
+
+// refresh renews token and stores the result
+func (l *LoginToken) refresh(ctx context.Context, token *Token) (*Token, error) {
+	refreshed, err := l.Flow.Refresh(ctx, token.RefreshToken)
+	if err != nil {
+		if l.LoginCommand != "" {
+			return nil, fmt.Errorf("the login expired and could not be refreshed (%w); run '%s' to log in again", err, l.LoginCommand)
+		}
+		return nil, fmt.Errorf("the login expired and could not be refreshed: %w", err)
+	}
+	if err := l.Store.Save(refreshed); err != nil {
+		return nil, fmt.Errorf("failed to store the refreshed token: %w", err)
+	}
+	return refreshed, nil
+}
+
+// renew refreshes the stored token even though it has not expired, e.g.
+// because the API rejected it. It returns nil when it cannot be refreshed.
+func (l *LoginToken) renew(ctx context.Context, rejected *Token) (*Token, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	token, err := l.Store.Load()
+	if err != nil || token == nil {
+		return nil, err
+	}
+	if token.AccessToken != rejected.AccessToken {
+		// Another request refreshed it in the meantime
+		return token, nil
+	}
+	if token.RefreshToken == "" || l.Flow == nil {
+		return nil, nil
+	}
+	return l.refresh(ctx, token)
+}
+
+// Middleware returns middleware that authorizes requests with the stored
+// token. Requests that already carry an Authorization header, or sent when
+// no usable token is stored, are sent unchanged. When the API rejects the
+// token with 401 Unauthorized and it can be refreshed, the request is
+// retried once with the refreshed token.
+func (l *LoginToken) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") != "" {
+				return next.RoundTrip(req)
+			}
+			token, err := l.Token(req.Context())
+			if err != nil {
+				return nil, err
+			}
+			if token == nil {
+				return next.RoundTrip(req)
+			}
+			return retryUnauthorized(next, req, token, func() (*Token, error) {
+				return l.renew(req.Context(), token)
+			})
+		})
+	}
+}
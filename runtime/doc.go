@@ -0,0 +1,9 @@
+// Package runtime is the support library for CLIs generated by opencligen.
+// It builds and sends requests, handles configuration and formats responses.
+//
+// The source of truth lives in internal/runtime_skel. It is copied into
+// internal/gen/runtime, which the generator embeds and vendors into each
+// generated project, and into the public github.com/crunchloop/opencligen/runtime
+// module imported by projects generated with --shared-runtime. Run
+// "make sync-runtime" after changing it.
+package runtime
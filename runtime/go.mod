@@ -0,0 +1,8 @@
+module github.com/crunchloop/opencligen/runtime
+
+go 1.22
+
+require (
+	github.com/jmespath/go-jmespath v0.4.0
+	gopkg.in/yaml.v3 v3.0.1
+)
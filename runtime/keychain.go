@@ -0,0 +1,230 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	goruntime "runtime"
+	"strings"
+)
+
+// ErrSecretNotFound is returned by SecretStore.Get for keys without a value
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretStore keeps credentials outside the config file
+type SecretStore interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error // deleting a missing key is not an error
+	String() string          // where secrets are kept, for messages
+}
+
+// Keychain is a SecretStore backed by the OS credential store: the macOS
+// Keychain, the Windows Credential Manager, or the Secret Service (GNOME
+// Keyring, KWallet) on Linux through secret-tool. Secrets are stored under
+// Service with the key as the account name.
+type Keychain struct {
+	Service string
+}
+
+// keychainBackend talks to the OS credential store
+type keychainBackend interface {
+	get(service, key string) (string, error)
+	set(service, key, value string) error
+	delete(service, key string) error
+}
+
+// osKeychain is the backend for this OS, set by the per-OS files; nil when
+// the OS has none
+var osKeychain keychainBackend
+
+func (k *Keychain) backend() (keychainBackend, error) {
+	if osKeychain == nil {
+		return nil, fmt.Errorf("the keychain is not supported on %s", goruntime.GOOS)
+	}
+	return osKeychain, nil
+}
+
+// String describes the keychain, for messages
+func (k *Keychain) String() string {
+	return "the keychain"
+}
+
+// Get returns the secret stored for key
+func (k *Keychain) Get(key string) (string, error) {
+	b, err := k.backend()
+	if err != nil {
+		return "", err
+	}
+	return b.get(k.Service, key)
+}
+
+// Set stores value for key, replacing any existing value
+func (k *Keychain) Set(key, value string) error {
+	b, err := k.backend()
+	if err != nil {
+		return err
+	}
+	return b.set(k.Service, key, value)
+}
+
+// Delete removes the secret stored for key
+func (k *Keychain) Delete(key string) error {
+	b, err := k.backend()
+	if err != nil {
+		return err
+	}
+	return b.delete(k.Service, key)
+}
+
+// Credential stores, selected by the credential_store config setting
+const (
+	CredentialStoreFile            = "file"
+	CredentialStoreCredentialsFile = "credentials_file"
+	CredentialStoreKeychain        = "keychain"
+)
+
+// SecretKeys are the config settings that can be kept in a SecretStore
+var SecretKeys = []string{"token", "client_secret", "signing_secret"}
+
+// ValidateSecretKey checks that key is one of SecretKeys
+func ValidateSecretKey(key string) error {
+	for _, k := range SecretKeys {
+		if k == key {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid secret %q (must be one of: %s)", key, strings.Join(SecretKeys, ", "))
+}
+
+// SecretKey returns the keychain key of the secret setting key for profile:
+// "<profile>:<key>", or key itself without a profile
+func SecretKey(profile, key string) string {
+	if profile == "" {
+		return key
+	}
+	return profile + ":" + key
+}
+
+// secret returns the config field holding the secret setting key
+func (c *Config) secret(key string) *string {
+	switch key {
+	case "token":
+		return &c.Token
+	case "client_secret":
+		return &c.ClientSecret
+	case "signing_secret":
+		return &c.SigningSecret
+	}
+	return nil
+}
+
+// SecretStore returns the credentials file or the keychain when the config
+// selects one, and nil when secrets are kept in the config file
+func (c *Config) SecretStore(appName string) (SecretStore, error) {
+	switch c.CredentialStore {
+	case "", CredentialStoreFile:
+		return nil, nil
+	case CredentialStoreCredentialsFile:
+		return NewCredentialsFile(appName)
+	case CredentialStoreKeychain:
+		return &Keychain{Service: appName}, nil
+	}
+	return nil, fmt.Errorf("invalid credential_store %q (must be %s, %s or %s)", c.CredentialStore,
+		CredentialStoreFile, CredentialStoreCredentialsFile, CredentialStoreKeychain)
+}
+
+// ConfiguredSecretStore returns the secret store selected by appName's
+// config file or <APP>_CREDENTIAL_STORE, for commands that store secrets
+// without loading a profile. It is nil when secrets are kept in the config
+// file.
+func ConfiguredSecretStore(appName string) (SecretStore, error) {
+	config := &Config{}
+	if path := getConfigPath(appName); path != "" {
+		// A config file that cannot be read selects no store, as in LoadProfile
+		_ = loadConfigFile(appName, path, config)
+	}
+	if store := os.Getenv(strings.ToUpper(appName) + "_CREDENTIAL_STORE"); store != "" {
+		config.CredentialStore = store
+	}
+	return config.SecretStore(appName)
+}
+
+// loadSecrets fills secret settings that are not set in the config file or
+// environment from the selected secret store. With a profile, its secrets
+// take precedence over those stored without one.
+func (c *Config) loadSecrets(appName string) error {
+	store, err := c.SecretStore(appName)
+	if err != nil || store == nil {
+		return err
+	}
+
+	for _, key := range SecretKeys {
+		field := c.secret(key)
+		if *field != "" {
+			continue
+		}
+		names := []string{key}
+		if c.Profile != "" {
+			names = []string{SecretKey(c.Profile, key), key}
+		}
+		for _, name := range names {
+			value, err := store.Get(name)
+			if errors.Is(err, ErrSecretNotFound) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read %s from %s: %w", name, store, err)
+			}
+			*field = value
+			break
+		}
+	}
+	return nil
+}
+
+// TokenStore persists a token between runs
+type TokenStore interface {
+	Load() (*Token, error) // nil when no token is stored
+	Save(token *Token) error
+	Clear() error
+}
+
+// SecretToken is a TokenStore that keeps the token as JSON under Key in a
+// SecretStore
+type SecretToken struct {
+	Store SecretStore
+	Key   string
+}
+
+// Load returns the stored token, or nil when there is none
+func (s *SecretToken) Load() (*Token, error) {
+	value, err := s.Store.Get(s.Key)
+	if errors.Is(err, ErrSecretNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var token Token
+	if err := json.Unmarshal([]byte(value), &token); err != nil {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+// Save replaces the stored token
+func (s *SecretToken) Save(token *Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return s.Store.Set(s.Key, string(data))
+}
+
+// Clear removes the stored token
+func (s *SecretToken) Clear() error {
+	return s.Store.Delete(s.Key)
+}
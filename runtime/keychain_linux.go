@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func init() {
+	osKeychain = secretToolKeychain{}
+}
+
+// secretToolKeychain stores secrets with the Secret Service API (GNOME
+// Keyring, KWallet) through secret-tool from libsecret
+type secretToolKeychain struct{}
+
+func (secretToolKeychain) get(service, key string) (string, error) {
+	var stdout bytes.Buffer
+	if err := runSecretTool(nil, &stdout, "lookup", "service", service, "account", key); err != nil {
+		return "", err
+	}
+	// secret-tool exits 1 without output for missing items, but some
+	// versions exit 0
+	if stdout.Len() == 0 {
+		return "", ErrSecretNotFound
+	}
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
+
+func (secretToolKeychain) set(service, key, value string) error {
+	label := fmt.Sprintf("--label=%s %s", service, key)
+	return runSecretTool(strings.NewReader(value), nil, "store", label, "service", service, "account", key)
+}
+
+func (secretToolKeychain) delete(service, key string) error {
+	err := runSecretTool(nil, nil, "clear", "service", service, "account", key)
+	if errors.Is(err, ErrSecretNotFound) {
+		return nil
+	}
+	return err
+}
+
+// runSecretTool runs secret-tool with args. A failure without a message is
+// reported as ErrSecretNotFound.
+func runSecretTool(stdin *strings.Reader, stdout *bytes.Buffer, args ...string) error {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return errors.New("secret-tool not found; install libsecret-tools (or libsecret) to use the keychain")
+	}
+
+	cmd := exec.Command(path, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && msg == "" {
+			return ErrSecretNotFound
+		}
+		return fmt.Errorf("secret-tool: %w: %s", err, msg)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+func init() {
+	osKeychain = credentialManager{}
+}
+
+// credentialManager stores secrets as generic credentials in the Windows
+// Credential Manager, with targets named "<service>:<key>"
+type credentialManager struct{}
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+// credential is the CREDENTIALW structure
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+func (credentialManager) get(service, key string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(service + ":" + key)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	ok, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ok == 0 {
+		if errors.Is(err, errorNotFound) {
+			return "", ErrSecretNotFound
+		}
+		return "", err
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+func (credentialManager) set(service, key, value string) error {
+	target, err := syscall.UTF16PtrFromString(service + ":" + key)
+	if err != nil {
+		return err
+	}
+	user, err := syscall.UTF16PtrFromString(key)
+	if err != nil {
+		return err
+	}
+
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(value)),
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	if len(value) > 0 {
+		blob := []byte(value)
+		cred.CredentialBlob = &blob[0]
+	}
+
+	if ok, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); ok == 0 {
+		return err
+	}
+	return nil
+}
+
+func (credentialManager) delete(service, key string) error {
+	target, err := syscall.UTF16PtrFromString(service + ":" + key)
+	if err != nil {
+		return err
+	}
+	if ok, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); ok == 0 && !errors.Is(err, errorNotFound) {
+		return err
+	}
+	return nil
+}
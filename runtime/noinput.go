@@ -0,0 +1,37 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// NoInput turns off everything that waits on or draws for a person at a
+// terminal, for CI: prompts, reads from a terminal stdin, and the download
+// progress bar. Whatever would have asked for a value fails at once with
+// ErrNoInput instead.
+var NoInput bool
+
+// ErrNoInput is returned, wrapped, when a value would have to be typed in
+// but NoInput is set
+var ErrNoInput = errors.New("input required but --no-input is set")
+
+// CheckInput returns an error when reading what from in would wait for
+// someone to type it, i.e. with NoInput set and in a terminal
+func CheckInput(in io.Reader, what string) error {
+	if NoInput && isTerminal(in) {
+		return fmt.Errorf("%w: %s must be piped to stdin", ErrNoInput, what)
+	}
+	return nil
+}
+
+// isTerminal reports whether f, a reader or writer, is a terminal
+func isTerminal(f any) bool {
+	file, ok := f.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
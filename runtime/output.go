@@ -0,0 +1,319 @@
+package runtime
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Supported output formats
+const (
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+	FormatTable = "table"
+
+	// FormatGoTemplate renders the response with a Go template, given as
+	// go-template=TEMPLATE or go-template-file=PATH
+	FormatGoTemplate = "go-template"
+)
+
+// OutputFormats lists the values accepted by the --output flag
+var OutputFormats = []string{FormatJSON, FormatJSONL, FormatTable, FormatGoTemplate + "=TEMPLATE", FormatGoTemplate + "-file=PATH"}
+
+// ValidateFormat returns an error if format is not a supported output format
+func ValidateFormat(format string) error {
+	_, _, err := ParseFormat(format)
+	return err
+}
+
+// ParseFormat splits an --output value into the format and, for the
+// go-template format, the parsed template
+func ParseFormat(value string) (string, *template.Template, error) {
+	switch value {
+	case FormatJSON, FormatJSONL, FormatTable:
+		return value, nil, nil
+	}
+
+	if text, ok := strings.CutPrefix(value, FormatGoTemplate+"="); ok {
+		tmpl, err := parseTemplate(text)
+		return FormatGoTemplate, tmpl, err
+	}
+	if path, ok := strings.CutPrefix(value, FormatGoTemplate+"-file="); ok {
+		text, err := os.ReadFile(ExpandHome(path))
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read template: %w", err)
+		}
+		tmpl, err := parseTemplate(string(text))
+		return FormatGoTemplate, tmpl, err
+	}
+	return "", nil, fmt.Errorf("unsupported output format %q (expected one of: %s)", value, strings.Join(OutputFormats, ", "))
+}
+
+// outputOptions controls how a response is rendered
+type outputOptions struct {
+	Format   string
+	Envelope *Envelope
+	Columns  []string           // table columns; chosen from the items when empty
+	Query    *Query             // optional; applied to the response before it is printed
+	Template *template.Template // for the go-template format
+	Raw      bool               // print the body exactly as received
+	Quiet    bool               // print only the identifiers of the response
+	ErrOut   io.Writer
+}
+
+// errOut returns the writer for diagnostics, defaulting to stderr
+func (o outputOptions) errOut() io.Writer {
+	if o.ErrOut != nil {
+		return o.ErrOut
+	}
+	return os.Stderr
+}
+
+// handleResponse handles a standard HTTP response
+func handleResponse(resp *http.Response, out io.Writer, opts outputOptions) error {
+	errOut := opts.errOut()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		return checkStatus(resp, body, errOut)
+	}
+
+	if opts.Raw {
+		if _, err := io.Copy(out, resp.Body); err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		return nil
+	}
+
+	// Bodies larger than streamThreshold are streamed when the format allows
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(streamThreshold)+1))
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if len(body) > streamThreshold {
+		if streamed, err := streamLarge(body, resp.Body, out, opts); streamed {
+			return err
+		}
+		rest, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		body = append(body, rest...)
+	}
+
+	if len(body) == 0 {
+		return nil
+	}
+
+	// Non-JSON bodies are printed as-is
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		if !opts.Quiet {
+			fmt.Fprintln(out, string(body))
+		}
+		return nil
+	}
+
+	// A query replaces the response, so the envelope no longer applies to
+	// what is printed
+	result, envelope := parsed, opts.Envelope
+	if opts.Query != nil {
+		if result, err = opts.Query.Search(parsed); err != nil {
+			return err
+		}
+		envelope = nil
+	}
+	if opts.Quiet {
+		writeIDs(result, envelope, out)
+		return nil
+	}
+	if err := writeParsed(result, envelope, opts, out); err != nil {
+		return err
+	}
+
+	opts.Envelope.writeTrailer(parsed, errOut)
+	return nil
+}
+
+// writeParsed writes decoded JSON in opts.Format
+func writeParsed(parsed interface{}, envelope *Envelope, opts outputOptions, out io.Writer) error {
+	switch opts.Format {
+	case FormatJSONL:
+		return writeJSONLines(parsed, envelope, out)
+	case FormatTable:
+		return writeTable(parsed, envelope, opts.Columns, out)
+	case FormatGoTemplate:
+		return writeTemplate(parsed, opts.Template, out)
+	}
+	pretty, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	fmt.Fprintln(out, string(pretty))
+	return nil
+}
+
+// checkStatus reports a non-2xx response, printing its body to errOut
+// unless JSONErrors is set
+func checkStatus(resp *http.Response, body []byte, errOut io.Writer) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	if !JSONErrors {
+		fmt.Fprintf(errOut, "Error: HTTP %d %s\n", resp.StatusCode, resp.Status)
+		if len(body) > 0 {
+			fmt.Fprintln(errOut, string(body))
+		}
+	}
+	return &StatusError{StatusCode: resp.StatusCode, RequestID: requestID(resp), Body: body}
+}
+
+// writeJSONLines writes one compact JSON document per line. Arrays, and the
+// items of a paginated envelope, are written one element per line.
+func writeJSONLines(parsed interface{}, envelope *Envelope, out io.Writer) error {
+	for _, item := range listItems(parsed, envelope) {
+		line, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("failed to encode output: %w", err)
+		}
+		fmt.Fprintln(out, string(line))
+	}
+	return nil
+}
+
+// listItems returns the items of a paginated envelope or array, or parsed
+// itself as the only item
+func listItems(parsed interface{}, envelope *Envelope) []interface{} {
+	if items, ok := envelope.unwrap(parsed); ok {
+		return items
+	}
+	if arr, ok := parsed.([]interface{}); ok {
+		return arr
+	}
+	return []interface{}{parsed}
+}
+
+// idKeys are the properties tried, in order, as an item's identifier
+var idKeys = []string{"id", "uuid", "name"}
+
+// writeIDs writes the identifier of each item, one per line, for --quiet.
+// Items without one are skipped, and scalar items, e.g. from a query, are
+// written as they are.
+func writeIDs(parsed interface{}, envelope *Envelope, out io.Writer) {
+	for _, item := range listItems(parsed, envelope) {
+		if id := itemID(item); id != "" {
+			fmt.Fprintln(out, id)
+		}
+	}
+}
+
+// itemID returns the identifier of item, or "" when it has none
+func itemID(item interface{}) string {
+	obj, ok := item.(map[string]interface{})
+	if !ok {
+		return scalarString(item)
+	}
+	for _, key := range idKeys {
+		if id := scalarString(obj[key]); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// binaryBody reports whether resp holds binary data rather than text, going
+// by its Content-Type or, without one, by sniffing the start of the body. It
+// also returns the content type it judged by.
+func binaryBody(resp *http.Response) (bool, string) {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		buffered := bufio.NewReader(resp.Body)
+		head, _ := buffered.Peek(512)
+		if len(head) == 0 {
+			return false, ""
+		}
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{buffered, resp.Body}
+		contentType = http.DetectContentType(head)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false, contentType
+	}
+	return !isTextMediaType(mediaType), mediaType
+}
+
+// hasBody reports whether resp has a body, reading ahead when its length
+// is unknown
+func hasBody(resp *http.Response) bool {
+	if resp.ContentLength >= 0 {
+		return resp.ContentLength > 0
+	}
+	buffered := bufio.NewReader(resp.Body)
+	if _, err := buffered.Peek(1); err != nil {
+		return false
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{buffered, resp.Body}
+	return true
+}
+
+// isJSONMediaType reports whether contentType is JSON, e.g.
+// application/json or application/problem+json
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// isTextMediaType reports whether a media type holds text: text/*, JSON,
+// XML, YAML, JavaScript and form data
+func isTextMediaType(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") || strings.HasSuffix(mediaType, "+yaml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/x-ndjson", "application/xml", "application/yaml", "application/x-yaml",
+		"application/javascript", "application/ecmascript", "application/graphql", "application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
+
+// isJSON checks if the content is valid JSON
+func isJSON(data []byte) bool {
+	var js interface{}
+	return json.Unmarshal(data, &js) == nil
+}
+
+// prettyPrint outputs JSON with indentation
+func prettyPrint(data []byte, out io.Writer) {
+	var parsed interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		// Fall back to raw output
+		fmt.Fprintln(out, string(data))
+		return
+	}
+
+	pretty, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		fmt.Fprintln(out, string(data))
+		return
+	}
+
+	fmt.Fprintln(out, string(pretty))
+}
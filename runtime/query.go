@@ -0,0 +1,38 @@
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/jmespath/go-jmespath"
+)
+
+// Query is a compiled JMESPath expression (https://jmespath.org), used by
+// --query to filter and reshape responses before they are printed, e.g.
+// "data[?status=='open'].{id: id, title: title}"
+type Query struct {
+	expr     string
+	compiled *jmespath.JMESPath
+}
+
+// CompileQuery parses a JMESPath expression
+func CompileQuery(expr string) (*Query, error) {
+	compiled, err := jmespath.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query %q: %w", expr, err)
+	}
+	return &Query{expr: expr, compiled: compiled}, nil
+}
+
+// String returns the source expression
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Search evaluates the query against decoded JSON data
+func (q *Query) Search(data interface{}) (interface{}, error) {
+	v, err := q.compiled.Search(data)
+	if err != nil {
+		return nil, fmt.Errorf("query %q: %w", q.expr, err)
+	}
+	return v, nil
+}
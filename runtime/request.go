@@ -0,0 +1,220 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pathParamRegex matches path parameters like {id} or {userId}
+var pathParamRegex = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Request represents an HTTP request to be executed
+type Request struct {
+	Method      string
+	Path        string
+	PathParams  map[string]string
+	QueryParams map[string]string
+	Headers     map[string]string
+	Body        []byte
+	Envelope    *Envelope
+	Pagination  *Pagination
+	Columns     []string      // default table columns
+	Events      *EventOptions // how an event stream response is printed
+	Stream      bool          // the response is a long-lived event stream
+	Accept      string        // media type asked for with --accept
+
+	// Operational hints from the spec (x-rate-cost, x-expected-latency)
+	RateCost        float64
+	ExpectedLatency time.Duration
+}
+
+// NewRequest creates a new Request
+func NewRequest(method, path string) *Request {
+	return &Request{
+		Method:      method,
+		Path:        path,
+		PathParams:  make(map[string]string),
+		QueryParams: make(map[string]string),
+		Headers:     make(map[string]string),
+	}
+}
+
+// SetPathParam sets a path parameter
+func (r *Request) SetPathParam(name, value string) {
+	r.PathParams[name] = value
+}
+
+// SetQueryParam sets a query parameter
+func (r *Request) SetQueryParam(name, value string) {
+	r.QueryParams[name] = value
+}
+
+// SetHeader sets a header
+func (r *Request) SetHeader(name, value string) {
+	r.Headers[name] = value
+}
+
+// SetBody sets the request body
+func (r *Request) SetBody(body []byte) {
+	r.Body = body
+}
+
+// SetAccept asks for the response as mediaType, e.g. text/csv, with the
+// Accept header. A response that is then not JSON is printed exactly as
+// received, as with --raw.
+func (r *Request) SetAccept(mediaType string) {
+	r.Accept = mediaType
+	r.Headers["Accept"] = mediaType
+}
+
+// SetEnvelope marks the response as a paginated envelope whose items, total
+// count and next cursor live at the given dotted paths
+func (r *Request) SetEnvelope(items, total, next string) {
+	r.Envelope = &Envelope{Items: items, Total: total, Next: next}
+}
+
+// SetPagination records how to request the page after each response, for
+// DoAll. Empty parameter names are ignored.
+func (r *Request) SetPagination(cursorParam, pageParam string, linkHeader bool) {
+	r.Pagination = &Pagination{CursorParam: cursorParam, PageParam: pageParam, LinkHeader: linkHeader}
+}
+
+// SetColumns sets the default columns of table output, as dotted paths into
+// each item
+func (r *Request) SetColumns(columns ...string) {
+	r.Columns = columns
+}
+
+// SetEvents sets how an event stream response is handled: only events of
+// the given types (all when none are given), with metadata each event as a
+// {"event", "id", "data"} JSON line, and with exec each event passed to a
+// shell command instead of being printed
+func (r *Request) SetEvents(names []string, metadata bool, exec string) {
+	r.Events = &EventOptions{Names: names, Metadata: metadata, Exec: exec}
+}
+
+// SetStream marks the response as a long-lived event stream, which is not
+// limited by the runtime's request timeout
+func (r *Request) SetStream() {
+	r.Stream = true
+}
+
+// SetHints records the operation's rate-limit cost and expected latency,
+// which --verbose logs and Batch paces its lines by
+func (r *Request) SetHints(rateCost float64, expectedLatency time.Duration) {
+	r.RateCost = rateCost
+	r.ExpectedLatency = expectedLatency
+}
+
+// hintsKey is the context key of the hints of the Request an http.Request
+// was built from
+type hintsKey struct{}
+
+// requestHints are the hints of a Request, carried in the context of its
+// http.Request for the middleware
+type requestHints struct {
+	rateCost        float64
+	expectedLatency time.Duration
+}
+
+// withHints returns ctx carrying the hints of r, when it has any
+func (r *Request) withHints(ctx context.Context) context.Context {
+	if r.RateCost == 0 && r.ExpectedLatency == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, hintsKey{}, requestHints{r.RateCost, r.ExpectedLatency})
+}
+
+// hintsOf returns the hints of the Request req was built from
+func hintsOf(req *http.Request) (requestHints, bool) {
+	hints, ok := req.Context().Value(hintsKey{}).(requestHints)
+	return hints, ok
+}
+
+// String describes the hints, e.g. "rate cost 5, expected latency 1.5s"
+func (h requestHints) String() string {
+	var parts []string
+	if h.rateCost != 0 {
+		parts = append(parts, "rate cost "+strconv.FormatFloat(h.rateCost, 'f', -1, 64))
+	}
+	if h.expectedLatency != 0 {
+		parts = append(parts, "expected latency "+h.expectedLatency.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Build creates an http.Request from this Request
+func (r *Request) Build(ctx context.Context, baseURL string) (*http.Request, error) {
+	// Validate all path parameters are provided
+	matches := pathParamRegex.FindAllStringSubmatch(r.Path, -1)
+	var missing []string
+	for _, match := range matches {
+		paramName := match[1]
+		if _, ok := r.PathParams[paramName]; !ok {
+			missing = append(missing, paramName)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required path parameter(s): %s", strings.Join(missing, ", "))
+	}
+
+	// Split off a query string embedded in the path (e.g. raw api calls)
+	path, rawQuery, _ := strings.Cut(r.Path, "?")
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query string in path: %w", err)
+	}
+
+	// Substitute path parameters
+	for name, value := range r.PathParams {
+		placeholder := "{" + name + "}"
+		path = strings.ReplaceAll(path, placeholder, url.PathEscape(value))
+	}
+
+	// Build full URL
+	fullURL := strings.TrimSuffix(baseURL, "/") + path
+
+	// Add query parameters
+	for name, value := range r.QueryParams {
+		params.Set(name, value)
+	}
+	if len(params) > 0 {
+		fullURL += "?" + params.Encode()
+	}
+
+	// Create request
+	var bodyReader *bytes.Reader
+	if r.Body != nil {
+		bodyReader = bytes.NewReader(r.Body)
+	}
+
+	ctx = r.withHints(ctx)
+	var req *http.Request
+	if bodyReader != nil {
+		req, err = http.NewRequestWithContext(ctx, r.Method, fullURL, bodyReader)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, r.Method, fullURL, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set headers
+	for name, value := range r.Headers {
+		req.Header.Set(name, value)
+	}
+
+	// Set content-type for JSON body
+	if r.Body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
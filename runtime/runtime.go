@@ -0,0 +1,304 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Runtime provides HTTP execution capabilities for the CLI
+type Runtime struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Headers    map[string]string
+	headersMu  sync.RWMutex
+	ExtraQuery url.Values // added to the query of every request
+	Timeout    time.Duration
+	Output     io.Writer
+	ErrOutput  io.Writer
+	Format     string
+	Columns    []string           // table columns, replacing each request's defaults
+	Query      *Query             // optional; filters JSON responses before they are printed
+	Template   *template.Template // for the go-template format
+	Raw        bool               // print response bodies exactly as received
+	Quiet      bool               // print only the identifiers of responses
+	OutputFile string             // write response bodies to this file instead of Output
+	Audit      *AuditLog          // optional; records every request when set
+	ReadOnly   bool               // refuse to send anything but GET and HEAD requests
+	Signer     Signer             // optional; signs each request just before it is sent
+	Debug      *DebugLog          // optional; logs each request and response
+	Log        *RequestLog        // optional; logs each request and response as JSON
+	Timing     *Timing            // optional; writes how long each request took
+	Tracer     *Tracer            // optional; traces requests with OpenTelemetry
+	DryRun     bool               // print requests to Output instead of sending them
+	middleware []Middleware
+
+	// StreamTimeout and IdleTimeout replace Timeout for event streams: the
+	// longest a stream may run, and the longest it may go without data (0
+	// for no limit)
+	StreamTimeout time.Duration
+	IdleTimeout   time.Duration
+
+	// DisableCompression stops requesting gzip or deflate encoded responses,
+	// for servers that mishandle them
+	DisableCompression bool
+
+	// PageConcurrency is the most pages DoAll fetches at once, when the
+	// first page of a page-numbered list gives the total count; below 2,
+	// pages are fetched one by one
+	PageConcurrency int
+
+	// BatchRate is the most rate-limit cost Batch spends per second, each
+	// request costing its operation's x-rate-cost, or 1 without one; 0 for
+	// no limit
+	BatchRate float64
+
+	// MaxRedirects is how many redirects a request may follow, and NoFollow
+	// prints where a redirect leads instead of following it
+	MaxRedirects int
+	NoFollow     bool
+}
+
+// ErrReadOnly is returned when a mutating request is attempted in read-only mode
+var ErrReadOnly = errors.New("read-only mode")
+
+// New creates a new Runtime with the given configuration
+func New(baseURL string, timeout time.Duration) *Runtime {
+	return &Runtime{
+		BaseURL: baseURL,
+		HTTPClient: &http.Client{
+			Timeout: timeout,
+		},
+		Headers:      make(map[string]string),
+		Timeout:      timeout,
+		IdleTimeout:  DefaultIdleTimeout,
+		MaxRedirects: DefaultMaxRedirects,
+		Output:       os.Stdout,
+		ErrOutput:    os.Stderr,
+		Format:       FormatJSON,
+	}
+}
+
+// AddHeader adds a header to all requests, replacing any header of the same
+// name regardless of case. An Accept header does not override a request's
+// SetAccept.
+func (r *Runtime) AddHeader(key, value string) {
+	r.headersMu.Lock()
+	defer r.headersMu.Unlock()
+	r.Headers[http.CanonicalHeaderKey(key)] = value
+}
+
+// ParseHeader parses a header given as "Name: value", as with curl -H. The
+// value may be empty.
+func ParseHeader(header string) (name, value string, err error) {
+	name, value, ok := strings.Cut(header, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("invalid header %q (must be \"Name: value\")", header)
+	}
+	return name, strings.TrimSpace(value), nil
+}
+
+// AddQueryParam adds a query parameter to all requests, after any the
+// request itself has
+func (r *Runtime) AddQueryParam(name, value string) {
+	if r.ExtraQuery == nil {
+		r.ExtraQuery = url.Values{}
+	}
+	r.ExtraQuery.Add(name, value)
+}
+
+// ParseQueryParam parses a query parameter given as "name=value". The value
+// may be empty.
+func ParseQueryParam(param string) (name, value string, err error) {
+	name, value, ok := strings.Cut(param, "=")
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid query parameter %q (must be \"name=value\")", param)
+	}
+	return name, value, nil
+}
+
+// Do executes an HTTP request and handles the response. When ctx is
+// canceled, the request or the output in progress is ended and
+// ErrInterrupted is returned.
+func (r *Runtime) Do(ctx context.Context, req *Request) (err error) {
+	defer func() { err = interruption(ctx, err) }()
+
+	httpReq, err := r.build(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if r.DryRun {
+		writeDryRun(r.Output, httpReq, req.Body)
+		return nil
+	}
+
+	resp, err := r.send(httpReq, req.Stream)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if r.NoFollow && isRedirect(resp) {
+		return writeRedirect(resp, r.Output, r.ErrOutput)
+	}
+
+	// HEAD responses have no body and OPTIONS responses rarely do; what they
+	// tell, e.g. Allow or rate limits, is in the status and headers, which
+	// are printed masked as by --verbose
+	if (req.Method == http.MethodHead || req.Method == http.MethodOptions) && resp.StatusCode < 300 && !r.Raw && !r.Quiet {
+		fmt.Fprintf(r.Output, "%s %s\n", resp.Proto, resp.Status)
+		writeHeaders(r.Output, "", resp.Header)
+		if req.Method == http.MethodHead || !hasBody(resp) {
+			return nil
+		}
+		fmt.Fprintln(r.Output)
+	}
+
+	if r.OutputFile != "" {
+		return r.download(resp)
+	}
+
+	// Check for SSE response
+	contentType := resp.Header.Get("Content-Type")
+	if isEventStream(contentType) {
+		return handleSSE(resp.Body, r.Output, outputOptions{ErrOut: r.ErrOutput}.errOut(), req.Events)
+	}
+
+	opts := r.outputOptions(req)
+	if isNDJSON(contentType) && !r.Raw && resp.StatusCode < 300 {
+		return handleNDJSON(resp.Body, r.Output, opts)
+	}
+
+	// Binary bodies are passed through untouched, and never printed to a
+	// terminal, where they would garble the screen
+	if !r.Raw && !r.Quiet && resp.StatusCode < 300 {
+		if binary, contentType := binaryBody(resp); binary {
+			if isTerminal(r.Output) {
+				return fmt.Errorf("refusing to print a binary response (%s) to the terminal; save it with --output-file, or print it anyway with --raw", contentType)
+			}
+			opts.Raw = true
+		}
+	}
+
+	// Another format than JSON asked for with --accept, e.g. CSV, is printed
+	// as received rather than reformatted
+	if req.Accept != "" && resp.StatusCode < 300 && !isJSONMediaType(contentType) {
+		opts.Raw = true
+	}
+
+	// Handle regular response
+	return handleResponse(resp, r.Output, opts)
+}
+
+// outputOptions returns how req's response is rendered
+func (r *Runtime) outputOptions(req *Request) outputOptions {
+	return outputOptions{
+		Format:   r.Format,
+		Envelope: req.Envelope,
+		Columns:  r.columns(req),
+		Query:    r.Query,
+		Template: r.Template,
+		Raw:      r.Raw,
+		Quiet:    r.Quiet,
+		ErrOut:   r.ErrOutput,
+	}
+}
+
+// columns returns the table columns for req's response
+func (r *Runtime) columns(req *Request) []string {
+	if len(r.Columns) > 0 {
+		return r.Columns
+	}
+	return req.Columns
+}
+
+// build checks that req may be sent and creates the HTTP request for it,
+// with the runtime headers added
+func (r *Runtime) build(ctx context.Context, req *Request) (*http.Request, error) {
+	if r.ReadOnly && req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return nil, fmt.Errorf("%w: refusing to send %s %s (only GET and HEAD are allowed)", ErrReadOnly, req.Method, req.Path)
+	}
+
+	httpReq, err := req.Build(ctx, r.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if len(r.ExtraQuery) > 0 {
+		query := httpReq.URL.Query()
+		for name, values := range r.ExtraQuery {
+			query[name] = append(query[name], values...)
+		}
+		httpReq.URL.RawQuery = query.Encode()
+	}
+
+	// Add runtime headers
+	r.headersMu.RLock()
+	for k, v := range r.Headers {
+		httpReq.Header.Set(k, v)
+	}
+	r.headersMu.RUnlock()
+	// --accept chooses among the operation's content types, so a global
+	// Accept must not replace it
+	if req.Accept != "" {
+		httpReq.Header.Set("Accept", req.Accept)
+	}
+
+	return httpReq, nil
+}
+
+// send sends httpReq and records it in the audit log. A stream is only
+// limited by StreamTimeout and IdleTimeout, not the client's timeout.
+func (r *Runtime) send(httpReq *http.Request, stream bool) (*http.Response, error) {
+	ctx := httpReq.Context()
+	client := r.client()
+	var idle *idleTimeout
+	if stream {
+		streamClient := *client
+		streamClient.Timeout = r.StreamTimeout
+		client = &streamClient
+		if r.IdleTimeout > 0 {
+			httpReq, idle = withIdleTimeout(httpReq, r.IdleTimeout)
+		}
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		err = idle.explain(err)
+		idle.stop()
+		if auditErr := r.record(httpReq, 0, err); auditErr != nil {
+			return nil, auditErr
+		}
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, ErrInterrupted
+		}
+		return nil, &NetworkError{Err: err}
+	}
+
+	resp.Body = idle.wrap(resp.Body)
+	if err := r.record(httpReq, resp.StatusCode, nil); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// record writes an audit entry for the request when auditing is enabled
+func (r *Runtime) record(httpReq *http.Request, status int, reqErr error) error {
+	if r.Audit == nil {
+		return nil
+	}
+	if err := r.Audit.Record(httpReq.Method, httpReq.URL.String(), status, reqErr); err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	goruntime "runtime"
+	"strings"
+)
+
+// MaxSSEEventSize is the maximum allowed size for a single SSE event (10MB)
+const MaxSSEEventSize = 10 * 1024 * 1024
+
+// ErrSSEEventTooLarge is returned when an SSE event exceeds MaxSSEEventSize
+var ErrSSEEventTooLarge = errors.New("SSE event data exceeds maximum allowed size")
+
+// isEventStream checks if content type indicates SSE
+func isEventStream(contentType string) bool {
+	return strings.Contains(contentType, "text/event-stream")
+}
+
+// EventOptions controls how a Server-Sent Events stream is printed
+type EventOptions struct {
+	Names    []string // only print events of these types; every event when empty
+	Metadata bool     // print each event as one {"event", "id", "data"} JSON line
+	Exec     string   // run this shell command for each event instead of printing it
+}
+
+// sseEvent is an event as printed with EventOptions.Metadata
+type sseEvent struct {
+	Event string      `json:"event"`
+	ID    string      `json:"id,omitempty"`
+	Data  interface{} `json:"data"`
+}
+
+// wants reports whether events of type name are printed
+func (o *EventOptions) wants(name string) bool {
+	if o == nil || len(o.Names) == 0 {
+		return true
+	}
+	for _, n := range o.Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSSE handles Server-Sent Events response, printing the data of each
+// event as it arrives
+func handleSSE(reader io.Reader, out, errOut io.Writer, opts *EventOptions) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxSSEEventSize+len("data: ")+1)
+	var dataBuffer strings.Builder
+	eventName, lastID := "", ""
+
+	dispatch := func() error {
+		data := strings.TrimSpace(dataBuffer.String())
+		name := eventName
+		if name == "" {
+			name = "message"
+		}
+		dataBuffer.Reset()
+		eventName = ""
+		if data == "" || !opts.wants(name) {
+			return nil
+		}
+		event := sseEvent{Event: name, ID: lastID, Data: data}
+		if opts != nil && opts.Exec != "" {
+			runEventCommand(opts, event, out, errOut)
+			return nil
+		}
+		return writeEvent(out, opts, event)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Empty line signals end of event
+		if line == "" {
+			if err := dispatch(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			// Comment/keep-alive, skip
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data := strings.TrimSpace(value)
+
+			// Check buffer size limit before appending
+			newSize := dataBuffer.Len() + len(data) + 1 // +1 for potential newline
+			if newSize > MaxSSEEventSize {
+				return ErrSSEEventTooLarge
+			}
+
+			if dataBuffer.Len() > 0 {
+				dataBuffer.WriteString("\n")
+			}
+			dataBuffer.WriteString(data)
+		case "event":
+			eventName = value
+		case "id":
+			// The last event ID carries over to following events
+			if !strings.ContainsRune(value, 0) {
+				lastID = value
+			}
+		}
+		// retry and unknown fields are ignored
+	}
+
+	// Handle any remaining data
+	if err := dispatch(); err != nil {
+		return err
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return ErrSSEEventTooLarge
+		}
+		return fmt.Errorf("error reading SSE stream: %w", err)
+	}
+
+	return nil
+}
+
+// writeEvent prints the data of event, pretty-printed when it is JSON, or
+// with EventOptions.Metadata the whole event on one line
+func writeEvent(out io.Writer, opts *EventOptions, event sseEvent) error {
+	data := event.Data.(string)
+	if opts == nil || !opts.Metadata {
+		if isJSON([]byte(data)) {
+			prettyPrint([]byte(data), out)
+		} else {
+			fmt.Fprintln(out, data)
+		}
+		return nil
+	}
+
+	line, err := event.metadataJSON()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, string(line))
+	return nil
+}
+
+// metadataJSON encodes the event with its type and ID, decoding JSON data
+func (e sseEvent) metadataJSON() ([]byte, error) {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(e.Data.(string)), &parsed); err == nil {
+		e.Data = parsed
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	return line, nil
+}
+
+// runEventCommand runs opts.Exec through the shell with the event's data,
+// or with Metadata the event as JSON, on stdin and its type and ID in the
+// EVENT_NAME and EVENT_ID environment variables. A failing command is
+// reported on errOut without ending the stream.
+func runEventCommand(opts *EventOptions, event sseEvent, out, errOut io.Writer) {
+	input := []byte(event.Data.(string))
+	if opts.Metadata {
+		line, err := event.metadataJSON()
+		if err != nil {
+			fmt.Fprintf(errOut, "exec: %v\n", err)
+			return
+		}
+		input = line
+	}
+
+	cmd := shellCommand(opts.Exec)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	cmd.Env = append(os.Environ(), "EVENT_NAME="+event.Event, "EVENT_ID="+event.ID)
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(errOut, "exec: %s event %s: %v\n", event.Event, event.ID, err)
+	}
+}
+
+// shellCommand returns a command running command through the system shell
+func shellCommand(command string) *exec.Cmd {
+	if goruntime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
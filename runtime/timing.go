@@ -0,0 +1,126 @@
+package runtime
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timing writes how long each phase of every request took to Out, to
+// diagnose latency: DNS lookup, TCP connect, TLS handshake, time to the
+// first response byte and total time, up to the end of the response body.
+// Phases a reused connection skips are left out.
+type Timing struct {
+	Out io.Writer
+}
+
+// requestTiming records the phases of one request, from httptrace hooks
+// that may run on other goroutines
+type requestTiming struct {
+	mu                       sync.Mutex
+	start                    time.Time
+	dnsStart, dnsDone        time.Time
+	connectStart, connectEnd time.Time
+	tlsStart, tlsDone        time.Time
+	firstByte                time.Time
+	reused                   bool
+}
+
+// mark records the time of a phase boundary, the first time it is reached
+func (rt *requestTiming) mark(t *time.Time) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if t.IsZero() {
+		*t = time.Now()
+	}
+}
+
+// trace returns the hooks that fill in rt
+func (rt *requestTiming) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { rt.mark(&rt.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { rt.mark(&rt.dnsDone) },
+		ConnectStart:         func(string, string) { rt.mark(&rt.connectStart) },
+		ConnectDone:          func(string, string, error) { rt.mark(&rt.connectEnd) },
+		TLSHandshakeStart:    func() { rt.mark(&rt.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { rt.mark(&rt.tlsDone) },
+		GotFirstResponseByte: func() { rt.mark(&rt.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			rt.mu.Lock()
+			rt.reused = info.Reused
+			rt.mu.Unlock()
+		},
+	}
+}
+
+// summary describes the phases of the request, ended at end
+func (rt *requestTiming) summary(end time.Time) string {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	var phases []string
+	phase := func(name string, from, to time.Time) {
+		if !from.IsZero() && !to.IsZero() {
+			phases = append(phases, name+" "+roundTiming(to.Sub(from)))
+		}
+	}
+	if rt.reused {
+		phases = append(phases, "reused connection")
+	}
+	phase("dns", rt.dnsStart, rt.dnsDone)
+	phase("connect", rt.connectStart, rt.connectEnd)
+	phase("tls", rt.tlsStart, rt.tlsDone)
+	phase("ttfb", rt.start, rt.firstByte)
+	phase("total", rt.start, end)
+	return strings.Join(phases, ", ")
+}
+
+// roundTiming rounds d for display, keeping sub-millisecond detail
+func roundTiming(d time.Duration) string {
+	return d.Round(100 * time.Microsecond).String()
+}
+
+// transport times requests around next. The line for a request is written
+// once its response body is read to the end or closed.
+func (t *Timing) transport(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		timing := &requestTiming{start: time.Now()}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), timing.trace()))
+		prefix := fmt.Sprintf("* timing %s %s: ", req.Method, redactQuery(req.URL))
+
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			fmt.Fprintf(t.Out, "%s%s, failed: %v\n", prefix, timing.summary(time.Now()), err)
+			return resp, err
+		}
+		resp.Body = &timedBody{ReadCloser: resp.Body, done: func() {
+			fmt.Fprintf(t.Out, "%s%s\n", prefix, timing.summary(time.Now()))
+		}}
+		return resp, nil
+	})
+}
+
+// timedBody calls done once, when the body is read to the end or closed
+type timedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.done)
+	}
+	return n, err
+}
+
+func (b *timedBody) Close() error {
+	b.once.Do(b.done)
+	return b.ReadCloser.Close()
+}
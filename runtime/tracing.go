@@ -0,0 +1,263 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceParentHeader is the W3C Trace Context header requests carry, so the
+// API's spans join the CLI's trace
+const TraceParentHeader = "traceparent"
+
+// tracerExportTimeout bounds how long End waits for the collector
+const tracerExportTimeout = 5 * time.Second
+
+// Tracer records an OpenTelemetry trace of a command: one span for the
+// command and a child span for every request it sends, each request carrying
+// a traceparent header. End sends the spans to an OTLP/HTTP collector as
+// JSON, so CLI calls can be correlated with backend traces.
+type Tracer struct {
+	// Endpoint is the collector's base URL; spans are posted to
+	// Endpoint + "/v1/traces"
+	Endpoint string
+	Headers  map[string]string // sent with the export, e.g. collector credentials
+	Service  string
+	Version  string
+
+	// HTTPClient sends the export, e.g. the runtime's BaseClient so it goes
+	// through the same proxy and TLS settings; http.DefaultClient when nil
+	HTTPClient *http.Client
+
+	traceID [16]byte
+	root    *span
+
+	mu    sync.Mutex
+	spans []*span
+}
+
+// span is a finished or running span of the trace
+type span struct {
+	id         [8]byte
+	parentID   [8]byte
+	name       string
+	kind       int
+	start, end time.Time
+	attributes map[string]interface{}
+	failed     bool
+	message    string
+}
+
+// OTLP span kinds
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+)
+
+// NewTracer starts the trace of a command named command. When traceParent
+// is a valid traceparent value, e.g. from the TRACEPARENT environment
+// variable of a CI job, the command's span joins that trace.
+func NewTracer(endpoint, service, version, command, traceParent string) (*Tracer, error) {
+	t := &Tracer{Endpoint: strings.TrimSuffix(endpoint, "/"), Service: service, Version: version}
+	root := &span{name: command, kind: spanKindInternal, start: time.Now()}
+	if traceID, parentID, ok := parseTraceParent(traceParent); ok {
+		t.traceID, root.parentID = traceID, parentID
+	} else if _, err := rand.Read(t.traceID[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate a trace ID: %w", err)
+	}
+	if _, err := rand.Read(root.id[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate a span ID: %w", err)
+	}
+	t.root = root
+	t.spans = append(t.spans, root)
+	return t, nil
+}
+
+// parseTraceParent parses a version 00 traceparent value
+func parseTraceParent(value string) (traceID [16]byte, parentID [8]byte, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return traceID, parentID, false
+	}
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || traceID == [16]byte{} {
+		return traceID, parentID, false
+	}
+	if _, err := hex.Decode(parentID[:], []byte(parts[2])); err != nil || parentID == [8]byte{} {
+		return traceID, parentID, false
+	}
+	return traceID, parentID, true
+}
+
+// traceParent returns the traceparent value naming s as the parent
+func (t *Tracer) traceParent(s *span) string {
+	return fmt.Sprintf("00-%x-%x-01", t.traceID, s.id)
+}
+
+// transport records a client span for each request around next and sends
+// the span's traceparent with it. The span ends once the response body is
+// read to the end or closed.
+func (t *Tracer) transport(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		s := &span{
+			parentID: t.root.id,
+			name:     req.Method,
+			kind:     spanKindClient,
+			start:    time.Now(),
+			attributes: map[string]interface{}{
+				"http.request.method": req.Method,
+				"url.full":            redactQuery(req.URL),
+				"server.address":      req.URL.Hostname(),
+			},
+		}
+		if _, err := rand.Read(s.id[:]); err != nil {
+			return nil, fmt.Errorf("failed to generate a span ID: %w", err)
+		}
+		req = req.Clone(req.Context())
+		req.Header.Set(TraceParentHeader, t.traceParent(s))
+
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			s.failed, s.message = true, err.Error()
+			t.finish(s)
+			return resp, err
+		}
+		s.attributes["http.response.status_code"] = resp.StatusCode
+		s.failed = resp.StatusCode >= 400
+		resp.Body = &timedBody{ReadCloser: resp.Body, done: func() { t.finish(s) }}
+		return resp, nil
+	})
+}
+
+// finish ends s and adds it to the trace
+func (t *Tracer) finish(s *span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.end = time.Now()
+	t.spans = append(t.spans, s)
+}
+
+// End ends the command's span, failed when err is not nil, and exports the
+// trace. Spans of requests whose response was never read are left out.
+func (t *Tracer) End(err error) error {
+	t.mu.Lock()
+	t.root.end = time.Now()
+	if err != nil {
+		t.root.failed, t.root.message = true, err.Error()
+	}
+	payload, encErr := json.Marshal(t.otlp())
+	t.mu.Unlock()
+	if encErr != nil {
+		return fmt.Errorf("failed to encode trace: %w", encErr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracerExportTimeout)
+	defer cancel()
+	req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, t.Endpoint+"/v1/traces", bytes.NewReader(payload))
+	if reqErr != nil {
+		return fmt.Errorf("failed to export trace: %w", reqErr)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.Headers {
+		req.Header.Set(k, v)
+	}
+	client := t.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, sendErr := client.Do(req)
+	if sendErr != nil {
+		return fmt.Errorf("failed to export trace: %w", sendErr)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to export trace: collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// otlp returns the trace as an OTLP/JSON ExportTraceServiceRequest
+func (t *Tracer) otlp() map[string]interface{} {
+	resource := map[string]interface{}{"service.name": t.Service}
+	if t.Version != "" {
+		resource["service.version"] = t.Version
+	}
+
+	spans := make([]map[string]interface{}, 0, len(t.spans))
+	for _, s := range t.spans {
+		out := map[string]interface{}{
+			"traceId":           hex.EncodeToString(t.traceID[:]),
+			"spanId":            hex.EncodeToString(s.id[:]),
+			"name":              s.name,
+			"kind":              s.kind,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        otlpAttributes(s.attributes),
+		}
+		if s.parentID != [8]byte{} {
+			out["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+		}
+		if s.failed {
+			out["status"] = map[string]interface{}{"code": 2, "message": s.message}
+		}
+		spans = append(spans, out)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": otlpAttributes(resource)},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "opencligen"},
+				"spans": spans,
+			}},
+		}},
+	}
+}
+
+// otlpAttributes encodes attributes as OTLP key-value pairs
+func otlpAttributes(attributes map[string]interface{}) []map[string]interface{} {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := make([]map[string]interface{}, 0, len(attributes))
+	for _, key := range keys {
+		var value map[string]interface{}
+		switch v := attributes[key].(type) {
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		out = append(out, map[string]interface{}{"key": key, "value": value})
+	}
+	return out
+}
+
+// OTLPHeaders parses the OTEL_EXPORTER_OTLP_HEADERS format, comma-separated
+// key=value pairs with URL-encoded values
+func OTLPHeaders(value string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(strings.TrimSpace(v)); err == nil {
+			v = unescaped
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return headers
+}
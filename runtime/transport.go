@@ -0,0 +1,290 @@
+package runtime
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// transport returns the runtime's own *http.Transport so connection settings
+// can be changed without affecting http.DefaultTransport. By default it
+// takes the proxy from HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+func (r *Runtime) transport() (*http.Transport, error) {
+	switch t := r.HTTPClient.Transport.(type) {
+	case nil:
+		own := http.DefaultTransport.(*http.Transport).Clone()
+		r.HTTPClient.Transport = own
+		return own, nil
+	case *http.Transport:
+		return t, nil
+	}
+	return nil, errors.New("the HTTP client has a custom transport that cannot be configured")
+}
+
+// SetProxy sends every request through the proxy at proxyURL, in place of
+// the proxy set by HTTP_PROXY, HTTPS_PROXY and NO_PROXY. A URL without a
+// scheme, e.g. proxy.example.com:8080, is an HTTP proxy; socks5://host:port
+// is a SOCKS5 proxy, which resolves host names itself. Credentials in the
+// URL are sent to the proxy.
+func (r *Runtime) SetProxy(proxyURL string) error {
+	proxy, err := ParseProxyURL(proxyURL)
+	if err != nil {
+		return err
+	}
+	t, err := r.transport()
+	if err != nil {
+		return err
+	}
+	t.Proxy = http.ProxyURL(proxy)
+	return nil
+}
+
+// ParseProxyURL parses and checks a proxy URL, defaulting the scheme to http
+func ParseProxyURL(proxyURL string) (*url.URL, error) {
+	if !strings.Contains(proxyURL, "://") {
+		proxyURL = "http://" + proxyURL
+	}
+	proxy, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: unsupported scheme %q (must be http, https, socks5 or socks5h)", proxyURL, proxy.Scheme)
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", proxyURL)
+	}
+	return proxy, nil
+}
+
+// UseAllProxy sends requests through the proxy set by ALL_PROXY, as curl
+// does, when HTTP_PROXY and HTTPS_PROXY set none for their scheme. NO_PROXY
+// and the exemption of loopback hosts still apply. Without ALL_PROXY, the
+// transport is left alone.
+func (r *Runtime) UseAllProxy() error {
+	allProxy := getenvAny("ALL_PROXY", "all_proxy")
+	if allProxy == "" {
+		return nil
+	}
+	proxy, err := ParseProxyURL(allProxy)
+	if err != nil {
+		return fmt.Errorf("ALL_PROXY: %w", err)
+	}
+	t, err := r.transport()
+	if err != nil {
+		return err
+	}
+	t.Proxy = func(req *http.Request) (*url.URL, error) {
+		if fromEnv, err := http.ProxyFromEnvironment(req); fromEnv != nil || err != nil {
+			return fromEnv, err
+		}
+		// A scheme proxy that is set but returned no proxy was skipped for
+		// this host, which ALL_PROXY must respect too
+		if schemeProxy(req.URL.Scheme) != "" || !useProxy(req.URL) {
+			return nil, nil
+		}
+		return proxy, nil
+	}
+	return nil
+}
+
+// schemeProxy returns the proxy the environment sets for scheme
+func schemeProxy(scheme string) string {
+	if scheme == "https" {
+		return getenvAny("HTTPS_PROXY", "https_proxy")
+	}
+	return getenvAny("HTTP_PROXY", "http_proxy")
+}
+
+// useProxy reports whether requests to u go through a proxy, with the
+// NO_PROXY rules of http.ProxyFromEnvironment: not for localhost or loopback
+// addresses, nor for hosts NO_PROXY exempts. Entries are "*", CIDR ranges,
+// IP addresses, and domains, which match the domain and its subdomains;
+// ".example.com" and "*.example.com" match only subdomains. An entry's port,
+// if any, must match the request's.
+//
+// This mirrors golang.org/x/net/http/httpproxy, which the standard library
+// vendors but does not export. http.ProxyFromEnvironment has no ALL_PROXY
+// and reads the environment only once, and depending on x/net would add a
+// module to every generated CLI for one function, so the rules are kept
+// here and tested against http.ProxyFromEnvironment's results.
+func useProxy(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return false
+	}
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+
+	for _, entry := range strings.Split(getenvAny("NO_PROXY", "no_proxy"), ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return false
+		}
+		if strings.Contains(entry, "/") {
+			if _, cidr, err := net.ParseCIDR(entry); err == nil && ip != nil && cidr.Contains(ip) {
+				return false
+			}
+			continue
+		}
+		entryPort := ""
+		if entryHost, p, err := net.SplitHostPort(entry); err == nil {
+			entry, entryPort = entryHost, p
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		if entryIP := net.ParseIP(entry); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return false
+			}
+			continue
+		}
+		if domain, ok := strings.CutPrefix(entry, "*."); ok {
+			entry = "." + domain
+		}
+		if strings.HasPrefix(entry, ".") {
+			if strings.HasSuffix(host, entry) {
+				return false
+			}
+		} else if host == entry || strings.HasSuffix(host, "."+entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// getenvAny returns the first of the environment variables names that is set
+func getenvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// SetResolve connects to fixed addresses in place of resolving host names,
+// like curl --resolve, e.g. to reach a canary host behind shared DNS. Each
+// entry is host:port:address, where port may be * for any port, e.g.
+// api.example.com:443:10.0.0.5. The request still names the host, so the
+// Host header, SNI and certificate checks are unchanged. Through a proxy,
+// only the proxy's own address is overridden.
+func (r *Runtime) SetResolve(entries []string) error {
+	addresses := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		hostPort, address, err := parseResolve(entry)
+		if err != nil {
+			return err
+		}
+		addresses[hostPort] = address
+	}
+	t, err := r.transport()
+	if err != nil {
+		return err
+	}
+
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, port, err := net.SplitHostPort(addr); err == nil {
+			host = strings.ToLower(host)
+			if address, ok := addresses[net.JoinHostPort(host, port)]; ok {
+				addr = net.JoinHostPort(address, port)
+			} else if address, ok := addresses[net.JoinHostPort(host, "*")]; ok {
+				addr = net.JoinHostPort(address, port)
+			}
+		}
+		return dial(ctx, network, addr)
+	}
+	return nil
+}
+
+// parseResolve parses a --resolve entry into the host:port it overrides and
+// the IP address to connect to instead
+func parseResolve(entry string) (hostPort, address string, err error) {
+	parts := strings.SplitN(entry, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", fmt.Errorf("invalid --resolve %q (must be host:port:address)", entry)
+	}
+	host, port, address := strings.ToLower(parts[0]), parts[1], strings.Trim(parts[2], "[]")
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil && port != "*" {
+		return "", "", fmt.Errorf("invalid --resolve %q: port %q is not a number or *", entry, port)
+	}
+	if net.ParseIP(address) == nil {
+		return "", "", fmt.Errorf("invalid --resolve %q: %q is not an IP address", entry, address)
+	}
+	return net.JoinHostPort(host, port), address, nil
+}
+
+// TLSOptions configures how the runtime verifies servers and authenticates
+// to them
+type TLSOptions struct {
+	CACert             string // PEM file of CA certificates trusted in addition to the system's
+	InsecureSkipVerify bool   // accept any server certificate
+	ClientCert         string // PEM file of a client certificate for mutual TLS
+	ClientKey          string // PEM file of its private key; defaults to ClientCert
+}
+
+// SetTLS applies opts to every request
+func (r *Runtime) SetTLS(opts TLSOptions) error {
+	config := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+
+	if opts.CACert != "" {
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return fmt.Errorf("failed to read CA certificates: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no PEM certificates found in %s", opts.CACert)
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.ClientKey != "" && opts.ClientCert == "" {
+		return errors.New("a client key requires a client certificate")
+	}
+	if opts.ClientCert != "" {
+		key := opts.ClientKey
+		if key == "" {
+			key = opts.ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, key)
+		if err != nil {
+			return fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	t, err := r.transport()
+	if err != nil {
+		return err
+	}
+	t.TLSClientConfig = config
+	return nil
+}